@@ -147,7 +147,10 @@ func App(modules []dingo.Module, options ...option) {
 	}, root.Modules...)
 
 	root.Modules = append(root.Modules, app)
-	config.Load(root, cfg.configDir)
+	if err := config.Load(root, cfg.configDir); err != nil {
+		fmt.Printf("parsing config from %s failed: %v\n", cfg.configDir, err)
+		os.Exit(-1)
+	}
 
 	rootCmd := root.Injector.GetAnnotatedInstance(new(cobra.Command), "flamingo").(*cobra.Command)
 	root.Injector.GetInstance(new(eventRouterProvider)).(eventRouterProvider)().Dispatch(context.Background(), new(flamingo.StartupEvent))
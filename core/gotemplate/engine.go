@@ -6,12 +6,15 @@ import (
 	"fmt"
 	"html/template"
 	"io"
-	"io/ioutil"
+	"net/http"
 	"net/url"
 	"os"
+	"path"
 	"path/filepath"
+	"regexp"
 	"strings"
 	"sync"
+	"text/template/parse"
 	"time"
 
 	"flamingo.me/flamingo/v3/framework/flamingo"
@@ -19,8 +22,6 @@ import (
 	"go.opencensus.io/trace"
 )
 
-const pathSeparatorString = string(os.PathSeparator)
-
 type (
 	templateFuncProvider func() map[string]flamingo.TemplateFunc
 
@@ -29,8 +30,42 @@ type (
 		layoutTemplatesDir string
 		debug              bool
 		tplFuncs           templateFuncProvider
-		templates          map[string]*template.Template
 		logger             flamingo.Logger
+		// fs contains the templates, the templates base path by default
+		fs http.FileSystem
+
+		// mu guards the layout and the compiled templates, renders of already compiled templates only share a read lock
+		mu        sync.RWMutex
+		layout    *compiledLayout
+		templates map[string]*compiledTemplate
+
+		// checkInterval throttles the checks for changed template files in debug mode
+		checkInterval time.Duration
+		checkMu       sync.Mutex
+		checked       time.Time
+	}
+
+	// compiledLayout contains all templates of the layout directory, it is the base of every compiled template
+	compiledLayout struct {
+		tpl   *template.Template
+		funcs *templateFuncs
+		dir   string
+		files fileStates
+	}
+
+	// compiledTemplate is a single template file, including the layout and the templates it extends.
+	// files contains the state of all files it has been compiled from. Templates failing to parse are cached as well,
+	// other failures, e.g. missing or unreadable files, are not cached.
+	// The pool contains executable clones of the template, so renders don't need to clone the template.
+	compiledTemplate struct {
+		tpl   *template.Template
+		err   error
+		files fileStates
+		funcs *templateFuncs
+		pool  sync.Pool
+		// blocks are the names of the blocks defined by the template file and the templates it extends,
+		// only these can be rendered as partials
+		blocks map[string]bool
 	}
 
 	urlRouter interface {
@@ -40,186 +75,385 @@ type (
 )
 
 var (
-	lock = &sync.Mutex{}
+	_ flamingo.TemplateEngine        = new(engine)
+	_ flamingo.PartialTemplateEngine = new(engine)
 )
 
+// debugCheckInterval is the minimum time between two checks for changed template files in debug mode
+const debugCheckInterval = time.Second
+
+// extendsDirective declares the parent template, it must be the first action of a template file
+var extendsDirective = regexp.MustCompile(`^\s*{{-?\s*extends\s+"([^"]+)"\s*-?}}`)
+
 // Inject engine dependencies
 func (e *engine) Inject(
 	tplFuncs templateFuncProvider,
 	logger flamingo.Logger,
 	config *struct {
-		TemplatesBasePath  string `inject:"config:gotemplates.engine.templates.basepath"`
-		LayoutTemplatesDir string `inject:"config:gotemplates.engine.layout.dir"`
-		Debug              bool   `inject:"config:debug.mode"`
+		TemplatesBasePath  string          `inject:"config:gotemplates.engine.templates.basepath"`
+		LayoutTemplatesDir string          `inject:"config:gotemplates.engine.layout.dir"`
+		Debug              bool            `inject:"config:debug.mode"`
+		FileSystem         http.FileSystem `inject:"flamingo.filesystem.templates,optional"`
 	},
 ) {
 	e.tplFuncs = tplFuncs
 	e.templatesBasePath = config.TemplatesBasePath
 	e.layoutTemplatesDir = config.LayoutTemplatesDir
 	e.debug = config.Debug
-	e.logger = logger
+	e.logger = logger.WithField(flamingo.LogKeyCategory, "gotemplate")
+	e.fs = config.FileSystem
+	if e.fs == nil {
+		e.fs = http.Dir(e.templatesBasePath)
+	}
+	e.checkInterval = debugCheckInterval
 }
 
+// Render the template with the given name, the name is the path of the template file without the .html suffix
 func (e *engine) Render(ctx context.Context, name string, data interface{}) (io.Reader, error) {
 	ctx, span := trace.StartSpan(ctx, "gotemplate/Render")
 	defer span.End()
 
-	lock.Lock()
-	if e.debug || e.templates == nil {
-		err := e.loadTemplates(ctx)
-		if err != nil {
-			return nil, err
-		}
+	compiled, err := e.template(ctx, name+".html")
+	if err != nil {
+		return nil, err
 	}
-	lock.Unlock()
 
 	_, span = trace.StartSpan(ctx, "gotemplate/Execute")
-	buf := &bytes.Buffer{}
+	defer span.End()
 
-	if _, ok := e.templates[name+".html"]; !ok {
-		return nil, errors.New("Could not find the template " + name + ".html")
+	tpl, err := compiled.acquire(ctx)
+	if err != nil {
+		return nil, err
 	}
-	tpl, err := e.templates[name+".html"].Clone()
+
+	buf := &bytes.Buffer{}
+	err = tpl.Execute(buf, data)
+	compiled.release(tpl, err)
+
+	return buf, err
+}
+
+// RenderPartials renders the requested blocks of the template, e.g. the blocks defined with `{{block "cart" .}}`.
+// Blocks which are not defined by the template or the templates it extends are skipped, e.g. layout templates.
+func (e *engine) RenderPartials(ctx context.Context, name string, data interface{}, partials []string) (map[string]io.Reader, error) {
+	ctx, span := trace.StartSpan(ctx, "gotemplate/RenderPartials")
+	defer span.End()
+
+	compiled, err := e.template(ctx, name+".html")
 	if err != nil {
 		return nil, err
 	}
 
-	tplFuncs := template.FuncMap{}
-	for k, f := range e.tplFuncs() {
-		tplFuncs[k] = f.Func(ctx)
+	_, span = trace.StartSpan(ctx, "gotemplate/Execute")
+	defer span.End()
+
+	tpl, err := compiled.acquire(ctx)
+	if err != nil {
+		return nil, err
 	}
-	tpl.Funcs(tplFuncs)
 
-	err = tpl.Execute(buf, data)
+	result := make(map[string]io.Reader, len(partials))
+	for _, partial := range partials {
+		partial = strings.TrimSpace(partial)
+		if !compiled.blocks[partial] {
+			continue
+		}
 
-	defer span.End()
+		buf := &bytes.Buffer{}
+		if err := tpl.ExecuteTemplate(buf, partial, data); err != nil {
+			compiled.release(tpl, err)
+			return nil, errors.Wrapf(err, "could not render the partial %s of %s", partial, name)
+		}
+		result[partial] = buf
+	}
+	compiled.release(tpl, nil)
 
-	return buf, err
+	return result, nil
 }
 
-func (e *engine) loadTemplates(ctx context.Context) error {
-	ctx, span := trace.StartSpan(ctx, "gotemplate/loadTemplates")
-	defer span.End()
+// Notify precompiles all templates when the server starts outside of debug mode,
+// so broken templates are reported on start instead of on their first render
+func (e *engine) Notify(ctx context.Context, event flamingo.Event) {
+	if _, ok := event.(*flamingo.ServerStartEvent); ok && !e.debug {
+		e.precompile(ctx)
+	}
+}
 
-	e.templates = make(map[string]*template.Template, 0)
+// precompile compiles all .html files outside of the layout directory, templates failing to compile are logged
+func (e *engine) precompile(ctx context.Context) {
+	layoutDir := path.Clean(filepath.ToSlash(e.layoutTemplatesDir)) + "/"
+	for _, name := range walkFiles(e.fs, ".").sorted() {
+		if path.Ext(name) != ".html" || (e.layoutTemplatesDir != "" && strings.HasPrefix(name, layoutDir)) {
+			continue
+		}
+		_, _ = e.template(ctx, name)
+	}
+}
 
-	functionsMap := template.FuncMap{
-		"Upper": strings.ToUpper,
-		"formatDate": func(t time.Time) string {
-			return t.Format("2006-01-02")
-		},
-		"map": func(p ...interface{}) map[string]interface{} {
-			res := make(map[string]interface{})
-			for i := 0; i < len(p); i += 2 {
-				res[fmt.Sprint(p[i])] = p[i+1]
-			}
-			return res
-		},
+// template returns the compiled template. Templates are compiled on first use or on the start of the server,
+// in debug mode they are compiled again if one of their files changed.
+func (e *engine) template(ctx context.Context, name string) (*compiledTemplate, error) {
+	name = filepath.ToSlash(name)
+
+	if e.debug {
+		e.checkChanges()
 	}
 
-	tplFuncs := template.FuncMap{}
-	for k, f := range e.tplFuncs() {
-		tplFuncs[k] = f.Func(ctx)
+	e.mu.RLock()
+	compiled, cached := e.templates[name]
+	e.mu.RUnlock()
+
+	if cached {
+		return compiled, compiled.err
 	}
 
-	layoutTemplate, err := e.parseLayoutTemplates(functionsMap, tplFuncs)
-	if err != nil {
-		return err
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	ctx, span := trace.StartSpan(ctx, "gotemplate/compile")
+	defer span.End()
+
+	if e.layout == nil {
+		e.layout = e.compileLayout(ctx)
+		e.templates = make(map[string]*compiledTemplate)
 	}
 
-	err = e.parseSiteTemplateDirectory(layoutTemplate, e.templatesBasePath)
-	if err != nil {
-		return err
+	compiled = e.compile(name, nil)
+	if compiled.err != nil {
+		e.logger.WithContext(ctx).Error(compiled.err)
 	}
 
-	return nil
+	return compiled, compiled.err
 }
 
-// parses all layout templates in a template instance which is the base instance for all other templates
-func (e *engine) parseLayoutTemplates(functionsMap template.FuncMap, funcs template.FuncMap) (*template.Template, error) {
-	tpl := template.New("").Funcs(functionsMap).Funcs(funcs)
+// checkChanges drops the compiled templates whose files changed, at most once per check interval. A changed layout
+// directory drops all templates. The files are checked without holding the engine lock, so renders are not blocked.
+func (e *engine) checkChanges() {
+	e.checkMu.Lock()
+	if time.Since(e.checked) < e.checkInterval {
+		e.checkMu.Unlock()
+		return
+	}
+	e.checked = time.Now()
+	e.checkMu.Unlock()
+
+	e.mu.RLock()
+	layout := e.layout
+	templates := make(map[string]*compiledTemplate, len(e.templates))
+	for name, compiled := range e.templates {
+		templates[name] = compiled
+	}
+	e.mu.RUnlock()
 
-	if e.layoutTemplatesDir == "" {
-		return tpl, nil
+	if layout == nil {
+		return
 	}
 
-	dir := e.templatesBasePath + pathSeparatorString + e.layoutTemplatesDir
+	if !layout.fresh(e.fs) {
+		e.mu.Lock()
+		if e.layout == layout {
+			e.layout = nil
+			e.templates = nil
+		}
+		e.mu.Unlock()
+		return
+	}
 
-	layoutFilesNames := make([]string, 0)
-	err := filepath.Walk(
-		dir,
-		func(path string, info os.FileInfo, err error) error {
-			if err != nil {
-				return err
-			}
-			if info.IsDir() {
-				return nil
+	var changed []string
+	for name, compiled := range templates {
+		if !compiled.files.fresh(e.fs) {
+			changed = append(changed, name)
+		}
+	}
+	if len(changed) == 0 {
+		return
+	}
+
+	e.mu.Lock()
+	for _, name := range changed {
+		if e.templates[name] == templates[name] {
+			delete(e.templates, name)
+		}
+	}
+	e.mu.Unlock()
+}
+
+// compile the template file with the given name. Already compiled templates are reused, changed templates have been
+// dropped by checkChanges in debug mode. Must be called with the write lock held.
+func (e *engine) compile(name string, chain []string) *compiledTemplate {
+	for i, parent := range chain {
+		if parent == name {
+			return &compiledTemplate{
+				err:   errors.Errorf("cyclic template inheritance: %s", strings.Join(append(chain[i:], name), " -> ")),
+				files: fileStates{},
 			}
+		}
+	}
 
-			layoutFilesNames = append(layoutFilesNames, path)
+	if compiled, ok := e.templates[name]; ok {
+		return compiled
+	}
 
-			return nil
-		},
-	)
+	compiled := &compiledTemplate{files: fileStates{}, funcs: e.layout.funcs, blocks: make(map[string]bool)}
+	compiled.files.add(e.fs, name)
+	// parseFailed caches the failed compilation, it fails again until the template files change
+	parseFailed := func(err error) *compiledTemplate {
+		compiled.err = err
+		e.templates[name] = compiled
+		return compiled
+	}
+
+	content, err := readFile(e.fs, name)
 	if err != nil {
-		return nil, err
+		if os.IsNotExist(err) {
+			compiled.err = errors.Errorf("Could not find the template %s", name)
+		} else {
+			compiled.err = errors.Wrapf(err, "could not read the template %s", name)
+		}
+		return compiled
 	}
 
-	for _, file := range layoutFilesNames {
-		tContent, err := ioutil.ReadFile(file)
-		if err != nil {
-			return nil, err
+	base, err := e.layout.tpl.Clone()
+	if err != nil {
+		compiled.err = errors.Wrapf(err, "could not clone the layout for %s", name)
+		return compiled
+	}
+	tpl := base.New(name)
+
+	if match := extendsDirective.FindSubmatchIndex(content); match != nil {
+		parentName := string(content[match[2]:match[3]])
+		if path.Ext(parentName) == "" {
+			parentName += ".html"
 		}
-		templateName, err := filepath.Rel(dir, file)
-		if err != nil {
-			return nil, err
+		content = content[match[1]:]
+
+		parent := e.compile(parentName, append(chain, name))
+		for file, state := range parent.files {
+			compiled.files[file] = state
+		}
+		if parent.err != nil {
+			compiled.err = errors.Wrapf(parent.err, "template %s extends %s", name, parentName)
+			if _, cached := e.templates[parentName]; cached {
+				e.templates[name] = compiled
+			}
+			return compiled
 		}
-		t := tpl.New(templateName)
 
-		_, err = t.Parse(string(tContent))
-		if err != nil {
+		// the parent is executed, the blocks defined in this template replace the blocks of the parent
+		if tpl, err = parent.tpl.Clone(); err != nil {
+			compiled.err = errors.Wrapf(err, "could not clone the template %s", parentName)
+			return compiled
+		}
+		for block := range parent.blocks {
+			compiled.blocks[block] = true
+		}
+
+		trees := parseTrees(tpl)
+		if _, err := tpl.New(name).Parse(string(content)); err != nil {
+			return parseFailed(errors.Wrapf(err, "could not parse the template %s", name))
+		}
+		compiled.addBlocks(name, tpl, trees)
+	} else {
+		trees := parseTrees(tpl)
+		if _, err := tpl.Parse(string(content)); err != nil {
+			return parseFailed(errors.Wrapf(err, "could not parse the template %s", name))
+		}
+		compiled.addBlocks(name, tpl, trees)
+	}
+
+	compiled.tpl = tpl
+	e.templates[name] = compiled
+	return compiled
+}
+
+// parseTrees returns the parse trees of all templates of the set, to find the blocks defined by a template file
+func parseTrees(tpl *template.Template) map[string]*parse.Tree {
+	trees := make(map[string]*parse.Tree)
+	for _, t := range tpl.Templates() {
+		trees[t.Name()] = t.Tree
+	}
+	return trees
+}
+
+// addBlocks adds the blocks which have been defined or redefined by parsing the template file with the given name,
+// trees are the parse trees of the set before parsing the file
+func (c *compiledTemplate) addBlocks(name string, tpl *template.Template, trees map[string]*parse.Tree) {
+	for _, t := range tpl.Templates() {
+		if t.Name() != name && t.Tree != nil && t.Tree != trees[t.Name()] {
+			c.blocks[t.Name()] = true
+		}
+	}
+}
+
+// acquire an executable clone of the template from the pool, or a new one, and bind it to the context
+func (c *compiledTemplate) acquire(ctx context.Context) (*executableTemplate, error) {
+	tpl, ok := c.pool.Get().(*executableTemplate)
+	if !ok {
+		var err error
+		if tpl, err = c.funcs.newExecutable(c.tpl); err != nil {
 			return nil, err
 		}
 	}
 
+	tpl.bind(ctx)
 	return tpl, nil
 }
 
-// parses all templates from a given directory into a clone of the given layout template, so that all layouts are available
-func (e *engine) parseSiteTemplateDirectory(layoutTemplate *template.Template, dir string) error {
-	files, err := ioutil.ReadDir(dir)
-	if err != nil {
-		return err
-	}
-	for _, f := range files {
-		t := template.Must(layoutTemplate.Clone())
-		fullName := dir + pathSeparatorString + f.Name()
-		if f.IsDir() {
-			err = e.parseSiteTemplateDirectory(layoutTemplate, fullName)
-			if err != nil {
-				return err
+// release the executable clone into the pool. Clones of failed renders are dropped, their state is unknown.
+func (c *compiledTemplate) release(tpl *executableTemplate, err error) {
+	tpl.unbind()
+	if err == nil {
+		c.pool.Put(tpl)
+	}
+}
+
+// compileLayout parses all layout templates in a template instance which is the base instance for all other templates.
+// Broken layout files are logged and skipped, so only templates using them fail.
+func (e *engine) compileLayout(ctx context.Context) *compiledLayout {
+	functionsMap := template.FuncMap{
+		"Upper": strings.ToUpper,
+		"formatDate": func(t time.Time) string {
+			return t.Format("2006-01-02")
+		},
+		"map": func(p ...interface{}) map[string]interface{} {
+			res := make(map[string]interface{})
+			for i := 0; i < len(p); i += 2 {
+				res[fmt.Sprint(p[i])] = p[i+1]
 			}
-			continue
-		}
-		tContent, err := ioutil.ReadFile(fullName)
-		if err != nil {
-			return err
-		}
+			return res
+		},
+	}
+
+	funcs, tplFuncs := newTemplateFuncs(ctx, e.tplFuncs())
 
-		templateName := strings.TrimPrefix(fullName, e.templatesBasePath+pathSeparatorString)
-		parsedTemplate, err := t.Parse(string(tContent))
+	layout := &compiledLayout{
+		tpl:   template.New("").Funcs(functionsMap).Funcs(tplFuncs),
+		funcs: funcs,
+	}
+
+	if e.layoutTemplatesDir == "" {
+		return layout
+	}
+
+	layout.dir = path.Clean(filepath.ToSlash(e.layoutTemplatesDir))
+	layout.files = walkFiles(e.fs, layout.dir)
+	for _, file := range layout.files.sorted() {
+		tContent, err := readFile(e.fs, file)
 		if err != nil {
-			e.logger.WithField("category", "gotemplate").Error(err)
+			e.logger.WithContext(ctx).Error(errors.Wrapf(err, "could not read the layout template %s", file))
 			continue
 		}
-		e.templates[templateName] = template.Must(parsedTemplate, err)
-		// operating systems like windows use \ instead of /, so `c.responder.Render("foo/bar", ...)` will not
-		// resolve the template properly, as it is known as `foo\bar`. this makes sure we register the template
-		// as `foo/bar` as well as `foo\bar`.
-		if pathSeparatorString != "/" {
-			e.templates[strings.Replace(templateName, pathSeparatorString, "/", -1)] = template.Must(parsedTemplate, err)
+		templateName := strings.TrimPrefix(file, layout.dir+"/")
+
+		if _, err := layout.tpl.New(templateName).Parse(string(tContent)); err != nil {
+			e.logger.WithContext(ctx).Error(errors.Wrapf(err, "could not parse the layout template %s", templateName))
 		}
 	}
 
-	return nil
+	return layout
+}
+
+// fresh checks if no layout file has been added, removed or modified since the layout has been compiled
+func (l *compiledLayout) fresh(fs http.FileSystem) bool {
+	return l != nil && (l.dir == "" || l.files.equal(walkFiles(fs, l.dir)))
 }
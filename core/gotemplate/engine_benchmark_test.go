@@ -0,0 +1,135 @@
+package gotemplate
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+
+	"flamingo.me/flamingo/v3/framework/flamingo"
+)
+
+type benchmarkFunc struct {
+	name string
+}
+
+func (f *benchmarkFunc) Func(ctx context.Context) interface{} {
+	return func(value interface{}) string {
+		return fmt.Sprint(f.name, ":", value)
+	}
+}
+
+// newBenchmarkEngine creates an engine with the given number of page templates, each extending a base template and
+// calling `calls` of the `funcs` template functions for every item of a list of 20 products
+func newBenchmarkEngine(pages, funcs, calls int) *engine {
+	tplFuncs := make(map[string]flamingo.TemplateFunc, funcs)
+	for i := 0; i < funcs; i++ {
+		tplFuncs[fmt.Sprintf("func%d", i)] = &benchmarkFunc{name: fmt.Sprintf("func%d", i)}
+	}
+
+	files := flamingo.MemoryFileSystem{
+		"base.html":                `<html><head>{{block "head" .}}{{end}}</head><body>{{block "content" .}}{{end}}</body></html>`,
+		"layouts/blocks/item.html": `<li>{{.}}</li>`,
+	}
+	for p := 0; p < pages; p++ {
+		var content strings.Builder
+		content.WriteString(`{{extends "base"}}{{define "head"}}<title>page</title>{{end}}{{define "content"}}<ul>{{range .}}`)
+		for c := 0; c < calls; c++ {
+			fmt.Fprintf(&content, `{{template "blocks/item.html" (func%d .)}}`, (p+c)%funcs)
+		}
+		content.WriteString(`{{end}}</ul>{{end}}`)
+		files[fmt.Sprintf("pages/page%d.html", p)] = content.String()
+	}
+
+	e := new(engine)
+	e.Inject(
+		func() map[string]flamingo.TemplateFunc { return tplFuncs },
+		flamingo.NullLogger{},
+		&struct {
+			TemplatesBasePath  string          `inject:"config:gotemplates.engine.templates.basepath"`
+			LayoutTemplatesDir string          `inject:"config:gotemplates.engine.layout.dir"`
+			Debug              bool            `inject:"config:debug.mode"`
+			FileSystem         http.FileSystem `inject:"flamingo.filesystem.templates,optional"`
+		}{
+			LayoutTemplatesDir: "layouts",
+			FileSystem:         files,
+		},
+	)
+
+	return e
+}
+
+func benchmarkRender(b *testing.B, pages, funcs, calls int) {
+	e := newBenchmarkEngine(pages, funcs, calls)
+	ctx := context.Background()
+	data := make([]int, 20)
+	for i := range data {
+		data[i] = i
+	}
+
+	// compile all templates before measuring the renders
+	for p := 0; p < pages; p++ {
+		if _, err := e.Render(ctx, fmt.Sprintf("pages/page%d", p), data); err != nil {
+			b.Fatal(err)
+		}
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	b.RunParallel(func(pb *testing.PB) {
+		p := 0
+		for pb.Next() {
+			result, err := e.Render(ctx, fmt.Sprintf("pages/page%d", p%pages), data)
+			if err != nil {
+				b.Fatal(err)
+			}
+			_, _ = ioutil.ReadAll(result)
+			p++
+		}
+	})
+}
+
+func BenchmarkEngine_Render(b *testing.B) {
+	b.Run("single page with few functions", func(b *testing.B) {
+		benchmarkRender(b, 1, 5, 2)
+	})
+	b.Run("many pages with many functions", func(b *testing.B) {
+		benchmarkRender(b, 100, 100, 5)
+	})
+	b.Run("large pages", func(b *testing.B) {
+		benchmarkRender(b, 10, 100, 50)
+	})
+}
+
+func BenchmarkEngine_RenderPartials(b *testing.B) {
+	e := newBenchmarkEngine(10, 100, 5)
+	ctx := context.Background()
+	partials := []string{"head", "content"}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		if _, err := e.RenderPartials(ctx, fmt.Sprintf("pages/page%d", i%10), []int{1, 2, 3}, partials); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkEngine_Compile(b *testing.B) {
+	ctx := context.Background()
+
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		e := newBenchmarkEngine(10, 100, 5)
+		for p := 0; p < 10; p++ {
+			if _, err := e.template(ctx, fmt.Sprintf("pages/page%d.html", p)); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
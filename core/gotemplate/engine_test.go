@@ -0,0 +1,432 @@
+package gotemplate
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"flamingo.me/flamingo/v3/framework/flamingo"
+	"flamingo.me/flamingo/v3/framework/web"
+)
+
+// modTimes are the modification times of the written templates, every write gets a later one,
+// so changes are visible even on file systems with a coarse modification time
+var modTimes = struct {
+	sync.Mutex
+	last time.Time
+}{last: time.Now()}
+
+func nextModTime() time.Time {
+	modTimes.Lock()
+	defer modTimes.Unlock()
+
+	modTimes.last = modTimes.last.Add(time.Second)
+	return modTimes.last
+}
+
+func writeTemplates(t *testing.T, dir string, templates map[string]string) {
+	t.Helper()
+
+	for name, content := range templates {
+		file := filepath.Join(dir, filepath.FromSlash(name))
+		require.NoError(t, os.MkdirAll(filepath.Dir(file), 0755))
+		require.NoError(t, ioutil.WriteFile(file, []byte(content), 0644))
+		modTime := nextModTime()
+		require.NoError(t, os.Chtimes(file, modTime, modTime))
+	}
+}
+
+// newTestEngine creates an engine for the templates in a temporary directory, callers must remove the directory
+func newTestEngine(t *testing.T, templates map[string]string, layoutDir string, debug bool) (*engine, string) {
+	t.Helper()
+
+	dir, err := ioutil.TempDir("", "gotemplate")
+	require.NoError(t, err)
+	written := false
+	defer func() {
+		// the caller does not get the directory if writing the templates failed
+		if !written {
+			_ = os.RemoveAll(dir)
+		}
+	}()
+	writeTemplates(t, dir, templates)
+	written = true
+
+	e := new(engine)
+	e.Inject(
+		func() map[string]flamingo.TemplateFunc { return nil },
+		flamingo.NullLogger{},
+		&struct {
+			TemplatesBasePath  string          `inject:"config:gotemplates.engine.templates.basepath"`
+			LayoutTemplatesDir string          `inject:"config:gotemplates.engine.layout.dir"`
+			Debug              bool            `inject:"config:debug.mode"`
+			FileSystem         http.FileSystem `inject:"flamingo.filesystem.templates,optional"`
+		}{
+			TemplatesBasePath:  dir,
+			LayoutTemplatesDir: layoutDir,
+			Debug:              debug,
+		},
+	)
+
+	return e, dir
+}
+
+func TestEngine_RenderFileSystem(t *testing.T) {
+	e := new(engine)
+	e.Inject(
+		func() map[string]flamingo.TemplateFunc { return nil },
+		flamingo.NullLogger{},
+		&struct {
+			TemplatesBasePath  string          `inject:"config:gotemplates.engine.templates.basepath"`
+			LayoutTemplatesDir string          `inject:"config:gotemplates.engine.layout.dir"`
+			Debug              bool            `inject:"config:debug.mode"`
+			FileSystem         http.FileSystem `inject:"flamingo.filesystem.templates,optional"`
+		}{
+			TemplatesBasePath:  "does-not-exist",
+			LayoutTemplatesDir: "layouts",
+			Debug:              true,
+			FileSystem: flamingo.MemoryFileSystem{
+				"base.html":                  `<main>{{block "content" .}}{{end}}</main>`,
+				"pages/index.html":           `{{extends "base"}}{{define "content"}}{{template "blocks/teaser.html" .}}{{end}}`,
+				"layouts/blocks/teaser.html": `Hello {{.}}`,
+			},
+		},
+	)
+
+	content, err := render(t, e, "pages/index", "World")
+	assert.NoError(t, err)
+	assert.Equal(t, "<main>Hello World</main>", content)
+}
+
+func render(t *testing.T, e *engine, name string, data interface{}) (string, error) {
+	t.Helper()
+
+	result, err := e.Render(context.Background(), name, data)
+	if err != nil {
+		return "", err
+	}
+	content, err := ioutil.ReadAll(result)
+	require.NoError(t, err)
+	return string(content), nil
+}
+
+func TestEngine_Render(t *testing.T) {
+	e, dir := newTestEngine(t, map[string]string{
+		"index.html":                     `Hello {{.}}`,
+		"deep/nested/page.html":          `{{template "snippets/greeting.html" .}}!`,
+		"layouts/snippets/greeting.html": `Hi {{.}}`,
+	}, "layouts", false)
+	defer os.RemoveAll(dir)
+
+	content, err := render(t, e, "index", "World")
+	assert.NoError(t, err)
+	assert.Equal(t, "Hello World", content)
+
+	content, err = render(t, e, "deep/nested/page", "World")
+	assert.NoError(t, err)
+	assert.Equal(t, "Hi World!", content)
+
+	_, err = render(t, e, "unknown", nil)
+	assert.EqualError(t, err, "Could not find the template unknown.html")
+}
+
+func TestEngine_RenderExtends(t *testing.T) {
+	e, dir := newTestEngine(t, map[string]string{
+		"base.html":          `<title>{{block "title" .}}Default{{end}}</title><main>{{block "content" .}}{{end}}</main>`,
+		"layouts/shop.html":  `{{extends "base.html"}}{{define "content"}}<nav/>{{block "shop" .}}{{end}}{{end}}`,
+		"pages/product.html": `{{extends "layouts/shop"}}{{define "title"}}{{.}}{{end}}{{define "shop"}}<h1>{{.}}</h1>{{end}}`,
+		"pages/plain.html":   "{{- extends \"base.html\" -}}\n{{define \"content\"}}plain{{end}}",
+		"cycle/a.html":       `{{extends "cycle/b.html"}}`,
+		"cycle/b.html":       `{{extends "cycle/a.html"}}`,
+		"broken/child.html":  `{{extends "broken/missing.html"}}`,
+	}, "", false)
+	defer os.RemoveAll(dir)
+
+	content, err := render(t, e, "pages/product", "Shoe")
+	assert.NoError(t, err)
+	assert.Equal(t, "<title>Shoe</title><main><nav/><h1>Shoe</h1></main>", content)
+
+	content, err = render(t, e, "pages/plain", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "<title>Default</title><main>plain</main>", content)
+
+	content, err = render(t, e, "base", nil)
+	assert.NoError(t, err, "the parent template is not changed by its children")
+	assert.Equal(t, "<title>Default</title><main></main>", content)
+
+	_, err = render(t, e, "cycle/a", nil)
+	assert.EqualError(t, err, "template cycle/a.html extends cycle/b.html: template cycle/b.html extends cycle/a.html: cyclic template inheritance: cycle/a.html -> cycle/b.html -> cycle/a.html")
+
+	_, err = render(t, e, "broken/child", nil)
+	assert.EqualError(t, err, "template broken/child.html extends broken/missing.html: Could not find the template broken/missing.html")
+}
+
+func TestEngine_RenderBrokenTemplate(t *testing.T) {
+	e, dir := newTestEngine(t, map[string]string{
+		"broken.html":         `{{if}}`,
+		"working.html":        `works`,
+		"layouts/broken.html": `{{end}}`,
+		"layouts/fine.html":   `fine`,
+		"snippet.html":        `{{template "fine.html"}}`,
+	}, "layouts", false)
+	defer os.RemoveAll(dir)
+
+	_, err := render(t, e, "broken", nil)
+	assert.Error(t, err)
+
+	content, err := render(t, e, "working", nil)
+	assert.NoError(t, err, "a broken template must not affect other templates")
+	assert.Equal(t, "works", content)
+
+	content, err = render(t, e, "snippet", nil)
+	assert.NoError(t, err, "a broken layout must not affect other layouts")
+	assert.Equal(t, "fine", content)
+}
+
+func TestEngine_RenderReload(t *testing.T) {
+	templates := map[string]string{
+		"base.html":         `[{{block "content" .}}{{end}}]`,
+		"page.html":         `{{extends "base.html"}}{{define "content"}}page{{end}}`,
+		"other.html":        `other`,
+		"layouts/snip.html": `snip`,
+		"snippet.html":      `{{template "snip.html"}}`,
+	}
+
+	t.Run("debug mode reloads changed templates", func(t *testing.T) {
+		e, dir := newTestEngine(t, templates, "layouts", true)
+		defer os.RemoveAll(dir)
+		e.checkInterval = 0
+
+		for _, name := range []string{"page", "other", "snippet"} {
+			_, err := render(t, e, name, nil)
+			require.NoError(t, err)
+		}
+		other := e.templates["other.html"]
+
+		writeTemplates(t, dir, map[string]string{"base.html": `({{block "content" .}}{{end}})`})
+		content, err := render(t, e, "page", nil)
+		assert.NoError(t, err)
+		assert.Equal(t, "(page)", content, "a changed parent is reloaded")
+		assert.Equal(t, other, e.templates["other.html"], "unchanged templates are not compiled again")
+
+		writeTemplates(t, dir, map[string]string{"new.html": `new`})
+		content, err = render(t, e, "new", nil)
+		assert.NoError(t, err)
+		assert.Equal(t, "new", content)
+
+		writeTemplates(t, dir, map[string]string{"layouts/snip.html": `changed snip`})
+		content, err = render(t, e, "snippet", nil)
+		assert.NoError(t, err)
+		assert.Equal(t, "changed snip", content, "a changed layout is reloaded")
+
+		writeTemplates(t, dir, map[string]string{"other.html": `{{if}}`})
+		_, err = render(t, e, "other", nil)
+		assert.Error(t, err)
+		writeTemplates(t, dir, map[string]string{"other.html": `fixed other`})
+		content, err = render(t, e, "other", nil)
+		assert.NoError(t, err, "a fixed template is reloaded")
+		assert.Equal(t, "fixed other", content)
+	})
+
+	t.Run("production mode compiles templates once", func(t *testing.T) {
+		e, dir := newTestEngine(t, templates, "layouts", false)
+		defer os.RemoveAll(dir)
+
+		_, err := render(t, e, "page", nil)
+		require.NoError(t, err)
+
+		writeTemplates(t, dir, map[string]string{"base.html": `({{block "content" .}}{{end}})`})
+		content, err := render(t, e, "page", nil)
+		assert.NoError(t, err)
+		assert.Equal(t, "[page]", content)
+	})
+
+	t.Run("production mode compiles all templates on server start", func(t *testing.T) {
+		e, dir := newTestEngine(t, templates, "layouts", false)
+		defer os.RemoveAll(dir)
+
+		e.Notify(context.Background(), &flamingo.ServerStartEvent{})
+		assert.Len(t, e.templates, 4, "all templates outside of the layout directory")
+		assert.NotContains(t, e.templates, "layouts/snip.html")
+
+		writeTemplates(t, dir, map[string]string{"page.html": `changed`})
+		content, err := render(t, e, "page", nil)
+		assert.NoError(t, err)
+		assert.Equal(t, "[page]", content, "precompiled templates are used")
+	})
+
+	t.Run("debug mode compiles templates on first render", func(t *testing.T) {
+		e, dir := newTestEngine(t, templates, "layouts", true)
+		defer os.RemoveAll(dir)
+
+		e.Notify(context.Background(), &flamingo.ServerStartEvent{})
+		assert.Empty(t, e.templates)
+	})
+
+	t.Run("debug mode checks for changes once per interval", func(t *testing.T) {
+		e, dir := newTestEngine(t, templates, "layouts", true)
+		defer os.RemoveAll(dir)
+		e.checkInterval = time.Hour
+
+		_, err := render(t, e, "page", nil)
+		require.NoError(t, err)
+
+		writeTemplates(t, dir, map[string]string{"base.html": `({{block "content" .}}{{end}})`})
+		content, err := render(t, e, "page", nil)
+		assert.NoError(t, err)
+		assert.Equal(t, "[page]", content)
+
+		e.checked = time.Time{}
+		content, err = render(t, e, "page", nil)
+		assert.NoError(t, err)
+		assert.Equal(t, "(page)", content)
+	})
+
+	t.Run("only parse errors are cached", func(t *testing.T) {
+		e, dir := newTestEngine(t, templates, "layouts", false)
+		defer os.RemoveAll(dir)
+
+		writeTemplates(t, dir, map[string]string{
+			"broken.html": `{{if}}`,
+			"child.html":  `{{extends "missing"}}{{define "content"}}child{{end}}`,
+		})
+		_, err := render(t, e, "broken", nil)
+		assert.Error(t, err)
+		_, err = render(t, e, "missing", nil)
+		assert.Error(t, err)
+		_, err = render(t, e, "child", nil)
+		assert.Error(t, err)
+
+		writeTemplates(t, dir, map[string]string{
+			"broken.html":  `fixed`,
+			"missing.html": `[{{block "content" .}}{{end}}]`,
+		})
+		_, err = render(t, e, "broken", nil)
+		assert.Error(t, err, "parse errors are cached without debug mode")
+		content, err := render(t, e, "missing", nil)
+		assert.NoError(t, err, "missing templates are not cached")
+		assert.Equal(t, "[]", content)
+		content, err = render(t, e, "child", nil)
+		assert.NoError(t, err, "templates extending missing templates are not cached")
+		assert.Equal(t, "[child]", content)
+	})
+}
+
+func TestEngine_RenderPartials(t *testing.T) {
+	e, dir := newTestEngine(t, map[string]string{
+		"base.html":            `<main>{{block "content" .}}{{end}}</main><aside>{{block "cart" .}}empty{{end}}</aside>`,
+		"page.html":            `{{extends "base"}}{{define "content"}}{{setPartialData "title" .}}<h1>{{.}}</h1>{{end}}`,
+		"layouts/account.html": `{{define "account"}}secret{{end}}`,
+	}, "layouts", false)
+	defer os.RemoveAll(dir)
+	e.tplFuncs = func() map[string]flamingo.TemplateFunc {
+		return map[string]flamingo.TemplateFunc{"setPartialData": new(web.SetPartialDataFunc)}
+	}
+
+	req := web.CreateRequest(httptest.NewRequest(http.MethodGet, "/", nil), nil)
+	ctx := web.ContextWithRequest(context.Background(), req)
+
+	partials, err := e.RenderPartials(ctx, "page", "Shoe", []string{"content", " cart", "unknown", "account", "account.html", "page.html", "base.html"})
+	require.NoError(t, err)
+	require.Len(t, partials, 2, "only blocks of the template and the templates it extends are rendered")
+
+	content, err := ioutil.ReadAll(partials["content"])
+	assert.NoError(t, err)
+	assert.Equal(t, "<h1>Shoe</h1>", string(content))
+
+	content, err = ioutil.ReadAll(partials["cart"])
+	assert.NoError(t, err)
+	assert.Equal(t, "empty", string(content))
+
+	assert.Equal(t, map[string]interface{}{"title": "Shoe"}, new(web.GetPartialDataFunc).Func(ctx).(func() map[string]interface{})())
+
+	_, err = e.RenderPartials(ctx, "missing", nil, []string{"content"})
+	assert.EqualError(t, err, "Could not find the template missing.html")
+}
+
+type (
+	countingFunc struct {
+		calls int
+	}
+
+	contextValueFunc struct{}
+
+	contextKey string
+)
+
+func (f *countingFunc) Func(ctx context.Context) interface{} {
+	f.calls++
+	return func(values ...string) string {
+		return strings.Join(values, "-")
+	}
+}
+
+func (f *contextValueFunc) Func(ctx context.Context) interface{} {
+	value := ctx.Value(contextKey("value"))
+	if value == "changed" {
+		return func() int { return 0 }
+	}
+	return func() interface{} {
+		return value
+	}
+}
+
+func TestEngine_RenderTemplateFuncs(t *testing.T) {
+	e, dir := newTestEngine(t, map[string]string{
+		"counting.html": `{{count "a" "b"}} {{count}} {{count "c"}}`,
+		"context.html":  `{{contextValue}}`,
+		"plain.html":    `plain`,
+	}, "", false)
+	defer os.RemoveAll(dir)
+	counting := new(countingFunc)
+	e.tplFuncs = func() map[string]flamingo.TemplateFunc {
+		return map[string]flamingo.TemplateFunc{"count": counting, "contextValue": new(contextValueFunc)}
+	}
+	ctx := func(value string) context.Context {
+		return context.WithValue(context.Background(), contextKey("value"), value)
+	}
+
+	t.Run("functions are bound lazily once per render", func(t *testing.T) {
+		content, err := render(t, e, "counting", nil)
+		assert.NoError(t, err)
+		assert.Equal(t, "a-b  c", content)
+		calls := counting.calls
+
+		_, err = render(t, e, "plain", nil)
+		assert.NoError(t, err)
+		assert.Equal(t, calls, counting.calls, "unused functions are not bound")
+
+		_, err = render(t, e, "counting", nil)
+		assert.NoError(t, err)
+		assert.Equal(t, calls+1, counting.calls)
+	})
+
+	t.Run("functions are bound to the context of each render", func(t *testing.T) {
+		for _, value := range []string{"first", "second", "first"} {
+			result, err := e.Render(ctx(value), "context", nil)
+			require.NoError(t, err)
+			content, _ := ioutil.ReadAll(result)
+			assert.Equal(t, value, string(content))
+		}
+	})
+
+	t.Run("functions must not change their signature", func(t *testing.T) {
+		_, err := e.Render(ctx("changed"), "context", nil)
+		assert.Error(t, err)
+
+		result, err := e.Render(ctx("fine"), "context", nil)
+		require.NoError(t, err)
+		content, _ := ioutil.ReadAll(result)
+		assert.Equal(t, "fine", string(content))
+	})
+}
@@ -0,0 +1,116 @@
+package gotemplate
+
+import (
+	"io/ioutil"
+	"net/http"
+	"path"
+	"sort"
+	"time"
+)
+
+type (
+	// fileStates are the states of files of a http.FileSystem, changes are detected by polling their modification time and size.
+	// Missing files have a zero state.
+	fileStates map[string]fileState
+
+	fileState struct {
+		exists  bool
+		modTime time.Time
+		size    int64
+	}
+)
+
+// readFile reads the whole file of the file system
+func readFile(fs http.FileSystem, name string) ([]byte, error) {
+	file, err := fs.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return ioutil.ReadAll(file)
+}
+
+// statFile returns the current state of the file
+func statFile(fs http.FileSystem, name string) fileState {
+	file, err := fs.Open(name)
+	if err != nil {
+		return fileState{}
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return fileState{}
+	}
+	return fileState{exists: true, modTime: info.ModTime(), size: info.Size()}
+}
+
+// walkFiles returns the state of all files in the directory and its subdirectories
+func walkFiles(fs http.FileSystem, dir string) fileStates {
+	files := fileStates{}
+
+	var walk func(dir string)
+	walk = func(dir string) {
+		file, err := fs.Open(dir)
+		if err != nil {
+			return
+		}
+		infos, _ := file.Readdir(-1)
+		_ = file.Close()
+
+		for _, info := range infos {
+			name := path.Join(dir, info.Name())
+			if info.IsDir() {
+				walk(name)
+				continue
+			}
+			files[name] = fileState{exists: true, modTime: info.ModTime(), size: info.Size()}
+		}
+	}
+	walk(dir)
+
+	return files
+}
+
+// add the current state of the file
+func (f fileStates) add(fs http.FileSystem, name string) {
+	f[name] = statFile(fs, name)
+}
+
+// fresh checks if none of the files has been created, removed or modified
+func (f fileStates) fresh(fs http.FileSystem) bool {
+	for file, state := range f {
+		if !statFile(fs, file).equal(state) {
+			return false
+		}
+	}
+	return true
+}
+
+// equal checks if both contain the same files in the same state
+func (f fileStates) equal(other fileStates) bool {
+	if len(f) != len(other) {
+		return false
+	}
+	for file, state := range f {
+		if o, ok := other[file]; !ok || !o.equal(state) {
+			return false
+		}
+	}
+	return true
+}
+
+// sorted returns the file names in a stable order
+func (f fileStates) sorted() []string {
+	files := make([]string, 0, len(f))
+	for file := range f {
+		files = append(files, file)
+	}
+	sort.Strings(files)
+	return files
+}
+
+// equal checks if the states are equal
+func (s fileState) equal(other fileState) bool {
+	return s.exists == other.exists && s.modTime.Equal(other.modTime) && s.size == other.size
+}
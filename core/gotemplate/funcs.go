@@ -0,0 +1,101 @@
+package gotemplate
+
+import (
+	"context"
+	"html/template"
+	"reflect"
+
+	"flamingo.me/flamingo/v3/framework/flamingo"
+	"github.com/pkg/errors"
+)
+
+type (
+	// templateFuncs are the template functions of a compiled layout with the signature of their functions
+	templateFuncs struct {
+		funcs      map[string]flamingo.TemplateFunc
+		signatures map[string]reflect.Type
+	}
+
+	// funcBinding binds the template functions of an executable template to the context of the current render.
+	// A function is only created via TemplateFunc.Func if the template calls it.
+	funcBinding struct {
+		funcs *templateFuncs
+		ctx   context.Context
+		bound map[string]reflect.Value
+	}
+
+	// executableTemplate is a clone of a compiled template, it is reused for renders via the pool of the compiled template.
+	// It must only be used by one render at a time.
+	executableTemplate struct {
+		*template.Template
+		binding *funcBinding
+	}
+)
+
+// newTemplateFuncs creates the template functions once with the given context to learn their signatures.
+// The returned functions are used to parse the templates, html/template requires functions anyway.
+func newTemplateFuncs(ctx context.Context, funcs map[string]flamingo.TemplateFunc) (*templateFuncs, template.FuncMap) {
+	tf := &templateFuncs{
+		funcs:      funcs,
+		signatures: make(map[string]reflect.Type, len(funcs)),
+	}
+
+	funcMap := make(template.FuncMap, len(funcs))
+	for name, f := range funcs {
+		fnc := f.Func(ctx)
+		funcMap[name] = fnc
+		tf.signatures[name] = reflect.TypeOf(fnc)
+	}
+
+	return tf, funcMap
+}
+
+// newExecutable clones the template and replaces the template functions with functions bound lazily on each render
+func (tf *templateFuncs) newExecutable(tpl *template.Template) (*executableTemplate, error) {
+	clone, err := tpl.Clone()
+	if err != nil {
+		return nil, err
+	}
+
+	binding := &funcBinding{funcs: tf, bound: make(map[string]reflect.Value)}
+	funcMap := make(template.FuncMap, len(tf.signatures))
+	for name, typ := range tf.signatures {
+		funcMap[name] = binding.lazy(name, typ)
+	}
+	clone.Funcs(funcMap)
+
+	return &executableTemplate{Template: clone, binding: binding}, nil
+}
+
+// bind the executable template to the context of a render
+func (x *executableTemplate) bind(ctx context.Context) {
+	x.binding.ctx = ctx
+}
+
+// unbind removes all references to the render's context, so the executable template can be reused
+func (x *executableTemplate) unbind() {
+	x.binding.ctx = nil
+	for name := range x.binding.bound {
+		delete(x.binding.bound, name)
+	}
+}
+
+// lazy returns a function with the given signature which calls the template function bound to the current context
+func (b *funcBinding) lazy(name string, typ reflect.Type) interface{} {
+	return reflect.MakeFunc(typ, func(args []reflect.Value) []reflect.Value {
+		fnc, ok := b.bound[name]
+		if !ok {
+			fnc = reflect.ValueOf(b.funcs.funcs[name].Func(b.ctx))
+			if fnc.Type() != typ {
+				// the template engine recovers the panic and fails the render with this error
+				panic(errors.Errorf("template function %s changed its signature from %s to %s", name, typ, fnc.Type()))
+			}
+			b.bound[name] = fnc
+		}
+
+		if typ.IsVariadic() {
+			return fnc.CallSlice(args)
+		}
+		return fnc.Call(args)
+	}).Interface()
+}
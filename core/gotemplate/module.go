@@ -13,7 +13,9 @@ type Module struct{}
 
 // Configure DI
 func (m *Module) Configure(injector *dingo.Injector) {
-	injector.Bind(new(flamingo.TemplateEngine)).In(dingo.ChildSingleton).To(engine{})
+	injector.Bind(engine{}).In(dingo.ChildSingleton)
+	injector.Bind(new(flamingo.TemplateEngine)).To(engine{})
+	flamingo.BindEventSubscriber(injector).To(engine{})
 	injector.Bind(new(urlRouter)).To(web.Router{})
 
 	flamingo.BindTemplateFunc(injector, "url", new(urlFunc))
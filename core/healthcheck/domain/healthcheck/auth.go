@@ -19,13 +19,18 @@ func (s *Auth) Inject(authManager *application.AuthManager) {
 	s.authManager = authManager
 }
 
-// Status checks the status
+// Status checks the status of all configured oauth providers
 func (s *Auth) Status() (bool, string) {
-	path := s.authManager.OAuth2Config(context.Background(), nil).AuthCodeURL("")
-	_, err := http.Get(path)
-	if err == nil {
-		return true, "success"
+	for _, name := range s.authManager.Providers() {
+		provider, err := s.authManager.Provider(name)
+		if err != nil {
+			return false, err.Error()
+		}
+		path := s.authManager.ProviderOAuth2Config(context.Background(), nil, provider).AuthCodeURL("")
+		if _, err := http.Get(path); err != nil {
+			return false, name + ": " + err.Error()
+		}
 	}
 
-	return false, err.Error()
+	return true, "success"
 }
@@ -0,0 +1,133 @@
+package application
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"flamingo.me/flamingo/v3/core/internalauth/domain"
+	"flamingo.me/flamingo/v3/framework/config"
+	"flamingo.me/flamingo/v3/framework/flamingo"
+	"github.com/pkg/errors"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/clientcredentials"
+)
+
+type (
+	// ClientTokenService manages the client credentials tokens of the clients configured in internalauth.clients
+	ClientTokenService struct {
+		sources map[string]*managedTokenSource
+	}
+
+	// ClientConfig configures a client credentials client
+	ClientConfig struct {
+		// TokenEndpoint is either an absolute URL or a path relative to internalauth.baseurl
+		TokenEndpoint  string
+		ClientID       string
+		ClientSecret   string
+		Scopes         []string
+		EndpointParams map[string]string
+		// RefreshBefore starts a background refresh of the token within this duration ahead of the expiry
+		RefreshBefore time.Duration `default:"1m"`
+	}
+)
+
+var _ domain.ClientTokenProvider = new(ClientTokenService)
+
+// ErrUnknownClient is returned for clients which are not configured
+var ErrUnknownClient = errors.New("unknown internalauth client")
+
+// Inject dependencies
+func (s *ClientTokenService) Inject(logger flamingo.Logger, cfg *struct {
+	BaseURL string     `inject:"config:internalauth.baseurl"`
+	Clients config.Map `inject:"config:internalauth.clients,optional"`
+}) {
+	s.sources = make(map[string]*managedTokenSource)
+	if cfg == nil {
+		return
+	}
+
+	var clients map[string]ClientConfig
+	if err := cfg.Clients.MapInto(&clients); err != nil {
+		panic(errors.Wrap(err, "internalauth.clients"))
+	}
+
+	logger = logger.WithField(flamingo.LogKeyModule, "internalauth")
+	for name, client := range clients {
+		if client.ClientID == "" || client.TokenEndpoint == "" {
+			panic(errors.Errorf("internalauth.clients.%s: clientID and tokenEndpoint are required", name))
+		}
+
+		s.sources[name] = newManagedTokenSource(client.credentialsConfig(cfg.BaseURL), client.RefreshBefore, logger.WithField("client", name))
+	}
+}
+
+// credentialsConfig creates the oauth2 client credentials config
+func (c ClientConfig) credentialsConfig(baseURL string) clientcredentials.Config {
+	params := make(url.Values, len(c.EndpointParams))
+	for key, value := range c.EndpointParams {
+		params.Set(key, value)
+	}
+
+	return clientcredentials.Config{
+		ClientID:       c.ClientID,
+		ClientSecret:   c.ClientSecret,
+		TokenURL:       resolveURL(baseURL, c.TokenEndpoint),
+		Scopes:         c.Scopes,
+		EndpointParams: params,
+	}
+}
+
+// resolveURL returns absolute URLs as they are, and paths relative to the base URL
+func resolveURL(baseURL, endpoint string) string {
+	if strings.HasPrefix(endpoint, "http://") || strings.HasPrefix(endpoint, "https://") {
+		return endpoint
+	}
+	return strings.TrimRight(baseURL, "/") + "/" + strings.TrimLeft(endpoint, "/")
+}
+
+func (s *ClientTokenService) source(client string) (*managedTokenSource, error) {
+	source, ok := s.sources[client]
+	if !ok {
+		return nil, errors.Wrap(ErrUnknownClient, client)
+	}
+	return source, nil
+}
+
+// Token returns the cached token of the client, a new token is only fetched if the cached one expired
+func (s *ClientTokenService) Token(ctx context.Context, client string) (*oauth2.Token, error) {
+	source, err := s.source(client)
+	if err != nil {
+		return nil, err
+	}
+	return source.Token(ctx)
+}
+
+// TokenSource returns the managed token source of the client
+func (s *ClientTokenService) TokenSource(client string) (oauth2.TokenSource, error) {
+	source, err := s.source(client)
+	if err != nil {
+		return nil, err
+	}
+	return &contextTokenSource{ctx: context.Background(), source: source}, nil
+}
+
+// RoundTripper returns a http.RoundTripper which adds the client's token to each request, base defaults to http.DefaultTransport
+func (s *ClientTokenService) RoundTripper(client string, base http.RoundTripper) (http.RoundTripper, error) {
+	source, err := s.source(client)
+	if err != nil {
+		return nil, err
+	}
+	return &tokenTransport{source: source, base: base}, nil
+}
+
+// HTTPClient returns a http.Client which authenticates all requests with the client's token
+func (s *ClientTokenService) HTTPClient(client string) (*http.Client, error) {
+	transport, err := s.RoundTripper(client, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &http.Client{Transport: transport}, nil
+}
@@ -0,0 +1,314 @@
+package application
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"flamingo.me/flamingo/v3/framework/config"
+	"flamingo.me/flamingo/v3/framework/flamingo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/clientcredentials"
+)
+
+// tokenServer issues numbered client credentials tokens
+type tokenServer struct {
+	*httptest.Server
+	requests  int32
+	expiresIn int
+}
+
+func newTokenServer(t *testing.T, expiresIn int) *tokenServer {
+	s := &tokenServer{expiresIn: expiresIn}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.NoError(t, r.ParseForm())
+		assert.Equal(t, "client_credentials", r.Form.Get("grant_type"))
+		assert.Equal(t, "catalog-api", r.Form.Get("audience"))
+
+		n := atomic.AddInt32(&s.requests, 1)
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"access_token": fmt.Sprintf("token-%d", n),
+			"token_type":   "Bearer",
+			"expires_in":   s.expiresIn,
+		})
+	}))
+	return s
+}
+
+func (s *tokenServer) count() int {
+	return int(atomic.LoadInt32(&s.requests))
+}
+
+// eventually polls the condition for up to a second
+func eventually(condition func() bool) bool {
+	for deadline := time.Now().Add(time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		if condition() {
+			return true
+		}
+	}
+	return false
+}
+
+func newClientTokenService(server *tokenServer, refreshBefore string) *ClientTokenService {
+	service := new(ClientTokenService)
+	service.Inject(flamingo.NullLogger{}, &struct {
+		BaseURL string     `inject:"config:internalauth.baseurl"`
+		Clients config.Map `inject:"config:internalauth.clients,optional"`
+	}{
+		BaseURL: server.URL,
+		Clients: config.Map{
+			"catalog": config.Map{
+				"tokenEndpoint":  "/oauth/token",
+				"clientID":       "shop",
+				"clientSecret":   "secret",
+				"endpointParams": config.Map{"audience": "catalog-api"},
+				"refreshBefore":  refreshBefore,
+			},
+		},
+	})
+	return service
+}
+
+func TestClientTokenService_Token(t *testing.T) {
+	defer func() { now = time.Now }()
+
+	server := newTokenServer(t, 3600)
+	defer server.Close()
+
+	service := newClientTokenService(server, "1m")
+
+	t.Run("token is cached", func(t *testing.T) {
+		var wg sync.WaitGroup
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				token, err := service.Token(context.Background(), "catalog")
+				assert.NoError(t, err)
+				assert.Equal(t, "token-1", token.AccessToken)
+			}()
+		}
+		wg.Wait()
+		assert.Equal(t, 1, server.count())
+	})
+
+	t.Run("token is refreshed in the background before it expires", func(t *testing.T) {
+		now = func() time.Time { return time.Now().Add(3600*time.Second - 30*time.Second) }
+
+		token, err := service.Token(context.Background(), "catalog")
+		require.NoError(t, err)
+		assert.Equal(t, "token-1", token.AccessToken, "the still valid token is returned")
+
+		assert.True(t, eventually(func() bool { return server.count() == 2 }))
+
+		now = time.Now
+		assert.True(t, eventually(func() bool {
+			token, err := service.Token(context.Background(), "catalog")
+			return err == nil && token.AccessToken == "token-2"
+		}))
+	})
+
+	t.Run("expired token is fetched again", func(t *testing.T) {
+		now = func() time.Time { return time.Now().Add(2 * time.Hour) }
+
+		token, err := service.Token(context.Background(), "catalog")
+		require.NoError(t, err)
+		assert.Equal(t, "token-3", token.AccessToken)
+	})
+
+	t.Run("unknown client", func(t *testing.T) {
+		_, err := service.Token(context.Background(), "unknown")
+		assert.Error(t, err)
+		_, err = service.TokenSource("unknown")
+		assert.Error(t, err)
+		_, err = service.RoundTripper("unknown", nil)
+		assert.Error(t, err)
+	})
+}
+
+func TestClientTokenService_RoundTripper(t *testing.T) {
+	server := newTokenServer(t, 3600)
+	defer server.Close()
+
+	service := newClientTokenService(server, "0s")
+
+	var reject int32
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.CompareAndSwapInt32(&reject, 1, 0) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_, _ = w.Write([]byte(r.Header.Get("Authorization")))
+	}))
+	defer api.Close()
+
+	client, err := service.HTTPClient("catalog")
+	require.NoError(t, err)
+
+	get := func() (int, string) {
+		req, err := http.NewRequest(http.MethodGet, api.URL, nil)
+		require.NoError(t, err)
+		resp, err := client.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		body := make([]byte, 64)
+		n, _ := resp.Body.Read(body)
+		assert.Empty(t, req.Header.Get("Authorization"), "the original request is not modified")
+		return resp.StatusCode, string(body[:n])
+	}
+
+	status, auth := get()
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "Bearer token-1", auth)
+
+	_, auth = get()
+	assert.Equal(t, "Bearer token-1", auth)
+	assert.Equal(t, 1, server.count())
+
+	// a rejected token is not used again
+	atomic.StoreInt32(&reject, 1)
+	status, _ = get()
+	assert.Equal(t, http.StatusUnauthorized, status)
+
+	_, auth = get()
+	assert.Equal(t, "Bearer token-2", auth)
+}
+
+func TestOauthService_GetOauthToken(t *testing.T) {
+	server := newTokenServer(t, 3600)
+	defer server.Close()
+
+	service := new(OauthService)
+	service.Inject(&struct {
+		BaseURL string          `inject:"config:internalauth.baseurl"`
+		Logger  flamingo.Logger `inject:",optional"`
+	}{BaseURL: server.URL})
+
+	cfg := service.GetConfig("/oauth/token", "shop", "secret", "client_credentials")
+	cfg.EndpointParams.Set("audience", "catalog-api")
+
+	for i := 0; i < 3; i++ {
+		token, err := service.GetOauthToken(context.Background(), &cfg)
+		require.NoError(t, err)
+		assert.Equal(t, "token-1", token.AccessToken)
+	}
+	assert.Equal(t, 1, server.count())
+
+	other := cfg
+	other.ClientID = "other"
+	token, err := service.GetOauthToken(context.Background(), &other)
+	require.NoError(t, err)
+	assert.Equal(t, "token-2", token.AccessToken)
+
+	defer func() { now = time.Now }()
+	now = func() time.Time { return time.Now().Add(time.Hour - 30*time.Second) }
+
+	token, err = service.GetOauthToken(context.Background(), &cfg)
+	require.NoError(t, err)
+	assert.Equal(t, "token-1", token.AccessToken, "the token is still valid")
+	assert.True(t, eventually(func() bool { return server.count() == 3 }), "the token is refreshed in the background before it expires")
+}
+
+func TestOauthService_EvictsLeastRecentlyUsedSources(t *testing.T) {
+	defer func(max int) { maxCachedSources = max }(maxCachedSources)
+	maxCachedSources = 2
+
+	server := newTokenServer(t, 3600)
+	defer server.Close()
+
+	service := new(OauthService)
+	service.Inject(&struct {
+		BaseURL string          `inject:"config:internalauth.baseurl"`
+		Logger  flamingo.Logger `inject:",optional"`
+	}{BaseURL: server.URL})
+
+	configs := make([]clientcredentials.Config, 3)
+	for i := range configs {
+		configs[i] = service.GetConfig("/oauth/token", fmt.Sprintf("client-%d", i), "secret", "client_credentials")
+		configs[i].EndpointParams.Set("audience", "catalog-api")
+	}
+
+	get := func(i int) string {
+		token, err := service.GetOauthToken(context.Background(), &configs[i])
+		require.NoError(t, err)
+		return token.AccessToken
+	}
+
+	assert.Equal(t, "token-1", get(0))
+	assert.Equal(t, "token-2", get(1))
+	assert.Equal(t, "token-1", get(0), "cached")
+	assert.Equal(t, "token-3", get(2), "evicts client-1")
+	assert.Len(t, service.sources, 2)
+	assert.Equal(t, "token-1", get(0), "still cached")
+	assert.Equal(t, "token-4", get(1), "fetched again after the eviction")
+}
+
+// countingTransport counts the requests passed to the default transport
+type countingTransport int32
+
+func (c *countingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	atomic.AddInt32((*int32)(c), 1)
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func TestManagedTokenSource_BackgroundRefreshUsesContextClient(t *testing.T) {
+	server := newTokenServer(t, 3600)
+	defer server.Close()
+
+	transport := new(countingTransport)
+	ctx := context.WithValue(context.Background(), oauth2.HTTPClient, &http.Client{Transport: transport})
+
+	cfg := clientcredentials.Config{ClientID: "shop", ClientSecret: "secret", TokenURL: server.URL + "/oauth/token", EndpointParams: url.Values{"audience": {"catalog-api"}}}
+	source := newManagedTokenSource(cfg, time.Minute, flamingo.NullLogger{})
+	source.token = &oauth2.Token{AccessToken: "current", Expiry: time.Now().Add(30 * time.Second)}
+
+	token, err := source.Token(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "current", token.AccessToken)
+
+	assert.True(t, eventually(func() bool { return server.count() == 1 }), "the token is refreshed in the background")
+	assert.Equal(t, int32(1), atomic.LoadInt32((*int32)(transport)), "the refresh uses the client of the context")
+}
+
+func TestManagedTokenSource_BackgroundRefreshTimeout(t *testing.T) {
+	defer func(timeout time.Duration) { backgroundRefreshTimeout = timeout }(backgroundRefreshTimeout)
+	backgroundRefreshTimeout = 50 * time.Millisecond
+
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+
+	service := new(OauthService)
+	service.Inject(&struct {
+		BaseURL string          `inject:"config:internalauth.baseurl"`
+		Logger  flamingo.Logger `inject:",optional"`
+	}{BaseURL: server.URL})
+	cfg := service.GetConfig("/oauth/token", "shop", "secret", "client_credentials")
+
+	source := newManagedTokenSource(cfg, time.Minute, flamingo.NullLogger{})
+	source.token = &oauth2.Token{AccessToken: "current", Expiry: time.Now().Add(30 * time.Second)}
+
+	token, err := source.Token(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "current", token.AccessToken)
+
+	assert.True(t, eventually(func() bool {
+		source.mu.Lock()
+		defer source.mu.Unlock()
+		return !source.refreshing
+	}), "the background refresh gives up after the timeout")
+}
@@ -2,24 +2,50 @@ package application
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"net/url"
 	"strings"
+	"sync"
 
+	"flamingo.me/flamingo/v3/framework/flamingo"
 	jwt "github.com/dgrijalva/jwt-go"
 	"golang.org/x/oauth2"
 	"golang.org/x/oauth2/clientcredentials"
 )
 
-// OauthService for internal direct token grant
-type OauthService struct {
-	baseURL string
-}
+type (
+	// OauthService for internal direct token grant
+	OauthService struct {
+		baseURL string
+		logger  flamingo.Logger
+
+		mu sync.Mutex
+		// sources caches the tokens per client credentials config, up to maxCachedSources
+		sources map[string]*cachedSource
+		uses    uint64
+	}
+
+	// cachedSource is a token source with its last use, to evict the least recently used source
+	cachedSource struct {
+		source *managedTokenSource
+		used   uint64
+	}
+)
+
+// maxCachedSources limits the cached token sources, as the configs passed to GetOauthToken are not known in advance
+var maxCachedSources = 100
 
 // Inject configuration
 func (os *OauthService) Inject(config *struct {
-	BaseURL string `inject:"config:internalauth.baseurl"`
+	BaseURL string          `inject:"config:internalauth.baseurl"`
+	Logger  flamingo.Logger `inject:",optional"`
 }) {
 	os.baseURL = config.BaseURL
+	os.logger = flamingo.NullLogger{}
+	if config.Logger != nil {
+		os.logger = config.Logger.WithField(flamingo.LogKeyModule, "internalauth")
+	}
 }
 
 // GetConfig returns an oauth config object
@@ -32,17 +58,60 @@ func (os *OauthService) GetConfig(TokenEndpointPath string, ClientID string, Cli
 	}
 }
 
-// GetOauthToken wraps the oauth2 call to retrieve a token
+// GetOauthToken wraps the oauth2 call to retrieve a token, the token is cached until it expires
+// and refreshed in the background within defaultRefreshBefore ahead of the expiry
 func (os *OauthService) GetOauthToken(ctx context.Context, config *clientcredentials.Config) (*oauth2.Token, error) {
-	token, err := config.Token(ctx)
-	if err != nil {
-		return nil, err
+	return os.source(config).Token(ctx)
+}
+
+// source returns the cached token source of the config, if the cache is full the least recently used source is evicted
+func (os *OauthService) source(config *clientcredentials.Config) *managedTokenSource {
+	key := configKey(config)
+
+	os.mu.Lock()
+	defer os.mu.Unlock()
+
+	os.uses++
+	if cached, ok := os.sources[key]; ok {
+		cached.used = os.uses
+		return cached.source
+	}
+
+	if os.sources == nil {
+		os.sources = make(map[string]*cachedSource)
+	}
+	if len(os.sources) >= maxCachedSources {
+		var leastRecent string
+		for key, cached := range os.sources {
+			if leastRecent == "" || cached.used < os.sources[leastRecent].used {
+				leastRecent = key
+			}
+		}
+		delete(os.sources, leastRecent)
 	}
 
-	return token, nil
+	logger := os.logger
+	if logger == nil {
+		logger = flamingo.NullLogger{}
+	}
+	source := newManagedTokenSource(*config, defaultRefreshBefore, logger)
+	os.sources[key] = &cachedSource{source: source, used: os.uses}
+
+	return source
+}
+
+// configKey identifies a client credentials config without exposing the secret
+func configKey(config *clientcredentials.Config) string {
+	hash := sha256.New()
+	for _, part := range []string{config.TokenURL, config.ClientID, config.ClientSecret, strings.Join(config.Scopes, " "), config.EndpointParams.Encode()} {
+		hash.Write([]byte(part))
+		hash.Write([]byte{0})
+	}
+	return hex.EncodeToString(hash.Sum(nil))
 }
 
-// GetClaimsFromToken is a "fix" for the oauth2 libs inability to decode extra data from the token
+// GetClaimsFromToken is a "fix" for the oauth2 libs inability to decode extra data from the token.
+// It does NOT verify the signature, so only use it for own tokens, received tokens must be verified with TokenVerifier.
 func (os *OauthService) GetClaimsFromToken(tokenString string) jwt.MapClaims {
 	claims := jwt.MapClaims{}
 
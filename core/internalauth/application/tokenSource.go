@@ -0,0 +1,188 @@
+package application
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"flamingo.me/flamingo/v3/framework/flamingo"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/clientcredentials"
+)
+
+type (
+	// managedTokenSource caches a client credentials token until it expires.
+	// Within refreshBefore ahead of the expiry the cached token is still returned while a new one is fetched in the background.
+	managedTokenSource struct {
+		config        clientcredentials.Config
+		refreshBefore time.Duration
+		logger        flamingo.Logger
+
+		mu         sync.Mutex
+		token      *oauth2.Token
+		refreshing bool
+		// fetching serializes synchronous fetches, so concurrent callers share one token request
+		fetching sync.Mutex
+	}
+
+	// contextTokenSource binds a managed token source to a context, to implement oauth2.TokenSource
+	contextTokenSource struct {
+		ctx    context.Context
+		source *managedTokenSource
+	}
+
+	// tokenTransport adds the token of a managed token source to outbound requests
+	tokenTransport struct {
+		source *managedTokenSource
+		base   http.RoundTripper
+	}
+)
+
+var _ http.RoundTripper = new(tokenTransport)
+
+const (
+	// defaultRefreshBefore is the refresh skew of token sources without configured refreshBefore
+	defaultRefreshBefore = time.Minute
+)
+
+var (
+	// now is used to fake the time in tests
+	now = time.Now
+
+	// backgroundRefreshTimeout limits the background refresh, so a hanging token endpoint does not block further refreshes
+	backgroundRefreshTimeout = 30 * time.Second
+)
+
+func newManagedTokenSource(config clientcredentials.Config, refreshBefore time.Duration, logger flamingo.Logger) *managedTokenSource {
+	return &managedTokenSource{
+		config:        config,
+		refreshBefore: refreshBefore,
+		logger:        logger,
+	}
+}
+
+// Token returns the cached token, or fetches a new one if there is no valid token
+func (s *managedTokenSource) Token(ctx context.Context) (*oauth2.Token, error) {
+	if token := s.cached(ctx); token != nil {
+		return token, nil
+	}
+
+	s.fetching.Lock()
+	defer s.fetching.Unlock()
+
+	// another caller might have fetched the token meanwhile
+	if token := s.cached(ctx); token != nil {
+		return token, nil
+	}
+
+	return s.fetch(ctx)
+}
+
+// cached returns the cached token if it is still valid, and starts a background refresh if it expires soon.
+// The refresh uses the HTTP client of the context, see oauth2.HTTPClient.
+func (s *managedTokenSource) cached(ctx context.Context) *oauth2.Token {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.token == nil || !s.valid(s.token, 0) {
+		return nil
+	}
+
+	if !s.refreshing && s.refreshBefore > 0 && !s.valid(s.token, s.refreshBefore) {
+		s.refreshing = true
+		client, _ := ctx.Value(oauth2.HTTPClient).(*http.Client)
+		go s.refresh(client)
+	}
+
+	return s.token
+}
+
+// valid checks if the token is still valid for the given duration, tokens without expiry are valid forever
+func (s *managedTokenSource) valid(token *oauth2.Token, duration time.Duration) bool {
+	if token.AccessToken == "" {
+		return false
+	}
+	return token.Expiry.IsZero() || now().Add(duration).Before(token.Expiry)
+}
+
+// refresh fetches a new token in the background with the given HTTP client, the current token is kept if this fails
+func (s *managedTokenSource) refresh(client *http.Client) {
+	s.fetching.Lock()
+	defer s.fetching.Unlock()
+
+	ctx := context.Background()
+	if client != nil {
+		ctx = context.WithValue(ctx, oauth2.HTTPClient, client)
+	}
+	ctx, cancel := context.WithTimeout(ctx, backgroundRefreshTimeout)
+	defer cancel()
+
+	if _, err := s.fetch(ctx); err != nil {
+		s.logger.Warn("background refresh of client credentials token failed: ", err)
+	}
+
+	s.mu.Lock()
+	s.refreshing = false
+	s.mu.Unlock()
+}
+
+// fetch requests a new token from the token endpoint, callers must hold the fetching lock
+func (s *managedTokenSource) fetch(ctx context.Context) (*oauth2.Token, error) {
+	token, err := s.config.Token(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	s.token = token
+	s.mu.Unlock()
+
+	return token, nil
+}
+
+// invalidate drops the cached token if it is the given one, e.g. after it has been rejected
+func (s *managedTokenSource) invalidate(token *oauth2.Token) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.token == token {
+		s.token = nil
+	}
+}
+
+// Token implements oauth2.TokenSource
+func (s *contextTokenSource) Token() (*oauth2.Token, error) {
+	return s.source.Token(s.ctx)
+}
+
+// RoundTrip adds the Authorization header to a copy of the request.
+// A rejected token is dropped from the cache, so the next request uses a new token.
+func (t *tokenTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	token, err := t.source.Token(req.Context())
+	if err != nil {
+		if req.Body != nil {
+			_ = req.Body.Close()
+		}
+		return nil, err
+	}
+
+	outbound := req.WithContext(req.Context())
+	outbound.Header = make(http.Header, len(req.Header)+1)
+	for key, values := range req.Header {
+		outbound.Header[key] = values
+	}
+	token.SetAuthHeader(outbound)
+
+	base := t.base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	resp, err := base.RoundTrip(outbound)
+	if err == nil && resp.StatusCode == http.StatusUnauthorized {
+		t.source.invalidate(token)
+	}
+
+	return resp, err
+}
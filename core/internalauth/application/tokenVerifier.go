@@ -0,0 +1,242 @@
+package application
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"time"
+
+	"flamingo.me/flamingo/v3/core/internalauth/domain"
+	jwt "github.com/dgrijalva/jwt-go"
+	"github.com/pkg/errors"
+	"gopkg.in/square/go-jose.v2"
+)
+
+type (
+	// TokenVerifier verifies received JWTs with the JSON web key set configured in internalauth.verify
+	TokenVerifier struct {
+		jwksURL      string
+		jwksFile     string
+		issuer       string
+		audience     string
+		leeway       time.Duration
+		keysLifetime time.Duration
+		httpClient   *http.Client
+
+		mu      sync.Mutex
+		keys    *jose.JSONWebKeySet
+		fetched time.Time
+		// fetching is closed when the key set fetch in flight is done, concurrent callers wait for it instead of fetching again
+		fetching chan struct{}
+		fetchErr error
+	}
+)
+
+var _ domain.TokenVerifier = new(TokenVerifier)
+
+const (
+	// minKeysRefetch limits refetching the key set for tokens signed with an unknown key
+	minKeysRefetch = 10 * time.Second
+)
+
+// Inject dependencies
+func (v *TokenVerifier) Inject(cfg *struct {
+	BaseURL      string        `inject:"config:internalauth.baseurl"`
+	JWKSURL      string        `inject:"config:internalauth.verify.jwksURL,optional"`
+	JWKSFile     string        `inject:"config:internalauth.verify.jwksFile,optional"`
+	Issuer       string        `inject:"config:internalauth.verify.issuer,optional"`
+	Audience     string        `inject:"config:internalauth.verify.audience,optional"`
+	Leeway       time.Duration `inject:"config:internalauth.verify.leeway,optional"`
+	KeysLifetime time.Duration `inject:"config:internalauth.verify.keysLifetime,optional"`
+}) {
+	v.httpClient = http.DefaultClient
+	if cfg == nil {
+		return
+	}
+
+	if cfg.JWKSURL != "" {
+		v.jwksURL = resolveURL(cfg.BaseURL, cfg.JWKSURL)
+	}
+	v.jwksFile = cfg.JWKSFile
+	v.issuer = cfg.Issuer
+	v.audience = cfg.Audience
+	v.leeway = cfg.Leeway
+	v.keysLifetime = cfg.KeysLifetime
+}
+
+// Verify checks the signature of the token with the key set, and its exp, nbf, iss and aud claims
+func (v *TokenVerifier) Verify(ctx context.Context, token string) (jwt.MapClaims, error) {
+	jws, err := jose.ParseSigned(token)
+	if err != nil {
+		return nil, errors.Wrap(err, "malformed jwt")
+	}
+	if len(jws.Signatures) != 1 {
+		return nil, errors.New("jwt must have exactly one signature")
+	}
+
+	keyID := jws.Signatures[0].Header.KeyID
+	keys, err := v.keySet(ctx, keyID)
+	if err != nil {
+		return nil, err
+	}
+
+	candidates := keys.Keys
+	if keyID != "" {
+		candidates = keys.Key(keyID)
+	}
+
+	var payload []byte
+	for _, key := range candidates {
+		if payload, err = jws.Verify(key); err == nil {
+			break
+		}
+	}
+	if payload == nil {
+		return nil, errors.New("failed to verify signature")
+	}
+
+	claims := jwt.MapClaims{}
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return nil, errors.Wrap(err, "malformed jwt claims")
+	}
+
+	if err := v.verifyClaims(claims); err != nil {
+		return nil, err
+	}
+
+	return claims, nil
+}
+
+// verifyClaims requires an expiry, and checks nbf, iss and aud if configured
+func (v *TokenVerifier) verifyClaims(claims jwt.MapClaims) error {
+	leeway := int64(v.leeway / time.Second)
+	current := now().Unix()
+
+	if _, ok := claims["exp"]; !ok {
+		return errors.New("jwt has no expiry")
+	}
+	if !claims.VerifyExpiresAt(current-leeway, true) {
+		return errors.New("jwt is expired")
+	}
+	if !claims.VerifyNotBefore(current+leeway, false) {
+		return errors.New("jwt is not valid yet")
+	}
+	if v.issuer != "" && !claims.VerifyIssuer(v.issuer, true) {
+		return errors.Errorf("jwt issuer %v does not match %q", claims["iss"], v.issuer)
+	}
+	if v.audience != "" && !hasAudience(claims["aud"], v.audience) {
+		return errors.Errorf("jwt audience %v does not contain %q", claims["aud"], v.audience)
+	}
+
+	return nil
+}
+
+// hasAudience checks the aud claim, which is either a string or a list of strings
+func hasAudience(aud interface{}, audience string) bool {
+	switch aud := aud.(type) {
+	case string:
+		return aud == audience
+	case []interface{}:
+		for _, a := range aud {
+			if a == audience {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// keySet returns the cached key set, it is fetched again after the keys lifetime, or if the key id is unknown.
+// The key set is fetched without holding the lock, so verifications with cached keys are not blocked by a slow fetch.
+func (v *TokenVerifier) keySet(ctx context.Context, keyID string) (*jose.JSONWebKeySet, error) {
+	v.mu.Lock()
+	if v.keys != nil {
+		expired := v.keysLifetime > 0 && now().Sub(v.fetched) > v.keysLifetime
+		unknownKey := keyID != "" && len(v.keys.Key(keyID)) == 0 && now().Sub(v.fetched) > minKeysRefetch
+		if v.jwksURL == "" || (!expired && !unknownKey) {
+			keys := v.keys
+			v.mu.Unlock()
+			return keys, nil
+		}
+	}
+
+	if fetching := v.fetching; fetching != nil {
+		v.mu.Unlock()
+		select {
+		case <-fetching:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		return v.fetchResult()
+	}
+
+	fetching := make(chan struct{})
+	v.fetching = fetching
+	v.mu.Unlock()
+
+	keys, err := v.loadKeySet(ctx)
+
+	v.mu.Lock()
+	if err == nil {
+		v.keys = keys
+		v.fetched = now()
+	}
+	v.fetchErr = err
+	v.fetching = nil
+	v.mu.Unlock()
+	close(fetching)
+
+	return v.fetchResult()
+}
+
+// fetchResult returns the key set after a fetch, the previous key set is kept if the fetch failed
+func (v *TokenVerifier) fetchResult() (*jose.JSONWebKeySet, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if v.keys == nil {
+		return nil, v.fetchErr
+	}
+	return v.keys, nil
+}
+
+func (v *TokenVerifier) loadKeySet(ctx context.Context) (*jose.JSONWebKeySet, error) {
+	var data []byte
+
+	switch {
+	case v.jwksURL != "":
+		req, err := http.NewRequest(http.MethodGet, v.jwksURL, nil)
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid jwks url")
+		}
+		resp, err := v.httpClient.Do(req.WithContext(ctx))
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to fetch jwks")
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return nil, errors.Errorf("failed to fetch jwks: %s", resp.Status)
+		}
+		if data, err = ioutil.ReadAll(resp.Body); err != nil {
+			return nil, errors.Wrap(err, "failed to fetch jwks")
+		}
+
+	case v.jwksFile != "":
+		var err error
+		if data, err = ioutil.ReadFile(v.jwksFile); err != nil {
+			return nil, errors.Wrap(err, "failed to read jwks")
+		}
+
+	default:
+		return nil, errors.New("neither internalauth.verify.jwksURL nor internalauth.verify.jwksFile is configured")
+	}
+
+	keys := new(jose.JSONWebKeySet)
+	if err := json.Unmarshal(data, keys); err != nil {
+		return nil, errors.Wrap(err, "invalid JSON web key set")
+	}
+
+	return keys, nil
+}
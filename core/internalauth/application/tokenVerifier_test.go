@@ -0,0 +1,186 @@
+package application
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/square/go-jose.v2"
+)
+
+type testSigner struct {
+	keyID  string
+	key    *rsa.PrivateKey
+	signer jose.Signer
+}
+
+func newTestSigner(t *testing.T, keyID string) *testSigner {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	signer, err := jose.NewSigner(
+		jose.SigningKey{Algorithm: jose.RS256, Key: jose.JSONWebKey{Key: key, KeyID: keyID}},
+		new(jose.SignerOptions).WithType("JWT"),
+	)
+	require.NoError(t, err)
+
+	return &testSigner{keyID: keyID, key: key, signer: signer}
+}
+
+func (s *testSigner) jwk() jose.JSONWebKey {
+	return jose.JSONWebKey{Key: &s.key.PublicKey, KeyID: s.keyID, Algorithm: string(jose.RS256), Use: "sig"}
+}
+
+func (s *testSigner) sign(t *testing.T, claims map[string]interface{}) string {
+	payload, err := json.Marshal(claims)
+	require.NoError(t, err)
+	jws, err := s.signer.Sign(payload)
+	require.NoError(t, err)
+	token, err := jws.CompactSerialize()
+	require.NoError(t, err)
+	return token
+}
+
+func TestTokenVerifier_Verify(t *testing.T) {
+	first := newTestSigner(t, "first")
+	second := newTestSigner(t, "second")
+
+	var rotated, fetches int32
+	jwks := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&fetches, 1)
+		keys := jose.JSONWebKeySet{Keys: []jose.JSONWebKey{first.jwk()}}
+		if atomic.LoadInt32(&rotated) == 1 {
+			keys.Keys = append(keys.Keys, second.jwk())
+		}
+		_ = json.NewEncoder(w).Encode(keys)
+	}))
+	defer jwks.Close()
+
+	verifier := new(TokenVerifier)
+	verifier.Inject(&struct {
+		BaseURL      string        `inject:"config:internalauth.baseurl"`
+		JWKSURL      string        `inject:"config:internalauth.verify.jwksURL,optional"`
+		JWKSFile     string        `inject:"config:internalauth.verify.jwksFile,optional"`
+		Issuer       string        `inject:"config:internalauth.verify.issuer,optional"`
+		Audience     string        `inject:"config:internalauth.verify.audience,optional"`
+		Leeway       time.Duration `inject:"config:internalauth.verify.leeway,optional"`
+		KeysLifetime time.Duration `inject:"config:internalauth.verify.keysLifetime,optional"`
+	}{
+		BaseURL:      jwks.URL,
+		JWKSURL:      "/certs",
+		Issuer:       "https://idp.example",
+		Audience:     "shop",
+		Leeway:       30 * time.Second,
+		KeysLifetime: time.Hour,
+	})
+
+	claims := func(modify func(map[string]interface{})) map[string]interface{} {
+		c := map[string]interface{}{
+			"iss": "https://idp.example",
+			"aud": []string{"catalog", "shop"},
+			"sub": "service-account",
+			"exp": time.Now().Add(time.Hour).Unix(),
+		}
+		if modify != nil {
+			modify(c)
+		}
+		return c
+	}
+
+	t.Run("valid token", func(t *testing.T) {
+		verified, err := verifier.Verify(context.Background(), first.sign(t, claims(nil)))
+		require.NoError(t, err)
+		assert.Equal(t, "service-account", verified["sub"])
+
+		_, err = verifier.Verify(context.Background(), first.sign(t, claims(func(c map[string]interface{}) {
+			c["exp"] = time.Now().Add(-10 * time.Second).Unix()
+		})))
+		assert.NoError(t, err, "expiry within the leeway")
+		assert.Equal(t, int32(1), atomic.LoadInt32(&fetches), "the key set is cached")
+	})
+
+	t.Run("invalid tokens", func(t *testing.T) {
+		for name, token := range map[string]string{
+			"expired":        first.sign(t, claims(func(c map[string]interface{}) { c["exp"] = time.Now().Add(-time.Hour).Unix() })),
+			"no expiry":      first.sign(t, claims(func(c map[string]interface{}) { delete(c, "exp") })),
+			"not yet valid":  first.sign(t, claims(func(c map[string]interface{}) { c["nbf"] = time.Now().Add(time.Hour).Unix() })),
+			"wrong issuer":   first.sign(t, claims(func(c map[string]interface{}) { c["iss"] = "https://evil.example" })),
+			"wrong audience": first.sign(t, claims(func(c map[string]interface{}) { c["aud"] = "catalog" })),
+			"wrong key":      newTestSigner(t, "first").sign(t, claims(nil)),
+			"malformed":      "not-a-jwt",
+		} {
+			t.Run(name, func(t *testing.T) {
+				_, err := verifier.Verify(context.Background(), token)
+				assert.Error(t, err)
+			})
+		}
+	})
+
+	t.Run("key rotation", func(t *testing.T) {
+		defer func() { now = time.Now }()
+
+		atomic.StoreInt32(&rotated, 1)
+		now = func() time.Time { return time.Now().Add(time.Minute) }
+
+		_, err := verifier.Verify(context.Background(), second.sign(t, claims(nil)))
+		assert.NoError(t, err, "the key set is fetched again for an unknown key id")
+	})
+}
+
+func TestTokenVerifier_ConcurrentKeySetFetch(t *testing.T) {
+	signer := newTestSigner(t, "first")
+
+	var fetches int32
+	release := make(chan struct{})
+	jwks := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&fetches, 1)
+		<-release
+		_ = json.NewEncoder(w).Encode(jose.JSONWebKeySet{Keys: []jose.JSONWebKey{signer.jwk()}})
+	}))
+	defer jwks.Close()
+
+	verifier := new(TokenVerifier)
+	verifier.Inject(&struct {
+		BaseURL      string        `inject:"config:internalauth.baseurl"`
+		JWKSURL      string        `inject:"config:internalauth.verify.jwksURL,optional"`
+		JWKSFile     string        `inject:"config:internalauth.verify.jwksFile,optional"`
+		Issuer       string        `inject:"config:internalauth.verify.issuer,optional"`
+		Audience     string        `inject:"config:internalauth.verify.audience,optional"`
+		Leeway       time.Duration `inject:"config:internalauth.verify.leeway,optional"`
+		KeysLifetime time.Duration `inject:"config:internalauth.verify.keysLifetime,optional"`
+	}{BaseURL: jwks.URL, JWKSURL: "/certs"})
+
+	token := signer.sign(t, map[string]interface{}{"sub": "service-account", "exp": time.Now().Add(time.Hour).Unix()})
+
+	errs := make(chan error, 5)
+	for i := 0; i < cap(errs); i++ {
+		go func() {
+			_, err := verifier.Verify(context.Background(), token)
+			errs <- err
+		}()
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	verifier.mu.Lock()
+	assert.NotNil(t, verifier.fetching, "the lock is not held during the fetch")
+	verifier.mu.Unlock()
+
+	close(release)
+	for i := 0; i < cap(errs); i++ {
+		assert.NoError(t, <-errs)
+	}
+	assert.Equal(t, int32(1), atomic.LoadInt32(&fetches), "concurrent verifications share one fetch")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := verifier.Verify(ctx, token)
+	assert.NoError(t, err, "cached keys are used without fetching")
+}
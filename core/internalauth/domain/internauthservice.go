@@ -2,16 +2,34 @@ package domain
 
 import (
 	"context"
+	"net/http"
 
 	jwt "github.com/dgrijalva/jwt-go"
 	"golang.org/x/oauth2"
 	"golang.org/x/oauth2/clientcredentials"
 )
 
-// InternalAuthService interface for internal oauth clients
-// todo necessary?
-type InternalAuthService interface {
-	GetConfig(TokenEndpointPath string, ClientID string, ClientSecret string, GrantType string) clientcredentials.Config
-	GetOauthToken(ctx context.Context, config *clientcredentials.Config) (*oauth2.Token, error)
-	GetClaimsFromToken(tokenString string) jwt.MapClaims
-}
+type (
+	// InternalAuthService interface for internal oauth clients
+	// todo necessary?
+	InternalAuthService interface {
+		GetConfig(TokenEndpointPath string, ClientID string, ClientSecret string, GrantType string) clientcredentials.Config
+		GetOauthToken(ctx context.Context, config *clientcredentials.Config) (*oauth2.Token, error)
+		GetClaimsFromToken(tokenString string) jwt.MapClaims
+	}
+
+	// ClientTokenProvider provides cached client credentials tokens of the clients configured in internalauth.clients
+	ClientTokenProvider interface {
+		// Token returns the current token of the client
+		Token(ctx context.Context, client string) (*oauth2.Token, error)
+		// TokenSource returns the managed token source of the client
+		TokenSource(client string) (oauth2.TokenSource, error)
+		// RoundTripper returns a http.RoundTripper which adds the client's token to outbound requests
+		RoundTripper(client string, base http.RoundTripper) (http.RoundTripper, error)
+	}
+
+	// TokenVerifier verifies the signature and claims of received tokens
+	TokenVerifier interface {
+		Verify(ctx context.Context, token string) (jwt.MapClaims, error)
+	}
+)
@@ -4,6 +4,7 @@ import (
 	"flamingo.me/dingo"
 	"flamingo.me/flamingo/v3/core/internalauth/application"
 	"flamingo.me/flamingo/v3/core/internalauth/domain"
+	"flamingo.me/flamingo/v3/framework/config"
 )
 
 // InternalAuth module for backend oauth usage
@@ -11,5 +12,28 @@ type InternalAuth struct{}
 
 // Configure the DI
 func (m *InternalAuth) Configure(injector *dingo.Injector) {
+	injector.Bind(application.OauthService{}).In(dingo.ChildSingleton)
 	injector.Bind(new(domain.InternalAuthService)).To(application.OauthService{})
+	injector.Bind(application.ClientTokenService{}).In(dingo.ChildSingleton)
+	injector.Bind(new(domain.ClientTokenProvider)).To(application.ClientTokenService{})
+	injector.Bind(application.TokenVerifier{}).In(dingo.ChildSingleton)
+	injector.Bind(new(domain.TokenVerifier)).To(application.TokenVerifier{})
+}
+
+// DefaultConfig for internalauth module
+func (m *InternalAuth) DefaultConfig() config.Map {
+	return config.Map{
+		"internalauth": config.Map{
+			"baseurl": "",
+			"clients": config.Map{},
+			"verify": config.Map{
+				"jwksURL":      "",
+				"jwksFile":     "",
+				"issuer":       "",
+				"audience":     "",
+				"leeway":       "30s",
+				"keysLifetime": "1h",
+			},
+		},
+	}
 }
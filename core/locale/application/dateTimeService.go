@@ -1,6 +1,7 @@
 package application
 
 import (
+	"context"
 	"time"
 
 	"flamingo.me/flamingo/v3/core/locale/domain"
@@ -9,10 +10,15 @@ import (
 )
 
 type (
-	// DateTimeServiceInterface to define a service to obtain formatted date time data
+	// DateTimeServiceInterface to define a service to obtain formatted date time data,
+	// the methods with context use the date formats and location of the locale of the request of the context
 	DateTimeServiceInterface interface {
+		// Deprecated: use GetDateTimeFormatterFromIsoStringWithContext
 		GetDateTimeFormatterFromIsoString(dateTimeString string) (*domain.DateTimeFormatter, error)
+		GetDateTimeFormatterFromIsoStringWithContext(ctx context.Context, dateTimeString string) (*domain.DateTimeFormatter, error)
+		// Deprecated: use GetDateTimeFormatterWithContext
 		GetDateTimeFormatter(dateTime time.Time) (*domain.DateTimeFormatter, error)
+		GetDateTimeFormatterWithContext(ctx context.Context, dateTime time.Time) (*domain.DateTimeFormatter, error)
 	}
 
 	// DateTimeService is a basic support service for date/time parsing
@@ -22,6 +28,8 @@ type (
 		dateTimeFormat string
 		location       string
 		logger         flamingo.Logger
+		// LocaleService provides the date settings configured for the locale of the request
+		LocaleService *LocaleService `inject:",optional"`
 	}
 )
 
@@ -46,29 +54,44 @@ func (dts *DateTimeService) Inject(
 }
 
 // GetDateTimeFormatterFromIsoString Need string in format ISO: "2017-11-25T06:30:00Z"
+// Deprecated: use GetDateTimeFormatterFromIsoStringWithContext, which uses the locale of the request
 func (dts *DateTimeService) GetDateTimeFormatterFromIsoString(dateTimeString string) (*domain.DateTimeFormatter, error) {
+	return dts.GetDateTimeFormatterFromIsoStringWithContext(context.Background(), dateTimeString)
+}
+
+// GetDateTimeFormatterFromIsoStringWithContext Need string in format ISO: "2017-11-25T06:30:00Z",
+// the formatter uses the locale of the request of the context
+func (dts *DateTimeService) GetDateTimeFormatterFromIsoStringWithContext(ctx context.Context, dateTimeString string) (*domain.DateTimeFormatter, error) {
 	timeResult, err := time.Parse(time.RFC3339, dateTimeString) //"2006-01-02T15:04:05Z"
 	if err != nil {
 		return nil, errors.Errorf("could not parse date in defined format: %v / Error: %v", dateTimeString, err)
 	}
 
-	return dts.GetDateTimeFormatter(timeResult)
+	return dts.GetDateTimeFormatterWithContext(ctx, timeResult)
 }
 
 // GetDateTimeFormatter from time
+// Deprecated: use GetDateTimeFormatterWithContext, which uses the locale of the request
 func (dts *DateTimeService) GetDateTimeFormatter(timeValue time.Time) (*domain.DateTimeFormatter, error) {
-	loc, err := dts.loadLocation()
+	return dts.GetDateTimeFormatterWithContext(context.Background(), timeValue)
+}
+
+// GetDateTimeFormatterWithContext returns a formatter with the date formats and location configured for the locale
+// of the request of the context, the formats and location of the area are used if the locale has none
+func (dts *DateTimeService) GetDateTimeFormatterWithContext(ctx context.Context, timeValue time.Time) (*domain.DateTimeFormatter, error) {
+	location := dts.setting(ctx, "location", dts.location)
+	loc, err := loadLocation(location)
 	if err != nil {
 		if dts.logger != nil {
-			dts.logger.Warn("dateTime Parsing error - could not load location - use UTC as fallback", dts.location)
+			dts.logger.Warn("dateTime Parsing error - could not load location - use UTC as fallback", location)
 		}
 		loc = time.UTC
 	}
 
 	dateTime := domain.DateTimeFormatter{
-		DateFormat:     dts.dateFormat,
-		TimeFormat:     dts.timeFormat,
-		DateTimeFormat: dts.dateTimeFormat,
+		DateFormat:     dts.setting(ctx, "dateFormat", dts.dateFormat),
+		TimeFormat:     dts.setting(ctx, "timeFormat", dts.timeFormat),
+		DateTimeFormat: dts.setting(ctx, "dateTimeFormat", dts.dateTimeFormat),
 	}
 
 	if dts.logger != nil {
@@ -80,11 +103,25 @@ func (dts *DateTimeService) GetDateTimeFormatter(timeValue time.Time) (*domain.D
 	return &dateTime, nil
 }
 
-func (dts *DateTimeService) loadLocation() (*time.Location, error) {
-	if dts.location == "" {
+// setting returns the date setting configured for the locale of the context, the given area setting otherwise
+func (dts *DateTimeService) setting(ctx context.Context, key string, areaSetting string) string {
+	if dts.LocaleService == nil {
+		return areaSetting
+	}
+
+	if setting, ok := dts.LocaleService.Setting(ctx, "date."+key); ok {
+		if setting, ok := setting.(string); ok {
+			return setting
+		}
+	}
+	return areaSetting
+}
+
+func loadLocation(location string) (*time.Location, error) {
+	if location == "" {
 		return nil, errors.Errorf("No location configured")
 	}
 
 	// try to load the configured location
-	return time.LoadLocation(dts.location)
+	return time.LoadLocation(location)
 }
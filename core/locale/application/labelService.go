@@ -1,6 +1,8 @@
 package application
 
 import (
+	"context"
+
 	"flamingo.me/flamingo/v3/core/locale/domain"
 	"flamingo.me/flamingo/v3/framework/config"
 )
@@ -12,6 +14,8 @@ type (
 		defaultLocaleCode          string
 		defaultFallbackLocaleCodes []string
 		translationService         domain.TranslationService
+		// LocaleService provides the locale of the request, the configured locale is used without it
+		LocaleService *LocaleService `inject:",optional"`
 	}
 
 	labelProvider func() *domain.Label
@@ -31,18 +35,35 @@ func (l *LabelService) Inject(labelProvider labelProvider, translationService do
 }
 
 // NewLabel factory
+// Deprecated: use NewLabelWithContext, which uses the locale of the request
 func (l *LabelService) NewLabel(key string) *domain.Label {
+	return l.NewLabelWithContext(context.Background(), key)
+}
+
+// NewLabelWithContext creates a label in the locale of the request of the context, the configured locale otherwise
+func (l *LabelService) NewLabelWithContext(ctx context.Context, key string) *domain.Label {
+	return l.newLabel(key, l.localeCode(ctx))
+}
+
+func (l *LabelService) newLabel(key string, localeCode string) *domain.Label {
 	label := l.labelProvider()
-	return label.SetKey(key).SetDefaultLabel(key).SetLocale(l.defaultLocaleCode).SetCount(1).SetFallbackLocales(l.defaultFallbackLocaleCodes)
+	return label.SetKey(key).SetDefaultLabel(key).SetLocale(localeCode).SetCount(1).SetFallbackLocales(l.defaultFallbackLocaleCodes)
 }
 
 // AllLabels return a array of all labels
+// Deprecated: use AllLabelsWithContext, which uses the locale of the request
 func (l *LabelService) AllLabels() []domain.Label {
+	return l.AllLabelsWithContext(context.Background())
+}
+
+// AllLabelsWithContext returns all labels in the locale of the request of the context
+func (l *LabelService) AllLabelsWithContext(ctx context.Context) []domain.Label {
+	localeCode := l.localeCode(ctx)
 	var labels []domain.Label
-	tags := l.translationService.AllTranslationKeys(l.defaultLocaleCode)
+	tags := l.translationService.AllTranslationKeys(localeCode)
 
 	for _, tag := range tags {
-		label := l.NewLabel(tag)
+		label := l.newLabel(tag, localeCode)
 		if label != nil {
 			labels = append(labels, *label)
 		}
@@ -50,3 +71,11 @@ func (l *LabelService) AllLabels() []domain.Label {
 
 	return labels
 }
+
+// localeCode returns the locale of the request of the context, the configured locale otherwise
+func (l *LabelService) localeCode(ctx context.Context) string {
+	if l.LocaleService != nil {
+		return l.LocaleService.LocaleCode(ctx)
+	}
+	return l.defaultLocaleCode
+}
@@ -0,0 +1,39 @@
+package application
+
+import (
+	"context"
+
+	"flamingo.me/flamingo/v3/core/locale/domain"
+	"flamingo.me/flamingo/v3/framework/config"
+)
+
+// LocaleService provides the locale of the current request and the settings configured for it
+type LocaleService struct {
+	defaultLocaleCode string
+	locales           config.Map
+}
+
+// Inject dependencies
+func (s *LocaleService) Inject(config *struct {
+	DefaultLocaleCode string     `inject:"config:locale.locale"`
+	Locales           config.Map `inject:"config:locale.locales,optional"`
+}) {
+	if config != nil {
+		s.defaultLocaleCode = config.DefaultLocaleCode
+		s.locales = config.Locales
+	}
+}
+
+// LocaleCode returns the locale resolved for the request of the context, the configured locale.locale otherwise
+func (s *LocaleService) LocaleCode(ctx context.Context) string {
+	if localeCode, ok := domain.LocaleFromContext(ctx); ok && localeCode != "" {
+		return localeCode
+	}
+	return s.defaultLocaleCode
+}
+
+// Setting returns a setting configured for the locale of the context in locale.locales, e.g. "numbers.decimal".
+// Settings which are not configured for the locale should fall back to the settings of the area.
+func (s *LocaleService) Setting(ctx context.Context, key string) (interface{}, bool) {
+	return domain.LocaleSetting(s.locales, s.LocaleCode(ctx), key)
+}
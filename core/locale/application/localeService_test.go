@@ -0,0 +1,141 @@
+package application
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"flamingo.me/flamingo/v3/core/locale/domain"
+	"flamingo.me/flamingo/v3/framework/config"
+	"flamingo.me/flamingo/v3/framework/flamingo"
+)
+
+type fakeTranslationService struct{}
+
+func (fakeTranslationService) TranslateLabel(label domain.Label) string {
+	return label.GetDefaultLabel()
+}
+
+func (fakeTranslationService) Translate(key string, defaultLabel string, localeCode string, count int, translationArguments map[string]interface{}) string {
+	return defaultLabel
+}
+
+func (fakeTranslationService) AllTranslationKeys(localeCode string) []string {
+	return []string{localeCode}
+}
+
+func newLabelService() *LabelService {
+	labelService := new(LabelService)
+	labelService.Inject(func() *domain.Label {
+		label := new(domain.Label)
+		label.Inject(fakeTranslationService{})
+		return label
+	}, fakeTranslationService{}, &struct {
+		DefaultLocaleCode string       `inject:"config:locale.locale"`
+		FallbackLocalCode config.Slice `inject:"config:locale.fallbackLocales,optional"`
+	}{DefaultLocaleCode: "en-US"})
+	labelService.LocaleService = newLocaleService()
+	return labelService
+}
+
+func newLocaleService() *LocaleService {
+	localeService := new(LocaleService)
+	localeService.Inject(&struct {
+		DefaultLocaleCode string     `inject:"config:locale.locale"`
+		Locales           config.Map `inject:"config:locale.locales,optional"`
+	}{
+		DefaultLocaleCode: "en-US",
+		Locales: config.Map{
+			"de-DE": config.Map{
+				"accounting": config.Map{
+					"default": config.Map{"decimal": ",", "thousand": ".", "format": "%v %s"},
+				},
+				"date": config.Map{
+					"dateFormat": "02.01.2006",
+					"location":   "Europe/Berlin",
+				},
+			},
+		},
+	})
+	return localeService
+}
+
+func TestLocaleService(t *testing.T) {
+	localeService := newLocaleService()
+
+	assert.Equal(t, "en-US", localeService.LocaleCode(context.Background()))
+	_, ok := localeService.Setting(context.Background(), "date.dateFormat")
+	assert.False(t, ok)
+
+	ctx := domain.ContextWithLocale(context.Background(), "de-de")
+	assert.Equal(t, "de-de", localeService.LocaleCode(ctx))
+	setting, ok := localeService.Setting(ctx, "date.dateFormat")
+	assert.True(t, ok)
+	assert.Equal(t, "02.01.2006", setting)
+	_, ok = localeService.Setting(ctx, "date.timeFormat")
+	assert.False(t, ok)
+}
+
+func TestLabelService_NewLabel(t *testing.T) {
+	labelService := newLabelService()
+	ctx := domain.ContextWithLocale(context.Background(), "de-DE")
+
+	assert.Equal(t, "en-US", labelService.NewLabel("key").GetLocaleCode())
+	assert.Equal(t, "en-US", labelService.NewLabelWithContext(context.Background(), "key").GetLocaleCode())
+	assert.Equal(t, "de-DE", labelService.NewLabelWithContext(ctx, "key").GetLocaleCode())
+
+	labelService.LocaleService = nil
+	assert.Equal(t, "en-US", labelService.NewLabelWithContext(ctx, "key").GetLocaleCode(), "configured locale without locale service")
+	labelService.LocaleService = newLocaleService()
+
+	labels := labelService.AllLabelsWithContext(ctx)
+	if assert.Len(t, labels, 1) {
+		assert.Equal(t, "de-DE", labels[0].GetKey())
+		assert.Equal(t, "de-DE", labels[0].GetLocaleCode())
+	}
+}
+
+func TestDateTimeService_GetDateTimeFormatter(t *testing.T) {
+	dateTimeService := new(DateTimeService)
+	dateTimeService.Inject(flamingo.NullLogger{}, &struct {
+		DateFormat     string `inject:"config:locale.date.dateFormat"`
+		TimeFormat     string `inject:"config:locale.date.timeFormat"`
+		DateTimeFormat string `inject:"config:locale.date.dateTimeFormat"`
+		Location       string `inject:"config:locale.date.location"`
+	}{
+		DateFormat:     "02 Jan 2006",
+		TimeFormat:     "15:04",
+		DateTimeFormat: "02 Jan 2006 15:04",
+		Location:       "UTC",
+	})
+	dateTimeService.LocaleService = newLocaleService()
+
+	dateTime := time.Date(2019, 3, 1, 23, 30, 0, 0, time.UTC)
+
+	f, err := dateTimeService.GetDateTimeFormatterWithContext(context.Background(), dateTime)
+	assert.NoError(t, err)
+	assert.Equal(t, "01 Mar 2019", f.FormatToLocalDate())
+
+	f, err = dateTimeService.GetDateTimeFormatterWithContext(domain.ContextWithLocale(context.Background(), "de-DE"), dateTime)
+	assert.NoError(t, err)
+	assert.Equal(t, "02.03.2019", f.FormatToLocalDate())
+	assert.Equal(t, "00:30", f.FormatToLocalTime(), "time format of the area with location of the locale")
+}
+
+func TestPriceService_FormatPrice(t *testing.T) {
+	priceService := new(PriceService)
+	priceService.Inject(newLabelService(), &struct {
+		Config config.Map `inject:"config:locale.accounting"`
+	}{
+		Config: config.Map{
+			"default": config.Map{"decimal": ".", "thousand": ",", "format": "%s %v"},
+		},
+	})
+	priceService.LocaleService = newLocaleService()
+
+	assert.Equal(t, "€ 21,500.99", priceService.FormatPrice(21500.99, "€"))
+	assert.Equal(t, "€ 21,500.99", priceService.FormatPriceWithContext(context.Background(), 21500.99, "€"))
+	assert.Equal(t, "21.500,99 €", priceService.FormatPriceWithContext(domain.ContextWithLocale(context.Background(), "de-DE"), 21500.99, "€"))
+}
@@ -1,6 +1,8 @@
 package application
 
 import (
+	"context"
+
 	"flamingo.me/flamingo/v3/framework/config"
 	"github.com/leekchan/accounting"
 )
@@ -9,6 +11,8 @@ import (
 type PriceService struct {
 	config       config.Map
 	labelService *LabelService
+	// LocaleService provides the accounting configured for the locale of the request
+	LocaleService *LocaleService `inject:",optional"`
 }
 
 // Inject dependencies
@@ -20,7 +24,27 @@ func (s *PriceService) Inject(labelService *LabelService, config *struct {
 }
 
 // GetConfigForCurrency get configuration for currency
+// Deprecated: use GetConfigForCurrencyWithContext, which uses the accounting of the locale of the request
 func (s *PriceService) GetConfigForCurrency(currency string) config.Map {
+	return s.GetConfigForCurrencyWithContext(context.Background(), currency)
+}
+
+// GetConfigForCurrencyWithContext returns the configuration for the currency in the locale of the request of the context.
+// The accounting configured for the locale in locale.locales is preferred to the accounting of the area.
+func (s *PriceService) GetConfigForCurrencyWithContext(ctx context.Context, currency string) config.Map {
+	if s.LocaleService != nil {
+		if localeConfig, ok := s.LocaleService.Setting(ctx, "accounting"); ok {
+			if localeConfig, ok := localeConfig.(config.Map); ok {
+				if configForCurrency, ok := localeConfig[currency].(config.Map); ok {
+					return configForCurrency
+				}
+				if defaultConfig, ok := localeConfig["default"].(config.Map); ok {
+					return defaultConfig
+				}
+			}
+		}
+	}
+
 	if configForCurrency, ok := s.config[currency]; ok {
 		return configForCurrency.(config.Map)
 	}
@@ -33,11 +57,19 @@ func (s *PriceService) GetConfigForCurrency(currency string) config.Map {
 }
 
 // FormatPrice by price
+// Deprecated: use FormatPriceWithContext, which uses the locale of the request
 func (s *PriceService) FormatPrice(value float64, currency string) string {
-	currency = s.labelService.NewLabel(currency).String()
+	return s.FormatPriceWithContext(context.Background(), value, currency)
+}
 
-	configForCurrency := s.GetConfigForCurrency(currency)
+// FormatPriceWithContext formats the price in the locale of the request of the context
+func (s *PriceService) FormatPriceWithContext(ctx context.Context, value float64, currency string) string {
+	currency = s.labelService.NewLabelWithContext(ctx, currency).String()
+
+	return formatPrice(value, currency, s.GetConfigForCurrencyWithContext(ctx, currency))
+}
 
+func formatPrice(value float64, currency string, configForCurrency config.Map) string {
 	ac := accounting.Accounting{
 		Symbol:    currency,
 		Precision: 2,
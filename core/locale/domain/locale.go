@@ -0,0 +1,68 @@
+package domain
+
+import (
+	"context"
+	"strings"
+
+	"flamingo.me/flamingo/v3/framework/config"
+	"flamingo.me/flamingo/v3/framework/web"
+)
+
+type (
+	// LocaleResolver resolves the locale of a request, e.g. from a cookie or the Accept-Language header.
+	// The resolvers configured in locale.resolution.chain are asked in order until one returns a supported locale.
+	LocaleResolver interface {
+		// Resolve returns the locale codes requested by the request, the preferred locale first
+		Resolve(ctx context.Context, r *web.Request) []string
+	}
+
+	contextKeyType string
+)
+
+const contextLocale contextKeyType = "locale"
+
+// ContextWithLocale returns a new context with the locale code of the request
+func ContextWithLocale(ctx context.Context, localeCode string) context.Context {
+	return context.WithValue(ctx, contextLocale, localeCode)
+}
+
+// StoreLocale stores the locale code in the request, so it is also available in contexts which are not derived
+// from the context of the locale resolution, e.g. when a template is rendered
+func StoreLocale(r *web.Request, localeCode string) {
+	r.Values.Store(contextLocale, localeCode)
+}
+
+// LocaleFromContext returns the locale code resolved for the request of the context
+func LocaleFromContext(ctx context.Context) (string, bool) {
+	if localeCode, ok := ctx.Value(contextLocale).(string); ok {
+		return localeCode, true
+	}
+
+	if r := web.RequestFromContext(ctx); r != nil {
+		if localeCode, ok := r.Values.Load(contextLocale); ok {
+			localeCode, ok := localeCode.(string)
+			return localeCode, ok
+		}
+	}
+
+	return "", false
+}
+
+// LocaleSetting returns a setting of the locale from the settings by locale configured in locale.locales,
+// e.g. "numbers.decimal". Locale codes are matched case-insensitive.
+func LocaleSetting(locales config.Map, localeCode string, key string) (interface{}, bool) {
+	settings, ok := locales[localeCode].(config.Map)
+	if !ok {
+		for code, value := range locales {
+			if strings.EqualFold(code, localeCode) {
+				settings, ok = value.(config.Map)
+				break
+			}
+		}
+	}
+	if !ok {
+		return nil, false
+	}
+
+	return settings.Get(key)
+}
@@ -0,0 +1,32 @@
+package domain
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"flamingo.me/flamingo/v3/framework/web"
+)
+
+func TestLocaleFromContext(t *testing.T) {
+	_, ok := LocaleFromContext(context.Background())
+	assert.False(t, ok)
+
+	localeCode, ok := LocaleFromContext(ContextWithLocale(context.Background(), "de-DE"))
+	assert.True(t, ok)
+	assert.Equal(t, "de-DE", localeCode)
+
+	r := web.CreateRequest(nil, nil)
+	ctx := web.ContextWithRequest(context.Background(), r)
+	_, ok = LocaleFromContext(ctx)
+	assert.False(t, ok)
+
+	StoreLocale(r, "fr-FR")
+	localeCode, ok = LocaleFromContext(ctx)
+	assert.True(t, ok)
+	assert.Equal(t, "fr-FR", localeCode, "the locale is stored in the request of the context")
+
+	localeCode, _ = LocaleFromContext(ContextWithLocale(ctx, "it-IT"))
+	assert.Equal(t, "it-IT", localeCode, "the locale of the context is preferred")
+}
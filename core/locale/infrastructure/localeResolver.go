@@ -0,0 +1,178 @@
+package infrastructure
+
+import (
+	"context"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+
+	"flamingo.me/flamingo/v3/core/locale/domain"
+	"flamingo.me/flamingo/v3/framework/config"
+	"flamingo.me/flamingo/v3/framework/web"
+)
+
+type (
+	// RouteLocaleResolver resolves the locale from a route parameter, e.g. /{locale}/checkout
+	RouteLocaleResolver struct {
+		param string
+	}
+
+	// SubdomainLocaleResolver resolves the locale from the first label of the host, e.g. de.example.com
+	SubdomainLocaleResolver struct {
+		subdomains map[string]string
+		// anySubdomain resolves unmapped subdomains as locale, only if the supported locales restrict them
+		anySubdomain bool
+	}
+
+	// CookieLocaleResolver resolves the locale from a cookie
+	CookieLocaleResolver struct {
+		name string
+	}
+
+	// SessionLocaleResolver resolves the locale from a session value
+	SessionLocaleResolver struct {
+		key string
+	}
+
+	// AcceptLanguageLocaleResolver resolves the locales from the Accept-Language header, ordered by their quality
+	AcceptLanguageLocaleResolver struct{}
+
+	acceptedLanguage struct {
+		tag     string
+		quality float64
+	}
+)
+
+var (
+	_ domain.LocaleResolver = new(RouteLocaleResolver)
+	_ domain.LocaleResolver = new(SubdomainLocaleResolver)
+	_ domain.LocaleResolver = new(CookieLocaleResolver)
+	_ domain.LocaleResolver = new(SessionLocaleResolver)
+	_ domain.LocaleResolver = new(AcceptLanguageLocaleResolver)
+)
+
+// Inject dependencies
+func (r *RouteLocaleResolver) Inject(config *struct {
+	Param string `inject:"config:locale.resolution.route.param"`
+}) {
+	r.param = config.Param
+}
+
+// Resolve the locale from the route parameter
+func (r *RouteLocaleResolver) Resolve(_ context.Context, req *web.Request) []string {
+	if localeCode := req.Params[r.param]; localeCode != "" {
+		return []string{localeCode}
+	}
+	return nil
+}
+
+// Inject dependencies
+func (r *SubdomainLocaleResolver) Inject(config *struct {
+	Subdomains       config.Map   `inject:"config:locale.resolution.subdomain.locales,optional"`
+	SupportedLocales config.Slice `inject:"config:locale.resolution.supportedLocales,optional"`
+}) {
+	r.anySubdomain = len(config.Subdomains) == 0 && len(config.SupportedLocales) > 0
+	r.subdomains = make(map[string]string, len(config.Subdomains))
+	for subdomain, localeCode := range config.Subdomains {
+		if localeCode, ok := localeCode.(string); ok {
+			r.subdomains[strings.ToLower(subdomain)] = localeCode
+		}
+	}
+}
+
+// Resolve the locale from the subdomain. If locale.resolution.subdomain.locales maps subdomains to locales,
+// only these subdomains are resolved. Otherwise the subdomain is the locale code, if locale.resolution.supportedLocales
+// is configured, so subdomains like www are not taken as locale. Without both nothing is resolved.
+func (r *SubdomainLocaleResolver) Resolve(_ context.Context, req *web.Request) []string {
+	host := req.Request().Host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	if net.ParseIP(host) != nil {
+		return nil
+	}
+
+	labels := strings.Split(host, ".")
+	if len(labels) < 3 {
+		return nil
+	}
+
+	subdomain := strings.ToLower(labels[0])
+	if r.anySubdomain {
+		return []string{subdomain}
+	}
+	if localeCode, ok := r.subdomains[subdomain]; ok {
+		return []string{localeCode}
+	}
+	return nil
+}
+
+// Inject dependencies
+func (r *CookieLocaleResolver) Inject(config *struct {
+	Name string `inject:"config:locale.resolution.cookie.name"`
+}) {
+	r.name = config.Name
+}
+
+// Resolve the locale from the cookie
+func (r *CookieLocaleResolver) Resolve(_ context.Context, req *web.Request) []string {
+	cookie, err := req.Request().Cookie(r.name)
+	if err != nil || cookie.Value == "" {
+		return nil
+	}
+	return []string{cookie.Value}
+}
+
+// Inject dependencies
+func (r *SessionLocaleResolver) Inject(config *struct {
+	Key string `inject:"config:locale.resolution.session.key"`
+}) {
+	r.key = config.Key
+}
+
+// Resolve the locale from the session
+func (r *SessionLocaleResolver) Resolve(_ context.Context, req *web.Request) []string {
+	if localeCode, ok := req.Session().Try(r.key).(string); ok && localeCode != "" {
+		return []string{localeCode}
+	}
+	return nil
+}
+
+// Resolve the locales from the Accept-Language header, e.g. "de-CH, de;q=0.9, en;q=0.8"
+func (r *AcceptLanguageLocaleResolver) Resolve(_ context.Context, req *web.Request) []string {
+	var languages []acceptedLanguage
+	for _, header := range req.Request().Header["Accept-Language"] {
+		for _, part := range strings.Split(header, ",") {
+			language := acceptedLanguage{quality: 1}
+			params := strings.Split(part, ";")
+			language.tag = strings.TrimSpace(params[0])
+			for _, param := range params[1:] {
+				param = strings.TrimSpace(param)
+				if !strings.HasPrefix(param, "q=") {
+					continue
+				}
+				quality, err := strconv.ParseFloat(param[2:], 64)
+				if err != nil {
+					quality = 0
+				}
+				language.quality = quality
+			}
+
+			if language.tag == "" || language.tag == "*" || language.quality <= 0 {
+				continue
+			}
+			languages = append(languages, language)
+		}
+	}
+
+	sort.SliceStable(languages, func(i, j int) bool {
+		return languages[i].quality > languages[j].quality
+	})
+
+	localeCodes := make([]string, len(languages))
+	for i, language := range languages {
+		localeCodes[i] = language.tag
+	}
+	return localeCodes
+}
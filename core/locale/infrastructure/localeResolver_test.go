@@ -0,0 +1,110 @@
+package infrastructure
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"flamingo.me/flamingo/v3/framework/config"
+	"flamingo.me/flamingo/v3/framework/web"
+)
+
+func TestRouteLocaleResolver_Resolve(t *testing.T) {
+	resolver := new(RouteLocaleResolver)
+	resolver.Inject(&struct {
+		Param string `inject:"config:locale.resolution.route.param"`
+	}{Param: "locale"})
+
+	r := web.CreateRequest(nil, nil)
+	assert.Empty(t, resolver.Resolve(context.Background(), r))
+
+	r.Params["locale"] = "de-DE"
+	assert.Equal(t, []string{"de-DE"}, resolver.Resolve(context.Background(), r))
+}
+
+func TestSubdomainLocaleResolver_Resolve(t *testing.T) {
+	supported := config.Slice{"de-DE", "fr-FR"}
+	tests := []struct {
+		name       string
+		subdomains config.Map
+		supported  config.Slice
+		host       string
+		expected   []string
+	}{
+		{name: "subdomain", supported: supported, host: "de.example.com", expected: []string{"de"}},
+		{name: "subdomain with port", supported: supported, host: "fr.example.com:3322", expected: []string{"fr"}},
+		{name: "subdomain without supported locales", host: "www.example.com"},
+		{name: "no subdomain", supported: supported, host: "example.com"},
+		{name: "ip address", supported: supported, host: "127.0.0.1:3322"},
+		{name: "mapped subdomain", subdomains: config.Map{"ch": "de-CH"}, host: "CH.example.com", expected: []string{"de-CH"}},
+		{name: "unmapped subdomain", subdomains: config.Map{"ch": "de-CH"}, host: "www.example.com"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resolver := new(SubdomainLocaleResolver)
+			resolver.Inject(&struct {
+				Subdomains       config.Map   `inject:"config:locale.resolution.subdomain.locales,optional"`
+				SupportedLocales config.Slice `inject:"config:locale.resolution.supportedLocales,optional"`
+			}{Subdomains: tt.subdomains, SupportedLocales: tt.supported})
+
+			r := web.CreateRequest(httptest.NewRequest(http.MethodGet, "http://"+tt.host+"/", nil), nil)
+			assert.Equal(t, tt.expected, resolver.Resolve(context.Background(), r))
+		})
+	}
+}
+
+func TestCookieLocaleResolver_Resolve(t *testing.T) {
+	resolver := new(CookieLocaleResolver)
+	resolver.Inject(&struct {
+		Name string `inject:"config:locale.resolution.cookie.name"`
+	}{Name: "locale"})
+
+	request := httptest.NewRequest(http.MethodGet, "/", nil)
+	assert.Empty(t, resolver.Resolve(context.Background(), web.CreateRequest(request, nil)))
+
+	request.AddCookie(&http.Cookie{Name: "locale", Value: "en-GB"})
+	assert.Equal(t, []string{"en-GB"}, resolver.Resolve(context.Background(), web.CreateRequest(request, nil)))
+}
+
+func TestSessionLocaleResolver_Resolve(t *testing.T) {
+	resolver := new(SessionLocaleResolver)
+	resolver.Inject(&struct {
+		Key string `inject:"config:locale.resolution.session.key"`
+	}{Key: "locale"})
+
+	session := web.EmptySession()
+	assert.Empty(t, resolver.Resolve(context.Background(), web.CreateRequest(nil, session)))
+
+	session.Store("locale", "it-IT")
+	assert.Equal(t, []string{"it-IT"}, resolver.Resolve(context.Background(), web.CreateRequest(nil, session)))
+}
+
+func TestAcceptLanguageLocaleResolver_Resolve(t *testing.T) {
+	tests := []struct {
+		name     string
+		header   []string
+		expected []string
+	}{
+		{name: "no header", expected: []string{}},
+		{name: "single language", header: []string{"de"}, expected: []string{"de"}},
+		{name: "ordered by quality", header: []string{"en;q=0.5, de-CH, fr;q=0.8, de;q=0.9"}, expected: []string{"de-CH", "de", "fr", "en"}},
+		{name: "equal quality keeps order", header: []string{"fr, en"}, expected: []string{"fr", "en"}},
+		{name: "multiple headers", header: []string{"fr;q=0.1", "it"}, expected: []string{"it", "fr"}},
+		{name: "wildcard and rejected languages", header: []string{"*, en;q=0, de;q=invalid, it;q=0.3"}, expected: []string{"it"}},
+	}
+
+	resolver := new(AcceptLanguageLocaleResolver)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodGet, "/", nil)
+			for _, header := range tt.header {
+				request.Header.Add("Accept-Language", header)
+			}
+			assert.Equal(t, tt.expected, resolver.Resolve(context.Background(), web.CreateRequest(request, nil)))
+		})
+	}
+}
@@ -0,0 +1,605 @@
+package infrastructure
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/pkg/errors"
+)
+
+type (
+	// MessageFormat is a parsed ICU MessageFormat message, e.g.
+	//
+	//	{gender, select, female {She has} other {They have}} {count, plural, =0 {no items} one {# item} other {# items}}
+	//
+	// Supported are simple arguments, number arguments with the styles integer and percent, select, plural with offset
+	// and exact matches, and selectordinal. Plural categories follow the CLDR rules of the locale, selectordinal is only
+	// supported for locales with known ordinal rules.
+	MessageFormat struct {
+		source string
+		nodes  []messageNode
+	}
+
+	// NumberSymbols are the separators of the locale used for number arguments and #, e.g. "," and "." in de-DE
+	NumberSymbols struct {
+		Decimal  string
+		Thousand string
+	}
+
+	messageNode interface {
+		format(f *messageFormatter, b *strings.Builder) error
+	}
+
+	// textNode is literal text
+	textNode string
+
+	// hashNode is the # in a plural message, it is replaced by the plural number minus the offset
+	hashNode struct{}
+
+	// argumentNode is {name} or {name, number[, style]}
+	argumentNode struct {
+		name  string
+		typ   string
+		style string
+	}
+
+	// pluralNode is {name, plural, ...} or {name, selectordinal, ...}
+	pluralNode struct {
+		name    string
+		ordinal bool
+		offset  float64
+		exact   map[float64][]messageNode
+		forms   map[string][]messageNode
+	}
+
+	// selectNode is {name, select, ...}
+	selectNode struct {
+		name  string
+		cases map[string][]messageNode
+	}
+
+	messageParser struct {
+		source []rune
+		pos    int
+	}
+
+	messageFormatter struct {
+		localeCode string
+		symbols    NumberSymbols
+		args       map[string]interface{}
+		// number is the number of the innermost plural, used for #
+		number *float64
+	}
+)
+
+// pluralCategories are the CLDR plural categories
+var pluralCategories = map[string]bool{"zero": true, "one": true, "two": true, "few": true, "many": true, "other": true}
+
+// ParseMessageFormat parses an ICU MessageFormat message
+func ParseMessageFormat(message string) (*MessageFormat, error) {
+	p := &messageParser{source: []rune(message)}
+
+	nodes, err := p.parseMessage(false, false)
+	if err != nil {
+		return nil, err
+	}
+
+	return &MessageFormat{source: message, nodes: nodes}, nil
+}
+
+// String returns the message source
+func (m *MessageFormat) String() string {
+	return m.source
+}
+
+// Format the message for the locale with the given arguments, numbers are formatted without thousand separator
+func (m *MessageFormat) Format(localeCode string, args map[string]interface{}) (string, error) {
+	return m.FormatWithSymbols(localeCode, NumberSymbols{Decimal: "."}, args)
+}
+
+// FormatWithSymbols formats the message for the locale with the given arguments and the number separators of the locale
+func (m *MessageFormat) FormatWithSymbols(localeCode string, symbols NumberSymbols, args map[string]interface{}) (string, error) {
+	f := &messageFormatter{localeCode: localeCode, symbols: symbols, args: args}
+	b := new(strings.Builder)
+	if err := formatNodes(f, b, m.nodes); err != nil {
+		return "", errors.Wrapf(err, "message %q", m.source)
+	}
+	return b.String(), nil
+}
+
+// checkLocale returns an error if the message can not be formatted for the locale,
+// e.g. selectordinal for a locale without known ordinal rules
+func (m *MessageFormat) checkLocale(localeCode string) error {
+	return checkNodes(localeCode, m.nodes)
+}
+
+func checkNodes(localeCode string, nodes []messageNode) error {
+	for _, node := range nodes {
+		switch node := node.(type) {
+		case *pluralNode:
+			if node.ordinal && !hasOrdinalRules(localeCode) {
+				return errors.Errorf("selectordinal of %q is not supported for the locale %q", node.name, localeCode)
+			}
+			for _, message := range node.exact {
+				if err := checkNodes(localeCode, message); err != nil {
+					return err
+				}
+			}
+			for _, message := range node.forms {
+				if err := checkNodes(localeCode, message); err != nil {
+					return err
+				}
+			}
+		case *selectNode:
+			for _, message := range node.cases {
+				if err := checkNodes(localeCode, message); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func formatNodes(f *messageFormatter, b *strings.Builder, nodes []messageNode) error {
+	for _, node := range nodes {
+		if err := node.format(f, b); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *messageParser) errorf(format string, args ...interface{}) error {
+	return errors.Errorf("%s at position %d", fmt.Sprintf(format, args...), p.pos)
+}
+
+func (p *messageParser) peek() rune {
+	if p.pos >= len(p.source) {
+		return 0
+	}
+	return p.source[p.pos]
+}
+
+func (p *messageParser) skipSpace() {
+	for p.pos < len(p.source) && unicode.IsSpace(p.source[p.pos]) {
+		p.pos++
+	}
+}
+
+// word reads an identifier, keyword or selector
+func (p *messageParser) word() string {
+	start := p.pos
+	for p.pos < len(p.source) {
+		r := p.source[p.pos]
+		if unicode.IsSpace(r) || strings.ContainsRune("{},:'#", r) {
+			break
+		}
+		p.pos++
+	}
+	return string(p.source[start:p.pos])
+}
+
+func (p *messageParser) expect(r rune) error {
+	p.skipSpace()
+	if p.peek() != r {
+		return p.errorf("expected %q", r)
+	}
+	p.pos++
+	return nil
+}
+
+// parseMessage parses text and arguments until the end of the message, or the closing } of a sub message
+func (p *messageParser) parseMessage(inPlural, nested bool) ([]messageNode, error) {
+	var nodes []messageNode
+	text := new(strings.Builder)
+	flush := func() {
+		if text.Len() > 0 {
+			nodes = append(nodes, textNode(text.String()))
+			text.Reset()
+		}
+	}
+
+	for p.pos < len(p.source) {
+		r := p.source[p.pos]
+		switch {
+		case r == '\'':
+			p.quoted(text, inPlural)
+
+		case r == '{':
+			flush()
+			node, err := p.parseArgument()
+			if err != nil {
+				return nil, err
+			}
+			nodes = append(nodes, node)
+
+		case r == '}':
+			if !nested {
+				return nil, p.errorf("unexpected }")
+			}
+			flush()
+			return nodes, nil
+
+		case r == '#' && inPlural:
+			flush()
+			nodes = append(nodes, hashNode{})
+			p.pos++
+
+		default:
+			text.WriteRune(r)
+			p.pos++
+		}
+	}
+
+	if nested {
+		return nil, p.errorf("missing }")
+	}
+	flush()
+	return nodes, nil
+}
+
+// quoted handles the apostrophe: two apostrophes are a literal apostrophe, an apostrophe before a syntax character starts quoted text
+func (p *messageParser) quoted(text *strings.Builder, inPlural bool) {
+	p.pos++
+	next := p.peek()
+	switch {
+	case next == '\'':
+		text.WriteRune('\'')
+		p.pos++
+	case next == '{' || next == '}' || next == '|' || (next == '#' && inPlural):
+		for p.pos < len(p.source) {
+			r := p.source[p.pos]
+			p.pos++
+			if r != '\'' {
+				text.WriteRune(r)
+				continue
+			}
+			if p.peek() != '\'' {
+				return
+			}
+			text.WriteRune('\'')
+			p.pos++
+		}
+	default:
+		text.WriteRune('\'')
+	}
+}
+
+// parseArgument parses an argument starting with {
+func (p *messageParser) parseArgument() (messageNode, error) {
+	p.pos++
+	p.skipSpace()
+	name := p.word()
+	if name == "" {
+		return nil, p.errorf("missing argument name")
+	}
+
+	p.skipSpace()
+	switch p.peek() {
+	case '}':
+		p.pos++
+		return &argumentNode{name: name}, nil
+	case ',':
+		p.pos++
+	default:
+		return nil, p.errorf("expected %q", '}')
+	}
+
+	p.skipSpace()
+	typ := p.word()
+	switch typ {
+	case "number":
+		node := &argumentNode{name: name, typ: typ}
+		p.skipSpace()
+		if p.peek() == ',' {
+			p.pos++
+			p.skipSpace()
+			node.style = p.word()
+			if node.style != "integer" && node.style != "percent" {
+				return nil, p.errorf("unsupported number style %q", node.style)
+			}
+		}
+		return node, p.expect('}')
+
+	case "plural", "selectordinal":
+		return p.parsePlural(name, typ == "selectordinal")
+
+	case "select":
+		return p.parseSelect(name)
+	}
+
+	return nil, p.errorf("unsupported argument type %q", typ)
+}
+
+func (p *messageParser) parsePlural(name string, ordinal bool) (messageNode, error) {
+	node := &pluralNode{
+		name:    name,
+		ordinal: ordinal,
+		exact:   make(map[float64][]messageNode),
+		forms:   make(map[string][]messageNode),
+	}
+
+	if err := p.expect(','); err != nil {
+		return nil, err
+	}
+
+	p.skipSpace()
+	if strings.HasPrefix(string(p.source[p.pos:]), "offset:") {
+		p.pos += len("offset:")
+		p.skipSpace()
+		offset, err := strconv.ParseFloat(p.word(), 64)
+		if err != nil {
+			return nil, p.errorf("invalid offset")
+		}
+		node.offset = offset
+	}
+
+	for {
+		p.skipSpace()
+		if p.peek() == '}' {
+			p.pos++
+			break
+		}
+
+		selector := p.word()
+		switch {
+		case strings.HasPrefix(selector, "="):
+			value, err := strconv.ParseFloat(selector[1:], 64)
+			if err != nil {
+				return nil, p.errorf("invalid selector %q", selector)
+			}
+			if _, ok := node.exact[value]; ok {
+				return nil, p.errorf("duplicate selector %q", selector)
+			}
+			message, err := p.parseSubMessage(true)
+			if err != nil {
+				return nil, err
+			}
+			node.exact[value] = message
+
+		case pluralCategories[selector]:
+			if _, ok := node.forms[selector]; ok {
+				return nil, p.errorf("duplicate selector %q", selector)
+			}
+			message, err := p.parseSubMessage(true)
+			if err != nil {
+				return nil, err
+			}
+			node.forms[selector] = message
+
+		case selector == "":
+			return nil, p.errorf("missing selector")
+
+		default:
+			return nil, p.errorf("invalid plural category %q", selector)
+		}
+	}
+
+	if _, ok := node.forms["other"]; !ok {
+		return nil, p.errorf("missing other selector for %q", name)
+	}
+	return node, nil
+}
+
+func (p *messageParser) parseSelect(name string) (messageNode, error) {
+	node := &selectNode{name: name, cases: make(map[string][]messageNode)}
+
+	if err := p.expect(','); err != nil {
+		return nil, err
+	}
+
+	for {
+		p.skipSpace()
+		if p.peek() == '}' {
+			p.pos++
+			break
+		}
+
+		selector := p.word()
+		if selector == "" {
+			return nil, p.errorf("missing selector")
+		}
+		if _, ok := node.cases[selector]; ok {
+			return nil, p.errorf("duplicate selector %q", selector)
+		}
+		message, err := p.parseSubMessage(false)
+		if err != nil {
+			return nil, err
+		}
+		node.cases[selector] = message
+	}
+
+	if _, ok := node.cases["other"]; !ok {
+		return nil, p.errorf("missing other selector for %q", name)
+	}
+	return node, nil
+}
+
+// parseSubMessage parses {message} of a selector
+func (p *messageParser) parseSubMessage(inPlural bool) ([]messageNode, error) {
+	if err := p.expect('{'); err != nil {
+		return nil, err
+	}
+	message, err := p.parseMessage(inPlural, true)
+	if err != nil {
+		return nil, err
+	}
+	p.pos++
+	return message, nil
+}
+
+// arg returns the argument, dots separate the keys of nested maps
+func (f *messageFormatter) arg(name string) (interface{}, error) {
+	var value interface{} = f.args
+	for _, key := range strings.Split(name, ".") {
+		m, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, errors.Errorf("missing argument %q", name)
+		}
+		if value, ok = m[key]; !ok {
+			return nil, errors.Errorf("missing argument %q", name)
+		}
+	}
+	return value, nil
+}
+
+func (n textNode) format(_ *messageFormatter, b *strings.Builder) error {
+	b.WriteString(string(n))
+	return nil
+}
+
+func (n hashNode) format(f *messageFormatter, b *strings.Builder) error {
+	if f.number == nil {
+		b.WriteRune('#')
+		return nil
+	}
+	b.WriteString(f.symbols.format(*f.number))
+	return nil
+}
+
+func (n *argumentNode) format(f *messageFormatter, b *strings.Builder) error {
+	value, err := f.arg(n.name)
+	if err != nil {
+		return err
+	}
+
+	if n.typ != "number" {
+		// only numbers are formatted, strings are printed unchanged even if they look like numbers, e.g. "007"
+		if str, ok := value.(string); ok {
+			b.WriteString(str)
+		} else if number, err := toNumber(value); err == nil {
+			b.WriteString(formatNumber(number))
+		} else {
+			b.WriteString(fmt.Sprint(value))
+		}
+		return nil
+	}
+
+	number, err := toNumber(value)
+	if err != nil {
+		return errors.Wrapf(err, "argument %q", n.name)
+	}
+	switch n.style {
+	case "integer":
+		number = math.Round(number)
+	case "percent":
+		b.WriteString(f.symbols.format(math.Round(number*100)) + "%")
+		return nil
+	}
+	b.WriteString(f.symbols.format(number))
+	return nil
+}
+
+func (n *pluralNode) format(f *messageFormatter, b *strings.Builder) error {
+	value, err := f.arg(n.name)
+	if err != nil {
+		return err
+	}
+	number, err := toNumber(value)
+	if err != nil {
+		return errors.Wrapf(err, "argument %q", n.name)
+	}
+
+	if n.ordinal && !hasOrdinalRules(f.localeCode) {
+		return errors.Errorf("selectordinal of %q is not supported for the locale %q", n.name, f.localeCode)
+	}
+
+	message, ok := n.exact[number]
+	if !ok {
+		var category string
+		if n.ordinal {
+			category = ordinalCategory(f.localeCode, number-n.offset)
+		} else {
+			category = cardinalCategory(f.localeCode, number-n.offset)
+		}
+		if message, ok = n.forms[category]; !ok {
+			message = n.forms["other"]
+		}
+	}
+
+	outer := f.number
+	hash := number - n.offset
+	f.number = &hash
+	err = formatNodes(f, b, message)
+	f.number = outer
+	return err
+}
+
+func (n *selectNode) format(f *messageFormatter, b *strings.Builder) error {
+	value, err := f.arg(n.name)
+	if err != nil {
+		return err
+	}
+
+	message, ok := n.cases[fmt.Sprint(value)]
+	if !ok {
+		message = n.cases["other"]
+	}
+	return formatNodes(f, b, message)
+}
+
+// toNumber converts numbers and numeric strings
+func toNumber(value interface{}) (float64, error) {
+	switch v := value.(type) {
+	case int:
+		return float64(v), nil
+	case int8:
+		return float64(v), nil
+	case int16:
+		return float64(v), nil
+	case int32:
+		return float64(v), nil
+	case int64:
+		return float64(v), nil
+	case uint:
+		return float64(v), nil
+	case uint8:
+		return float64(v), nil
+	case uint16:
+		return float64(v), nil
+	case uint32:
+		return float64(v), nil
+	case uint64:
+		return float64(v), nil
+	case float32:
+		return float64(v), nil
+	case float64:
+		return v, nil
+	case string:
+		return strconv.ParseFloat(v, 64)
+	}
+	return 0, errors.Errorf("%v (%T) is not a number", value, value)
+}
+
+func formatNumber(number float64) string {
+	return strconv.FormatFloat(number, 'f', -1, 64)
+}
+
+// format the number with the separators, the integer part is grouped by thousands
+func (s NumberSymbols) format(number float64) string {
+	digits := formatNumber(math.Abs(number))
+	fraction := ""
+	if i := strings.IndexByte(digits, '.'); i >= 0 {
+		digits, fraction = digits[:i], digits[i+1:]
+	}
+
+	b := new(strings.Builder)
+	if number < 0 {
+		b.WriteRune('-')
+	}
+	for i, r := range digits {
+		if i > 0 && (len(digits)-i)%3 == 0 {
+			b.WriteString(s.Thousand)
+		}
+		b.WriteRune(r)
+	}
+	if fraction != "" {
+		b.WriteString(s.Decimal)
+		b.WriteString(fraction)
+	}
+	return b.String()
+}
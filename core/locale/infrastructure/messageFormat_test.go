@@ -0,0 +1,187 @@
+package infrastructure
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMessageFormat_Format(t *testing.T) {
+	tests := []struct {
+		name       string
+		message    string
+		localeCode string
+		args       map[string]interface{}
+		expected   string
+	}{
+		{name: "text", message: "Hello World", expected: "Hello World"},
+		{name: "argument", message: "Hello {name}!", args: map[string]interface{}{"name": "Max"}, expected: "Hello Max!"},
+		{name: "nested argument", message: "Hello {user.name}", args: map[string]interface{}{"user": map[string]interface{}{"name": "Max"}}, expected: "Hello Max"},
+		{name: "string argument", message: "{code} {n}", args: map[string]interface{}{"code": "007", "n": 1000}, expected: "007 1000"},
+		{name: "number", message: "{n, number} {n, number, integer} {p, number, percent}", args: map[string]interface{}{"n": 2.5, "p": 0.25}, expected: "2.5 3 25%"},
+		{name: "quoting", message: "It''s '{name}' and '#' isn''t quoted", args: map[string]interface{}{}, expected: "It's {name} and '#' isn't quoted"},
+		{
+			name:       "plural english",
+			message:    "{count, plural, =0 {no items} one {# item} other {# items}}",
+			localeCode: "en-US",
+			args:       map[string]interface{}{"count": 1},
+			expected:   "1 item",
+		},
+		{
+			name:       "plural exact match",
+			message:    "{count, plural, =0 {no items} one {# item} other {# items}}",
+			localeCode: "en-US",
+			args:       map[string]interface{}{"count": 0},
+			expected:   "no items",
+		},
+		{
+			name:       "plural other",
+			message:    "{count, plural, one {# item} other {# items}}",
+			localeCode: "en-US",
+			args:       map[string]interface{}{"count": "2.5"},
+			expected:   "2.5 items",
+		},
+		{
+			name:       "plural russian",
+			message:    "{count, plural, one {# файл} few {# файла} many {# файлов} other {# файла}}",
+			localeCode: "ru",
+			args:       map[string]interface{}{"count": 22},
+			expected:   "22 файла",
+		},
+		{
+			name:       "plural with offset",
+			message:    "{guests, plural, offset:1 =0 {nobody} =1 {{host}} one {{host} and # other} other {{host} and # others}}",
+			localeCode: "en",
+			args:       map[string]interface{}{"guests": 3, "host": "Anna"},
+			expected:   "Anna and 2 others",
+		},
+		{
+			name:       "selectordinal english",
+			message:    "{n, selectordinal, one {#st} two {#nd} few {#rd} other {#th}} {m, selectordinal, one {#st} two {#nd} few {#rd} other {#th}} {o, selectordinal, one {#st} two {#nd} few {#rd} other {#th}}",
+			localeCode: "en-GB",
+			args:       map[string]interface{}{"n": 22, "m": 13, "o": 101},
+			expected:   "22nd 13th 101st",
+		},
+		{
+			name:       "selectordinal without ordinal rules",
+			message:    "{n, selectordinal, one {#st} other {#.}}",
+			localeCode: "de_DE",
+			args:       map[string]interface{}{"n": 1},
+			expected:   "1.",
+		},
+		{
+			name:       "selectordinal welsh",
+			message:    "{n, selectordinal, zero {#fed} one {#af} two {#ail} few {#ydd} many {#ed} other {#fed}}",
+			localeCode: "cy",
+			args:       map[string]interface{}{"n": 3},
+			expected:   "3ydd",
+		},
+		{
+			name:       "nested select and plural",
+			message:    "{gender, select, female {She has {count, plural, one {# message} other {# messages}}} other {They have {count, plural, one {# message} other {# messages}}}}",
+			localeCode: "en",
+			args:       map[string]interface{}{"gender": "female", "count": 5},
+			expected:   "She has 5 messages",
+		},
+		{
+			name:     "select other",
+			message:  "{gender, select, female {She} male {He} other {They}}",
+			args:     map[string]interface{}{"gender": "unknown"},
+			expected: "They",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			message, err := ParseMessageFormat(tt.message)
+			require.NoError(t, err)
+			assert.Equal(t, tt.message, message.String())
+
+			result, err := message.Format(tt.localeCode, tt.args)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, result)
+		})
+	}
+}
+
+func TestMessageFormat_FormatWithSymbols(t *testing.T) {
+	message, err := ParseMessageFormat("{n, number} {n, number, integer} {p, number, percent} {count, plural, other {# items}} {n}")
+	require.NoError(t, err)
+
+	args := map[string]interface{}{"n": -1234567.5, "p": 12.5, "count": 1000}
+
+	result, err := message.FormatWithSymbols("de-DE", NumberSymbols{Decimal: ",", Thousand: "."}, args)
+	assert.NoError(t, err)
+	assert.Equal(t, "-1.234.567,5 -1.234.568 1.250% 1.000 items -1234567.5", result)
+
+	result, err = message.Format("de-DE", args)
+	assert.NoError(t, err)
+	assert.Equal(t, "-1234567.5 -1234568 1250% 1000 items -1234567.5", result)
+}
+
+func TestMessageFormat_FormatErrors(t *testing.T) {
+	tests := []struct {
+		name       string
+		message    string
+		localeCode string
+		args       map[string]interface{}
+	}{
+		{name: "missing argument", message: "Hello {name}"},
+		{name: "missing nested argument", message: "Hello {user.name}", args: map[string]interface{}{"user": "Max"}},
+		{name: "plural of text", message: "{count, plural, other {#}}", args: map[string]interface{}{"count": "many"}},
+		{name: "number of text", message: "{count, number}", args: map[string]interface{}{"count": "many"}},
+		{name: "selectordinal without known ordinal rules", message: "{n, selectordinal, other {#.}}", localeCode: "yo", args: map[string]interface{}{"n": 1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			message, err := ParseMessageFormat(tt.message)
+			require.NoError(t, err)
+
+			localeCode := tt.localeCode
+			if localeCode == "" {
+				localeCode = "en"
+			}
+			_, err = message.Format(localeCode, tt.args)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestParseMessageFormat_Errors(t *testing.T) {
+	tests := []struct {
+		message string
+		err     string
+	}{
+		{message: "Hello {name", err: `expected '}' at position 11`},
+		{message: "Hello name}", err: "unexpected } at position 10"},
+		{message: "Hello {}", err: "missing argument name at position 7"},
+		{message: "{n, date}", err: `unsupported argument type "date" at position 8`},
+		{message: "{n, number, currency}", err: `unsupported number style "currency" at position 20`},
+		{message: "{n, plural, one {#}}", err: `missing other selector for "n" at position 20`},
+		{message: "{n, plural, some {#} other {#}}", err: `invalid plural category "some" at position 16`},
+		{message: "{n, plural, one {#} one {#} other {#}}", err: `duplicate selector "one" at position 23`},
+		{message: "{n, plural, =x {#} other {#}}", err: `invalid selector "=x" at position 14`},
+		{message: "{n, plural, offset:x other {#}}", err: "invalid offset at position 20"},
+		{message: "{n, select, male {he} female {she}}", err: `missing other selector for "n" at position 35`},
+		{message: "{n, select, other {they}", err: "missing selector at position 24"},
+		{message: "{n, select, other {they", err: "missing } at position 23"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.message, func(t *testing.T) {
+			_, err := ParseMessageFormat(tt.message)
+			assert.EqualError(t, err, tt.err)
+		})
+	}
+}
+
+func TestMessageFormat_checkLocale(t *testing.T) {
+	message, err := ParseMessageFormat("{gender, select, female {{n, selectordinal, one {#st} other {#th}}} other {#}}")
+	require.NoError(t, err)
+
+	assert.NoError(t, message.checkLocale("en-US"))
+	assert.NoError(t, message.checkLocale("de_DE"))
+	assert.EqualError(t, message.checkLocale("yo"), `selectordinal of "n" is not supported for the locale "yo"`)
+}
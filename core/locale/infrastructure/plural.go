@@ -0,0 +1,233 @@
+package infrastructure
+
+import (
+	"math"
+	"strings"
+
+	"github.com/nicksnyder/go-i18n/i18n/language"
+)
+
+// ordinalRules are the CLDR ordinal rules by language
+// http://www.unicode.org/cldr/charts/latest/supplemental/language_plural_rules.html
+var ordinalRules = map[string]func(n int64) string{
+	"en": func(n int64) string {
+		switch {
+		case n%10 == 1 && n%100 != 11:
+			return "one"
+		case n%10 == 2 && n%100 != 12:
+			return "two"
+		case n%10 == 3 && n%100 != 13:
+			return "few"
+		}
+		return "other"
+	},
+	"fr": func(n int64) string {
+		if n == 1 {
+			return "one"
+		}
+		return "other"
+	},
+	"it": func(n int64) string {
+		if n == 11 || n == 8 || n == 80 || n == 800 {
+			return "many"
+		}
+		return "other"
+	},
+	"sv": func(n int64) string {
+		if (n%10 == 1 || n%10 == 2) && n%100 != 11 && n%100 != 12 {
+			return "one"
+		}
+		return "other"
+	},
+	"ca": func(n int64) string {
+		switch n {
+		case 1, 3:
+			return "one"
+		case 2:
+			return "two"
+		case 4:
+			return "few"
+		}
+		return "other"
+	},
+	"hu": func(n int64) string {
+		if n == 1 || n == 5 {
+			return "one"
+		}
+		return "other"
+	},
+	"ro":  oneOrdinal,
+	"ms":  oneOrdinal,
+	"vi":  oneOrdinal,
+	"fil": oneOrdinal,
+	"tl":  oneOrdinal,
+	"ga":  oneOrdinal,
+	"hy":  oneOrdinal,
+	"lo":  oneOrdinal,
+	"uk": func(n int64) string {
+		if n%10 == 3 && n%100 != 13 {
+			return "few"
+		}
+		return "other"
+	},
+	"be": func(n int64) string {
+		if (n%10 == 2 || n%10 == 3) && n%100 != 12 && n%100 != 13 {
+			return "few"
+		}
+		return "other"
+	},
+	"kk": func(n int64) string {
+		if n%10 == 6 || n%10 == 9 || (n%10 == 0 && n != 0) {
+			return "many"
+		}
+		return "other"
+	},
+	"mk": func(n int64) string {
+		switch {
+		case n%10 == 1 && n%100 != 11:
+			return "one"
+		case n%10 == 2 && n%100 != 12:
+			return "two"
+		case (n%10 == 7 || n%10 == 8) && n%100 != 17 && n%100 != 18:
+			return "many"
+		}
+		return "other"
+	},
+	"hi": indicOrdinal,
+	"gu": indicOrdinal,
+	"bn": func(n int64) string {
+		switch n {
+		case 1, 5, 7, 8, 9, 10:
+			return "one"
+		case 2, 3:
+			return "two"
+		case 4:
+			return "few"
+		case 6:
+			return "many"
+		}
+		return "other"
+	},
+	"mr": func(n int64) string {
+		switch n {
+		case 1:
+			return "one"
+		case 2, 3:
+			return "two"
+		case 4:
+			return "few"
+		}
+		return "other"
+	},
+	"ne": func(n int64) string {
+		if n >= 1 && n <= 4 {
+			return "one"
+		}
+		return "other"
+	},
+	"sq": func(n int64) string {
+		switch {
+		case n == 1:
+			return "one"
+		case n%10 == 4 && n%100 != 14:
+			return "many"
+		}
+		return "other"
+	},
+	"cy": func(n int64) string {
+		switch n {
+		case 0, 7, 8, 9:
+			return "zero"
+		case 1:
+			return "one"
+		case 2:
+			return "two"
+		case 3, 4:
+			return "few"
+		case 5, 6:
+			return "many"
+		}
+		return "other"
+	},
+	"ka": func(n int64) string {
+		switch {
+		case n == 1:
+			return "one"
+		case n == 0 || (n%100 >= 2 && n%100 <= 20) || n%100 == 40 || n%100 == 60 || n%100 == 80:
+			return "many"
+		}
+		return "other"
+	},
+}
+
+// otherOrdinalLanguages are the languages whose CLDR ordinal rules only use the category other
+var otherOrdinalLanguages = map[string]bool{
+	"af": true, "am": true, "an": true, "ar": true, "bg": true, "bs": true, "ce": true, "cs": true, "da": true,
+	"de": true, "dsb": true, "el": true, "es": true, "et": true, "eu": true, "fa": true, "fi": true, "fy": true,
+	"gl": true, "gsw": true, "he": true, "hr": true, "hsb": true, "ia": true, "id": true, "in": true, "is": true,
+	"iw": true, "ja": true, "km": true, "kn": true, "ko": true, "ky": true, "lt": true, "lv": true, "ml": true,
+	"mn": true, "my": true, "nb": true, "nl": true, "no": true, "pa": true, "pl": true, "prg": true, "ps": true,
+	"pt": true, "ru": true, "sd": true, "sh": true, "si": true, "sk": true, "sl": true, "sr": true, "sw": true,
+	"ta": true, "te": true, "th": true, "tpi": true, "tr": true, "ur": true, "uz": true, "yue": true, "zh": true,
+	"zu": true,
+}
+
+func oneOrdinal(n int64) string {
+	if n == 1 {
+		return "one"
+	}
+	return "other"
+}
+
+func indicOrdinal(n int64) string {
+	switch n {
+	case 1:
+		return "one"
+	case 2, 3:
+		return "two"
+	case 4:
+		return "few"
+	case 6:
+		return "many"
+	}
+	return "other"
+}
+
+// cardinalCategory returns the CLDR plural category of the number for the locale, e.g. "one" for 1 in en-US
+func cardinalCategory(localeCode string, number float64) string {
+	spec := language.GetPluralSpec(localeCode)
+	if spec == nil {
+		return "other"
+	}
+
+	plural, err := spec.Plural(formatNumber(number))
+	if err != nil {
+		return "other"
+	}
+	return string(plural)
+}
+
+// hasOrdinalRules reports if the CLDR ordinal rules of the locale are known, selectordinal is not supported otherwise
+func hasOrdinalRules(localeCode string) bool {
+	lang := baseLanguage(localeCode)
+	_, ok := ordinalRules[lang]
+	return ok || otherOrdinalLanguages[lang]
+}
+
+// ordinalCategory returns the CLDR ordinal category of the number for the locale, e.g. "two" for 22 in en-US
+func ordinalCategory(localeCode string, number float64) string {
+	rule, ok := ordinalRules[baseLanguage(localeCode)]
+	if !ok || number != math.Trunc(number) {
+		return "other"
+	}
+	return rule(int64(math.Abs(number)))
+}
+
+// baseLanguage returns the lower case language of the locale, e.g. "en" for en_US
+func baseLanguage(localeCode string) string {
+	lang := strings.ToLower(localeCode)
+	if i := strings.IndexAny(lang, "-_"); i >= 0 {
+		lang = lang[:i]
+	}
+	return lang
+}
@@ -0,0 +1,166 @@
+package infrastructure
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+
+	"github.com/ghodss/yaml"
+	"github.com/nicksnyder/go-i18n/i18n/language"
+	"github.com/nicksnyder/go-i18n/i18n/translation"
+	"github.com/pelletier/go-toml"
+	"github.com/pkg/errors"
+)
+
+// Message formats of translations, selected per translation with the "format" key or by locale.messageFormat
+const (
+	MessageFormatGoTemplate = "gotemplate"
+	MessageFormatICU        = "icu"
+)
+
+type (
+	// translationFile contains the translations of a translation file, split by their message format
+	translationFile struct {
+		language *language.Language
+		// legacy translations are go-i18n translations with text/template arguments
+		legacy []translation.Translation
+		// messages are ICU MessageFormat messages by their id
+		messages map[string]*MessageFormat
+	}
+)
+
+// loadTranslationFile loads a translation file in the formats of go-i18n, json and yaml in the standard or flat format
+// and toml in the flat format. The language is parsed from the file name, e.g. en-US.all.json.
+// Every translation is validated, invalid translations are returned as errors and skipped.
+func loadTranslationFile(filename string, defaultFormat string) (*translationFile, []error) {
+	langs := language.Parse(filepath.Base(filename))
+	if len(langs) != 1 {
+		return nil, []error{errors.Errorf("expected exactly one language in the file name %q, found %d", filename, len(langs))}
+	}
+
+	buf, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, []error{err}
+	}
+
+	entries, err := parseTranslationEntries(filepath.Ext(filename), buf)
+	if err != nil {
+		return nil, []error{errors.Wrapf(err, "failed to parse %s", filename)}
+	}
+
+	file := &translationFile{language: langs[0], messages: make(map[string]*MessageFormat)}
+	var errs []error
+	for i, entry := range entries {
+		id, ok := entry["id"].(string)
+		if !ok || id == "" {
+			errs = append(errs, errors.Errorf("%s: translation #%d has no id", filename, i))
+			continue
+		}
+
+		format := defaultFormat
+		if f, ok := entry["format"].(string); ok {
+			format = f
+		}
+
+		switch format {
+		case MessageFormatICU:
+			source, ok := entry["translation"].(string)
+			if !ok {
+				errs = append(errs, errors.Errorf("%s: translation %q must be a string in the icu format", filename, id))
+				continue
+			}
+			message, err := ParseMessageFormat(source)
+			if err != nil {
+				errs = append(errs, errors.Wrapf(err, "%s: translation %q", filename, id))
+				continue
+			}
+			if err := message.checkLocale(file.language.Tag); err != nil {
+				errs = append(errs, errors.Wrapf(err, "%s: translation %q", filename, id))
+				continue
+			}
+			file.messages[id] = message
+
+		case MessageFormatGoTemplate, "":
+			legacy, err := translation.NewTranslation(map[string]interface{}{"id": id, "translation": entry["translation"]})
+			if err != nil {
+				errs = append(errs, errors.Wrapf(err, "%s: translation %q", filename, id))
+				continue
+			}
+			file.legacy = append(file.legacy, legacy)
+
+		default:
+			errs = append(errs, errors.Errorf("%s: translation %q has the unknown format %q", filename, id, format))
+		}
+	}
+
+	return file, errs
+}
+
+// parseTranslationEntries returns the translations in the go-i18n standard format: a list of id and translation
+func parseTranslationEntries(ext string, buf []byte) ([]map[string]interface{}, error) {
+	var data interface{}
+	switch ext {
+	case ".toml":
+		tree, err := toml.LoadBytes(buf)
+		if err != nil {
+			return nil, err
+		}
+		data = tree.ToMap()
+	case ".json", ".yaml", ".yml":
+		if err := yaml.Unmarshal(buf, &data); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, errors.Errorf("unsupported file extension %s", ext)
+	}
+
+	switch data := data.(type) {
+	case nil:
+		return nil, nil
+
+	case []interface{}:
+		entries := make([]map[string]interface{}, len(data))
+		for i, entry := range data {
+			var ok bool
+			if entries[i], ok = entry.(map[string]interface{}); !ok {
+				return nil, errors.Errorf("translation #%d is not an object", i)
+			}
+		}
+		return entries, nil
+
+	case map[string]interface{}:
+		// the flat format maps ids to the plural categories, only "other" is a single translation
+		ids := make([]string, 0, len(data))
+		for id := range data {
+			ids = append(ids, id)
+		}
+		sort.Strings(ids)
+
+		entries := make([]map[string]interface{}, 0, len(data))
+		for _, id := range ids {
+			forms, ok := data[id].(map[string]interface{})
+			if !ok {
+				return nil, errors.Errorf("translation %q is not an object", id)
+			}
+
+			entry := map[string]interface{}{"id": id}
+			translation := make(map[string]interface{}, len(forms))
+			for key, value := range forms {
+				if key == "format" {
+					entry["format"] = value
+					continue
+				}
+				translation[key] = value
+			}
+			if other, ok := translation["other"]; ok && len(translation) == 1 {
+				entry["translation"] = other
+			} else {
+				entry["translation"] = translation
+			}
+			entries = append(entries, entry)
+		}
+		return entries, nil
+	}
+
+	return nil, errors.Errorf("unexpected translations of type %T", data)
+}
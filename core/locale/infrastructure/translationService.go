@@ -4,6 +4,8 @@ import (
 	"bytes"
 	"errors"
 	"fmt"
+	"strings"
+	"sync"
 	"text/template"
 
 	"flamingo.me/flamingo/v3/core/locale/domain"
@@ -11,6 +13,7 @@ import (
 	"flamingo.me/flamingo/v3/framework/config"
 	"flamingo.me/flamingo/v3/framework/flamingo"
 	"github.com/nicksnyder/go-i18n/i18n/bundle"
+	"github.com/nicksnyder/go-i18n/i18n/language"
 )
 
 type (
@@ -22,6 +25,14 @@ type (
 		devmode          bool
 		filesLoaded      bool
 		i18bundle        *bundle.Bundle
+		messageFormat    string
+		numberSymbols    NumberSymbols
+		locales          config.Map
+		// mu guards the ICU messages, which are replaced on every load
+		mu sync.RWMutex
+		// messages are the ICU messages by language tag and id, fallbackMessages the messages for less specific tags
+		messages         map[string]map[string]*MessageFormat
+		fallbackMessages map[string]map[string]*MessageFormat
 	}
 )
 
@@ -35,13 +46,27 @@ func (ts *TranslationService) Inject(
 		DevMode          bool         `inject:"config:debug.mode"`
 		TranslationFile  string       `inject:"config:locale.translationFile,optional"`
 		TranslationFiles config.Slice `inject:"config:locale.translationFiles,optional"`
+		MessageFormat    string       `inject:"config:locale.messageFormat,optional"`
+		Decimal          string       `inject:"config:locale.numbers.decimal,optional"`
+		Thousand         string       `inject:"config:locale.numbers.thousand,optional"`
+		Locales          config.Map   `inject:"config:locale.locales,optional"`
 	},
 ) {
 	ts.logger = logger.WithField(flamingo.LogKeyModule, "locale").WithField("category", "locale.translationService")
+	ts.messageFormat = MessageFormatGoTemplate
+	ts.numberSymbols = NumberSymbols{Decimal: "."}
 	if config != nil {
 		ts.translationFile = config.TranslationFile
 		ts.translationFiles = config.TranslationFiles
 		ts.devmode = config.DevMode
+		if config.MessageFormat != "" {
+			ts.messageFormat = config.MessageFormat
+		}
+		if config.Decimal != "" {
+			ts.numberSymbols.Decimal = config.Decimal
+		}
+		ts.numberSymbols.Thousand = config.Thousand
+		ts.locales = config.Locales
 	}
 }
 
@@ -62,7 +87,7 @@ func (ts *TranslationService) TranslateLabel(label domain.Label) string {
 	}
 	//Fallback if label was not translated
 	if translatedString == label.GetKey() && label.GetDefaultLabel() != "" {
-		return ts.parseDefaultLabel(label.GetDefaultLabel(), label.GetKey(), label.GetTranslationArguments())
+		return ts.parseDefaultLabel(label.GetDefaultLabel(), label.GetKey(), label.GetLocaleCode(), label.GetCount(), label.GetTranslationArguments())
 	}
 	return translatedString
 }
@@ -79,19 +104,49 @@ func (ts *TranslationService) Translate(key string, defaultLabel string, localeC
 
 	//Fallback if label was not translated
 	if label == key && defaultLabel != "" {
-		return ts.parseDefaultLabel(defaultLabel, key, translationArguments)
+		return ts.parseDefaultLabel(defaultLabel, key, localeCode, count, translationArguments)
 	}
 	return label
 
 }
 
-// AllTranslationKeys returns all keys for a given locale code
+// AllTranslationKeys returns all keys for a given locale code, keys translated in both formats are returned once
 func (ts *TranslationService) AllTranslationKeys(localeCode string) []string {
 	ts.initAndLoad()
-	return ts.i18bundle.LanguageTranslationIDs(localeCode)
+	tag := language.NormalizeTag(localeCode)
+	keys := ts.i18bundle.LanguageTranslationIDs(tag)
+
+	known := make(map[string]bool, len(keys))
+	for _, id := range keys {
+		known[id] = true
+	}
+
+	ts.mu.RLock()
+	defer ts.mu.RUnlock()
+	for id := range ts.messages[tag] {
+		if !known[id] {
+			keys = append(keys, id)
+		}
+	}
+	return keys
 }
 
-func (ts *TranslationService) parseDefaultLabel(defaultLabel string, key string, translationArguments map[string]interface{}) string {
+// parseDefaultLabel formats the default label in the configured message format
+func (ts *TranslationService) parseDefaultLabel(defaultLabel string, key string, localeCode string, count int, translationArguments map[string]interface{}) string {
+	if ts.messageFormat == MessageFormatICU {
+		message, err := ParseMessageFormat(defaultLabel)
+		if err != nil {
+			ts.logger.Warn(fmt.Sprintf("invalid default label of %q: %s", key, err))
+			return defaultLabel
+		}
+		label, err := message.FormatWithSymbols(localeCode, ts.symbols(localeCode), messageArguments(count, translationArguments))
+		if err != nil {
+			ts.logger.Warn(fmt.Sprintf("default label of %q: %s", key, err))
+			return defaultLabel
+		}
+		return label
+	}
+
 	if translationArguments == nil {
 		translationArguments = make(map[string]interface{})
 	}
@@ -108,6 +163,15 @@ func (ts *TranslationService) parseDefaultLabel(defaultLabel string, key string,
 }
 
 func (ts *TranslationService) translateWithLib(localeCode string, key string, count int, translationArguments map[string]interface{}) (string, error) {
+	if message := ts.message(localeCode, key); message != nil {
+		label, err := message.FormatWithSymbols(localeCode, ts.symbols(localeCode), messageArguments(count, translationArguments))
+		if err != nil {
+			ts.logger.Warn(fmt.Sprintf("translation of %q: %s", key, err))
+			return "", err
+		}
+		return label, nil
+	}
+
 	if translationArguments == nil {
 		translationArguments = make(map[string]interface{})
 	}
@@ -125,27 +189,109 @@ func (ts *TranslationService) translateWithLib(localeCode string, key string, co
 	}
 	return label, nil
 }
+
+// symbols returns the number separators of the locale, configured in locale.locales or locale.numbers
+func (ts *TranslationService) symbols(localeCode string) NumberSymbols {
+	symbols := ts.numberSymbols
+	if setting, ok := domain.LocaleSetting(ts.locales, localeCode, "numbers.decimal"); ok {
+		if setting, ok := setting.(string); ok {
+			symbols.Decimal = setting
+		}
+	}
+	if setting, ok := domain.LocaleSetting(ts.locales, localeCode, "numbers.thousand"); ok {
+		if setting, ok := setting.(string); ok {
+			symbols.Thousand = setting
+		}
+	}
+	return symbols
+}
+
+// message returns the ICU message of the key, nil if the key is not translated in the icu format
+func (ts *TranslationService) message(localeCode string, key string) *MessageFormat {
+	tag := language.NormalizeTag(localeCode)
+
+	ts.mu.RLock()
+	defer ts.mu.RUnlock()
+
+	if message, ok := ts.messages[tag][key]; ok {
+		return message
+	}
+	if message, ok := ts.fallbackMessages[tag][key]; ok {
+		return message
+	}
+	for i := strings.LastIndex(tag, "-"); i > 0; i = strings.LastIndex(tag, "-") {
+		tag = tag[:i]
+		if message, ok := ts.messages[tag][key]; ok {
+			return message
+		}
+	}
+	return nil
+}
+
+// messageArguments adds the count to the arguments, unless they contain a count already
+func messageArguments(count int, translationArguments map[string]interface{}) map[string]interface{} {
+	args := make(map[string]interface{}, len(translationArguments)+1)
+	for k, v := range translationArguments {
+		args[k] = v
+	}
+	if _, ok := args["count"]; !ok {
+		args["count"] = count
+	}
+	return args
+}
+
 func (ts *TranslationService) loadFiles() {
 	if ts.filesLoaded && !ts.devmode {
 		return
 	}
 
+	files := make([]string, 0, len(ts.translationFiles)+1)
 	if ts.translationFile != "" {
-		err := ts.i18bundle.LoadTranslationFile(ts.translationFile)
-		if err != nil {
-			ts.logger.Warn(fmt.Sprintf("Load translationfile failed: %s", err))
+		files = append(files, ts.translationFile)
+	}
+	for _, file := range ts.translationFiles {
+		if fileName, ok := file.(string); ok {
+			files = append(files, fileName)
 		}
 	}
-	if len(ts.translationFiles) > 0 {
-		for _, file := range ts.translationFiles {
-			if fileName, ok := file.(string); ok {
-				err := ts.i18bundle.LoadTranslationFile(fileName)
-				if err != nil {
-					ts.logger.Warn(fmt.Sprintf("Load translationfile failed: %s", err))
+
+	messages := make(map[string]map[string]*MessageFormat)
+	fallbackMessages := make(map[string]map[string]*MessageFormat)
+	for _, fileName := range files {
+		file, errs := loadTranslationFile(fileName, ts.messageFormat)
+		for _, err := range errs {
+			ts.logger.Warn(fmt.Sprintf("Load translationfile failed: %s", err))
+		}
+		if file == nil {
+			continue
+		}
+
+		ts.i18bundle.AddTranslation(file.language, file.legacy...)
+
+		if messages[file.language.Tag] == nil {
+			messages[file.language.Tag] = make(map[string]*MessageFormat)
+		}
+		for id, message := range file.messages {
+			messages[file.language.Tag][id] = message
+		}
+		// a less specific tag falls back to the messages of all files matching it, the file loaded first wins
+		for _, tag := range file.language.MatchingTags() {
+			if fallbackMessages[tag] == nil {
+				fallbackMessages[tag] = make(map[string]*MessageFormat)
+			}
+			for id, message := range file.messages {
+				if _, ok := fallbackMessages[tag][id]; !ok {
+					fallbackMessages[tag][id] = message
 				}
 			}
 		}
 	}
+
+	ts.mu.Lock()
+	ts.messages = messages
+	ts.fallbackMessages = fallbackMessages
+	ts.mu.Unlock()
+
 	ts.filesLoaded = true
 }
 
@@ -0,0 +1,188 @@
+package infrastructure
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"flamingo.me/flamingo/v3/core/locale/domain"
+	"flamingo.me/flamingo/v3/framework/config"
+	"flamingo.me/flamingo/v3/framework/flamingo"
+)
+
+func newTranslationService(t *testing.T, messageFormat string, files map[string]string) (*TranslationService, func()) {
+	t.Helper()
+
+	dir, err := ioutil.TempDir("", "translations")
+	require.NoError(t, err)
+
+	var translationFiles config.Slice
+	for name, content := range files {
+		file := filepath.Join(dir, name)
+		require.NoError(t, ioutil.WriteFile(file, []byte(content), 0644))
+		translationFiles = append(translationFiles, file)
+	}
+
+	ts := new(TranslationService)
+	ts.Inject(flamingo.NullLogger{}, &struct {
+		DevMode          bool         `inject:"config:debug.mode"`
+		TranslationFile  string       `inject:"config:locale.translationFile,optional"`
+		TranslationFiles config.Slice `inject:"config:locale.translationFiles,optional"`
+		MessageFormat    string       `inject:"config:locale.messageFormat,optional"`
+		Decimal          string       `inject:"config:locale.numbers.decimal,optional"`
+		Thousand         string       `inject:"config:locale.numbers.thousand,optional"`
+		Locales          config.Map   `inject:"config:locale.locales,optional"`
+	}{
+		TranslationFiles: translationFiles,
+		MessageFormat:    messageFormat,
+		Decimal:          ".",
+		Thousand:         ",",
+		Locales:          config.Map{"de-DE": config.Map{"numbers": config.Map{"decimal": ",", "thousand": "."}}},
+	})
+
+	return ts, func() { os.RemoveAll(dir) }
+}
+
+func TestTranslationService_Translate(t *testing.T) {
+	ts, cleanup := newTranslationService(t, "", map[string]string{
+		"en-US.legacy.json": `[
+			{"id": "greeting", "translation": "Hello {{.Name}}"},
+			{"id": "mails", "translation": {"one": "{{.Count}} mail", "other": "{{.Count}} mails"}},
+			{"id": "broken", "translation": "Hello {{.Name"},
+			{"id": "both", "translation": "legacy"}
+		]`,
+		"en-US.icu.yaml": `
+- id: items
+  format: icu
+  translation: "{count, plural, =0 {no items} one {# item} other {# items}}"
+- id: place
+  format: icu
+  translation: "{place, selectordinal, one {#st} two {#nd} few {#rd} other {#th}} place"
+- id: invalid
+  format: icu
+  translation: "{count, plural, one {# item}}"
+- id: both
+  format: icu
+  translation: "icu"
+- id: total
+  format: icu
+  translation: "{total, number} {count, plural, one {# item} other {# items}}"
+`,
+		"en-GB.icu.yaml": `
+- id: colour
+  format: icu
+  translation: "{colour}"
+`,
+		"de-DE.flat.toml": `
+[items]
+format = "icu"
+other = "{count, plural, one {# Artikel} other {# Artikel}} im {gender, select, female {ihrem} other {seinem}} Warenkorb"
+
+[greeting]
+other = "Hallo {{.Name}}"
+
+[total]
+format = "icu"
+other = "{total, number} für {count, plural, one {# Artikel} other {# Artikel}}"
+`,
+	})
+	defer cleanup()
+
+	tests := []struct {
+		name       string
+		key        string
+		localeCode string
+		count      int
+		args       map[string]interface{}
+		expected   string
+	}{
+		{name: "legacy translation", key: "greeting", localeCode: "en-US", args: map[string]interface{}{"Name": "Max"}, expected: "Hello Max"},
+		{name: "legacy plural", key: "mails", localeCode: "en-US", count: 3, expected: "3 mails"},
+		{name: "icu plural with count", key: "items", localeCode: "en-US", count: 1, expected: "1 item"},
+		{name: "icu plural with argument", key: "items", localeCode: "en-US", args: map[string]interface{}{"count": 0}, expected: "no items"},
+		{name: "icu ordinal", key: "place", localeCode: "en-US", args: map[string]interface{}{"place": 23}, expected: "23rd place"},
+		{name: "icu for less specific locale", key: "place", localeCode: "en", args: map[string]interface{}{"place": 2}, expected: "2nd place"},
+		{name: "icu for less specific locale from another file", key: "colour", localeCode: "en", args: map[string]interface{}{"colour": "007"}, expected: "007"},
+		{name: "icu for more specific locale", key: "items", localeCode: "de-DE-x", count: 2, args: map[string]interface{}{"gender": "female"}, expected: "2 Artikel im ihrem Warenkorb"},
+		{name: "flat legacy translation", key: "greeting", localeCode: "de-DE", args: map[string]interface{}{"Name": "Max"}, expected: "Hallo Max"},
+		{name: "icu numbers", key: "total", localeCode: "en-US", count: 1200, args: map[string]interface{}{"total": 1234.5}, expected: "1,234.5 1,200 items"},
+		{name: "icu numbers with separators of the locale", key: "total", localeCode: "de-de", count: 1200, args: map[string]interface{}{"total": 1234.5}, expected: "1.234,5 für 1.200 Artikel"},
+		{name: "invalid icu message", key: "invalid", localeCode: "en-US", expected: "invalid"},
+		{name: "invalid legacy message", key: "broken", localeCode: "en-US", expected: "broken"},
+		{name: "icu message with missing argument", key: "place", localeCode: "en-US", expected: "place"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, ts.Translate(tt.key, "", tt.localeCode, tt.count, tt.args))
+		})
+	}
+
+	keys := ts.AllTranslationKeys("en-US")
+	sort.Strings(keys)
+	assert.Equal(t, []string{"both", "greeting", "items", "mails", "place", "total"}, keys)
+}
+
+func TestTranslationService_DefaultLabel(t *testing.T) {
+	t.Run("gotemplate", func(t *testing.T) {
+		ts, cleanup := newTranslationService(t, MessageFormatGoTemplate, nil)
+		defer cleanup()
+
+		assert.Equal(t, "Hello Max", ts.Translate("missing", "Hello {{.Name}}", "en", 1, map[string]interface{}{"Name": "Max"}))
+	})
+
+	t.Run("icu", func(t *testing.T) {
+		ts, cleanup := newTranslationService(t, MessageFormatICU, nil)
+		defer cleanup()
+
+		assert.Equal(t, "3 items", ts.Translate("missing", "{count, plural, one {# item} other {# items}}", "en", 3, nil))
+
+		label := new(domain.Label)
+		label.Inject(ts)
+		label.SetKey("missing").SetLocale("en").SetDefaultLabel("Hello {name}").SetTranslationArguments(map[string]interface{}{"name": "Max"})
+		assert.Equal(t, "Hello Max", label.String())
+	})
+}
+
+func TestLoadTranslationFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "translations")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "en.json")
+	require.NoError(t, ioutil.WriteFile(file, []byte(`{
+		"valid": {"other": "{count} items", "format": "icu"},
+		"invalid": {"other": "{count items", "format": "icu"},
+		"plural": {"one": "#", "other": "#", "format": "icu"},
+		"unknown": {"other": "x", "format": "xliff"}
+	}`), 0644))
+
+	loaded, errs := loadTranslationFile(file, MessageFormatGoTemplate)
+	require.NotNil(t, loaded)
+	assert.Equal(t, "en", loaded.language.Tag)
+	assert.Len(t, loaded.messages, 1)
+	assert.Contains(t, loaded.messages, "valid")
+	require.Len(t, errs, 3)
+	assert.Contains(t, errs[0].Error(), `translation "invalid": expected '}'`)
+	assert.Contains(t, errs[1].Error(), `translation "plural" must be a string in the icu format`)
+	assert.Contains(t, errs[2].Error(), `translation "unknown" has the unknown format "xliff"`)
+
+	_, errs = loadTranslationFile(filepath.Join(dir, "translations.json"), MessageFormatGoTemplate)
+	assert.Len(t, errs, 1)
+
+	file = filepath.Join(dir, "yo.json")
+	require.NoError(t, ioutil.WriteFile(file, []byte(`{
+		"place": {"other": "{n, selectordinal, other {#}}", "format": "icu"}
+	}`), 0644))
+
+	loaded, errs = loadTranslationFile(file, MessageFormatGoTemplate)
+	require.NotNil(t, loaded)
+	assert.Empty(t, loaded.messages)
+	require.Len(t, errs, 1)
+	assert.Contains(t, errs[0].Error(), `translation "place": selectordinal of "n" is not supported for the locale "yo"`)
+}
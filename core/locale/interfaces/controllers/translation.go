@@ -32,7 +32,7 @@ func (c *TranslationController) Inject(
 // GetAllTranslations controller for TranslationController
 func (c *TranslationController) GetAllTranslations(ctx context.Context, r *web.Request) web.Result {
 	translations := []TranslationJSON{}
-	l := c.labelService.AllLabels()
+	l := c.labelService.AllLabelsWithContext(ctx)
 
 	for _, la := range l {
 		translations = append(translations, TranslationJSON{
@@ -0,0 +1,126 @@
+package filter
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+
+	"flamingo.me/flamingo/v3/core/locale/domain"
+	"flamingo.me/flamingo/v3/framework/config"
+	"flamingo.me/flamingo/v3/framework/flamingo"
+	"flamingo.me/flamingo/v3/framework/web"
+)
+
+// LocaleFilter resolves the locale of the request with the resolvers of locale.resolution.chain
+// and sets it in the request context
+type LocaleFilter struct {
+	resolverProvider func() map[string]domain.LocaleResolver
+	logger           flamingo.Logger
+	chain            []string
+	supportedLocales []string
+
+	once      sync.Once
+	resolvers []domain.LocaleResolver
+}
+
+// localeCodePattern accepts language tags like en, de-DE or en_GB, anything else is ignored
+var localeCodePattern = regexp.MustCompile(`^[a-zA-Z]{2,8}([-_][a-zA-Z0-9]{1,8})*$`)
+
+// Inject dependencies
+func (f *LocaleFilter) Inject(
+	resolverProvider func() map[string]domain.LocaleResolver,
+	logger flamingo.Logger,
+	cfg *struct {
+		Chain            config.Slice `inject:"config:locale.resolution.chain"`
+		SupportedLocales config.Slice `inject:"config:locale.resolution.supportedLocales,optional"`
+	},
+) {
+	f.resolverProvider = resolverProvider
+	f.logger = logger.WithField(flamingo.LogKeyModule, "locale").WithField(flamingo.LogKeyCategory, "locale.filter")
+	if cfg != nil {
+		if err := cfg.Chain.MapInto(&f.chain); err != nil {
+			panic(err)
+		}
+		if err := cfg.SupportedLocales.MapInto(&f.supportedLocales); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// Filter sets the resolved locale in the context, requests without resolved locale use the configured locale.locale
+func (f *LocaleFilter) Filter(ctx context.Context, r *web.Request, w http.ResponseWriter, chain *web.FilterChain) web.Result {
+	if localeCode, ok := f.resolve(ctx, r); ok {
+		ctx = domain.ContextWithLocale(ctx, localeCode)
+		domain.StoreLocale(r, localeCode)
+	}
+
+	return chain.Next(ctx, r, w)
+}
+
+// resolve asks the resolvers in the order of the chain until one returns a supported locale
+func (f *LocaleFilter) resolve(ctx context.Context, r *web.Request) (string, bool) {
+	if len(f.chain) == 0 {
+		return "", false
+	}
+
+	f.once.Do(func() {
+		resolvers := f.resolverProvider()
+		for _, name := range f.chain {
+			resolver, ok := resolvers[name]
+			if !ok {
+				f.logger.Warn(fmt.Sprintf("unknown locale resolver %q in locale.resolution.chain", name))
+				continue
+			}
+			f.resolvers = append(f.resolvers, resolver)
+		}
+	})
+
+	for _, resolver := range f.resolvers {
+		for _, localeCode := range resolver.Resolve(ctx, r) {
+			if localeCode, ok := f.match(localeCode); ok {
+				return localeCode, true
+			}
+		}
+	}
+
+	return "", false
+}
+
+// match returns the supported locale for the requested locale code. Locale codes of the same language match if
+// no supported locale matches exactly, e.g. de-CH matches de-DE. Without supported locales every locale code matches.
+func (f *LocaleFilter) match(localeCode string) (string, bool) {
+	if !localeCodePattern.MatchString(localeCode) {
+		return "", false
+	}
+	localeCode = strings.Replace(localeCode, "_", "-", -1)
+
+	if len(f.supportedLocales) == 0 {
+		return localeCode, true
+	}
+
+	for _, supported := range f.supportedLocales {
+		if strings.EqualFold(strings.Replace(supported, "_", "-", -1), localeCode) {
+			return supported, true
+		}
+	}
+
+	lang := baseLanguage(localeCode)
+	for _, supported := range f.supportedLocales {
+		if strings.EqualFold(baseLanguage(supported), lang) {
+			return supported, true
+		}
+	}
+
+	return "", false
+}
+
+// baseLanguage returns the language of a locale code, e.g. de for de-DE
+func baseLanguage(localeCode string) string {
+	if i := strings.IndexAny(localeCode, "-_"); i >= 0 {
+		return localeCode[:i]
+	}
+	return localeCode
+}
@@ -0,0 +1,76 @@
+package filter
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"flamingo.me/flamingo/v3/core/locale/domain"
+	"flamingo.me/flamingo/v3/framework/config"
+	"flamingo.me/flamingo/v3/framework/flamingo"
+	"flamingo.me/flamingo/v3/framework/web"
+)
+
+type staticResolver []string
+
+func (s staticResolver) Resolve(context.Context, *web.Request) []string {
+	return s
+}
+
+func TestLocaleFilter_Filter(t *testing.T) {
+	resolvers := map[string]domain.LocaleResolver{
+		"none":    staticResolver(nil),
+		"invalid": staticResolver{"<script>", "de DE"},
+		"cookie":  staticResolver{"en_GB"},
+		"header":  staticResolver{"it", "de-CH", "fr"},
+	}
+
+	tests := []struct {
+		name             string
+		chain            config.Slice
+		supportedLocales config.Slice
+		expected         string
+	}{
+		{name: "no chain"},
+		{name: "unknown resolver", chain: config.Slice{"unknown", "none"}},
+		{name: "first resolved locale", chain: config.Slice{"none", "cookie", "header"}, expected: "en-GB"},
+		{name: "invalid locale codes are ignored", chain: config.Slice{"invalid", "header"}, expected: "it"},
+		{name: "supported locale", chain: config.Slice{"header"}, supportedLocales: config.Slice{"fr", "de-CH"}, expected: "de-CH"},
+		{name: "supported locale of same language", chain: config.Slice{"cookie", "header"}, supportedLocales: config.Slice{"de_DE", "en_US"}, expected: "en_US"},
+		{name: "unsupported locales", chain: config.Slice{"cookie", "header"}, supportedLocales: config.Slice{"nl-NL"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := new(LocaleFilter)
+			f.Inject(
+				func() map[string]domain.LocaleResolver { return resolvers },
+				flamingo.NullLogger{},
+				&struct {
+					Chain            config.Slice `inject:"config:locale.resolution.chain"`
+					SupportedLocales config.Slice `inject:"config:locale.resolution.supportedLocales,optional"`
+				}{
+					Chain:            tt.chain,
+					SupportedLocales: tt.supportedLocales,
+				},
+			)
+
+			r := web.CreateRequest(nil, nil)
+			ctx := web.ContextWithRequest(context.Background(), r)
+
+			var resolved string
+			chain := web.NewFilterChain(func(ctx context.Context, r *web.Request, w http.ResponseWriter) web.Result {
+				resolved, _ = domain.LocaleFromContext(ctx)
+				return nil
+			}, f)
+			chain.Next(ctx, r, httptest.NewRecorder())
+
+			assert.Equal(t, tt.expected, resolved)
+			stored, _ := domain.LocaleFromContext(ctx)
+			assert.Equal(t, tt.expected, stored, "the locale is stored in the request")
+		})
+	}
+}
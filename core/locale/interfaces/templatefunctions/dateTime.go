@@ -30,11 +30,11 @@ func (tf *DateTimeFormatFromIso) Inject(service *application.DateTimeService, lo
 }
 
 // Func template function factory
-func (tf *DateTimeFormatFromIso) Func(context.Context) interface{} {
+func (tf *DateTimeFormatFromIso) Func(ctx context.Context) interface{} {
 	// Usage
 	// dateTimeFormatFromIso(dateTimeString).formatDate()
 	return func(dateTimeString string) *domain.DateTimeFormatter {
-		dateTimeFormatter, e := tf.dateTimeService.GetDateTimeFormatterFromIsoString(dateTimeString)
+		dateTimeFormatter, e := tf.dateTimeService.GetDateTimeFormatterFromIsoStringWithContext(ctx, dateTimeString)
 		if e != nil {
 			tf.logger.Error("Error Parsing dateTime %v / %v", dateTimeString, e)
 			return &domain.DateTimeFormatter{}
@@ -50,11 +50,11 @@ func (tf *DateTimeFormatFromTime) Inject(service *application.DateTimeService, l
 }
 
 // Func template function factory
-func (tf *DateTimeFormatFromTime) Func(context.Context) interface{} {
+func (tf *DateTimeFormatFromTime) Func(ctx context.Context) interface{} {
 	// Usage
 	// dateTimeFormat(dateTime).formatDate()
 	return func(dateTime time.Time) *domain.DateTimeFormatter {
-		dateTimeFormatter, e := tf.dateTimeService.GetDateTimeFormatter(dateTime)
+		dateTimeFormatter, e := tf.dateTimeService.GetDateTimeFormatterWithContext(ctx, dateTime)
 		if e != nil {
 			tf.logger.Error("Error getting formatter dateTime %v", e)
 			return &domain.DateTimeFormatter{}
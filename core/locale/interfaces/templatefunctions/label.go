@@ -23,8 +23,8 @@ func (tf *Label) Inject(labelService *application.LabelService, logger flamingo.
 	tf.logger = logger.WithField("module", "locale").WithField("category", "templatefunctions.label")
 }
 
-// Func template function factory
-func (tf *Label) Func(context.Context) interface{} {
+// Func template function factory, the labels are created in the locale of the request
+func (tf *Label) Func(ctx context.Context) interface{} {
 
 	return func(key string, params ...interface{}) *domain.Label {
 
@@ -32,6 +32,6 @@ func (tf *Label) Func(context.Context) interface{} {
 			tf.logger.Warn("Deprecated unsupported parameters given! Use the Setters provided by the returned Label " + key)
 
 		}
-		return tf.labelService.NewLabel(key)
+		return tf.labelService.NewLabelWithContext(ctx, key)
 	}
 }
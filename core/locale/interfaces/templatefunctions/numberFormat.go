@@ -4,6 +4,7 @@ import (
 	"context"
 	"math/big"
 
+	"flamingo.me/flamingo/v3/core/locale/application"
 	"flamingo.me/flamingo/v3/framework/flamingo"
 
 	"github.com/leekchan/accounting"
@@ -15,6 +16,8 @@ type NumberFormatFunc struct {
 	decimal   string
 	thousand  string
 	logger    flamingo.Logger
+	// LocaleService provides the number settings configured for the locale of the request
+	LocaleService *application.LocaleService `inject:",optional"`
 }
 
 // Inject dependencies
@@ -32,11 +35,30 @@ func (nff *NumberFormatFunc) Inject(
 	nff.logger = logger
 }
 
-// Func returns the template function for formatting numbers
-func (nff *NumberFormatFunc) Func(context.Context) interface{} {
+// Func returns the template function for formatting numbers in the locale of the request
+func (nff *NumberFormatFunc) Func(ctx context.Context) interface{} {
+	defaultPrecision, decimal, thousand := nff.precision, nff.decimal, nff.thousand
+	if nff.LocaleService != nil {
+		if setting, ok := nff.LocaleService.Setting(ctx, "numbers.precision"); ok {
+			if setting, ok := setting.(float64); ok {
+				defaultPrecision = setting
+			}
+		}
+		if setting, ok := nff.LocaleService.Setting(ctx, "numbers.decimal"); ok {
+			if setting, ok := setting.(string); ok {
+				decimal = setting
+			}
+		}
+		if setting, ok := nff.LocaleService.Setting(ctx, "numbers.thousand"); ok {
+			if setting, ok := setting.(string); ok {
+				thousand = setting
+			}
+		}
+	}
+
 	return func(value interface{}, params ...int) string {
 
-		precision := int(nff.precision)
+		precision := int(defaultPrecision)
 		if len(params) > 0 {
 			precision = params[0]
 		}
@@ -49,9 +71,9 @@ func (nff *NumberFormatFunc) Func(context.Context) interface{} {
 
 		valueBigFloat, ok := value.(*big.Float)
 		if ok {
-			return accounting.FormatNumberBigFloat(valueBigFloat, precision, nff.thousand, nff.decimal)
+			return accounting.FormatNumberBigFloat(valueBigFloat, precision, thousand, decimal)
 		}
 
-		return accounting.FormatNumber(value, precision, nff.thousand, nff.decimal)
+		return accounting.FormatNumber(value, precision, thousand, decimal)
 	}
 }
@@ -20,8 +20,8 @@ func (pff *PriceFormatFunc) Inject(priceService *application.PriceService) {
 
 // Func formats the value and adds currency sign/symbol
 // example output could be: $ 21,500.99
-func (pff *PriceFormatFunc) Func(context.Context) interface{} {
+func (pff *PriceFormatFunc) Func(ctx context.Context) interface{} {
 	return func(value float64, currency string) string {
-		return pff.priceService.FormatPrice(value, currency)
+		return pff.priceService.FormatPriceWithContext(ctx, value, currency)
 	}
 }
@@ -37,10 +37,10 @@ func (pff *PriceFormatLongFunc) Func(ctx context.Context) interface{} {
 	return func(value float64, currency string, currencyLabel string) string {
 		priceFunc := pff.priceFormat.Func(ctx).(func(value float64, currency string) string)
 		price := priceFunc(value, currency)
-		currencyLabel = pff.labelService.NewLabel(currencyLabel).String()
+		currencyLabel = pff.labelService.NewLabelWithContext(ctx, currencyLabel).String()
 
 		// get config for currency or default config
-		formatConfig := pff.priceService.GetConfigForCurrency(currency)
+		formatConfig := pff.priceService.GetConfigForCurrencyWithContext(ctx, currency)
 
 		format, ok := formatConfig["formatLong"].(string)
 		if ok {
@@ -6,6 +6,7 @@ import (
 	"flamingo.me/flamingo/v3/core/locale/domain"
 	"flamingo.me/flamingo/v3/core/locale/infrastructure"
 	"flamingo.me/flamingo/v3/core/locale/interfaces/controllers"
+	"flamingo.me/flamingo/v3/core/locale/interfaces/filter"
 	"flamingo.me/flamingo/v3/core/locale/interfaces/templatefunctions"
 	"flamingo.me/flamingo/v3/framework/config"
 	"flamingo.me/flamingo/v3/framework/flamingo"
@@ -28,6 +29,13 @@ func (m *Module) Configure(injector *dingo.Injector) {
 	injector.Bind(new(domain.TranslationService)).In(dingo.ChildSingleton).To(infrastructure.TranslationService{})
 	injector.Bind(new(application.DateTimeServiceInterface)).To(application.DateTimeService{})
 
+	BindLocaleResolver(injector, "route", new(infrastructure.RouteLocaleResolver))
+	BindLocaleResolver(injector, "subdomain", new(infrastructure.SubdomainLocaleResolver))
+	BindLocaleResolver(injector, "cookie", new(infrastructure.CookieLocaleResolver))
+	BindLocaleResolver(injector, "session", new(infrastructure.SessionLocaleResolver))
+	BindLocaleResolver(injector, "acceptLanguage", new(infrastructure.AcceptLanguageLocaleResolver))
+	injector.BindMulti(new(web.Filter)).To(new(filter.LocaleFilter))
+
 	if m.EnableTranslationAPI {
 		web.BindRoutes(injector, new(routes))
 	}
@@ -40,6 +48,11 @@ func (m *Module) Configure(injector *dingo.Injector) {
 	flamingo.BindTemplateFunc(injector, "dateTimeFormat", new(templatefunctions.DateTimeFormatFromTime))
 }
 
+// BindLocaleResolver registers a locale resolver, which is used if its name is part of locale.resolution.chain
+func BindLocaleResolver(injector *dingo.Injector, name string, resolver domain.LocaleResolver) {
+	injector.BindMap(new(domain.LocaleResolver), name).To(resolver)
+}
+
 func (r *routes) Inject(
 	tc *controllers.TranslationController,
 ) {
@@ -58,7 +71,8 @@ func (r *routes) Routes(registry *web.RouterRegistry) {
 func (m *Module) DefaultConfig() config.Map {
 	return config.Map{
 		"locale": config.Map{
-			"locale": "en-US",
+			"locale":        "en-US",
+			"messageFormat": "gotemplate",
 			"accounting": config.Map{
 				"default": config.Map{
 					"decimal":    ".",
@@ -79,6 +93,18 @@ func (m *Module) DefaultConfig() config.Map {
 				"dateTimeFormat": "02 Jan 2006 15:04:05",
 				"location":       "Europe/London",
 			},
+			"resolution": config.Map{
+				"chain": config.Slice{},
+				"route": config.Map{
+					"param": "locale",
+				},
+				"cookie": config.Map{
+					"name": "locale",
+				},
+				"session": config.Map{
+					"key": "locale",
+				},
+			},
 		},
 	}
 }
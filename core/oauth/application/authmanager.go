@@ -6,6 +6,7 @@ import (
 	"net/http"
 	"os"
 	"runtime/debug"
+	"time"
 
 	"flamingo.me/flamingo/v3/core/oauth/domain"
 	"flamingo.me/flamingo/v3/framework/config"
@@ -40,17 +41,18 @@ func init() {
 type (
 	// AuthManager handles authentication related operations
 	AuthManager struct {
-		server              string
-		secret              string
-		clientID            string
-		disableOfflineToken bool
-		scopes              config.Slice
-		idTokenMapping      config.Slice
-		userInfoMapping     config.Slice
-		logger              flamingo.Logger
-		router              *web.Router
-		openIDProvider      *oidc.Provider
-		tokenExtras         config.Slice
+		logger          flamingo.Logger
+		router          *web.Router
+		providers       map[string]*Provider
+		defaultProvider string
+		stateLifetime   time.Duration
+		maxLoginStates  int
+		logoutRegistry  domain.LogoutRegistry
+		refreshGroup    domain.RefreshGroup
+		eventPublisher  *EventPublisher
+		refreshSkew     time.Duration
+		// allowedLogoutRedirects are absolute post logout redirect URLs outside of the application
+		allowedLogoutRedirects []string
 	}
 
 	loggingRoundTripper struct {
@@ -77,45 +79,155 @@ func (f *loggingRoundTripper) RoundTrip(req *http.Request) (*http.Response, erro
 	return res, err
 }
 
-// Inject authManager dependencies
+// Inject authManager dependencies. The openIDProvider is not used, the providers are discovered from the configuration.
 func (am *AuthManager) Inject(logger flamingo.Logger, router *web.Router, openIDProvider *oidc.Provider, config *struct {
-	Server              string       `inject:"config:oauth.server"`
-	Secret              string       `inject:"config:oauth.secret"`
-	ClientID            string       `inject:"config:oauth.clientid"`
-	DisableOfflineToken bool         `inject:"config:oauth.disableOfflineToken"`
-	Scopes              config.Slice `inject:"config:oauth.scopes"`
-	IDTokenMapping      config.Slice `inject:"config:oauth.claims.idToken"`
-	UserInfoMapping     config.Slice `inject:"config:oauth.claims.userInfo"`
-	TokenExtras         config.Slice `inject:"config:oauth.tokenExtras"`
-	DebugMode           bool         `inject:"config:debug.mode"`
+	Server              string        `inject:"config:oauth.server"`
+	Secret              string        `inject:"config:oauth.secret"`
+	ClientID            string        `inject:"config:oauth.clientid"`
+	DisableOfflineToken bool          `inject:"config:oauth.disableOfflineToken"`
+	Scopes              config.Slice  `inject:"config:oauth.scopes"`
+	IDTokenMapping      config.Slice  `inject:"config:oauth.claims.idToken"`
+	UserInfoMapping     config.Slice  `inject:"config:oauth.claims.userInfo"`
+	TokenExtras         config.Slice  `inject:"config:oauth.tokenExtras"`
+	Providers           config.Map    `inject:"config:oauth.providers,optional"`
+	DefaultProvider     string        `inject:"config:oauth.defaultProvider,optional"`
+	PKCE                bool          `inject:"config:oauth.pkce,optional"`
+	StateLifetime       time.Duration `inject:"config:oauth.stateLifetime,optional"`
+	MaxLoginStates      int           `inject:"config:oauth.maxLoginStates,optional"`
+	BearerAudience      string        `inject:"config:oauth.bearer.audience,optional"`
+	BearerIssuer        string        `inject:"config:oauth.bearer.issuer,optional"`
+	BearerJWKSFile      string        `inject:"config:oauth.bearer.jwksFile,optional"`
+	LogoutRedirects     config.Slice  `inject:"config:oauth.logout.allowedRedirectURLs,optional"`
+	RefreshSkew         time.Duration `inject:"config:oauth.refresh.skew,optional"`
+	DebugMode           bool          `inject:"config:debug.mode"`
+	// LogoutRegistry enables the back-channel logout, RefreshGroup shares token refreshes between instances
+	LogoutRegistry domain.LogoutRegistry `inject:",optional"`
+	RefreshGroup   domain.RefreshGroup   `inject:",optional"`
+	EventPublisher *EventPublisher       `inject:",optional"`
 }) {
 	am.logger = logger.WithField(flamingo.LogKeyModule, "oauth")
 	am.router = router
+	am.providers = make(map[string]*Provider)
+	am.defaultProvider = DefaultProvider
+	am.stateLifetime = 10 * time.Minute
+	am.maxLoginStates = 10
 	if config != nil {
-		am.server = config.Server
-		am.secret = config.Secret
-		am.clientID = config.ClientID
-		am.disableOfflineToken = config.DisableOfflineToken
-		am.scopes = config.Scopes
-		am.idTokenMapping = config.IDTokenMapping
-		am.userInfoMapping = config.UserInfoMapping
-		am.tokenExtras = config.TokenExtras
-
-		var err error
-		am.openIDProvider, err = oidc.NewProvider(context.Background(), config.Server)
-		if err != nil {
-			if config.DebugMode {
-				am.logger.Error(err)
-			} else {
-				//panic on err since we really expect a valid authmanager state and application is in a failed state otherwise
-				panic(err)
+		am.logoutRegistry = config.LogoutRegistry
+		am.refreshGroup = config.RefreshGroup
+		am.eventPublisher = config.EventPublisher
+		if config.DefaultProvider != "" {
+			am.defaultProvider = config.DefaultProvider
+		}
+		if config.StateLifetime > 0 {
+			am.stateLifetime = config.StateLifetime
+		}
+		if config.MaxLoginStates > 0 {
+			am.maxLoginStates = config.MaxLoginStates
+		}
+		am.refreshSkew = config.RefreshSkew
+
+		defaultConfig := ProviderConfig{
+			Server:              config.Server,
+			Secret:              config.Secret,
+			ClientID:            config.ClientID,
+			DisableOfflineToken: config.DisableOfflineToken,
+			PKCE:                config.PKCE,
+		}
+		am.mapConfig(config.Scopes, &defaultConfig.Scopes)
+		am.mapConfig(config.IDTokenMapping, &defaultConfig.Claims.IDToken)
+		am.mapConfig(config.UserInfoMapping, &defaultConfig.Claims.UserInfo)
+		am.mapConfig(config.TokenExtras, &defaultConfig.TokenExtras)
+		am.mapConfig(config.LogoutRedirects, &am.allowedLogoutRedirects)
+		defaultConfig.Bearer.Audience = config.BearerAudience
+		defaultConfig.Bearer.Issuer = config.BearerIssuer
+		defaultConfig.Bearer.JWKSFile = config.BearerJWKSFile
+
+		configs, err := providerConfigs(defaultConfig, config.Providers)
+		if err == nil {
+			if _, ok := configs[am.defaultProvider]; !ok {
+				err = errors.Errorf("unknown oauth.defaultProvider %q", am.defaultProvider)
+			}
+		}
+		am.handleInitError(err, config.DebugMode)
+
+		for name, cfg := range configs {
+			provider, err := newProvider(context.Background(), name, cfg)
+			am.handleInitError(err, config.DebugMode)
+			am.providers[name] = provider
+		}
+	}
+}
+
+func (am *AuthManager) handleInitError(err error, debugMode bool) {
+	if err == nil {
+		return
+	}
+	if debugMode {
+		am.logger.Error(err)
+	} else {
+		//panic on err since we really expect a valid authmanager state and application is in a failed state otherwise
+		panic(err)
+	}
+}
+
+func (am *AuthManager) mapConfig(cfg config.Slice, out *[]string) {
+	if err := cfg.MapInto(out); err != nil {
+		am.logger.WithField(flamingo.LogKeyCategory, "auth").Error("could not map configuration", err)
+	}
+}
+
+// Provider returns a configured provider by its name
+func (am *AuthManager) Provider(name string) (*Provider, error) {
+	provider, ok := am.providers[name]
+	if !ok {
+		return nil, errors.Errorf("unknown oauth provider %q", name)
+	}
+	return provider, nil
+}
+
+// Providers returns the names of all configured providers
+func (am *AuthManager) Providers() []string {
+	return sortedProviderNames(am.providers)
+}
+
+// DefaultProvider returns the provider used if no provider is given
+func (am *AuthManager) DefaultProvider() *Provider {
+	provider, ok := am.providers[am.defaultProvider]
+	if !ok {
+		return &Provider{name: am.defaultProvider}
+	}
+	return provider
+}
+
+// SessionProvider returns the provider which authenticated the session, or the default provider
+func (am *AuthManager) SessionProvider(session *web.Session) *Provider {
+	if session != nil {
+		if name, ok := domain.LoadProvider(session); ok {
+			if provider, ok := am.providers[name]; ok {
+				return provider
 			}
 		}
 	}
+	return am.DefaultProvider()
+}
+
+// RequestProvider returns the provider given by the request's `provider` param, or the default provider
+func (am *AuthManager) RequestProvider(req *web.Request) (*Provider, error) {
+	if req != nil {
+		if name, ok := req.Params["provider"]; ok && name != "" {
+			return am.Provider(name)
+		}
+	}
+	return am.DefaultProvider(), nil
+}
+
+// StoreProvider remembers the provider which authenticated the session, so token refresh and logout use the same provider
+func (am *AuthManager) StoreProvider(session *web.Session, provider *Provider) {
+	domain.StoreProvider(session, provider.Name())
 }
 
 // Auth tries to retrieve the authentication context for a active session - this is used to pass Authentication to services
-//	- if the stored token for the Auth is not valid anymore it will refresh the token before
+//	- if the stored token for the Auth is not valid anymore, or expires within the configured skew, it will refresh the token before
 func (am *AuthManager) Auth(c context.Context, session *web.Session) (domain.Auth, error) {
 	c = am.OAuthCtx(c)
 	currentToken, err := am.OAuth2Token(session)
@@ -123,12 +235,15 @@ func (am *AuthManager) Auth(c context.Context, session *web.Session) (domain.Aut
 		am.logger.WithContext(c).Error(err)
 		return domain.Auth{}, err
 	}
-	if !currentToken.Valid() {
+	if am.tokenNeedsRefresh(currentToken) {
 		err := am.refreshTokenAndUpdateStore(c, session)
-		if err != nil {
+		if err != nil && !currentToken.Valid() {
 			am.logger.WithContext(c).Error(err)
 			return domain.Auth{}, err
 		}
+		if err != nil {
+			am.logger.WithContext(c).Warn("refresh ahead of expiry failed, using the current token: ", err)
+		}
 	}
 	ts, err := am.TokenSource(c, session)
 	if err != nil {
@@ -147,9 +262,9 @@ func (am *AuthManager) Auth(c context.Context, session *web.Session) (domain.Aut
 	}, nil
 }
 
-// OpenIDProvider is a lazy initialized OID provider
+// OpenIDProvider returns the OpenID provider of the default provider
 func (am *AuthManager) OpenIDProvider() *oidc.Provider {
-	return am.openIDProvider
+	return am.DefaultProvider().OpenIDProvider()
 }
 
 //OAuthCtx - returns ctx that should be used to pass to oauth2 lib - it enables logging for Debug reasons
@@ -165,46 +280,38 @@ func (am *AuthManager) OAuthCtx(ctx context.Context) context.Context {
 	return ctx
 }
 
-// OAuth2Config is lazy setup oauth2config
-func (am *AuthManager) OAuth2Config(_ context.Context, req *web.Request) *oauth2.Config {
+// OAuth2Config is lazy setup oauth2config for the provider of the request
+func (am *AuthManager) OAuth2Config(ctx context.Context, req *web.Request) *oauth2.Config {
+	provider, err := am.RequestProvider(req)
+	if err != nil {
+		am.logger.WithContext(ctx).WithField(flamingo.LogKeyCategory, "auth").Error(err)
+		provider = am.DefaultProvider()
+	}
+
+	return am.ProviderOAuth2Config(ctx, req, provider)
+}
 
+// ProviderOAuth2Config returns the oauth2config of the given provider, with a callback URL for the request if given
+func (am *AuthManager) ProviderOAuth2Config(_ context.Context, req *web.Request, provider *Provider) *oauth2.Config {
 	var redirectURL string
 	if req != nil {
-		callbackURL, _ := am.router.Absolute(req, "auth.callback", nil)
+		var params map[string]string
+		if provider.Name() != am.defaultProvider {
+			params = map[string]string{"provider": provider.Name()}
+		}
+		callbackURL, _ := am.router.Absolute(req, "auth.callback", params)
 		redirectURL = callbackURL.String()
 	}
 
-	var scopes []string
-	err := am.scopes.MapInto(&scopes)
-	if err != nil {
-		am.logger.WithField(flamingo.LogKeyCategory, "auth").Error("could not parse scopes from config", am.scopes, err)
-	}
-
-	scopes = append([]string{oidc.ScopeOpenID}, scopes...)
-	if !am.disableOfflineToken {
-		scopes = append(scopes, oidc.ScopeOfflineAccess)
-	}
-
-	oauth2Config := &oauth2.Config{
-		ClientID:     am.clientID,
-		ClientSecret: am.secret,
-		RedirectURL:  redirectURL,
-
-		Endpoint: am.OpenIDProvider().Endpoint(),
-
-		// "openid" is a required scope for OpenID Connect flows.
-		Scopes: scopes,
-
-		ClaimSet: am.getClaimsRequestParameter(),
-	}
+	oauth2Config := provider.OAuth2Config(redirectURL)
 
 	am.logger.WithField(flamingo.LogKeyCategory, "auth").Debug("am.oauth2Config", oauth2Config)
 	return oauth2Config
 }
 
-// Verifier creates an OID verifier
+// Verifier creates an OID verifier for the default provider, nil if the discovery failed in debug mode
 func (am *AuthManager) Verifier() *oidc.IDTokenVerifier {
-	return am.OpenIDProvider().Verifier(&oidc.Config{ClientID: am.clientID})
+	return am.DefaultProvider().Verifier()
 }
 
 // OAuth2Token retrieves the oauth2 token from the session
@@ -242,10 +349,14 @@ func (am *AuthManager) getIDToken(c context.Context, session *web.Session) (*oid
 		return nil, "", errors.New("no session configured")
 	}
 
+	verifier, err := am.SessionProvider(session).verifier()
+	if err != nil {
+		return nil, "", err
+	}
 	if token, ok := session.Load(keyRawIDToken); ok {
-		idtoken, err := am.Verifier().Verify(c, token.(string))
+		idtoken, err := verifier.Verify(c, token.(string))
 		if err == nil {
-			return idtoken, token.(string), nil
+			return am.checkLoggedOut(c, session, idtoken, token.(string))
 		}
 		am.logger.WithContext(c).Debug("keyRawIDToken not verified (anymore)")
 		err = am.refreshTokenAndUpdateStore(c, session)
@@ -256,11 +367,11 @@ func (am *AuthManager) getIDToken(c context.Context, session *web.Session) (*oid
 		if !ok {
 			return nil, "", errors.New("no token after refreshToken")
 		}
-		idtoken, err = am.Verifier().Verify(c, token.(string))
+		idtoken, err = verifier.Verify(c, token.(string))
 		if err != nil {
 			return nil, "", errors.New("no verified id token after refreshToken")
 		}
-		return idtoken, token.(string), nil
+		return am.checkLoggedOut(c, session, idtoken, token.(string))
 
 	}
 
@@ -268,15 +379,26 @@ func (am *AuthManager) getIDToken(c context.Context, session *web.Session) (*oid
 
 }
 
-// refreshTokenAndUpdateStore
+// checkLoggedOut removes the token details if the identity provider logged out the session via back-channel logout
+func (am *AuthManager) checkLoggedOut(c context.Context, session *web.Session, idtoken *oidc.IDToken, raw string) (*oidc.IDToken, string, error) {
+	if am.loggedOut(c, session, idtoken) {
+		am.DeleteTokenDetails(session)
+		am.DeleteAuthState(session)
+		return nil, "", errors.New("session was logged out by the identity provider")
+	}
+	return idtoken, raw, nil
+}
+
+// refreshTokenAndUpdateStore refreshes the token of the session, concurrent requests of the session share one refresh
 func (am *AuthManager) refreshTokenAndUpdateStore(c context.Context, session *web.Session) error {
 	c = am.OAuthCtx(c)
-	tokenSource, err := am.TokenSource(c, session)
+	currentToken, err := am.OAuth2Token(session)
 	if err != nil {
 		return errors.WithStack(err)
 	}
 
-	token, err := tokenSource.Token()
+	provider := am.SessionProvider(session)
+	token, err := am.refreshToken(c, provider, currentToken)
 	if err != nil {
 		return errors.WithStack(err)
 	}
@@ -285,36 +407,11 @@ func (am *AuthManager) refreshTokenAndUpdateStore(c context.Context, session *we
 	if err != nil {
 		return errors.WithStack(err)
 	}
-	return nil
-}
-
-func (am *AuthManager) getClaimsRequestParameter() *oauth2.ClaimSet {
-	var claimSet *oauth2.ClaimSet
-
-	claimSet = am.createClaimSetFromMapping(oauth2.IdTokenClaim, am.idTokenMapping, claimSet)
-	claimSet = am.createClaimSetFromMapping(oauth2.UserInfoClaim, am.userInfoMapping, claimSet)
-
-	return claimSet
-}
-
-func (am *AuthManager) createClaimSetFromMapping(topLevelName string, configuration config.Slice, claimSet *oauth2.ClaimSet) *oauth2.ClaimSet {
-	var mapping []string
-	err := configuration.MapInto(&mapping)
-	if err != nil {
-		am.logger.WithField(flamingo.LogKeyCategory, "auth").Error("could not map configuration", err)
-	}
 
-	for _, name := range mapping {
-		if name == "" {
-			continue
-		}
-		if claimSet == nil {
-			claimSet = &oauth2.ClaimSet{}
-		}
-		claimSet.AddVoluntaryClaim(topLevelName, name)
+	if am.eventPublisher != nil {
+		am.eventPublisher.PublishTokenRefreshedEvent(c, &domain.TokenRefreshedEvent{Session: session, Provider: provider.Name()})
 	}
-
-	return claimSet
+	return nil
 }
 
 // AccessToken - used to get access token
@@ -349,7 +446,7 @@ func (am *AuthManager) TokenSource(c context.Context, session *web.Session) (oau
 		return nil, err
 	}
 
-	return am.OAuth2Config(c, nil).TokenSource(c, oauth2Token), nil
+	return am.ProviderOAuth2Config(c, nil, am.SessionProvider(session)).TokenSource(c, oauth2Token), nil
 }
 
 // HTTPClient to retrieve a client with automatic tokensource
@@ -382,13 +479,8 @@ func (am *AuthManager) StoreTokenDetails(ctx context.Context, session *web.Sessi
 		return err
 	}
 
-	var extras []string
-	err = am.tokenExtras.MapInto(&extras)
-	if err != nil {
-		return err
-	}
 	tokenExtras := domain.TokenExtras{}
-	for _, extra := range extras {
+	for _, extra := range am.SessionProvider(session).Config().TokenExtras {
 		value := oauth2Token.Extra(extra)
 		parsed, ok := value.(string)
 		if !ok {
@@ -411,21 +503,25 @@ func (am *AuthManager) DeleteTokenDetails(session *web.Session) {
 	session.Delete(keyToken)
 	session.Delete(keyRawIDToken)
 	session.Delete(keyTokenExtras)
+	domain.DeleteProvider(session)
 }
 
 // StoreAuthState stores auth state into session, used to connect passed state id in auth callback with the one stored in session
+// Deprecated: use NewLoginState, which supports multiple login attempts, nonce and PKCE
 func (am *AuthManager) StoreAuthState(session *web.Session, state string) {
 	session.Store(keyAuthstate, state)
 }
 
 // LoadAuthState loads auth state from session
+// Deprecated: use ConsumeLoginState
 func (am *AuthManager) LoadAuthState(session *web.Session) (string, bool) {
 	value, _ := session.Load(keyAuthstate)
 	state, ok := value.(string)
 	return state, ok
 }
 
-// DeleteAuthState deletes auth state from session
+// DeleteAuthState deletes auth state and all pending login attempts from session
 func (am *AuthManager) DeleteAuthState(session *web.Session) {
 	session.Delete(keyAuthstate)
+	am.DeleteLoginStates(session)
 }
@@ -0,0 +1,265 @@
+package application
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"strings"
+
+	"flamingo.me/flamingo/v3/core/oauth/domain"
+	"flamingo.me/flamingo/v3/framework/flamingo"
+	"flamingo.me/flamingo/v3/framework/web"
+	"github.com/coreos/go-oidc"
+	"github.com/pkg/errors"
+	"golang.org/x/oauth2"
+	"gopkg.in/square/go-jose.v2"
+)
+
+const (
+	// keyBearerAuth defines where the result of the bearer authentication is cached in the request values
+	keyBearerAuth = "auth.bearer"
+)
+
+type (
+	// BearerAuthenticator authenticates stateless requests with an `Authorization: Bearer` JWT,
+	// verified with the JSON web key set of the provider which issued the token
+	BearerAuthenticator struct {
+		authManager    *AuthManager
+		mappingService *domain.UserMappingService
+		logger         flamingo.Logger
+		enabled        bool
+	}
+
+	// BearerAuth is a verified bearer token
+	BearerAuth struct {
+		Provider *Provider
+		RawToken string
+		Token    *oidc.IDToken
+		User     *domain.User
+	}
+
+	bearerResult struct {
+		auth *BearerAuth
+		err  error
+	}
+
+	// localKeySet verifies signatures with a static JSON web key set
+	localKeySet struct {
+		keys jose.JSONWebKeySet
+	}
+)
+
+// ErrNoBearerToken is returned if the request has no bearer token
+var ErrNoBearerToken = errors.New("no bearer token")
+
+// Inject dependencies
+func (b *BearerAuthenticator) Inject(authManager *AuthManager, mappingService *domain.UserMappingService, logger flamingo.Logger, cfg *struct {
+	Enabled bool `inject:"config:oauth.bearer.enabled,optional"`
+}) {
+	b.authManager = authManager
+	b.mappingService = mappingService
+	b.logger = logger.WithField(flamingo.LogKeyModule, "oauth").WithField(flamingo.LogKeyCategory, "bearer")
+	if cfg != nil {
+		b.enabled = cfg.Enabled
+	}
+}
+
+// Authenticate verifies the bearer token of the request in the context. The result is cached per request.
+// It returns ErrNoBearerToken if bearer authentication is disabled or the request has no bearer token.
+func (b *BearerAuthenticator) Authenticate(ctx context.Context) (*BearerAuth, error) {
+	req := web.RequestFromContext(ctx)
+	if !b.enabled || req == nil {
+		return nil, ErrNoBearerToken
+	}
+
+	if cached, ok := req.Values.Load(keyBearerAuth); ok {
+		result := cached.(bearerResult)
+		return result.auth, result.err
+	}
+
+	auth, err := b.authenticate(ctx, req)
+	if err != nil && err != ErrNoBearerToken {
+		b.logger.WithContext(ctx).Info("bearer token rejected: ", err)
+	}
+	req.Values.Store(keyBearerAuth, bearerResult{auth: auth, err: err})
+
+	return auth, err
+}
+
+// User returns the user of a valid bearer token
+func (b *BearerAuthenticator) User(ctx context.Context) (*domain.User, bool) {
+	auth, err := b.Authenticate(ctx)
+	if err != nil {
+		return nil, false
+	}
+	return auth.User, true
+}
+
+// Auth returns the authentication context of a valid bearer token, to pass the token on to other services
+func (b *BearerAuthenticator) Auth(ctx context.Context) (domain.Auth, error) {
+	auth, err := b.Authenticate(ctx)
+	if err != nil {
+		return domain.Auth{}, err
+	}
+	return domain.Auth{
+		TokenSource: oauth2.StaticTokenSource(&oauth2.Token{AccessToken: auth.RawToken, TokenType: "Bearer"}),
+		IDToken:     auth.Token,
+	}, nil
+}
+
+func (b *BearerAuthenticator) authenticate(ctx context.Context, req *web.Request) (*BearerAuth, error) {
+	header := req.Request().Header.Get("Authorization")
+	if len(header) < 7 || !strings.EqualFold(header[:7], "Bearer ") {
+		return nil, ErrNoBearerToken
+	}
+	rawToken := strings.TrimSpace(header[7:])
+
+	issuer, err := unverifiedIssuer(rawToken)
+	if err != nil {
+		return nil, err
+	}
+
+	var provider *Provider
+	for _, name := range b.authManager.Providers() {
+		p, _ := b.authManager.Provider(name)
+		if p.bearerIssuer() == issuer {
+			provider = p
+			break
+		}
+	}
+	if provider == nil {
+		return nil, errors.Errorf("no provider for issuer %q", issuer)
+	}
+
+	verifier, err := provider.BearerVerifier()
+	if err != nil {
+		return nil, err
+	}
+	token, err := verifier.Verify(b.authManager.OAuthCtx(ctx), rawToken)
+	if err != nil {
+		return nil, err
+	}
+
+	var claims map[string]interface{}
+	if err := token.Claims(&claims); err != nil {
+		return nil, err
+	}
+
+	return &BearerAuth{
+		Provider: provider,
+		RawToken: rawToken,
+		Token:    token,
+		User:     b.mappingService.MapToProviderUser(provider.Name(), claims, nil),
+	}, nil
+}
+
+// unverifiedIssuer reads the issuer of a JWT without verification, to find the provider which can verify it
+func unverifiedIssuer(rawToken string) (string, error) {
+	parts := strings.Split(rawToken, ".")
+	if len(parts) != 3 {
+		return "", errors.New("malformed jwt")
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return "", errors.Wrap(err, "malformed jwt payload")
+	}
+
+	var claims struct {
+		Issuer string `json:"iss"`
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return "", errors.Wrap(err, "malformed jwt payload")
+	}
+
+	return claims.Issuer, nil
+}
+
+// NewLocalKeySet creates a key set from a JSON web key set document, e.g. to verify tokens without a running identity provider
+func NewLocalKeySet(jwks []byte) (oidc.KeySet, error) {
+	keySet := new(localKeySet)
+	if err := json.Unmarshal(jwks, &keySet.keys); err != nil {
+		return nil, errors.Wrap(err, "invalid JSON web key set")
+	}
+	return keySet, nil
+}
+
+// VerifySignature verifies the signature with the key given in the header, or with all keys if there is no key id
+func (l *localKeySet) VerifySignature(_ context.Context, jwt string) ([]byte, error) {
+	jws, err := jose.ParseSigned(jwt)
+	if err != nil {
+		return nil, errors.Wrap(err, "malformed jwt")
+	}
+
+	keys := l.keys.Keys
+	if len(jws.Signatures) > 0 && jws.Signatures[0].Header.KeyID != "" {
+		keys = l.keys.Key(jws.Signatures[0].Header.KeyID)
+	}
+
+	for _, key := range keys {
+		if payload, err := jws.Verify(key); err == nil {
+			return payload, nil
+		}
+	}
+
+	return nil, errors.New("failed to verify signature")
+}
+
+// bearerIssuer returns the expected issuer of bearer tokens
+func (p *Provider) bearerIssuer() string {
+	if p.config.Bearer.Issuer != "" {
+		return p.config.Bearer.Issuer
+	}
+
+	if p.openIDProvider != nil {
+		var claims struct {
+			Issuer string `json:"issuer"`
+		}
+		if err := p.openIDProvider.Claims(&claims); err == nil && claims.Issuer != "" {
+			return claims.Issuer
+		}
+	}
+
+	return p.config.Server
+}
+
+// BearerVerifier returns the verifier for bearer tokens, using the configured local key set or the provider's jwks_uri.
+// The audience defaults to the client id.
+func (p *Provider) BearerVerifier() (*oidc.IDTokenVerifier, error) {
+	p.bearerOnce.Do(func() {
+		var keySet oidc.KeySet
+		if p.config.Bearer.JWKSFile != "" {
+			jwks, err := ioutil.ReadFile(p.config.Bearer.JWKSFile)
+			if err != nil {
+				p.bearerErr = errors.Wrapf(err, "oauth provider %q", p.name)
+				return
+			}
+			keySet, p.bearerErr = NewLocalKeySet(jwks)
+			if p.bearerErr != nil {
+				return
+			}
+		} else {
+			var claims struct {
+				JWKSURL string `json:"jwks_uri"`
+			}
+			if p.openIDProvider == nil {
+				p.bearerErr = errors.Errorf("oauth provider %q: no jwks_uri discovered", p.name)
+				return
+			}
+			if err := p.openIDProvider.Claims(&claims); err != nil || claims.JWKSURL == "" {
+				p.bearerErr = errors.Errorf("oauth provider %q: no jwks_uri discovered", p.name)
+				return
+			}
+			keySet = oidc.NewRemoteKeySet(context.Background(), claims.JWKSURL)
+		}
+
+		audience := p.config.Bearer.Audience
+		if audience == "" {
+			audience = p.config.ClientID
+		}
+		p.bearerVerifier = oidc.NewVerifier(p.bearerIssuer(), keySet, &oidc.Config{ClientID: audience})
+	})
+
+	return p.bearerVerifier, p.bearerErr
+}
@@ -0,0 +1,158 @@
+package application
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"flamingo.me/flamingo/v3/core/oauth/domain"
+	"flamingo.me/flamingo/v3/framework/config"
+	"flamingo.me/flamingo/v3/framework/flamingo"
+	"flamingo.me/flamingo/v3/framework/web"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/square/go-jose.v2"
+)
+
+type bearerTestIssuer struct {
+	key    *rsa.PrivateKey
+	signer jose.Signer
+}
+
+func newBearerTestIssuer(t *testing.T) *bearerTestIssuer {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	signer, err := jose.NewSigner(
+		jose.SigningKey{Algorithm: jose.RS256, Key: jose.JSONWebKey{Key: key, KeyID: "test-key"}},
+		new(jose.SignerOptions).WithType("JWT"),
+	)
+	require.NoError(t, err)
+
+	return &bearerTestIssuer{key: key, signer: signer}
+}
+
+func (i *bearerTestIssuer) jwks(t *testing.T) []byte {
+	jwks, err := json.Marshal(jose.JSONWebKeySet{Keys: []jose.JSONWebKey{
+		{Key: &i.key.PublicKey, KeyID: "test-key", Algorithm: string(jose.RS256), Use: "sig"},
+	}})
+	require.NoError(t, err)
+	return jwks
+}
+
+func (i *bearerTestIssuer) token(t *testing.T, claims map[string]interface{}) string {
+	payload, err := json.Marshal(claims)
+	require.NoError(t, err)
+
+	jws, err := i.signer.Sign(payload)
+	require.NoError(t, err)
+
+	token, err := jws.CompactSerialize()
+	require.NoError(t, err)
+	return token
+}
+
+func bearerRequest(token string) context.Context {
+	req := web.CreateRequest(&http.Request{
+		URL:    &url.URL{Path: "/api"},
+		Header: http.Header{"Authorization": []string{"Bearer " + token}},
+	}, nil)
+	return web.ContextWithRequest(context.Background(), req)
+}
+
+func TestBearerAuthenticator(t *testing.T) {
+	issuer := newBearerTestIssuer(t)
+
+	dir, err := ioutil.TempDir("", "bearer")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	jwksFile := filepath.Join(dir, "jwks.json")
+	require.NoError(t, ioutil.WriteFile(jwksFile, issuer.jwks(t), 0644))
+
+	provider := &Provider{name: DefaultProvider, config: ProviderConfig{ClientID: "api"}}
+	provider.config.Bearer.Issuer = "https://idp.example"
+	provider.config.Bearer.JWKSFile = jwksFile
+
+	mappingService := new(domain.UserMappingService)
+	mappingService.Inject(new(domain.DefaultUserFactory), &struct {
+		IDTokenMapping config.Map `inject:"config:oauth.mapping.idToken"`
+		ClaimsMapping  config.Map `inject:"config:oauth.mapping.claims,optional"`
+		Providers      config.Map `inject:"config:oauth.providers,optional"`
+	}{
+		IDTokenMapping: config.Map{"sub": "sub", "email": "email", "name": "name"},
+	})
+
+	authenticator := new(BearerAuthenticator)
+	authenticator.Inject(
+		&AuthManager{providers: map[string]*Provider{DefaultProvider: provider}, defaultProvider: DefaultProvider},
+		mappingService,
+		flamingo.NullLogger{},
+		&struct {
+			Enabled bool `inject:"config:oauth.bearer.enabled,optional"`
+		}{Enabled: true},
+	)
+
+	claims := func(modify func(map[string]interface{})) map[string]interface{} {
+		c := map[string]interface{}{
+			"iss":   "https://idp.example",
+			"aud":   "api",
+			"sub":   "user-1",
+			"email": "user@example.com",
+			"exp":   time.Now().Add(time.Hour).Unix(),
+		}
+		if modify != nil {
+			modify(c)
+		}
+		return c
+	}
+
+	t.Run("valid token", func(t *testing.T) {
+		ctx := bearerRequest(issuer.token(t, claims(nil)))
+
+		user, ok := authenticator.User(ctx)
+		require.True(t, ok)
+		assert.Equal(t, "user-1", user.Sub)
+		assert.Equal(t, "user@example.com", user.Email)
+
+		auth, err := authenticator.Auth(ctx)
+		require.NoError(t, err)
+		token, err := auth.TokenSource.Token()
+		require.NoError(t, err)
+		assert.Equal(t, "Bearer", token.TokenType)
+	})
+
+	t.Run("invalid tokens", func(t *testing.T) {
+		other := newBearerTestIssuer(t)
+
+		for name, token := range map[string]string{
+			"expired":         issuer.token(t, claims(func(c map[string]interface{}) { c["exp"] = time.Now().Add(-time.Hour).Unix() })),
+			"wrong audience":  issuer.token(t, claims(func(c map[string]interface{}) { c["aud"] = "other" })),
+			"unknown issuer":  issuer.token(t, claims(func(c map[string]interface{}) { c["iss"] = "https://evil.example" })),
+			"wrong signature": other.token(t, claims(nil)),
+			"malformed":       "not-a-jwt",
+		} {
+			t.Run(name, func(t *testing.T) {
+				_, err := authenticator.Authenticate(bearerRequest(token))
+				assert.Error(t, err)
+				assert.NotEqual(t, ErrNoBearerToken, err)
+			})
+		}
+	})
+
+	t.Run("no bearer token", func(t *testing.T) {
+		req := web.CreateRequest(&http.Request{URL: &url.URL{Path: "/api"}, Header: http.Header{}}, nil)
+		_, err := authenticator.Authenticate(web.ContextWithRequest(context.Background(), req))
+		assert.Equal(t, ErrNoBearerToken, err)
+
+		_, err = authenticator.Authenticate(context.Background())
+		assert.Equal(t, ErrNoBearerToken, err)
+	})
+}
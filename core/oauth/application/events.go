@@ -27,7 +27,12 @@ func (e *EventPublisher) PublishLogoutEvent(ctx context.Context, event *domain.L
 	e.router.Dispatch(ctx, event)
 }
 
-// EventHandler for logout events
+// PublishTokenRefreshedEvent dispatches the token refreshed event on the contexts event router
+func (e *EventPublisher) PublishTokenRefreshedEvent(ctx context.Context, event *domain.TokenRefreshedEvent) {
+	e.router.Dispatch(ctx, event)
+}
+
+// EventHandler for login and logout events
 type EventHandler struct {
 	authManager *AuthManager
 }
@@ -37,11 +42,20 @@ func (e *EventHandler) Inject(authManager *AuthManager) {
 	e.authManager = authManager
 }
 
-// Notify calls AuthManager on each logout, so it can destroy data stored for previously logged in user
-func (e *EventHandler) Notify(_ context.Context, event flamingo.Event) {
-	logoutEvent, ok := event.(*domain.LogoutEvent)
-	if ok {
-		e.authManager.DeleteTokenDetails(logoutEvent.Session)
-		e.authManager.DeleteAuthState(logoutEvent.Session)
+// Notify regenerates the session ID on login and logout to prevent session fixation,
+// and calls AuthManager on each logout, so it can destroy data stored for previously logged in user.
+// The events are audited by the security module, if security.audit.enabled is set.
+func (e *EventHandler) Notify(ctx context.Context, event flamingo.Event) {
+	switch event := event.(type) {
+	case *domain.LoginEvent:
+		if event.Session != nil {
+			event.Session.Regenerate()
+		}
+	case *domain.LogoutEvent:
+		e.authManager.DeleteTokenDetails(event.Session)
+		e.authManager.DeleteAuthState(event.Session)
+		if event.Session != nil {
+			event.Session.Regenerate()
+		}
 	}
 }
@@ -0,0 +1,162 @@
+package application
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/gob"
+	"time"
+
+	"flamingo.me/flamingo/v3/framework/web"
+	"github.com/pkg/errors"
+	"golang.org/x/oauth2"
+)
+
+const (
+	// keyLoginStates defines where the pending login attempts are saved
+	keyLoginStates = "auth.loginstates"
+)
+
+type (
+	// LoginState is stored for each login attempt and validated in the callback
+	LoginState struct {
+		Provider     string
+		Nonce        string
+		CodeVerifier string
+		RedirectURL  string
+		Created      time.Time
+	}
+
+	// loginStates are the pending login attempts of a session, by state parameter
+	loginStates map[string]LoginState
+)
+
+var now = time.Now
+
+func init() {
+	gob.Register(loginStates{})
+}
+
+// randomString returns a random, url safe string with 256 bits of entropy
+func randomString() string {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// NewLoginState starts a login attempt for the provider and stores it in the session.
+// It returns the state parameter and the options for the authorization URL, containing the nonce and the PKCE code challenge.
+func (am *AuthManager) NewLoginState(session *web.Session, provider *Provider, redirectURL string) (string, []oauth2.AuthCodeOption) {
+	state := randomString()
+	loginState := LoginState{
+		Provider:    provider.Name(),
+		Nonce:       randomString(),
+		RedirectURL: redirectURL,
+		Created:     now(),
+	}
+
+	options := []oauth2.AuthCodeOption{oauth2.SetAuthURLParam("nonce", loginState.Nonce)}
+
+	if provider.Config().PKCE {
+		loginState.CodeVerifier = randomString()
+		challenge := sha256.Sum256([]byte(loginState.CodeVerifier))
+		options = append(options,
+			oauth2.SetAuthURLParam("code_challenge", base64.RawURLEncoding.EncodeToString(challenge[:])),
+			oauth2.SetAuthURLParam("code_challenge_method", "S256"),
+		)
+	}
+
+	states := am.loadLoginStates(session)
+	states[state] = loginState
+
+	// drop the oldest attempts if there are too many
+	for len(states) > am.maxLoginStates && am.maxLoginStates > 0 {
+		oldest := ""
+		for key, s := range states {
+			if oldest == "" || s.Created.Before(states[oldest].Created) {
+				oldest = key
+			}
+		}
+		delete(states, oldest)
+	}
+
+	session.Store(keyLoginStates, states)
+
+	return state, options
+}
+
+// ConsumeLoginState returns and removes the login attempt for the state parameter.
+// It fails for unknown states and for states older than the configured lifetime.
+func (am *AuthManager) ConsumeLoginState(session *web.Session, state string) (LoginState, error) {
+	value, _ := session.Load(keyLoginStates)
+	stored, _ := value.(loginStates)
+	loginState, ok := stored[state]
+
+	states := am.loadLoginStates(session)
+	delete(states, state)
+	session.Store(keyLoginStates, states)
+
+	if !ok || state == "" {
+		return LoginState{}, errors.New("Invalid State")
+	}
+	if now().Sub(loginState.Created) > am.stateLifetime {
+		return LoginState{}, errors.New("Expired State")
+	}
+
+	return loginState, nil
+}
+
+// ExchangeOptions returns the token exchange options for a login attempt, containing the PKCE code verifier
+func (am *AuthManager) ExchangeOptions(loginState LoginState) []oauth2.AuthCodeOption {
+	if loginState.CodeVerifier == "" {
+		return nil
+	}
+	return []oauth2.AuthCodeOption{oauth2.SetAuthURLParam("code_verifier", loginState.CodeVerifier)}
+}
+
+// VerifyNonce verifies the ID token of a fresh token and checks that it belongs to the login attempt
+func (am *AuthManager) VerifyNonce(ctx context.Context, provider *Provider, oauth2Token *oauth2.Token, loginState LoginState) error {
+	rawIDToken, err := am.ExtractRawIDToken(oauth2Token)
+	if err != nil {
+		return err
+	}
+
+	verifier, err := provider.verifier()
+	if err != nil {
+		return err
+	}
+
+	idToken, err := verifier.Verify(am.OAuthCtx(ctx), rawIDToken)
+	if err != nil {
+		return errors.Wrap(err, "ID token verification failed")
+	}
+
+	if idToken.Nonce != loginState.Nonce {
+		return errors.New("Invalid Nonce")
+	}
+
+	return nil
+}
+
+// DeleteLoginStates removes all pending login attempts from the session
+func (am *AuthManager) DeleteLoginStates(session *web.Session) {
+	session.Delete(keyLoginStates)
+}
+
+// loadLoginStates returns the pending login attempts of the session, without expired ones
+func (am *AuthManager) loadLoginStates(session *web.Session) loginStates {
+	value, _ := session.Load(keyLoginStates)
+	stored, _ := value.(loginStates)
+
+	states := make(loginStates, len(stored)+1)
+	for key, s := range stored {
+		if now().Sub(s.Created) <= am.stateLifetime {
+			states[key] = s
+		}
+	}
+
+	return states
+}
@@ -0,0 +1,89 @@
+package application
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"net/url"
+	"testing"
+	"time"
+
+	"flamingo.me/flamingo/v3/framework/web"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/oauth2"
+)
+
+func TestAuthManager_LoginState(t *testing.T) {
+	am := &AuthManager{stateLifetime: time.Minute, maxLoginStates: 2}
+	provider := &Provider{name: "test", config: ProviderConfig{PKCE: true}}
+	cfg := &oauth2.Config{Endpoint: oauth2.Endpoint{AuthURL: "https://idp.example/auth"}}
+
+	t.Run("nonce and pkce", func(t *testing.T) {
+		session := web.EmptySession()
+		state, options := am.NewLoginState(session, provider, "https://example.com/")
+
+		authURL, err := url.Parse(cfg.AuthCodeURL(state, options...))
+		require.NoError(t, err)
+		assert.Equal(t, state, authURL.Query().Get("state"))
+		assert.NotEmpty(t, authURL.Query().Get("nonce"))
+		assert.Equal(t, "S256", authURL.Query().Get("code_challenge_method"))
+
+		loginState, err := am.ConsumeLoginState(session, state)
+		require.NoError(t, err)
+		assert.Equal(t, "test", loginState.Provider)
+		assert.Equal(t, "https://example.com/", loginState.RedirectURL)
+		assert.Equal(t, authURL.Query().Get("nonce"), loginState.Nonce)
+
+		challenge := sha256.Sum256([]byte(loginState.CodeVerifier))
+		assert.Equal(t, base64.RawURLEncoding.EncodeToString(challenge[:]), authURL.Query().Get("code_challenge"))
+		assert.Len(t, am.ExchangeOptions(loginState), 1)
+
+		_, err = am.ConsumeLoginState(session, state)
+		assert.Error(t, err, "a state can only be used once")
+	})
+
+	t.Run("without pkce", func(t *testing.T) {
+		session := web.EmptySession()
+		state, options := am.NewLoginState(session, &Provider{name: "test"}, "")
+		assert.Len(t, options, 1)
+
+		loginState, err := am.ConsumeLoginState(session, state)
+		require.NoError(t, err)
+		assert.Empty(t, am.ExchangeOptions(loginState))
+	})
+
+	t.Run("concurrent login attempts", func(t *testing.T) {
+		session := web.EmptySession()
+		first, _ := am.NewLoginState(session, provider, "")
+		second, _ := am.NewLoginState(session, provider, "")
+
+		_, err := am.ConsumeLoginState(session, second)
+		assert.NoError(t, err)
+		_, err = am.ConsumeLoginState(session, first)
+		assert.NoError(t, err)
+		_, err = am.ConsumeLoginState(session, "")
+		assert.Error(t, err)
+	})
+
+	t.Run("oldest attempts are dropped", func(t *testing.T) {
+		session := web.EmptySession()
+		first, _ := am.NewLoginState(session, provider, "")
+		time.Sleep(time.Millisecond)
+		am.NewLoginState(session, provider, "")
+		am.NewLoginState(session, provider, "")
+
+		_, err := am.ConsumeLoginState(session, first)
+		assert.Error(t, err)
+	})
+
+	t.Run("expired state", func(t *testing.T) {
+		defer func() { now = time.Now }()
+
+		session := web.EmptySession()
+		state, _ := am.NewLoginState(session, provider, "")
+
+		now = func() time.Time { return time.Now().Add(2 * time.Minute) }
+		_, err := am.ConsumeLoginState(session, state)
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,184 @@
+package application
+
+import (
+	"context"
+	"encoding/json"
+	"net/url"
+	"strings"
+
+	"flamingo.me/flamingo/v3/framework/web"
+	"github.com/coreos/go-oidc"
+	"github.com/pkg/errors"
+)
+
+const (
+	// backChannelLogoutEvent must be contained in the events claim of a logout token
+	backChannelLogoutEvent = "http://schemas.openid.net/event/backchannel-logout"
+)
+
+type (
+	// LogoutToken is a verified back-channel logout token
+	LogoutToken struct {
+		Provider  string
+		Subject   string
+		SessionID string
+	}
+)
+
+// LogoutTokenVerifier creates a verifier for back-channel logout tokens, which do not need to expire.
+// It is nil if the discovery failed in debug mode.
+func (p *Provider) LogoutTokenVerifier() *oidc.IDTokenVerifier {
+	if p.openIDProvider == nil {
+		return nil
+	}
+	return p.openIDProvider.Verifier(&oidc.Config{ClientID: p.config.ClientID, SkipExpiryCheck: true})
+}
+
+// EndSessionEndpoint returns the discovered end_session_endpoint for RP-initiated logout
+func (p *Provider) EndSessionEndpoint() (*url.URL, error) {
+	if p.openIDProvider == nil {
+		return nil, errors.Errorf("oauth provider %q: not discovered", p.name)
+	}
+
+	var claims struct {
+		EndSessionEndpoint string `json:"end_session_endpoint"`
+	}
+	if err := p.openIDProvider.Claims(&claims); err != nil {
+		return nil, err
+	}
+	if claims.EndSessionEndpoint == "" {
+		return nil, errors.Errorf("oauth provider %q: no end_session_endpoint", p.name)
+	}
+
+	return url.Parse(claims.EndSessionEndpoint)
+}
+
+// StoredRawIDToken returns the raw ID token of the session without verification, e.g. for the id_token_hint of the logout
+func (am *AuthManager) StoredRawIDToken(session *web.Session) (string, bool) {
+	value, _ := session.Load(keyRawIDToken)
+	token, ok := value.(string)
+	return token, ok
+}
+
+// PostLogoutRedirectURL returns the requested `redirecturl` if it is allowed, otherwise the absolute URL of the application's root.
+// Relative paths and URLs of the application's host are allowed, as well as the configured oauth.logout.allowedRedirectURLs.
+func (am *AuthManager) PostLogoutRedirectURL(req *web.Request) *url.URL {
+	home, _ := am.router.Absolute(req, "", nil)
+	if home == nil {
+		home = new(url.URL)
+	}
+
+	requested, ok := req.Params["redirecturl"]
+	if !ok || requested == "" {
+		return home
+	}
+
+	for _, allowed := range am.allowedLogoutRedirects {
+		if requested == allowed {
+			u, err := url.Parse(requested)
+			if err == nil {
+				return u
+			}
+		}
+	}
+
+	u, err := url.Parse(requested)
+	if err != nil || strings.HasPrefix(requested, "//") || strings.Contains(requested, `\`) {
+		am.logger.Warn("post logout redirect not allowed: ", requested)
+		return home
+	}
+
+	if u.Scheme == "" && u.Host == "" && strings.HasPrefix(u.Path, "/") {
+		return home.ResolveReference(u)
+	}
+
+	if u.Scheme == home.Scheme && u.Host == home.Host {
+		return u
+	}
+
+	am.logger.Warn("post logout redirect not allowed: ", requested)
+	return home
+}
+
+// VerifyLogoutToken verifies a back-channel logout token of the provider
+func (am *AuthManager) VerifyLogoutToken(ctx context.Context, provider *Provider, rawToken string) (*LogoutToken, error) {
+	if provider.OpenIDProvider() == nil {
+		return nil, errors.Errorf("oauth provider %q: not discovered", provider.Name())
+	}
+
+	return verifyLogoutToken(am.OAuthCtx(ctx), provider.Name(), provider.LogoutTokenVerifier(), rawToken)
+}
+
+func verifyLogoutToken(ctx context.Context, provider string, verifier *oidc.IDTokenVerifier, rawToken string) (*LogoutToken, error) {
+	token, err := verifier.Verify(ctx, rawToken)
+	if err != nil {
+		return nil, errors.Wrap(err, "logout token verification failed")
+	}
+
+	if !token.Expiry.IsZero() && token.Expiry.Before(now()) {
+		return nil, errors.New("logout token is expired")
+	}
+
+	var claims struct {
+		SessionID string                     `json:"sid"`
+		Nonce     *string                    `json:"nonce"`
+		Events    map[string]json.RawMessage `json:"events"`
+	}
+	if err := token.Claims(&claims); err != nil {
+		return nil, err
+	}
+
+	if _, ok := claims.Events[backChannelLogoutEvent]; !ok {
+		return nil, errors.New("logout token has no back-channel logout event")
+	}
+	if claims.Nonce != nil {
+		return nil, errors.New("logout token must not contain a nonce")
+	}
+	if claims.SessionID == "" && token.Subject == "" {
+		return nil, errors.New("logout token contains neither sid nor sub")
+	}
+
+	return &LogoutToken{
+		Provider:  provider,
+		Subject:   token.Subject,
+		SessionID: claims.SessionID,
+	}, nil
+}
+
+// BackChannelLogout verifies the logout token and logs out all affected sessions
+func (am *AuthManager) BackChannelLogout(ctx context.Context, provider *Provider, rawToken string) error {
+	if am.logoutRegistry == nil {
+		return errors.New("back-channel logout is not supported without logout registry")
+	}
+
+	logoutToken, err := am.VerifyLogoutToken(ctx, provider, rawToken)
+	if err != nil {
+		return err
+	}
+
+	// a sid only ends that provider session, a sub alone ends all sessions of the user
+	if logoutToken.SessionID != "" {
+		return am.logoutRegistry.Logout(ctx, logoutToken.Provider, logoutToken.SessionID, "", now())
+	}
+	return am.logoutRegistry.Logout(ctx, logoutToken.Provider, "", logoutToken.Subject, now())
+}
+
+// loggedOut checks if the session of the ID token was logged out by the identity provider after the token was issued
+func (am *AuthManager) loggedOut(ctx context.Context, session *web.Session, idToken *oidc.IDToken) bool {
+	if am.logoutRegistry == nil {
+		return false
+	}
+
+	var claims struct {
+		SessionID string `json:"sid"`
+	}
+	_ = idToken.Claims(&claims)
+
+	loggedOut, err := am.logoutRegistry.LoggedOutSince(ctx, am.SessionProvider(session).Name(), claims.SessionID, idToken.Subject, idToken.IssuedAt)
+	if err != nil {
+		am.logger.WithContext(ctx).Error("could not check back-channel logouts: ", err)
+		return false
+	}
+
+	return loggedOut
+}
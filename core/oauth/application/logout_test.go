@@ -0,0 +1,95 @@
+package application
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"flamingo.me/flamingo/v3/framework/flamingo"
+	"flamingo.me/flamingo/v3/framework/web"
+	"github.com/coreos/go-oidc"
+	"github.com/gorilla/sessions"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerifyLogoutToken(t *testing.T) {
+	issuer := newBearerTestIssuer(t)
+	keySet, err := NewLocalKeySet(issuer.jwks(t))
+	require.NoError(t, err)
+	verifier := oidc.NewVerifier("https://idp.example", keySet, &oidc.Config{ClientID: "client", SkipExpiryCheck: true})
+
+	claims := func(modify func(map[string]interface{})) map[string]interface{} {
+		c := map[string]interface{}{
+			"iss":    "https://idp.example",
+			"aud":    "client",
+			"iat":    time.Now().Unix(),
+			"jti":    "logout-1",
+			"sub":    "user-1",
+			"sid":    "session-1",
+			"events": map[string]interface{}{backChannelLogoutEvent: map[string]interface{}{}},
+		}
+		if modify != nil {
+			modify(c)
+		}
+		return c
+	}
+
+	t.Run("valid", func(t *testing.T) {
+		logoutToken, err := verifyLogoutToken(context.Background(), "test", verifier, issuer.token(t, claims(nil)))
+		require.NoError(t, err)
+		assert.Equal(t, &LogoutToken{Provider: "test", Subject: "user-1", SessionID: "session-1"}, logoutToken)
+	})
+
+	for name, modify := range map[string]func(map[string]interface{}){
+		"missing event":       func(c map[string]interface{}) { delete(c, "events") },
+		"with nonce":          func(c map[string]interface{}) { c["nonce"] = "nonce" },
+		"neither sid nor sub": func(c map[string]interface{}) { delete(c, "sid"); delete(c, "sub") },
+		"wrong audience":      func(c map[string]interface{}) { c["aud"] = "other" },
+		"expired":             func(c map[string]interface{}) { c["exp"] = time.Now().Add(-time.Minute).Unix() },
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := verifyLogoutToken(context.Background(), "test", verifier, issuer.token(t, claims(modify)))
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestAuthManager_PostLogoutRedirectURL(t *testing.T) {
+	router := new(web.Router)
+	router.Inject(&struct {
+		Scheme       string         `inject:"config:flamingo.router.scheme,optional"`
+		Host         string         `inject:"config:flamingo.router.host,optional"`
+		Path         string         `inject:"config:flamingo.router.path,optional"`
+		External     string         `inject:"config:flamingo.router.external,optional"`
+		SessionStore sessions.Store `inject:",optional"`
+		SessionName  string         `inject:"config:session.name,optional"`
+	}{
+		Scheme: "https",
+		Host:   "shop.example",
+	}, new(flamingo.DefaultEventRouter), func() []web.Filter { return nil }, func() []web.RoutesModule { return nil }, func() []web.RouteGuard { return nil }, flamingo.NullLogger{}, nil)
+
+	am := &AuthManager{
+		logger:                 flamingo.NullLogger{},
+		router:                 router,
+		allowedLogoutRedirects: []string{"https://other.example/bye"},
+	}
+
+	for requested, expected := range map[string]string{
+		"":                           "https://shop.example/",
+		"/account/bye":               "https://shop.example/account/bye",
+		"https://shop.example/bye":   "https://shop.example/bye",
+		"https://other.example/bye":  "https://other.example/bye",
+		"https://evil.example/bye":   "https://shop.example/",
+		"//evil.example/bye":         "https://shop.example/",
+		"/\\evil.example":            "https://shop.example/",
+		"javascript:alert(1)":        "https://shop.example/",
+		"https://other.example/bye2": "https://shop.example/",
+	} {
+		req := web.CreateRequest(&http.Request{URL: &url.URL{Path: "/auth/logout"}, Header: http.Header{}}, nil)
+		req.Params["redirecturl"] = requested
+		assert.Equal(t, expected, am.PostLogoutRedirectURL(req).String(), requested)
+	}
+}
@@ -0,0 +1,180 @@
+package application
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"flamingo.me/flamingo/v3/framework/config"
+	"github.com/coreos/go-oidc"
+	"github.com/pkg/errors"
+	"golang.org/x/oauth2"
+)
+
+const (
+	// DefaultProvider is the name of the provider configured directly under `oauth`
+	DefaultProvider = "default"
+)
+
+type (
+	// Provider is a configured OpenID Connect identity provider
+	Provider struct {
+		name           string
+		config         ProviderConfig
+		openIDProvider *oidc.Provider
+		bearerOnce     sync.Once
+		bearerVerifier *oidc.IDTokenVerifier
+		bearerErr      error
+	}
+
+	// ProviderConfig is the broker configuration of a provider, either directly under `oauth` or under `oauth.providers.<name>`
+	ProviderConfig struct {
+		Server              string
+		Secret              string
+		ClientID            string `json:"clientid"`
+		DisableOfflineToken bool
+		// PKCE enables the proof key for code exchange (S256) for the authorization code flow
+		PKCE   bool `json:"pkce"`
+		Scopes []string
+		Claims struct {
+			IDToken  []string `json:"idToken"`
+			UserInfo []string `json:"userInfo"`
+		}
+		TokenExtras []string
+		// Bearer configures the verification of bearer tokens for stateless requests
+		Bearer struct {
+			Audience string
+			Issuer   string
+			JWKSFile string `json:"jwksFile"`
+		}
+	}
+)
+
+// providerConfigs returns the configuration of all providers, the values of `oauth` are used for everything
+// not configured for a named provider, except the credentials: named providers need their own clientid and secret.
+// The default provider is only added if it has a server or no other provider exists.
+func providerConfigs(defaultConfig ProviderConfig, providers config.Map) (map[string]ProviderConfig, error) {
+	configs := make(map[string]ProviderConfig, len(providers)+1)
+
+	if defaultConfig.Server != "" || len(providers) == 0 {
+		configs[DefaultProvider] = defaultConfig
+	}
+
+	for name, providerConfig := range providers {
+		cfg, ok := providerConfig.(config.Map)
+		if !ok {
+			return nil, errors.Errorf("oauth.providers.%s: expected a map but got %T", name, providerConfig)
+		}
+
+		result := defaultConfig
+		result.ClientID, result.Secret = "", ""
+		if err := cfg.MapInto(&result); err != nil {
+			return nil, errors.Wrapf(err, "oauth.providers.%s", name)
+		}
+		if result.ClientID == "" || result.Secret == "" {
+			return nil, errors.Errorf("oauth.providers.%s: clientid and secret are required, they are not inherited from oauth", name)
+		}
+		configs[name] = result
+	}
+
+	return configs, nil
+}
+
+// newProvider creates a provider and discovers the OpenID configuration of its server
+func newProvider(ctx context.Context, name string, cfg ProviderConfig) (*Provider, error) {
+	p := &Provider{name: name, config: cfg}
+
+	var err error
+	p.openIDProvider, err = oidc.NewProvider(ctx, cfg.Server)
+	if err != nil {
+		return p, errors.Wrapf(err, "oauth provider %q", name)
+	}
+
+	return p, nil
+}
+
+// Name of the provider
+func (p *Provider) Name() string {
+	return p.name
+}
+
+// Config returns the provider's configuration
+func (p *Provider) Config() ProviderConfig {
+	return p.config
+}
+
+// OpenIDProvider returns the discovered OpenID Connect provider, which may be nil if the discovery failed in debug mode
+func (p *Provider) OpenIDProvider() *oidc.Provider {
+	return p.openIDProvider
+}
+
+// Verifier creates an ID token verifier for the provider's client, nil if the discovery failed in debug mode
+func (p *Provider) Verifier() *oidc.IDTokenVerifier {
+	if p.openIDProvider == nil {
+		return nil
+	}
+	return p.openIDProvider.Verifier(&oidc.Config{ClientID: p.config.ClientID})
+}
+
+// verifier returns the ID token verifier, or an error if the provider has not been discovered
+func (p *Provider) verifier() (*oidc.IDTokenVerifier, error) {
+	verifier := p.Verifier()
+	if verifier == nil {
+		return nil, errors.Errorf("oauth provider %q: not discovered", p.name)
+	}
+	return verifier, nil
+}
+
+// OAuth2Config creates the oauth2 config with the given callback URL
+func (p *Provider) OAuth2Config(redirectURL string) *oauth2.Config {
+	scopes := append([]string{oidc.ScopeOpenID}, p.config.Scopes...)
+	if !p.config.DisableOfflineToken {
+		scopes = append(scopes, oidc.ScopeOfflineAccess)
+	}
+
+	var claimSet *oauth2.ClaimSet
+	claimSet = createClaimSet(oauth2.IdTokenClaim, p.config.Claims.IDToken, claimSet)
+	claimSet = createClaimSet(oauth2.UserInfoClaim, p.config.Claims.UserInfo, claimSet)
+
+	// the endpoint stays empty if the discovery failed in debug mode
+	var endpoint oauth2.Endpoint
+	if p.openIDProvider != nil {
+		endpoint = p.openIDProvider.Endpoint()
+	}
+
+	return &oauth2.Config{
+		ClientID:     p.config.ClientID,
+		ClientSecret: p.config.Secret,
+		RedirectURL:  redirectURL,
+
+		Endpoint: endpoint,
+
+		// "openid" is a required scope for OpenID Connect flows.
+		Scopes: scopes,
+
+		ClaimSet: claimSet,
+	}
+}
+
+func createClaimSet(topLevelName string, names []string, claimSet *oauth2.ClaimSet) *oauth2.ClaimSet {
+	for _, name := range names {
+		if name == "" {
+			continue
+		}
+		if claimSet == nil {
+			claimSet = &oauth2.ClaimSet{}
+		}
+		claimSet.AddVoluntaryClaim(topLevelName, name)
+	}
+
+	return claimSet
+}
+
+func sortedProviderNames(providers map[string]*Provider) []string {
+	names := make([]string, 0, len(providers))
+	for name := range providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,98 @@
+package application
+
+import (
+	"context"
+	"testing"
+
+	"flamingo.me/flamingo/v3/framework/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/oauth2"
+)
+
+func TestProviderConfigs(t *testing.T) {
+	defaultConfig := ProviderConfig{
+		Server:      "https://default.example",
+		ClientID:    "default-client",
+		Secret:      "default-secret",
+		Scopes:      []string{"profile", "email"},
+		TokenExtras: []string{"extra"},
+	}
+
+	t.Run("default only", func(t *testing.T) {
+		configs, err := providerConfigs(defaultConfig, config.Map{})
+		require.NoError(t, err)
+		assert.Equal(t, map[string]ProviderConfig{DefaultProvider: defaultConfig}, configs)
+	})
+
+	t.Run("named providers inherit the default settings", func(t *testing.T) {
+		providers := make(config.Map)
+		require.NoError(t, providers.Add(config.Map{
+			"corporate.server":         "https://sso.corp.example",
+			"corporate.clientid":       "corp-client",
+			"corporate.secret":         "corp-secret",
+			"corporate.scopes":         config.Slice{"groups"},
+			"corporate.claims.idToken": config.Slice{"upn"},
+		}))
+
+		configs, err := providerConfigs(defaultConfig, providers)
+		require.NoError(t, err)
+		require.Contains(t, configs, DefaultProvider)
+		require.Contains(t, configs, "corporate")
+
+		corporate := configs["corporate"]
+		assert.Equal(t, "https://sso.corp.example", corporate.Server)
+		assert.Equal(t, "corp-client", corporate.ClientID)
+		assert.Equal(t, "corp-secret", corporate.Secret)
+		assert.Equal(t, []string{"groups"}, corporate.Scopes)
+		assert.Equal(t, []string{"upn"}, corporate.Claims.IDToken)
+		assert.Equal(t, []string{"extra"}, corporate.TokenExtras)
+	})
+
+	t.Run("no default provider without server", func(t *testing.T) {
+		configs, err := providerConfigs(ProviderConfig{}, config.Map{"customer": config.Map{"server": "https://idp.example", "clientid": "customer", "secret": "secret"}})
+		require.NoError(t, err)
+		assert.NotContains(t, configs, DefaultProvider)
+		assert.Contains(t, configs, "customer")
+	})
+
+	t.Run("named providers need their own credentials", func(t *testing.T) {
+		_, err := providerConfigs(defaultConfig, config.Map{"corporate": config.Map{"server": "https://sso.corp.example"}})
+		assert.Error(t, err)
+		_, err = providerConfigs(defaultConfig, config.Map{"corporate": config.Map{"server": "https://sso.corp.example", "clientid": "corp-client"}})
+		assert.Error(t, err)
+	})
+
+	t.Run("invalid provider config", func(t *testing.T) {
+		_, err := providerConfigs(defaultConfig, config.Map{"broken": "https://idp.example"})
+		assert.Error(t, err)
+	})
+}
+
+func TestProvider_OAuth2Config(t *testing.T) {
+	p := &Provider{name: "test", config: ProviderConfig{
+		ClientID:            "client",
+		Secret:              "secret",
+		Scopes:              []string{"email"},
+		DisableOfflineToken: true,
+	}}
+	p.config.Claims.IDToken = []string{"upn"}
+
+	cfg := p.OAuth2Config("https://example.com/auth/callback/test")
+	assert.Equal(t, []string{"openid", "email"}, cfg.Scopes)
+	assert.Equal(t, "client", cfg.ClientID)
+	assert.Equal(t, "secret", cfg.ClientSecret)
+	assert.Equal(t, "https://example.com/auth/callback/test", cfg.RedirectURL)
+	assert.NotNil(t, cfg.ClaimSet)
+}
+
+func TestProvider_VerifierWithoutDiscovery(t *testing.T) {
+	p := &Provider{name: "test", config: ProviderConfig{ClientID: "client"}}
+
+	assert.Nil(t, p.Verifier())
+	assert.Nil(t, p.LogoutTokenVerifier())
+
+	am := &AuthManager{providers: map[string]*Provider{"test": p}}
+	err := am.VerifyNonce(context.Background(), p, new(oauth2.Token).WithExtra(map[string]interface{}{"id_token": "token"}), LoginState{})
+	assert.EqualError(t, err, `oauth provider "test": not discovered`)
+}
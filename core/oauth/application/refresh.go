@@ -0,0 +1,106 @@
+package application
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+
+	"flamingo.me/flamingo/v3/framework/opencensus"
+	"go.opencensus.io/stats"
+	"go.opencensus.io/stats/view"
+	"go.opencensus.io/tag"
+	"golang.org/x/oauth2"
+)
+
+type (
+	// refreshedToken is the shareable form of a refreshed token, including the extras which are not serialized by oauth2
+	refreshedToken struct {
+		Token  oauth2.Token
+		Extras map[string]interface{}
+	}
+)
+
+var (
+	// refreshMeasure counts token refreshes
+	refreshMeasure = stats.Int64("flamingo/oauth/token_refresh", "Count of oauth token refreshes", stats.UnitDimensionless)
+
+	// keyProvider defines the oauth provider
+	keyProvider, _ = tag.NewKey("provider")
+	// keyRefreshResult is either success or failure
+	keyRefreshResult, _ = tag.NewKey("result")
+)
+
+func init() {
+	if err := opencensus.View("flamingo/oauth/token_refresh", refreshMeasure, view.Count(), keyProvider, keyRefreshResult); err != nil {
+		panic(err)
+	}
+}
+
+// tokenNeedsRefresh checks if the token is invalid, or expires within the refresh skew and can be refreshed
+func (am *AuthManager) tokenNeedsRefresh(token *oauth2.Token) bool {
+	if !token.Valid() {
+		return true
+	}
+	return token.RefreshToken != "" && !token.Expiry.IsZero() && token.Expiry.Add(-am.refreshSkew).Before(now())
+}
+
+// refreshToken refreshes the token once, concurrent refreshes with the same refresh token share the result
+func (am *AuthManager) refreshToken(ctx context.Context, provider *Provider, current *oauth2.Token) (*oauth2.Token, error) {
+	refresh := func() ([]byte, error) {
+		// the token source only refreshes tokens without access token, so the refresh happens ahead of the expiry
+		token, err := am.ProviderOAuth2Config(ctx, nil, provider).TokenSource(ctx, &oauth2.Token{RefreshToken: current.RefreshToken}).Token()
+		if err != nil {
+			return nil, err
+		}
+		return encodeRefreshedToken(token, append([]string{"id_token"}, provider.Config().TokenExtras...))
+	}
+
+	var data []byte
+	var err error
+	if am.refreshGroup != nil {
+		data, err = am.refreshGroup.Do(ctx, refreshKey(provider.Name(), current.RefreshToken), refresh)
+	} else {
+		data, err = refresh()
+	}
+
+	am.recordRefresh(ctx, provider, err)
+	if err != nil {
+		return nil, err
+	}
+
+	return decodeRefreshedToken(data)
+}
+
+func (am *AuthManager) recordRefresh(ctx context.Context, provider *Provider, err error) {
+	result := "success"
+	if err != nil {
+		result = "failure"
+	}
+	ctx, _ = tag.New(ctx, tag.Upsert(keyProvider, provider.Name()), tag.Upsert(keyRefreshResult, result))
+	stats.Record(ctx, refreshMeasure.M(1))
+}
+
+// refreshKey identifies a refresh token without exposing it
+func refreshKey(provider, refreshToken string) string {
+	hash := sha256.Sum256([]byte(provider + ":" + refreshToken))
+	return hex.EncodeToString(hash[:])
+}
+
+func encodeRefreshedToken(token *oauth2.Token, extras []string) ([]byte, error) {
+	shared := refreshedToken{Token: *token, Extras: make(map[string]interface{}, len(extras))}
+	for _, extra := range extras {
+		if value := token.Extra(extra); value != nil {
+			shared.Extras[extra] = value
+		}
+	}
+	return json.Marshal(shared)
+}
+
+func decodeRefreshedToken(data []byte) (*oauth2.Token, error) {
+	var shared refreshedToken
+	if err := json.Unmarshal(data, &shared); err != nil {
+		return nil, err
+	}
+	return shared.Token.WithExtra(shared.Extras), nil
+}
@@ -0,0 +1,43 @@
+package application
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/oauth2"
+)
+
+func TestAuthManager_TokenNeedsRefresh(t *testing.T) {
+	am := &AuthManager{refreshSkew: time.Minute}
+
+	assert.True(t, am.tokenNeedsRefresh(&oauth2.Token{AccessToken: "a", RefreshToken: "r", Expiry: time.Now().Add(-time.Second)}), "expired")
+	assert.True(t, am.tokenNeedsRefresh(&oauth2.Token{AccessToken: "a", RefreshToken: "r", Expiry: time.Now().Add(30 * time.Second)}), "expires within skew")
+	assert.False(t, am.tokenNeedsRefresh(&oauth2.Token{AccessToken: "a", RefreshToken: "r", Expiry: time.Now().Add(time.Hour)}), "valid")
+	assert.False(t, am.tokenNeedsRefresh(&oauth2.Token{AccessToken: "a", Expiry: time.Now().Add(30 * time.Second)}), "no refresh token")
+	assert.False(t, am.tokenNeedsRefresh(&oauth2.Token{AccessToken: "a", RefreshToken: "r"}), "no expiry")
+}
+
+func TestRefreshedToken(t *testing.T) {
+	token := (&oauth2.Token{
+		AccessToken:  "access",
+		TokenType:    "Bearer",
+		RefreshToken: "refresh",
+		Expiry:       time.Now().Add(time.Hour).Round(time.Second),
+	}).WithExtra(map[string]interface{}{"id_token": "id", "custom": "extra", "ignored": "value"})
+
+	data, err := encodeRefreshedToken(token, []string{"id_token", "custom", "missing"})
+	require.NoError(t, err)
+
+	decoded, err := decodeRefreshedToken(data)
+	require.NoError(t, err)
+	assert.Equal(t, "access", decoded.AccessToken)
+	assert.Equal(t, "refresh", decoded.RefreshToken)
+	assert.True(t, token.Expiry.Equal(decoded.Expiry))
+	assert.Equal(t, "id", decoded.Extra("id_token"))
+	assert.Equal(t, "extra", decoded.Extra("custom"))
+	assert.Nil(t, decoded.Extra("ignored"))
+
+	assert.NotEqual(t, refreshKey("a", "refresh"), refreshKey("b", "refresh"))
+}
@@ -2,24 +2,115 @@ package application
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/gob"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
 
 	authDomain "flamingo.me/flamingo/v3/core/oauth/domain"
 	securityDomain "flamingo.me/flamingo/v3/core/security/domain"
+	"flamingo.me/flamingo/v3/framework/config"
 	"flamingo.me/flamingo/v3/framework/web"
+	"github.com/pkg/errors"
+)
+
+const (
+	// keyRoles defines where the mapped roles are cached
+	keyRoles = "auth.roles"
 )
 
 type (
-	// AuthRoleProvider implements the RoleProvider interface for authenticated users
+	// AuthRoleProvider implements the RoleProvider interface for authenticated users.
+	// Besides the logged in role it maps the user's groups and attributes to roles via oauth.roles.mapping.
 	AuthRoleProvider struct {
 		userService UserServiceInterface
+		// Bearer identifies stateless requests, their roles are not cached
+		Bearer        *BearerAuthenticator `inject:",optional"`
+		Mapping       config.Slice         `inject:"config:oauth.roles.mapping,optional"`
+		CacheLifetime time.Duration        `inject:"config:oauth.roles.cacheLifetime,optional"`
+		rulesOnce     sync.Once
+		rules         []roleRule
+	}
+
+	// roleRule maps a group or an attribute value, given exactly or as regular expression, to a role
+	roleRule struct {
+		Group       string
+		Attribute   string
+		Value       interface{}
+		Pattern     string
+		Role        string
+		Permissions []string
+		pattern     *regexp.Regexp
+	}
+
+	// cachedRoles are the mapped roles of a user, valid as long as the user's groups and attributes do not change
+	cachedRoles struct {
+		Fingerprint string
+		Created     time.Time
+		Roles       []cachedRole
+	}
+
+	cachedRole struct {
+		Label       string
+		Permissions []string
 	}
 )
 
-// Inject userService dependency
+func init() {
+	gob.Register(cachedRoles{})
+}
+
+// Inject dependencies
 func (p *AuthRoleProvider) Inject(us UserServiceInterface) {
 	p.userService = us
 }
 
+// ValidateRoleMapping checks the rules of oauth.roles.mapping, the module calls it to fail on startup
+func ValidateRoleMapping(mapping config.Slice) error {
+	_, err := parseRoleRules(mapping)
+	return err
+}
+
+// roleRules parses the mapping once, invalid mappings are rejected on startup by ValidateRoleMapping
+func (p *AuthRoleProvider) roleRules() []roleRule {
+	p.rulesOnce.Do(func() {
+		p.rules, _ = parseRoleRules(p.Mapping)
+	})
+
+	return p.rules
+}
+
+func parseRoleRules(mapping config.Slice) ([]roleRule, error) {
+	var rules []roleRule
+	if err := mapping.MapInto(&rules); err != nil {
+		return nil, errors.Wrap(err, "oauth.roles.mapping")
+	}
+
+	for i := range rules {
+		rule := &rules[i]
+		if rule.Role == "" {
+			return nil, errors.Errorf("oauth.roles.mapping.%d: no role", i)
+		}
+		if rule.Group == "" && rule.Attribute == "" && rule.Pattern == "" {
+			return nil, errors.Errorf("oauth.roles.mapping.%d: neither group, attribute nor pattern", i)
+		}
+		if rule.Pattern != "" {
+			pattern, err := regexp.Compile(rule.Pattern)
+			if err != nil {
+				return nil, errors.Wrapf(err, "oauth.roles.mapping.%d", i)
+			}
+			rule.pattern = pattern
+		}
+	}
+
+	return rules, nil
+}
+
 // All return all associated roles
 func (p *AuthRoleProvider) All(ctx context.Context, session *web.Session) []securityDomain.Role {
 	var roles []securityDomain.Role
@@ -27,7 +118,171 @@ func (p *AuthRoleProvider) All(ctx context.Context, session *web.Session) []secu
 	user := p.userService.GetUser(ctx, session)
 	if user != nil && user.Type == authDomain.USER {
 		roles = append(roles, authDomain.OAuthRoleUser)
+		if len(p.roleRules()) > 0 {
+			roles = append(roles, p.mappedRoles(ctx, session, user)...)
+		}
 	}
 
 	return roles
 }
+
+// RefreshRoles checks the roles cached in the session, so a change of the roles regenerates the session ID
+// before the response is written. Sessions without cached roles are skipped, they have no roles which could change.
+func (p *AuthRoleProvider) RefreshRoles(ctx context.Context, session *web.Session) {
+	if len(p.roleRules()) == 0 || p.CacheLifetime <= 0 || session == nil {
+		return
+	}
+	if _, ok := session.Load(keyRoles); !ok {
+		return
+	}
+
+	user := p.userService.GetUser(ctx, session)
+	if user != nil && user.Type == authDomain.USER {
+		p.mappedRoles(ctx, session, user)
+	}
+}
+
+// mappedRoles returns the roles of the user, cached in the session for the configured lifetime.
+// If the roles of the session change, the session ID is regenerated.
+// Stateless bearer requests are not cached, so they do not create sessions.
+func (p *AuthRoleProvider) mappedRoles(ctx context.Context, session *web.Session, user *authDomain.User) []securityDomain.Role {
+	if p.CacheLifetime <= 0 || session == nil || p.isBearerRequest(ctx) {
+		return toRoles(p.mapRoles(user))
+	}
+
+	fingerprint := userFingerprint(user)
+	value, _ := session.Load(keyRoles)
+	cached, hasCached := value.(cachedRoles)
+	if hasCached && cached.Fingerprint == fingerprint && now().Sub(cached.Created) < p.CacheLifetime {
+		return toRoles(cached.Roles)
+	}
+
+	mapped := p.mapRoles(user)
+	if hasCached && !sameRoles(cached.Roles, mapped) {
+		// the privileges of the session changed
+		session.Regenerate()
+	}
+	session.Store(keyRoles, cachedRoles{Fingerprint: fingerprint, Created: now(), Roles: mapped})
+
+	return toRoles(mapped)
+}
+
+func (p *AuthRoleProvider) isBearerRequest(ctx context.Context) bool {
+	if p.Bearer == nil {
+		return false
+	}
+	_, err := p.Bearer.Authenticate(ctx)
+	return err == nil
+}
+
+// mapRoles evaluates all rules for the user's groups and attributes
+func (p *AuthRoleProvider) mapRoles(user *authDomain.User) []cachedRole {
+	var roles []cachedRole
+
+	for _, rule := range p.roleRules() {
+		var values []string
+		if rule.Attribute != "" {
+			values = attributeValues(user.Attributes[rule.Attribute])
+		} else {
+			values = user.Groups
+		}
+
+		for _, value := range values {
+			if role, ok := rule.match(value); ok {
+				roles = append(roles, role)
+			}
+		}
+	}
+
+	return roles
+}
+
+// match checks the value, submatches of the pattern can be used in the role and permissions, e.g. `$1`
+func (r roleRule) match(value string) (cachedRole, bool) {
+	switch {
+	case r.Group != "":
+		if value != r.Group {
+			return cachedRole{}, false
+		}
+	case r.Value != nil:
+		if value != fmt.Sprint(r.Value) {
+			return cachedRole{}, false
+		}
+	}
+
+	if r.pattern == nil {
+		return cachedRole{Label: r.Role, Permissions: r.Permissions}, true
+	}
+
+	submatches := r.pattern.FindStringSubmatchIndex(value)
+	if submatches == nil {
+		return cachedRole{}, false
+	}
+
+	expand := func(template string) string {
+		return string(r.pattern.ExpandString(nil, template, value, submatches))
+	}
+
+	role := cachedRole{Label: expand(r.Role)}
+	for _, permission := range r.Permissions {
+		role.Permissions = append(role.Permissions, expand(permission))
+	}
+
+	return role, true
+}
+
+// attributeValues returns the values of a single or multi valued attribute as strings
+func attributeValues(attribute interface{}) []string {
+	switch attribute := attribute.(type) {
+	case nil:
+		return nil
+	case []string:
+		return attribute
+	case []interface{}:
+		values := make([]string, 0, len(attribute))
+		for _, value := range attribute {
+			values = append(values, fmt.Sprint(value))
+		}
+		return values
+	default:
+		return []string{fmt.Sprint(attribute)}
+	}
+}
+
+// sameRoles compares the labels and permissions of the roles
+func sameRoles(a, b []cachedRole) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].Label != b[i].Label || strings.Join(a[i].Permissions, ",") != strings.Join(b[i].Permissions, ",") {
+			return false
+		}
+	}
+	return true
+}
+
+// toRoles creates the security roles, roles without permissions grant their name as permission
+func toRoles(cached []cachedRole) []securityDomain.Role {
+	roles := make([]securityDomain.Role, 0, len(cached))
+	for _, role := range cached {
+		if len(role.Permissions) == 0 {
+			roles = append(roles, securityDomain.StringRole(role.Label))
+			continue
+		}
+		roles = append(roles, securityDomain.NewRole(role.Label, role.Permissions))
+	}
+	return roles
+}
+
+// userFingerprint changes whenever the user, its groups or its attributes change
+func userFingerprint(user *authDomain.User) string {
+	data, _ := json.Marshal(struct {
+		Sub        string
+		Groups     []string
+		Attributes map[string]interface{}
+	}{user.Sub, user.Groups, user.Attributes})
+
+	hash := sha256.Sum256(data)
+	return hex.EncodeToString(hash[:])
+}
@@ -3,11 +3,15 @@ package application
 import (
 	"context"
 	"testing"
+	"time"
 
 	"flamingo.me/flamingo/v3/core/oauth/application/fake"
 	authDomain "flamingo.me/flamingo/v3/core/oauth/domain"
 	securityDomain "flamingo.me/flamingo/v3/core/security/domain"
+	"flamingo.me/flamingo/v3/framework/config"
 	"flamingo.me/flamingo/v3/framework/web"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"github.com/stretchr/testify/suite"
 )
 
@@ -47,3 +51,89 @@ func (t *AuthRoleProviderTestSuite) TestAll_RoleUser() {
 		authDomain.OAuthRoleUser,
 	}, t.provider.All(t.context, webSession))
 }
+
+func TestAuthRoleProvider_Mapping(t *testing.T) {
+	provider := &AuthRoleProvider{
+		Mapping: config.Slice{
+			config.Map{"group": "admins", "role": "admin", "permissions": config.Slice{"PermissionAdmin", "PermissionEdit"}},
+			config.Map{"pattern": "^shop-(\\w+)-editors$", "role": "editor-$1", "permissions": config.Slice{"PermissionEdit.$1"}},
+			config.Map{"attribute": "verified", "value": true, "role": "PermissionVerified"},
+			config.Map{"attribute": "department", "pattern": "^sales", "role": "sales"},
+		},
+		CacheLifetime: time.Minute,
+	}
+	provider.Inject(new(fake.UserService))
+
+	session := web.EmptySession()
+	session.Store(fake.UserSessionKey, authDomain.User{
+		Type:   authDomain.USER,
+		Sub:    "user-1",
+		Groups: []string{"admins", "shop-de-editors", "shop-fr-editors", "other"},
+		Attributes: map[string]interface{}{
+			"verified":   true,
+			"department": "sales-north",
+		},
+	})
+
+	permissions := func(roles []securityDomain.Role) map[string][]string {
+		result := make(map[string][]string)
+		for _, role := range roles {
+			result[role.Label()] = role.Permissions()
+		}
+		return result
+	}
+
+	expected := map[string][]string{
+		authDomain.OAuthRoleUser.Label(): {securityDomain.PermissionAuthorized},
+		"admin":                          {"PermissionAdmin", "PermissionEdit"},
+		"editor-de":                      {"PermissionEdit.de"},
+		"editor-fr":                      {"PermissionEdit.fr"},
+		"PermissionVerified":             {"PermissionVerified"},
+		"sales":                          {"sales"},
+	}
+	assert.Equal(t, expected, permissions(provider.All(context.Background(), session)))
+
+	cached, ok := session.Load(keyRoles)
+	require.True(t, ok)
+	assert.Len(t, cached.(cachedRoles).Roles, 5)
+	assert.Equal(t, expected, permissions(provider.All(context.Background(), session)), "cached roles")
+
+	session.Store(fake.UserSessionKey, authDomain.User{Type: authDomain.USER, Sub: "user-1", Groups: []string{"other"}})
+	assert.Equal(t, map[string][]string{authDomain.OAuthRoleUser.Label(): {securityDomain.PermissionAuthorized}}, permissions(provider.All(context.Background(), session)), "changed groups")
+}
+
+func TestAuthRoleProvider_RefreshRoles(t *testing.T) {
+	provider := &AuthRoleProvider{
+		Mapping:       config.Slice{config.Map{"group": "admins", "role": "admin"}},
+		CacheLifetime: time.Minute,
+	}
+	provider.Inject(new(fake.UserService))
+
+	session := web.EmptySession()
+	session.Store(fake.UserSessionKey, authDomain.User{Type: authDomain.USER, Sub: "user-1", Groups: []string{"admins"}})
+
+	provider.RefreshRoles(context.Background(), session)
+	_, ok := session.Load(keyRoles)
+	assert.False(t, ok, "sessions without cached roles are skipped")
+
+	provider.All(context.Background(), session)
+	session.Store(fake.UserSessionKey, authDomain.User{Type: authDomain.USER, Sub: "user-1"})
+
+	provider.RefreshRoles(context.Background(), session)
+	cached, ok := session.Load(keyRoles)
+	require.True(t, ok)
+	assert.Empty(t, cached.(cachedRoles).Roles, "the changed roles are cached before the controller runs")
+}
+
+func TestValidateRoleMapping(t *testing.T) {
+	assert.NoError(t, ValidateRoleMapping(nil))
+
+	err := ValidateRoleMapping(config.Slice{config.Map{"group": "admins"}})
+	assert.Error(t, err, "missing role")
+
+	err = ValidateRoleMapping(config.Slice{config.Map{"role": "admin"}})
+	assert.Error(t, err, "missing condition")
+
+	err = ValidateRoleMapping(config.Slice{config.Map{"pattern": "(", "role": "admin"}})
+	assert.Error(t, err, "invalid pattern")
+}
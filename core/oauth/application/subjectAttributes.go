@@ -0,0 +1,41 @@
+package application
+
+import (
+	"context"
+
+	authDomain "flamingo.me/flamingo/v3/core/oauth/domain"
+	"flamingo.me/flamingo/v3/core/security/application/voter"
+	"flamingo.me/flamingo/v3/framework/web"
+)
+
+// UserSubjectAttributes provides the logged in user as attributes for the security policies, e.g. `user.sub`
+type UserSubjectAttributes struct {
+	userService UserServiceInterface
+}
+
+var _ voter.SubjectAttributesProvider = new(UserSubjectAttributes)
+
+// Inject dependencies
+func (p *UserSubjectAttributes) Inject(us UserServiceInterface) {
+	p.userService = us
+}
+
+// Attributes of the logged in user: sub, name, email, groups, type and all mapped attributes
+func (p *UserSubjectAttributes) Attributes(ctx context.Context, session *web.Session) map[string]interface{} {
+	user := p.userService.GetUser(ctx, session)
+	if user == nil || user.Type != authDomain.USER {
+		return nil
+	}
+
+	attributes := make(map[string]interface{}, len(user.Attributes)+5)
+	for key, value := range user.Attributes {
+		attributes[key] = value
+	}
+	attributes["sub"] = user.Sub
+	attributes["name"] = user.Name
+	attributes["email"] = user.Email
+	attributes["groups"] = user.Groups
+	attributes["type"] = string(user.Type)
+
+	return attributes
+}
@@ -12,6 +12,8 @@ type (
 	UserService struct {
 		authManager    *AuthManager
 		mappingService *domain.UserMappingService
+		// Bearer authenticates stateless requests by their access token, it is optional
+		Bearer *BearerAuthenticator `inject:",optional"`
 	}
 
 	// UserServiceInterface to mock in tests
@@ -41,6 +43,13 @@ func (us *UserService) IsLoggedIn(c context.Context, session *web.Session) bool
 }
 
 func (us *UserService) getUser(c context.Context, session *web.Session) *domain.User {
+	// stateless requests are authenticated by their bearer token
+	if us.Bearer != nil {
+		if user, ok := us.Bearer.User(c); ok {
+			return user
+		}
+	}
+
 	id, err := us.authManager.IDToken(c, session)
 	if err != nil {
 		return domain.Guest
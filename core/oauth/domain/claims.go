@@ -0,0 +1,292 @@
+package domain
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"flamingo.me/flamingo/v3/framework/config"
+	"github.com/pkg/errors"
+)
+
+type (
+	// claimRule maps claims to a user attribute, configured either as claim path or as map
+	claimRule struct {
+		// Claim is the claim path, nested claims are separated by dots, alternatives by semicolons
+		Claim string
+		// Type converts the value: string, bool, int, float, []string, array or object
+		Type string
+		// Transform is applied before the type conversion: lowercase, uppercase, trim or split[:separator]
+		Transform []string
+		// Default is used if the claim is missing or can not be converted
+		Default interface{}
+		// Overwrite replaces the value of a user field with the same name which is mapped via the idToken mapping
+		Overwrite bool
+	}
+
+	claimRules map[string]claimRule
+)
+
+// parseClaimRules reads the attribute mapping, a rule is either the claim path or a map with claim, type, transform, default and overwrite
+func parseClaimRules(cfg config.Map) (claimRules, error) {
+	rules := make(claimRules, len(cfg))
+	for name, value := range cfg {
+		switch value := value.(type) {
+		case string:
+			rules[name] = claimRule{Claim: value}
+		case config.Map:
+			var rule claimRule
+			if err := value.MapInto(&rule); err != nil {
+				return nil, errors.Wrapf(err, "claims mapping %q", name)
+			}
+			if rule.Claim == "" {
+				rule.Claim = name
+			}
+			if err := rule.validate(); err != nil {
+				return nil, errors.Wrapf(err, "claims mapping %q", name)
+			}
+			rules[name] = rule
+		default:
+			return nil, errors.Errorf("claims mapping %q: expected a claim or a map, but got %T", name, value)
+		}
+	}
+	return rules, nil
+}
+
+// validate checks the type and the transformations of the rule
+func (rule claimRule) validate() error {
+	switch strings.ToLower(rule.Type) {
+	case "", "string", "bool", "int", "float", "[]string", "array", "object":
+	default:
+		return errors.Errorf("unknown type %q", rule.Type)
+	}
+
+	for _, transformation := range rule.Transform {
+		name := transformation
+		if i := strings.Index(transformation, ":"); i >= 0 {
+			name = transformation[:i]
+		}
+		switch strings.ToLower(name) {
+		case "lowercase", "uppercase", "trim", "split":
+		default:
+			return errors.Errorf("unknown transformation %q", transformation)
+		}
+	}
+
+	return nil
+}
+
+// apply maps all rules, attributes without value are left out
+func (rules claimRules) apply(claims map[string]interface{}) map[string]interface{} {
+	attributes := make(map[string]interface{}, len(rules))
+	for name, rule := range rules {
+		if value, ok := rule.apply(claims); ok {
+			attributes[name] = value
+		}
+	}
+	return attributes
+}
+
+func (rule claimRule) apply(claims map[string]interface{}) (interface{}, bool) {
+	if value, ok := lookupClaim(claims, rule.Claim); ok {
+		value, err := convertClaim(transformClaim(value, rule.Transform), rule.Type)
+		if err == nil {
+			return value, true
+		}
+	}
+
+	if rule.Default == nil {
+		return nil, false
+	}
+
+	value, err := convertClaim(rule.Default, rule.Type)
+	return value, err == nil
+}
+
+// lookupClaim finds the first existing claim of the semicolon separated paths.
+// A path is used as claim name first, then as dot separated path into nested objects and arrays.
+func lookupClaim(claims map[string]interface{}, paths string) (interface{}, bool) {
+	for _, path := range strings.Split(paths, ";") {
+		path = strings.TrimSpace(path)
+		if path == "" {
+			continue
+		}
+
+		if value, ok := claims[path]; ok && value != nil {
+			return value, true
+		}
+
+		var current interface{} = claims
+		found := true
+		for _, part := range strings.Split(path, ".") {
+			switch node := current.(type) {
+			case map[string]interface{}:
+				current, found = node[part]
+			case []interface{}:
+				index, err := strconv.Atoi(part)
+				found = err == nil && index >= 0 && index < len(node)
+				if found {
+					current = node[index]
+				}
+			default:
+				found = false
+			}
+			if !found {
+				break
+			}
+		}
+
+		if found && current != nil {
+			return current, true
+		}
+	}
+
+	return nil, false
+}
+
+// transformClaim applies the transformations, string transformations are applied to each element of arrays
+func transformClaim(value interface{}, transformations []string) interface{} {
+	for _, transformation := range transformations {
+		name, arg := transformation, ""
+		if i := strings.Index(transformation, ":"); i >= 0 {
+			name, arg = transformation[:i], transformation[i+1:]
+		}
+
+		switch strings.ToLower(name) {
+		case "lowercase":
+			value = mapStrings(value, strings.ToLower)
+		case "uppercase":
+			value = mapStrings(value, strings.ToUpper)
+		case "trim":
+			value = mapStrings(value, strings.TrimSpace)
+		case "split":
+			if arg == "" {
+				arg = ","
+			}
+			if s, ok := value.(string); ok {
+				var parts []interface{}
+				for _, part := range strings.Split(s, arg) {
+					if part = strings.TrimSpace(part); part != "" {
+						parts = append(parts, part)
+					}
+				}
+				value = parts
+			}
+		}
+	}
+
+	return value
+}
+
+func mapStrings(value interface{}, f func(string) string) interface{} {
+	switch value := value.(type) {
+	case string:
+		return f(value)
+	case []interface{}:
+		result := make([]interface{}, len(value))
+		for i, v := range value {
+			result[i] = mapStrings(v, f)
+		}
+		return result
+	}
+	return value
+}
+
+// convertClaim converts a JSON value to the given type
+func convertClaim(value interface{}, typ string) (interface{}, error) {
+	switch strings.ToLower(typ) {
+	case "":
+		return value, nil
+
+	case "string":
+		switch v := value.(type) {
+		case string:
+			return v, nil
+		case bool:
+			return strconv.FormatBool(v), nil
+		case float64:
+			return strconv.FormatFloat(v, 'f', -1, 64), nil
+		case int:
+			return strconv.Itoa(v), nil
+		}
+
+	case "bool":
+		switch v := value.(type) {
+		case bool:
+			return v, nil
+		case string:
+			return strconv.ParseBool(v)
+		case float64:
+			return v != 0, nil
+		case int:
+			return v != 0, nil
+		}
+
+	case "int":
+		switch v := value.(type) {
+		case float64:
+			if v == math.Trunc(v) {
+				return int(v), nil
+			}
+		case int:
+			return v, nil
+		case string:
+			return strconv.Atoi(strings.TrimSpace(v))
+		}
+
+	case "float":
+		switch v := value.(type) {
+		case float64:
+			return v, nil
+		case int:
+			return float64(v), nil
+		case string:
+			return strconv.ParseFloat(strings.TrimSpace(v), 64)
+		}
+
+	case "[]string":
+		switch v := value.(type) {
+		case []interface{}:
+			result := make([]string, 0, len(v))
+			for _, element := range v {
+				s, err := convertClaim(element, "string")
+				if err != nil {
+					return nil, err
+				}
+				result = append(result, s.(string))
+			}
+			return result, nil
+		case []string:
+			return v, nil
+		case string:
+			return []string{v}, nil
+		}
+
+	case "array":
+		switch v := value.(type) {
+		case []interface{}:
+			return v, nil
+		default:
+			return []interface{}{v}, nil
+		}
+
+	case "object":
+		if v, ok := value.(map[string]interface{}); ok {
+			return v, nil
+		}
+
+	default:
+		return nil, errors.Errorf("unknown claim type %q", typ)
+	}
+
+	return nil, errors.Errorf("can not convert %s to %s", describeClaim(value), typ)
+}
+
+func describeClaim(value interface{}) string {
+	if value == nil {
+		return "null"
+	}
+	return fmt.Sprintf("%s %v", reflect.TypeOf(value), value)
+}
@@ -0,0 +1,79 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConvertClaim(t *testing.T) {
+	for _, tt := range []struct {
+		value    interface{}
+		typ      string
+		expected interface{}
+		err      bool
+	}{
+		{value: "text", typ: "", expected: "text"},
+		{value: float64(1.5), typ: "string", expected: "1.5"},
+		{value: true, typ: "string", expected: "true"},
+		{value: "true", typ: "bool", expected: true},
+		{value: float64(0), typ: "bool", expected: false},
+		{value: "yes", typ: "bool", err: true},
+		{value: float64(3), typ: "int", expected: 3},
+		{value: "17", typ: "int", expected: 17},
+		{value: float64(3.5), typ: "int", err: true},
+		{value: "2.5", typ: "float", expected: 2.5},
+		{value: []interface{}{"a", float64(1)}, typ: "[]string", expected: []string{"a", "1"}},
+		{value: []interface{}{map[string]interface{}{}}, typ: "[]string", err: true},
+		{value: "single", typ: "[]string", expected: []string{"single"}},
+		{value: "single", typ: "array", expected: []interface{}{"single"}},
+		{value: map[string]interface{}{"a": "b"}, typ: "object", expected: map[string]interface{}{"a": "b"}},
+		{value: "text", typ: "object", err: true},
+		{value: "text", typ: "unknown", err: true},
+	} {
+		value, err := convertClaim(tt.value, tt.typ)
+		if tt.err {
+			assert.Error(t, err, "%v to %s", tt.value, tt.typ)
+			continue
+		}
+		assert.NoError(t, err, "%v to %s", tt.value, tt.typ)
+		assert.Equal(t, tt.expected, value, "%v to %s", tt.value, tt.typ)
+	}
+}
+
+func TestLookupClaim(t *testing.T) {
+	claims := map[string]interface{}{
+		"plain":        "value",
+		"with.dot":     "literal",
+		"nested":       map[string]interface{}{"child": map[string]interface{}{"leaf": "deep"}},
+		"list":         []interface{}{"first", map[string]interface{}{"name": "second"}},
+		"null":         nil,
+		"alternative2": "fallback",
+	}
+
+	for path, expected := range map[string]interface{}{
+		"plain":                      "value",
+		"with.dot":                   "literal",
+		"nested.child.leaf":          "deep",
+		"list.0":                     "first",
+		"list.1.name":                "second",
+		"null;alternative2":          "fallback",
+		"missing; alternative2":      "fallback",
+		"nested.missing;list.1.name": "second",
+	} {
+		value, ok := lookupClaim(claims, path)
+		assert.True(t, ok, path)
+		assert.Equal(t, expected, value, path)
+	}
+
+	for _, path := range []string{"missing", "nested.child.leaf.more", "list.2", "list.x", "null", ""} {
+		_, ok := lookupClaim(claims, path)
+		assert.False(t, ok, path)
+	}
+}
+
+func TestTransformClaim(t *testing.T) {
+	assert.Equal(t, []interface{}{"a", "b"}, transformClaim(" A ;B", []string{"split:;", "lowercase"}))
+	assert.Equal(t, []interface{}{"X", "Y"}, transformClaim([]interface{}{" x", "y "}, []string{"trim", "uppercase"}))
+	assert.Equal(t, float64(1), transformClaim(float64(1), []string{"lowercase"}))
+}
@@ -0,0 +1,17 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+type (
+	// LogoutRegistry remembers back-channel logouts of the identity providers.
+	// The session store can not be searched for the sessions of a user, so affected sessions are invalidated on their next request.
+	LogoutRegistry interface {
+		// Logout records the logout of a provider session (sid) or of all sessions of a user (sub), either can be empty
+		Logout(ctx context.Context, provider, sid, sub string, at time.Time) error
+		// LoggedOutSince checks if the provider session or the user has been logged out after the given time
+		LoggedOutSince(ctx context.Context, provider, sid, sub string, since time.Time) (bool, error)
+	}
+)
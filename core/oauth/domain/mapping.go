@@ -2,11 +2,13 @@ package domain
 
 import (
 	"encoding/gob"
+	"reflect"
 	"strings"
 
 	"flamingo.me/flamingo/v3/framework/config"
 	"flamingo.me/flamingo/v3/framework/web"
 	"github.com/coreos/go-oidc"
+	"github.com/pkg/errors"
 )
 
 type (
@@ -26,20 +28,47 @@ type (
 		Groups       string
 	}
 
-	// UserMappingService maps a user based on data available via the idTokenMapping setting
+	// UserMappingService maps a user based on data available via the idTokenMapping setting,
+	// which can be overridden per provider via oauth.providers.<name>.mapping.idToken.
+	// Additional typed attributes are mapped via oauth.mapping.claims and oauth.providers.<name>.mapping.claims.
+	// The mappings are parsed once on injection, so invalid mappings fail on start instead of on login.
 	UserMappingService struct {
-		idTokenMapping config.Map
+		mappings map[string]providerMapping
+		factory  UserFactory
 	}
+
+	// providerMapping is the parsed mapping of a provider, the mapping of the empty provider name is the global one
+	providerMapping struct {
+		user   userMapping
+		claims claimRules
+	}
+
+	// DefaultUserFactory returns the mapped user
+	DefaultUserFactory struct{}
 )
 
+// CreateUser returns the mapped user unchanged
+func (*DefaultUserFactory) CreateUser(_ string, user *User, _ map[string]interface{}) *User {
+	return user
+}
+
 // Inject dependencies
-func (ums *UserMappingService) Inject(config *struct {
+func (ums *UserMappingService) Inject(factory UserFactory, config *struct {
 	IDTokenMapping config.Map `inject:"config:oauth.mapping.idToken"`
+	ClaimsMapping  config.Map `inject:"config:oauth.mapping.claims,optional"`
+	Providers      config.Map `inject:"config:oauth.providers,optional"`
 }) {
-	ums.idTokenMapping = config.IDTokenMapping
+	ums.factory = factory
+
+	mappings, err := parseMappings(config.IDTokenMapping, config.ClaimsMapping, config.Providers)
+	if err != nil {
+		panic(err)
+	}
+	ums.mappings = mappings
 }
 
-// UserFromIDToken returns a user mapped with data from a provided OpenID connect token
+// UserFromIDToken returns a user mapped with data from a provided OpenID connect token,
+// using the mapping of the provider which authenticated the session
 func (ums *UserMappingService) UserFromIDToken(idToken *oidc.IDToken, session *web.Session) (*User, error) {
 	var claims map[string]interface{}
 	err := idToken.Claims(&claims)
@@ -47,26 +76,79 @@ func (ums *UserMappingService) UserFromIDToken(idToken *oidc.IDToken, session *w
 		return nil, err
 	}
 
-	return ums.MapToUser(claims, session), nil
+	provider, _ := LoadProvider(session)
+	return ums.MapToProviderUser(provider, claims, session), nil
+}
+
+// parseMappings parses the global mapping and the mapping of each provider, provider mappings override the global one
+func parseMappings(idTokenMapping, claimsMapping, providers config.Map) (map[string]providerMapping, error) {
+	global, err := parseMapping(idTokenMapping, nil, claimsMapping, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "oauth.mapping")
+	}
+
+	mappings := map[string]providerMapping{"": global}
+	for provider := range providers {
+		providerIDTokenMapping, _ := providers.Get(provider + ".mapping.idToken")
+		providerClaimsMapping, _ := providers.Get(provider + ".mapping.claims")
+		mapping, err := parseMapping(idTokenMapping, providerIDTokenMapping, claimsMapping, providerClaimsMapping)
+		if err != nil {
+			return nil, errors.Wrapf(err, "oauth.providers.%s.mapping", provider)
+		}
+		mappings[provider] = mapping
+	}
+
+	return mappings, nil
 }
 
-func (ums *UserMappingService) getMapping(config.Map) (userMapping, error) {
-	var mapping userMapping
-	err := ums.idTokenMapping.MapInto(&mapping)
+// parseMapping parses a mapping, the provider's rules replace global rules with the same name
+func parseMapping(idTokenMapping config.Map, providerIDTokenMapping interface{}, claimsMapping config.Map, providerClaimsMapping interface{}) (providerMapping, error) {
+	var mapping providerMapping
+	if err := idTokenMapping.MapInto(&mapping.user); err != nil {
+		return mapping, err
+	}
+	if providerIDTokenMapping, ok := providerIDTokenMapping.(config.Map); ok {
+		if err := providerIDTokenMapping.MapInto(&mapping.user); err != nil {
+			return mapping, err
+		}
+	}
+
+	merged := make(config.Map, len(claimsMapping))
+	for name, rule := range claimsMapping {
+		merged[name] = rule
+	}
+	if providerClaimsMapping, ok := providerClaimsMapping.(config.Map); ok {
+		for name, rule := range providerClaimsMapping {
+			merged[name] = rule
+		}
+	}
 
+	var err error
+	mapping.claims, err = parseClaimRules(merged)
 	return mapping, err
 }
 
+// getMapping returns the mapping of the provider, or the global mapping for unknown providers
+func (ums *UserMappingService) getMapping(provider string) providerMapping {
+	if mapping, ok := ums.mappings[provider]; ok {
+		return mapping
+	}
+	return ums.mappings[""]
+}
+
 // MapToUser returns the user mapped from the claims
 func (ums *UserMappingService) MapToUser(claims map[string]interface{}, session *web.Session) *User {
-	mapping, err := ums.getMapping(ums.idTokenMapping)
-	if err != nil {
-		panic(err)
-	}
+	return ums.MapToProviderUser("", claims, session)
+}
+
+// MapToProviderUser returns the user mapped from the claims with the mapping of the given provider
+func (ums *UserMappingService) MapToProviderUser(provider string, claims map[string]interface{}, session *web.Session) *User {
+	parsed := ums.getMapping(provider)
+	mapping, rules := parsed.user, parsed.claims
 
 	claims = ensureClaims(claims, session)
 
-	return &User{
+	user := &User{
 		Sub:          ums.mapField(mapping.Sub, claims),
 		Name:         ums.mapField(mapping.Name, claims),
 		Email:        ums.mapField(mapping.Email, claims),
@@ -82,6 +164,49 @@ func (ums *UserMappingService) MapToUser(claims map[string]interface{}, session
 		Type:         USER,
 		Groups:       ums.mapSliceField(mapping.Groups, claims),
 	}
+
+	if len(rules) > 0 {
+		user.Attributes = rules.apply(claims)
+		setUserFields(user, user.Attributes, rules)
+	}
+
+	if ums.factory != nil {
+		user = ums.factory.CreateUser(provider, user, claims)
+	}
+
+	return user
+}
+
+// setUserFields sets the string fields and the groups of the user to mapped attributes with the same name and type.
+// Fields mapped via the idToken mapping take precedence, unless the rule opts in to overwrite them.
+// The subject is never set by an attribute, it identifies the user.
+func setUserFields(user *User, attributes map[string]interface{}, rules claimRules) {
+	value := reflect.ValueOf(user).Elem()
+	for name, attribute := range attributes {
+		field := value.FieldByNameFunc(func(field string) bool {
+			return field != "Type" && field != "Sub" && strings.EqualFold(field, name)
+		})
+		if !field.IsValid() || (field.Type() != reflect.TypeOf("") && field.Type() != reflect.TypeOf([]string{})) {
+			continue
+		}
+		if !isUnmapped(field) && !rules[name].Overwrite {
+			continue
+		}
+		if attributeValue := reflect.ValueOf(attribute); attributeValue.Type() == field.Type() {
+			field.Set(attributeValue)
+		}
+	}
+}
+
+// isUnmapped checks if the field got no value from the idToken mapping, unmapped groups are a single empty string
+func isUnmapped(field reflect.Value) bool {
+	switch value := field.Interface().(type) {
+	case string:
+		return value == ""
+	case []string:
+		return len(value) == 0 || (len(value) == 1 && value[0] == "")
+	}
+	return false
 }
 
 type cachedClaims string
@@ -94,6 +219,11 @@ func init() {
 }
 
 func ensureClaims(claims map[string]interface{}, session *web.Session) map[string]interface{} {
+	// stateless requests have no session to cache the claims
+	if session == nil {
+		return claims
+	}
+
 	var cached map[string]interface{}
 	if raw, ok := session.Load(sessionkey); ok {
 		cached, _ = raw.(map[string]interface{})
@@ -125,9 +255,12 @@ func (ums *UserMappingService) mapCustomFields(mapping []string, claims map[stri
 }
 
 func (ums *UserMappingService) mapField(mappedFieldName string, claims map[string]interface{}) string {
-	claimKeys := strings.Split(mappedFieldName, ";")
-	for _, key := range claimKeys {
-		if value, ok := claims[strings.TrimSpace(key)].(string); ok {
+	for _, key := range strings.Split(mappedFieldName, ";") {
+		value, ok := lookupClaim(claims, key)
+		if !ok {
+			continue
+		}
+		if value, ok := value.(string); ok {
 			return value
 		}
 	}
@@ -135,5 +268,15 @@ func (ums *UserMappingService) mapField(mappedFieldName string, claims map[strin
 }
 
 func (ums *UserMappingService) mapSliceField(mappedFieldName string, claims map[string]interface{}) []string {
+	// claims can contain arrays as well as comma separated strings
+	for _, key := range strings.Split(mappedFieldName, ";") {
+		if value, ok := lookupClaim(claims, key); ok {
+			if values, err := convertClaim(value, "[]string"); err == nil {
+				if _, isString := value.(string); !isString {
+					return values.([]string)
+				}
+			}
+		}
+	}
 	return strings.Split(ums.mapField(mappedFieldName, claims), ",")
 }
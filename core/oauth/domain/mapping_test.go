@@ -24,6 +24,14 @@ func (t *UserMappingServiceTestSuite) SetupTest() {
 	t.mappingService = &UserMappingService{}
 }
 
+func (t *UserMappingServiceTestSuite) inject(factory UserFactory, idTokenMapping, claimsMapping, providers config.Map) {
+	t.mappingService.Inject(factory, &struct {
+		IDTokenMapping config.Map `inject:"config:oauth.mapping.idToken"`
+		ClaimsMapping  config.Map `inject:"config:oauth.mapping.claims,optional"`
+		Providers      config.Map `inject:"config:oauth.providers,optional"`
+	}{IDTokenMapping: idTokenMapping, ClaimsMapping: claimsMapping, Providers: providers})
+}
+
 func (t *UserMappingServiceTestSuite) TestMapToUser_Default() {
 	claims := map[string]interface{}{
 		"sub":      "ID123456",
@@ -32,11 +40,12 @@ func (t *UserMappingServiceTestSuite) TestMapToUser_Default() {
 		"whatever": "whatever",
 	}
 
-	t.mappingService.idTokenMapping = config.Map{
+	idTokenMapping := config.Map{
 		"sub":   "sub",
 		"email": "email",
 		"name":  "name",
 	}
+	t.inject(nil, idTokenMapping, nil, nil)
 
 	t.Equal(&User{
 		Sub:          "ID123456",
@@ -65,7 +74,7 @@ func (t *UserMappingServiceTestSuite) TestMapToUser_AllMainFields() {
 		"whatever":    "whatever",
 	}
 
-	t.mappingService.idTokenMapping = config.Map{
+	idTokenMapping := config.Map{
 		"sub":         "sub",
 		"email":       "email",
 		"name":        "name",
@@ -79,6 +88,7 @@ func (t *UserMappingServiceTestSuite) TestMapToUser_AllMainFields() {
 		"country":     "country",
 		"groups":      "groups",
 	}
+	t.inject(nil, idTokenMapping, nil, nil)
 
 	t.Equal(&User{
 		Sub:          "ID123456",
@@ -103,9 +113,10 @@ func (t *UserMappingServiceTestSuite) TestMapToUser_CustomFields() {
 		"whatever": "value",
 	}
 
-	t.mappingService.idTokenMapping = config.Map{
+	idTokenMapping := config.Map{
 		"customFields": config.Slice{"whatever"},
 	}
+	t.inject(nil, idTokenMapping, nil, nil)
 
 	t.Equal(&User{
 		CustomFields: map[string]string{
@@ -133,7 +144,7 @@ func (t *UserMappingServiceTestSuite) TestMapToUser_AllDifferent() {
 		"customer_groups": "RU",
 	}
 
-	t.mappingService.idTokenMapping = config.Map{
+	idTokenMapping := config.Map{
 		"sub":          "someSub",
 		"email":        "someEmail",
 		"name":         "someName",
@@ -148,6 +159,7 @@ func (t *UserMappingServiceTestSuite) TestMapToUser_AllDifferent() {
 		"customFields": config.Slice{"whatever"},
 		"groups":       "userType;customer_groups",
 	}
+	t.inject(nil, idTokenMapping, nil, nil)
 
 	t.Equal(&User{
 		Sub:         "ID123456",
@@ -168,3 +180,158 @@ func (t *UserMappingServiceTestSuite) TestMapToUser_AllDifferent() {
 		Groups: []string{"RU"},
 	}, t.mappingService.MapToUser(claims, web.EmptySession()))
 }
+
+func (t *UserMappingServiceTestSuite) TestMapToUser_Provider() {
+	claims := map[string]interface{}{
+		"sub":       "ID123456",
+		"name":      "Mr. Awesome",
+		"upn":       "awesome@corp.example",
+		"email":     "email@domain.com",
+		"whatever":  "value",
+		"corpGroup": "admins",
+	}
+
+	idTokenMapping := config.Map{
+		"sub":   "sub",
+		"email": "email",
+		"name":  "name",
+	}
+	providers := config.Map{
+		"corporate": config.Map{
+			"mapping": config.Map{
+				"idToken": config.Map{
+					"email":  "upn",
+					"groups": "corpGroup",
+				},
+			},
+		},
+	}
+	t.inject(nil, idTokenMapping, nil, providers)
+
+	session := web.EmptySession()
+	StoreProvider(session, "corporate")
+
+	t.Equal(&User{
+		Sub:          "ID123456",
+		Name:         "Mr. Awesome",
+		Email:        "awesome@corp.example",
+		CustomFields: map[string]string{},
+		Type:         USER,
+		Groups:       []string{"admins"},
+	}, t.mappingService.MapToProviderUser("corporate", claims, session))
+
+	t.Equal("email@domain.com", t.mappingService.MapToUser(claims, web.EmptySession()).Email)
+}
+
+type testUserDetails struct {
+	Tenant string
+}
+
+type testUserFactory struct{}
+
+func (*testUserFactory) CreateUser(provider string, user *User, claims map[string]interface{}) *User {
+	user.Details = &testUserDetails{Tenant: provider + ":" + user.StringAttribute("tenant")}
+	return user
+}
+
+func (t *UserMappingServiceTestSuite) TestMapToUser_Claims() {
+	claims := map[string]interface{}{
+		"sub":            "ID123456",
+		"email":          "Email@Domain.com",
+		"email_verified": true,
+		"age":            float64(42),
+		"tenant":         "ACME",
+		"tags":           "a, b,c",
+		"realm_access": map[string]interface{}{
+			"roles": []interface{}{"admin", "user"},
+		},
+		"address": map[string]interface{}{
+			"street_address": "some street",
+			"locality":       "Whitecity",
+		},
+		"https://example.com/claims/level": "gold",
+	}
+
+	idTokenMapping := config.Map{
+		"sub":   "sub",
+		"email": "email",
+	}
+	claimsMapping := config.Map{
+		"email": config.Map{
+			"transform": config.Slice{"lowercase"},
+			"overwrite": true,
+		},
+		"sub":  "email",
+		"name": "tenant",
+		"groups": config.Map{
+			"claim": "realm_access.roles",
+			"type":  "[]string",
+		},
+		"street":   "address.street_address",
+		"verified": config.Map{"claim": "email_verified", "type": "bool"},
+		"age":      config.Map{"claim": "age", "type": "int"},
+		"tags":     config.Map{"claim": "tags", "transform": config.Slice{"split:,"}, "type": "[]string"},
+		"address":  config.Map{"claim": "address", "type": "object"},
+		"level":    "https://example.com/claims/level",
+		"locale":   config.Map{"claim": "locale;lang", "default": "en"},
+		"tenant":   "tenant",
+	}
+	t.inject(new(testUserFactory), idTokenMapping, claimsMapping, nil)
+
+	user := t.mappingService.MapToProviderUser("corporate", claims, web.EmptySession())
+
+	t.Equal("email@domain.com", user.Email, "overwritten by the attribute")
+	t.Equal("ID123456", user.Sub, "the subject is never overwritten")
+	t.Equal("ACME", user.Name, "set by the attribute as it is not mapped")
+	t.Equal("some street", user.Street)
+	t.Equal([]string{"admin", "user"}, user.Groups)
+	t.True(user.BoolAttribute("verified"))
+	t.Equal(42, user.IntAttribute("age"))
+	t.Equal([]string{"a", "b", "c"}, user.StringsAttribute("tags"))
+	t.Equal("gold", user.StringAttribute("level"))
+	t.Equal("en", user.StringAttribute("locale"))
+	t.Equal(map[string]interface{}{"street_address": "some street", "locality": "Whitecity"}, user.Attributes["address"])
+	t.Equal(&testUserDetails{Tenant: "corporate:ACME"}, user.Details)
+	t.Equal(USER, user.Type)
+}
+
+func (t *UserMappingServiceTestSuite) TestMapToUser_GroupsArray() {
+	claims := map[string]interface{}{
+		"sub":    "ID123456",
+		"groups": []interface{}{"GROUP1", "GROUP2"},
+	}
+
+	idTokenMapping := config.Map{
+		"sub":    "sub",
+		"groups": "groups",
+	}
+	t.inject(nil, idTokenMapping, nil, nil)
+
+	t.Equal([]string{"GROUP1", "GROUP2"}, t.mappingService.MapToUser(claims, web.EmptySession()).Groups)
+}
+
+func (t *UserMappingServiceTestSuite) TestInject_InvalidMapping() {
+	idTokenMapping := config.Map{"sub": "sub"}
+
+	t.Panics(func() {
+		t.inject(nil, idTokenMapping, config.Map{"age": config.Map{"claim": "age", "type": "integer"}}, nil)
+	}, "unknown types fail on injection")
+
+	t.Panics(func() {
+		t.inject(nil, idTokenMapping, nil, config.Map{
+			"corporate": config.Map{
+				"mapping": config.Map{
+					"claims": config.Map{"email": config.Map{"transform": config.Slice{"lower"}}},
+				},
+			},
+		})
+	}, "unknown transformations of provider mappings fail on injection")
+
+	t.Panics(func() {
+		t.inject(nil, idTokenMapping, config.Map{"email": float64(1)}, nil)
+	}, "rules which are neither a claim nor a map fail on injection")
+
+	t.NotPanics(func() {
+		t.inject(nil, idTokenMapping, config.Map{"tags": config.Map{"transform": config.Slice{"split:;", "Trim"}, "type": "[]String"}}, nil)
+	})
+}
@@ -0,0 +1,25 @@
+package domain
+
+import (
+	"flamingo.me/flamingo/v3/framework/web"
+)
+
+// keyProvider defines where the name of the provider which authenticated the session is saved
+const keyProvider = "auth.provider"
+
+// StoreProvider remembers which provider authenticated the session
+func StoreProvider(session *web.Session, provider string) {
+	session.Store(keyProvider, provider)
+}
+
+// LoadProvider returns the name of the provider which authenticated the session
+func LoadProvider(session *web.Session) (string, bool) {
+	value, _ := session.Load(keyProvider)
+	provider, ok := value.(string)
+	return provider, ok && provider != ""
+}
+
+// DeleteProvider removes the provider from the session
+func DeleteProvider(session *web.Session) {
+	session.Delete(keyProvider)
+}
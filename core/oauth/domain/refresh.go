@@ -0,0 +1,12 @@
+package domain
+
+import "context"
+
+type (
+	// RefreshGroup makes sure a refresh token is only used once at a time.
+	// Concurrent calls for the same key wait for the running refresh and get its result,
+	// so a rotated refresh token is not overwritten by a failing second refresh.
+	RefreshGroup interface {
+		Do(ctx context.Context, key string, refresh func() ([]byte, error)) ([]byte, error)
+	}
+)
@@ -29,6 +29,15 @@ type (
 		CustomFields map[string]string
 		Type         UserType
 		Groups       []string
+		// Attributes are mapped via oauth.mapping.claims, with the configured types
+		Attributes map[string]interface{}
+		// Details can be set by an own UserFactory, e.g. to an application specific user type
+		Details interface{}
+	}
+
+	// UserFactory creates the user after the mapping, bind an own factory to enrich the user or to set the Details
+	UserFactory interface {
+		CreateUser(provider string, user *User, claims map[string]interface{}) *User
 	}
 
 	// LoginEvent for the current session
@@ -41,6 +50,12 @@ type (
 		Session *web.Session
 	}
 
+	// TokenRefreshedEvent after the tokens of the current session have been refreshed
+	TokenRefreshedEvent struct {
+		Session  *web.Session
+		Provider string
+	}
+
 	// Auth information
 	Auth struct {
 		TokenSource oauth2.TokenSource
@@ -48,8 +63,30 @@ type (
 	}
 )
 
+var (
+	_ domain.AuditableEvent = new(LoginEvent)
+	_ domain.AuditableEvent = new(LogoutEvent)
+	_ domain.AuditableEvent = new(TokenRefreshedEvent)
+)
+
 func init() {
 	gob.Register(User{})
+	gob.Register([]interface{}{})
+}
+
+// Audit returns the login audit event, see domain.AuditableEvent of core/security
+func (e *LoginEvent) Audit() (*web.Session, *domain.AuditEvent) {
+	return e.Session, &domain.AuditEvent{Type: domain.AuditLogin}
+}
+
+// Audit returns the logout audit event
+func (e *LogoutEvent) Audit() (*web.Session, *domain.AuditEvent) {
+	return e.Session, &domain.AuditEvent{Type: domain.AuditLogout}
+}
+
+// Audit returns the token refresh audit event with the provider
+func (e *TokenRefreshedEvent) Audit() (*web.Session, *domain.AuditEvent) {
+	return e.Session, &domain.AuditEvent{Type: domain.AuditTokenRefreshed, Fields: map[string]interface{}{"provider": e.Provider}}
 }
 
 // Get a custom field by the name
@@ -60,6 +97,36 @@ func (u User) Get(name string) string {
 	return u.CustomFields[name]
 }
 
+// Attribute returns a mapped attribute
+func (u User) Attribute(name string) (interface{}, bool) {
+	value, ok := u.Attributes[name]
+	return value, ok
+}
+
+// StringAttribute returns a mapped string attribute, or an empty string
+func (u User) StringAttribute(name string) string {
+	value, _ := u.Attributes[name].(string)
+	return value
+}
+
+// BoolAttribute returns a mapped bool attribute, or false
+func (u User) BoolAttribute(name string) bool {
+	value, _ := u.Attributes[name].(bool)
+	return value
+}
+
+// IntAttribute returns a mapped int attribute, or 0
+func (u User) IntAttribute(name string) int {
+	value, _ := u.Attributes[name].(int)
+	return value
+}
+
+// StringsAttribute returns a mapped []string attribute, or nil
+func (u User) StringsAttribute(name string) []string {
+	value, _ := u.Attributes[name].([]string)
+	return value
+}
+
 const (
 	// GUEST user
 	GUEST UserType = "guest"
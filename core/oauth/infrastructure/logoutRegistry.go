@@ -0,0 +1,144 @@
+package infrastructure
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+
+	"flamingo.me/flamingo/v3/core/oauth/domain"
+	"github.com/gomodule/redigo/redis"
+)
+
+type (
+	// MemoryLogoutRegistry keeps back-channel logouts in memory, it is only suitable for a single instance
+	MemoryLogoutRegistry struct {
+		mu       sync.Mutex
+		logouts  map[string]time.Time
+		lifetime time.Duration
+	}
+
+	// RedisLogoutRegistry keeps back-channel logouts in the redis of the session backend, so all instances see them
+	RedisLogoutRegistry struct {
+		pool     *redis.Pool
+		lifetime time.Duration
+	}
+)
+
+var (
+	_ domain.LogoutRegistry = (*MemoryLogoutRegistry)(nil)
+	_ domain.LogoutRegistry = (*RedisLogoutRegistry)(nil)
+)
+
+// logoutKeys returns the registry keys for a provider session and a user
+func logoutKeys(provider, sid, sub string) []string {
+	var keys []string
+	if sid != "" {
+		keys = append(keys, "flamingo.oauth.logout:"+provider+":sid:"+sid)
+	}
+	if sub != "" {
+		keys = append(keys, "flamingo.oauth.logout:"+provider+":sub:"+sub)
+	}
+	return keys
+}
+
+// Inject configuration
+func (m *MemoryLogoutRegistry) Inject(cfg *struct {
+	Lifetime time.Duration `inject:"config:oauth.logout.backChannel.lifetime"`
+}) {
+	m.logouts = make(map[string]time.Time)
+	m.lifetime = cfg.Lifetime
+}
+
+// Logout records a logout and removes all logouts older than the lifetime
+func (m *MemoryLogoutRegistry) Logout(_ context.Context, provider, sid, sub string, at time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.logouts == nil {
+		m.logouts = make(map[string]time.Time)
+	}
+
+	for key, logout := range m.logouts {
+		if m.lifetime > 0 && time.Since(logout) > m.lifetime {
+			delete(m.logouts, key)
+		}
+	}
+
+	for _, key := range logoutKeys(provider, sid, sub) {
+		m.logouts[key] = at
+	}
+
+	return nil
+}
+
+// LoggedOutSince checks for a logout after the given time
+func (m *MemoryLogoutRegistry) LoggedOutSince(_ context.Context, provider, sid, sub string, since time.Time) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, key := range logoutKeys(provider, sid, sub) {
+		if logout, ok := m.logouts[key]; ok && !logout.Before(since) {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// Inject dependencies
+func (r *RedisLogoutRegistry) Inject(pool *redis.Pool, cfg *struct {
+	Lifetime time.Duration `inject:"config:oauth.logout.backChannel.lifetime"`
+}) {
+	r.pool = pool
+	r.lifetime = cfg.Lifetime
+}
+
+// Logout records a logout, which expires after the lifetime
+func (r *RedisLogoutRegistry) Logout(_ context.Context, provider, sid, sub string, at time.Time) error {
+	conn := r.pool.Get()
+	defer conn.Close()
+
+	for _, key := range logoutKeys(provider, sid, sub) {
+		args := redis.Args{key, at.Unix()}
+		if r.lifetime > 0 {
+			args = args.Add("EX", int64(r.lifetime/time.Second))
+		}
+		if _, err := conn.Do("SET", args...); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// LoggedOutSince checks for a logout after the given time
+func (r *RedisLogoutRegistry) LoggedOutSince(_ context.Context, provider, sid, sub string, since time.Time) (bool, error) {
+	keys := logoutKeys(provider, sid, sub)
+	if len(keys) == 0 {
+		return false, nil
+	}
+
+	conn := r.pool.Get()
+	defer conn.Close()
+
+	values, err := redis.Strings(conn.Do("MGET", redis.Args{}.AddFlat(keys)...))
+	if err != nil {
+		return false, err
+	}
+
+	for _, value := range values {
+		if value == "" {
+			continue
+		}
+		logout, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return false, err
+		}
+		if logout >= since.Unix() {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
@@ -0,0 +1,39 @@
+package infrastructure
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryLogoutRegistry(t *testing.T) {
+	registry := new(MemoryLogoutRegistry)
+	registry.Inject(&struct {
+		Lifetime time.Duration `inject:"config:oauth.logout.backChannel.lifetime"`
+	}{Lifetime: time.Hour})
+
+	ctx := context.Background()
+	login := time.Now().Add(-time.Minute)
+
+	require.NoError(t, registry.Logout(ctx, "default", "session-1", "", time.Now()))
+	require.NoError(t, registry.Logout(ctx, "default", "", "user-2", time.Now()))
+
+	loggedOut, err := registry.LoggedOutSince(ctx, "default", "session-1", "user-1", login)
+	require.NoError(t, err)
+	assert.True(t, loggedOut, "the provider session is logged out")
+
+	loggedOut, _ = registry.LoggedOutSince(ctx, "default", "session-2", "user-1", login)
+	assert.False(t, loggedOut, "other sessions of the user stay logged in")
+
+	loggedOut, _ = registry.LoggedOutSince(ctx, "default", "session-3", "user-2", login)
+	assert.True(t, loggedOut, "all sessions of the user are logged out")
+
+	loggedOut, _ = registry.LoggedOutSince(ctx, "default", "session-3", "user-2", time.Now().Add(time.Minute))
+	assert.False(t, loggedOut, "sessions logged in after the logout are not affected")
+
+	loggedOut, _ = registry.LoggedOutSince(ctx, "other", "session-1", "user-2", login)
+	assert.False(t, loggedOut, "logouts are per provider")
+}
@@ -0,0 +1,219 @@
+package infrastructure
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"sync"
+	"time"
+
+	"flamingo.me/flamingo/v3/core/oauth/domain"
+	"github.com/gomodule/redigo/redis"
+	"github.com/pkg/errors"
+)
+
+type (
+	// LocalRefreshGroup shares refreshes between the requests of this instance.
+	// Results are kept for the grace period, for requests which still carry the old refresh token.
+	LocalRefreshGroup struct {
+		mu    sync.Mutex
+		calls map[string]*refreshCall
+		grace time.Duration
+	}
+
+	refreshCall struct {
+		done     chan struct{}
+		value    []byte
+		err      error
+		finished time.Time
+	}
+
+	// RedisRefreshGroup shares refreshes between all instances, using a lock and the result in the redis of the session backend.
+	// The result contains the tokens, so it is stored encrypted with a key derived from the session secret.
+	RedisRefreshGroup struct {
+		local       *LocalRefreshGroup
+		aead        cipher.AEAD
+		pool        *redis.Pool
+		lockTimeout time.Duration
+		grace       time.Duration
+		pollDelay   time.Duration
+	}
+)
+
+var (
+	_ domain.RefreshGroup = (*LocalRefreshGroup)(nil)
+	_ domain.RefreshGroup = (*RedisRefreshGroup)(nil)
+
+	// releaseLock deletes the lock only if it is still held by the caller
+	releaseLock = redis.NewScript(1, `if redis.call("GET", KEYS[1]) == ARGV[1] then return redis.call("DEL", KEYS[1]) else return 0 end`)
+)
+
+// Inject configuration
+func (l *LocalRefreshGroup) Inject(cfg *struct {
+	Grace time.Duration `inject:"config:oauth.refresh.grace"`
+}) {
+	l.grace = cfg.Grace
+}
+
+// Do runs the refresh once for concurrent calls with the same key
+func (l *LocalRefreshGroup) Do(ctx context.Context, key string, refresh func() ([]byte, error)) ([]byte, error) {
+	l.mu.Lock()
+	if l.calls == nil {
+		l.calls = make(map[string]*refreshCall)
+	}
+	for k, c := range l.calls {
+		if !c.finished.IsZero() && time.Since(c.finished) > l.grace {
+			delete(l.calls, k)
+		}
+	}
+
+	if c, ok := l.calls[key]; ok {
+		l.mu.Unlock()
+		select {
+		case <-c.done:
+			return c.value, c.err
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	c := &refreshCall{done: make(chan struct{})}
+	l.calls[key] = c
+	l.mu.Unlock()
+
+	value, err := refresh()
+
+	l.mu.Lock()
+	c.value, c.err, c.finished = value, err, time.Now()
+	if err != nil {
+		// only successful refreshes are shared with later requests
+		delete(l.calls, key)
+	}
+	l.mu.Unlock()
+	close(c.done)
+
+	return value, err
+}
+
+// Inject dependencies
+func (r *RedisRefreshGroup) Inject(local *LocalRefreshGroup, pool *redis.Pool, cfg *struct {
+	LockTimeout   time.Duration `inject:"config:oauth.refresh.lockTimeout"`
+	Grace         time.Duration `inject:"config:oauth.refresh.grace"`
+	SessionSecret string        `inject:"config:session.secret"`
+}) {
+	aead, err := newResultCipher(cfg.SessionSecret)
+	if err != nil {
+		panic(err)
+	}
+	r.aead = aead
+	r.local = local
+	r.pool = pool
+	r.lockTimeout = cfg.LockTimeout
+	r.grace = cfg.Grace
+	r.pollDelay = 50 * time.Millisecond
+}
+
+// Do runs the refresh once for concurrent calls with the same key on all instances
+func (r *RedisRefreshGroup) Do(ctx context.Context, key string, refresh func() ([]byte, error)) ([]byte, error) {
+	return r.local.Do(ctx, key, func() ([]byte, error) {
+		return r.do(ctx, key, refresh)
+	})
+}
+
+func (r *RedisRefreshGroup) do(ctx context.Context, key string, refresh func() ([]byte, error)) ([]byte, error) {
+	lockKey := "flamingo.oauth.refresh:" + key + ":lock"
+	resultKey := "flamingo.oauth.refresh:" + key + ":result"
+
+	conn := r.pool.Get()
+	defer conn.Close()
+
+	lockID := make([]byte, 16)
+	if _, err := rand.Read(lockID); err != nil {
+		return nil, err
+	}
+	lock := base64.RawURLEncoding.EncodeToString(lockID)
+
+	deadline := time.Now().Add(r.lockTimeout)
+	for time.Now().Before(deadline) {
+		if value, err := redis.Bytes(conn.Do("GET", resultKey)); err == nil {
+			return r.open(resultKey, value)
+		} else if err != redis.ErrNil {
+			return nil, err
+		}
+
+		_, err := redis.String(conn.Do("SET", lockKey, lock, "NX", "PX", int64(r.lockTimeout/time.Millisecond)))
+		if err == nil {
+			return r.refreshLocked(conn, lockKey, resultKey, lock, refresh)
+		}
+		if err != redis.ErrNil {
+			return nil, err
+		}
+
+		// another instance refreshes, wait for its result
+		select {
+		case <-time.After(r.pollDelay):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	return nil, errors.New("timeout waiting for the token refresh of another instance")
+}
+
+func (r *RedisRefreshGroup) refreshLocked(conn redis.Conn, lockKey, resultKey, lock string, refresh func() ([]byte, error)) ([]byte, error) {
+	defer releaseLock.Do(conn, lockKey, lock)
+
+	// the refresh might have finished between the check and acquiring the lock
+	if value, err := redis.Bytes(conn.Do("GET", resultKey)); err == nil {
+		return r.open(resultKey, value)
+	}
+
+	value, err := refresh()
+	if err != nil {
+		return nil, err
+	}
+
+	// the refresh token is used up, so the new token is returned even if it can not be shared
+	if r.grace > 0 {
+		if sealed, err := r.seal(resultKey, value); err == nil {
+			_, _ = conn.Do("SET", resultKey, sealed, "PX", int64(r.grace/time.Millisecond))
+		}
+	}
+
+	return value, nil
+}
+
+// newResultCipher derives an AES-256-GCM cipher from the session secret, separate from the key of the session encryption
+func newResultCipher(secret string) (cipher.AEAD, error) {
+	key := sha256.Sum256([]byte("flamingo.oauth.refresh:" + secret))
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// seal encrypts a refresh result, the result key is authenticated so a result can not be moved to another key
+func (r *RedisRefreshGroup) seal(resultKey string, value []byte) ([]byte, error) {
+	nonce := make([]byte, r.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return r.aead.Seal(nonce, nonce, value, []byte(resultKey)), nil
+}
+
+// open decrypts a refresh result stored by seal
+func (r *RedisRefreshGroup) open(resultKey string, sealed []byte) ([]byte, error) {
+	if len(sealed) < r.aead.NonceSize() {
+		return nil, errors.New("invalid refresh result")
+	}
+	nonce, ciphertext := sealed[:r.aead.NonceSize()], sealed[r.aead.NonceSize():]
+	value, err := r.aead.Open(nil, nonce, ciphertext, []byte(resultKey))
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid refresh result")
+	}
+	return value, nil
+}
@@ -0,0 +1,94 @@
+package infrastructure
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLocalRefreshGroup(t *testing.T) {
+	group := new(LocalRefreshGroup)
+	group.Inject(&struct {
+		Grace time.Duration `inject:"config:oauth.refresh.grace"`
+	}{Grace: time.Minute})
+
+	t.Run("concurrent refreshes share one call", func(t *testing.T) {
+		var calls int32
+		release := make(chan struct{})
+		refresh := func() ([]byte, error) {
+			atomic.AddInt32(&calls, 1)
+			<-release
+			return []byte("token"), nil
+		}
+
+		var wg sync.WaitGroup
+		results := make([][]byte, 10)
+		for i := range results {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				results[i], _ = group.Do(context.Background(), "shared", refresh)
+			}(i)
+		}
+		time.Sleep(10 * time.Millisecond)
+		close(release)
+		wg.Wait()
+
+		assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+		for _, result := range results {
+			assert.Equal(t, []byte("token"), result)
+		}
+
+		result, err := group.Do(context.Background(), "shared", refresh)
+		assert.NoError(t, err)
+		assert.Equal(t, []byte("token"), result, "late requests with the old refresh token get the result")
+		assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+	})
+
+	t.Run("failures are not shared with later calls", func(t *testing.T) {
+		var calls int32
+		refresh := func() ([]byte, error) {
+			atomic.AddInt32(&calls, 1)
+			return nil, errors.New("refresh failed")
+		}
+
+		_, err := group.Do(context.Background(), "failing", refresh)
+		assert.Error(t, err)
+		_, err = group.Do(context.Background(), "failing", refresh)
+		assert.Error(t, err)
+		assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+	})
+}
+
+func TestRedisRefreshGroupResultEncryption(t *testing.T) {
+	group := new(RedisRefreshGroup)
+	group.Inject(new(LocalRefreshGroup), nil, &struct {
+		LockTimeout   time.Duration `inject:"config:oauth.refresh.lockTimeout"`
+		Grace         time.Duration `inject:"config:oauth.refresh.grace"`
+		SessionSecret string        `inject:"config:session.secret"`
+	}{SessionSecret: "secret"})
+
+	sealed, err := group.seal("result", []byte("token"))
+	assert.NoError(t, err)
+	assert.NotContains(t, string(sealed), "token", "the tokens are not stored in plaintext")
+
+	value, err := group.open("result", sealed)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("token"), value)
+
+	_, err = group.open("other-result", sealed)
+	assert.Error(t, err, "results are bound to their key")
+
+	other := new(RedisRefreshGroup)
+	other.aead, _ = newResultCipher("other-secret")
+	_, err = other.open("result", sealed)
+	assert.Error(t, err, "results can only be read with the same session secret")
+
+	_, err = group.open("result", []byte("short"))
+	assert.Error(t, err)
+}
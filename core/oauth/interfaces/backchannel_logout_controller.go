@@ -0,0 +1,62 @@
+package interfaces
+
+import (
+	"context"
+	"net/http"
+
+	"flamingo.me/flamingo/v3/core/oauth/application"
+	"flamingo.me/flamingo/v3/framework/flamingo"
+	"flamingo.me/flamingo/v3/framework/web"
+)
+
+type (
+	// BackChannelLogoutController receives the OpenID Connect back-channel logout requests of the identity provider
+	BackChannelLogoutController struct {
+		responder   *web.Responder
+		logger      flamingo.Logger
+		authManager *application.AuthManager
+	}
+)
+
+// Inject BackChannelLogoutController dependencies
+func (b *BackChannelLogoutController) Inject(
+	responder *web.Responder,
+	logger flamingo.Logger,
+	authManager *application.AuthManager,
+) {
+	b.responder = responder
+	b.logger = logger.WithField(flamingo.LogKeyModule, "oauth").WithField(flamingo.LogKeyCategory, "logout")
+	b.authManager = authManager
+}
+
+// Post handler for the logout token of the provider given by the `provider` param, or the default provider
+func (b *BackChannelLogoutController) Post(ctx context.Context, request *web.Request) web.Result {
+	provider, err := b.authManager.RequestProvider(request)
+	if err != nil {
+		return b.badRequest(ctx, err)
+	}
+
+	logoutToken, err := request.Form1("logout_token")
+	if err != nil {
+		return b.badRequest(ctx, err)
+	}
+
+	if err := b.authManager.BackChannelLogout(ctx, provider, logoutToken); err != nil {
+		return b.badRequest(ctx, err)
+	}
+
+	response := b.responder.HTTP(http.StatusOK, nil)
+	response.Header.Set("Cache-Control", "no-store")
+	return response
+}
+
+func (b *BackChannelLogoutController) badRequest(ctx context.Context, err error) web.Result {
+	b.logger.WithContext(ctx).Warn("back-channel logout failed: ", err)
+
+	response := b.responder.Data(map[string]string{
+		"error":             "invalid_request",
+		"error_description": err.Error(),
+	}).Status(http.StatusBadRequest)
+	response.Header.Set("Cache-Control", "no-store")
+	return response
+}
@@ -2,7 +2,6 @@ package interfaces
 
 import (
 	"context"
-	"fmt"
 	"net/url"
 
 	"github.com/pkg/errors"
@@ -67,12 +66,21 @@ func (cc *CallbackController) Inject(
 // Get handler for callbacks
 func (cc *CallbackController) Get(ctx context.Context, request *web.Request) web.Result {
 	// Verify state and errors.
-	defer cc.authManager.DeleteAuthState(request.Session())
+	loginState, err := cc.authManager.ConsumeLoginState(request.Session(), request.Request().URL.Query().Get("state"))
+	if err != nil {
+		cc.logger.Error("core.auth.callback state verification failed", err)
+		stats.Record(ctx, loginFailedCount.M(1))
+		return cc.responder.ServerError(err)
+	}
 
-	if state, ok := cc.authManager.LoadAuthState(request.Session()); !ok || state != request.Request().URL.Query().Get("state") {
-		cc.logger.Error(fmt.Sprintf("Invalid State - expected: %v  got: %v", state, request.Request().URL.Query().Get("state")))
+	provider, err := cc.authManager.RequestProvider(request)
+	if err == nil && provider.Name() != loginState.Provider {
+		err = errors.Errorf("login was started for provider %q", loginState.Provider)
+	}
+	if err != nil {
+		cc.logger.Error("core.auth.callback unknown provider", err)
 		stats.Record(ctx, loginFailedCount.M(1))
-		return cc.responder.ServerError(errors.New("Invalid State"))
+		return cc.responder.NotFound(err)
 	}
 
 	code := request.Request().URL.Query().Get("code")
@@ -84,13 +92,21 @@ func (cc *CallbackController) Get(ctx context.Context, request *web.Request) web
 		stats.Record(ctx, loginFailedCount.M(1))
 		return cc.responder.ServerError(errors.WithStack(err))
 	} else if code != "" {
-		oauth2Token, err := cc.authManager.OAuth2Config(ctx, request).Exchange(cc.authManager.OAuthCtx(ctx), code)
+		oauth2Token, err := cc.authManager.ProviderOAuth2Config(ctx, request, provider).Exchange(cc.authManager.OAuthCtx(ctx), code, cc.authManager.ExchangeOptions(loginState)...)
 		if err != nil {
 			cc.logger.Error("core.auth.callback Error OAuth2Config Exchange", err)
 			stats.Record(ctx, loginFailedCount.M(1))
 			return cc.responder.ServerError(errors.WithStack(err))
 		}
 
+		err = cc.authManager.VerifyNonce(ctx, provider, oauth2Token, loginState)
+		if err != nil {
+			cc.logger.Error("core.auth.callback ID token verification failed", err)
+			stats.Record(ctx, loginFailedCount.M(1))
+			return cc.responder.ServerError(errors.WithStack(err))
+		}
+
+		cc.authManager.StoreProvider(request.Session(), provider)
 		err = cc.authManager.StoreTokenDetails(ctx, request.Session(), oauth2Token)
 		if err != nil {
 			cc.logger.Error("core.auth.callback Error", err)
@@ -107,9 +123,8 @@ func (cc *CallbackController) Get(ctx context.Context, request *web.Request) web
 		stats.Record(ctx, loginFailedCount.M(1))
 	}
 
-	if redirect, ok := request.Session().Load("auth.redirect"); ok {
-		request.Session().Delete("auth.redirect")
-		redirectURL, _ := url.Parse(redirect.(string))
+	if loginState.RedirectURL != "" {
+		redirectURL, _ := url.Parse(loginState.RedirectURL)
 		return cc.responder.URLRedirect(redirectURL)
 	}
 	return cc.responder.RouteRedirect("home", nil)
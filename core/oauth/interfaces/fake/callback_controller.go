@@ -47,6 +47,7 @@ func (c *CallbackController) Get(_ context.Context, request *web.Request) web.Re
 	}
 
 	request.Session().Store(fake.UserSessionKey, user)
+	request.Session().Regenerate()
 
 	value, _ := request.Session().Load("auth.redirect")
 	redirectURL, ok := value.(string)
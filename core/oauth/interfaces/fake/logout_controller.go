@@ -39,7 +39,5 @@ func (l *LogoutController) Get(ctx context.Context, request *web.Request) web.Re
 		Session: request.Session(),
 	})
 
-	redirectURL, _ := l.router.Absolute(request, "", nil)
-
-	return l.responder.URLRedirect(redirectURL)
+	return l.responder.URLRedirect(l.authManager.PostLogoutRedirectURL(request))
 }
@@ -6,7 +6,6 @@ import (
 
 	"flamingo.me/flamingo/v3/core/oauth/application"
 	"flamingo.me/flamingo/v3/framework/web"
-	uuid "github.com/satori/go.uuid"
 	"golang.org/x/oauth2"
 )
 
@@ -56,11 +55,13 @@ func (l *LoginController) Get(c context.Context, request *web.Request) web.Resul
 		redirecturl = absolute.String()
 	}
 
-	state := uuid.NewV4().String()
-	l.authManager.StoreAuthState(request.Session(), state)
-	request.Session().Store("auth.redirect", redirecturl)
+	provider, err := l.authManager.RequestProvider(request)
+	if err != nil {
+		return l.responder.NotFound(err)
+	}
+
+	state, parameters := l.authManager.NewLoginState(request.Session(), provider, redirecturl)
 
-	var parameters []oauth2.AuthCodeOption
 	for _, hook := range l.parameterHooks {
 		keyValue := hook.Parameters(c, request)
 		for key, value := range keyValue {
@@ -68,6 +69,6 @@ func (l *LoginController) Get(c context.Context, request *web.Request) web.Resul
 		}
 	}
 
-	redirectURL, _ := url.Parse(l.authManager.OAuth2Config(c, request).AuthCodeURL(state, parameters...))
+	redirectURL, _ := url.Parse(l.authManager.ProviderOAuth2Config(c, request, provider).AuthCodeURL(state, parameters...))
 	return l.responder.URLRedirect(redirectURL)
 }
@@ -46,11 +46,14 @@ func (d *DefaultLogoutRedirect) Inject(manager *application.AuthManager, router
 	d.router = router
 }
 
-// GetRedirectURL builds default redirect URL for logout
+// GetRedirectURL builds the RP-initiated logout URL, with the session's ID token as id_token_hint and the validated post_logout_redirect_uri
 func (d *DefaultLogoutRedirect) GetRedirectURL(c context.Context, r *web.Request, u *url.URL) (*url.URL, error) {
-	query := url.Values{}
-	ru, _ := d.router.Absolute(r, "", nil)
-	query.Set("redirect_uri", ru.String())
+	query := u.Query()
+	if idToken, ok := d.authManager.StoredRawIDToken(r.Session()); ok {
+		query.Set("id_token_hint", idToken)
+	}
+	query.Set("client_id", d.authManager.SessionProvider(r.Session()).Config().ClientID)
+	query.Set("post_logout_redirect_uri", d.authManager.PostLogoutRedirectURL(r).String())
 	u.RawQuery = query.Encode()
 	return u, nil
 }
@@ -74,23 +77,12 @@ func (l *LogoutController) Inject(
 
 // Get handler for logout
 func (l *LogoutController) Get(ctx context.Context, request *web.Request) web.Result {
-	var claims struct {
-		EndSessionEndpoint string `json:"end_session_endpoint"`
-	}
-
-	ru, _ := l.router.Absolute(request, "", nil)
-
-	err := l.authManager.OpenIDProvider().Claims(&claims)
-	if err != nil {
-		l.logoutLocally(ctx, request)
-		l.logger.Error("Logout locally only. Could not unmarshal raw fields", err.Error())
-		return l.responder.URLRedirect(ru)
-	}
+	ru := l.authManager.PostLogoutRedirectURL(request)
 
-	endURL, err := url.Parse(claims.EndSessionEndpoint)
+	endURL, err := l.authManager.SessionProvider(request.Session()).EndSessionEndpoint()
 	if err != nil {
 		l.logoutLocally(ctx, request)
-		l.logger.Error("Logout locally only. Could not parse end_session_endpoint claim to logout from IDP", err.Error())
+		l.logger.Error("Logout locally only. Could not get end_session_endpoint to logout from IDP", err.Error())
 		return l.responder.URLRedirect(ru)
 	}
 
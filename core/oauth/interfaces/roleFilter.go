@@ -0,0 +1,29 @@
+package interfaces
+
+import (
+	"context"
+	"net/http"
+
+	"flamingo.me/flamingo/v3/core/oauth/application"
+	"flamingo.me/flamingo/v3/framework/web"
+)
+
+// RoleRefreshFilter checks the mapped roles of the session before the controller runs.
+// Roles are also mapped while templates are rendered, after the session has been saved, so a change detected there
+// could not regenerate the session ID with the same response.
+type RoleRefreshFilter struct {
+	roleProvider *application.AuthRoleProvider
+}
+
+var _ web.Filter = new(RoleRefreshFilter)
+
+// Inject RoleRefreshFilter dependencies
+func (f *RoleRefreshFilter) Inject(roleProvider *application.AuthRoleProvider) {
+	f.roleProvider = roleProvider
+}
+
+// Filter refreshes the roles of the session
+func (f *RoleRefreshFilter) Filter(ctx context.Context, r *web.Request, w http.ResponseWriter, chain *web.FilterChain) web.Result {
+	f.roleProvider.RefreshRoles(ctx, r.Session())
+	return chain.Next(ctx, r, w)
+}
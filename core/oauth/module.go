@@ -4,9 +4,12 @@ import (
 	"flamingo.me/dingo"
 	"flamingo.me/flamingo/v3/core/oauth/application"
 	fakeService "flamingo.me/flamingo/v3/core/oauth/application/fake"
+	"flamingo.me/flamingo/v3/core/oauth/domain"
+	"flamingo.me/flamingo/v3/core/oauth/infrastructure"
 	"flamingo.me/flamingo/v3/core/oauth/interfaces"
 	fakeController "flamingo.me/flamingo/v3/core/oauth/interfaces/fake"
 	"flamingo.me/flamingo/v3/core/security/application/role"
+	"flamingo.me/flamingo/v3/core/security/application/voter"
 	"flamingo.me/flamingo/v3/framework/config"
 	"flamingo.me/flamingo/v3/framework/flamingo"
 	"flamingo.me/flamingo/v3/framework/web"
@@ -14,16 +17,31 @@ import (
 
 // Module for core.auth
 type Module struct {
-	UseFake                     bool   `inject:"config:oauth.useFake"`
-	PreventSimultaneousSessions bool   `inject:"config:oauth.preventSimultaneousSessions"`
-	SessionBackend              string `inject:"config:session.backend"`
+	UseFake                     bool         `inject:"config:oauth.useFake"`
+	PreventSimultaneousSessions bool         `inject:"config:oauth.preventSimultaneousSessions"`
+	SessionBackend              string       `inject:"config:session.backend"`
+	RoleMapping                 config.Slice `inject:"config:oauth.roles.mapping,optional"`
 }
 
 // Configure core.auth module
 func (m *Module) Configure(injector *dingo.Injector) {
+	if err := application.ValidateRoleMapping(m.RoleMapping); err != nil {
+		panic(err)
+	}
+
 	injector.Bind(application.AuthManager{}).In(dingo.ChildSingleton)
 	injector.Bind(new(interfaces.LogoutRedirectAware)).To(interfaces.DefaultLogoutRedirect{})
 	flamingo.BindEventSubscriber(injector).To(&application.EventHandler{})
+	injector.Bind(new(domain.UserFactory)).To(domain.DefaultUserFactory{})
+	injector.Bind(domain.UserMappingService{}).In(dingo.ChildSingleton)
+	injector.Bind(infrastructure.LocalRefreshGroup{}).In(dingo.ChildSingleton)
+	if m.SessionBackend == "redis" {
+		injector.Bind(new(domain.LogoutRegistry)).To(infrastructure.RedisLogoutRegistry{}).In(dingo.ChildSingleton)
+		injector.Bind(new(domain.RefreshGroup)).To(infrastructure.RedisRefreshGroup{}).In(dingo.ChildSingleton)
+	} else {
+		injector.Bind(new(domain.LogoutRegistry)).To(infrastructure.MemoryLogoutRegistry{}).In(dingo.ChildSingleton)
+		injector.Bind(new(domain.RefreshGroup)).To(infrastructure.LocalRefreshGroup{}).In(dingo.ChildSingleton)
+	}
 	if !m.UseFake {
 		injector.Bind(new(application.UserServiceInterface)).To(application.UserService{})
 		injector.Bind(new(interfaces.LoginControllerInterface)).To(interfaces.LoginController{})
@@ -37,6 +55,8 @@ func (m *Module) Configure(injector *dingo.Injector) {
 	}
 
 	injector.BindMulti(new(role.Provider)).To(application.AuthRoleProvider{})
+	injector.BindMulti(new(web.Filter)).To(interfaces.RoleRefreshFilter{})
+	injector.BindMulti(new(voter.SubjectAttributesProvider)).To(application.UserSubjectAttributes{})
 
 	web.BindRoutes(injector, new(routes))
 }
@@ -65,18 +85,46 @@ func (m *Module) DefaultConfig() config.Map {
 					"email": "email",
 					"name":  "name",
 				},
+				"claims": config.Map{},
 			},
 			"preventSimultaneousSessions": false,
+			"providers":                   config.Map{},
+			"defaultProvider":             "default",
+			"pkce":                        true,
+			"stateLifetime":               "10m",
+			"maxLoginStates":              10,
+			"bearer": config.Map{
+				"enabled":  false,
+				"audience": "",
+				"issuer":   "",
+				"jwksFile": "",
+			},
+			"roles": config.Map{
+				"mapping":       config.Slice{},
+				"cacheLifetime": "5m",
+			},
+			"refresh": config.Map{
+				"skew":        "1m",
+				"lockTimeout": "10s",
+				"grace":       "30s",
+			},
+			"logout": config.Map{
+				"allowedRedirectURLs": config.Slice{},
+				"backChannel": config.Map{
+					"lifetime": "720h",
+				},
+			},
 		},
 	}
 }
 
 type routes struct {
-	login    interfaces.LoginControllerInterface
-	logout   interfaces.LogoutControllerInterface
-	callback interfaces.CallbackControllerInterface
-	user     *interfaces.UserController
-	UseFake  bool `inject:"config:oauth.useFake"`
+	login             interfaces.LoginControllerInterface
+	logout            interfaces.LogoutControllerInterface
+	callback          interfaces.CallbackControllerInterface
+	backChannelLogout *interfaces.BackChannelLogoutController
+	user              *interfaces.UserController
+	UseFake           bool `inject:"config:oauth.useFake"`
 }
 
 // Inject routes dependencies
@@ -84,12 +132,14 @@ func (r *routes) Inject(
 	login interfaces.LoginControllerInterface,
 	logout interfaces.LogoutControllerInterface,
 	callback interfaces.CallbackControllerInterface,
+	backChannelLogout *interfaces.BackChannelLogoutController,
 	user *interfaces.UserController,
 	fake *bool,
 ) {
 	r.login = login
 	r.logout = logout
 	r.callback = callback
+	r.backChannelLogout = backChannelLogout
 	r.user = user
 	r.UseFake = *fake
 }
@@ -97,15 +147,21 @@ func (r *routes) Inject(
 // Routes module
 func (r *routes) Routes(registry *web.RouterRegistry) {
 	registry.Route("/auth/login", `auth.login(redirecturl?="")`)
+	registry.Route("/auth/login/:provider", `auth.login(provider, redirecturl?="")`)
 	registry.HandleGet("auth.login", r.login.Get)
 	if r.UseFake {
 		registry.Route("/auth/callback", `auth.callback(group?="")`)
+		registry.Route("/auth/callback/:provider", `auth.callback(provider, group?="")`)
 	} else {
 		registry.Route("/auth/callback", `auth.callback`)
+		registry.Route("/auth/callback/:provider", `auth.callback(provider)`)
 	}
 	registry.HandleGet("auth.callback", r.callback.Get)
-	registry.Route("/auth/logout", "auth.logout")
+	registry.Route("/auth/logout", `auth.logout(redirecturl?="")`)
 	registry.HandleGet("auth.logout", r.logout.Get)
+	registry.Route("/auth/backchannel-logout", "auth.backchannelLogout")
+	registry.Route("/auth/backchannel-logout/:provider", "auth.backchannelLogout(provider)")
+	registry.HandlePost("auth.backchannelLogout", r.backChannelLogout.Post)
 
 	registry.HandleData("user", r.user.Data)
 }
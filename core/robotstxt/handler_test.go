@@ -0,0 +1,84 @@
+package robotstxt
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"flamingo.me/flamingo/v3/framework/flamingo"
+)
+
+type handlerConfig = struct {
+	Filepath   string          `inject:"config:robotstxt.filepath"`
+	FileSystem http.FileSystem `inject:"flamingo.filesystem.robotstxt,optional"`
+}
+
+func serve(h *handler) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/robots.txt", nil))
+	return rec
+}
+
+func TestHandler_ServeHTTP(t *testing.T) {
+	t.Run("file on disk", func(t *testing.T) {
+		dir, err := ioutil.TempDir("", "robotstxt")
+		require.NoError(t, err)
+		defer os.RemoveAll(dir)
+		require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "robots.txt"), []byte("User-agent: *"), 0644))
+
+		h := new(handler)
+		h.Inject(&handlerConfig{Filepath: filepath.Join(dir, "robots.txt")})
+
+		rec := serve(h)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "User-agent: *", rec.Body.String())
+	})
+
+	t.Run("file system", func(t *testing.T) {
+		h := new(handler)
+		h.Inject(&handlerConfig{
+			Filepath:   "static/robots.txt",
+			FileSystem: flamingo.MemoryFileSystem{"static/robots.txt": "Disallow: /"},
+		})
+
+		rec := serve(h)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "Disallow: /", rec.Body.String())
+	})
+
+	t.Run("missing file", func(t *testing.T) {
+		h := new(handler)
+		h.Inject(&handlerConfig{Filepath: "robots.txt", FileSystem: flamingo.MemoryFileSystem{}})
+
+		assert.Equal(t, http.StatusNotFound, serve(h).Code)
+	})
+}
+
+func TestModule_DefaultMux(t *testing.T) {
+	calls := 0
+	mux := http.NewServeMux()
+	m := &Module{
+		DefaultMux: mux,
+		HandlerProvider: func() *handler {
+			calls++
+			h := new(handler)
+			h.Inject(&handlerConfig{Filepath: "robots.txt", FileSystem: flamingo.MemoryFileSystem{"robots.txt": "Disallow: /"}})
+			return h
+		},
+	}
+	m.Configure(nil)
+	assert.Equal(t, 0, calls, "the handler is resolved on request")
+
+	for i := 0; i < 2; i++ {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/robots.txt", nil))
+		assert.Equal(t, "Disallow: /", rec.Body.String())
+	}
+	assert.Equal(t, 2, calls)
+}
@@ -2,9 +2,11 @@ package robotstxt
 
 import (
 	"net/http"
+	"path/filepath"
 
 	"flamingo.me/dingo"
 	"flamingo.me/flamingo/v3/framework/config"
+	"flamingo.me/flamingo/v3/framework/flamingo"
 )
 
 type (
@@ -12,14 +14,26 @@ type (
 	Module struct {
 		DefaultMux *http.ServeMux `inject:",optional"`
 		Filepath   string         `inject:"config:robotstxt.filepath"`
+		// HandlerProvider resolves the handler per request, so file systems bound by modules configured later are available
+		HandlerProvider handlerProvider `inject:""`
+	}
+
+	handlerProvider func() *handler
+
+	// handler serves the robots.txt from the http.FileSystem bound with flamingo.FileSystemRobotsTxt,
+	// or from the configured file on disk
+	handler struct {
+		fs   http.FileSystem
+		name string
 	}
 )
 
 // Configure DI
 func (m *Module) Configure(injector *dingo.Injector) {
-	if m.DefaultMux != nil {
+	if m.DefaultMux != nil && m.HandlerProvider != nil {
+		provider := m.HandlerProvider
 		m.DefaultMux.HandleFunc("/robots.txt", func(rw http.ResponseWriter, req *http.Request) {
-			http.ServeFile(rw, req, m.Filepath)
+			provider().ServeHTTP(rw, req)
 		})
 	}
 }
@@ -30,3 +44,21 @@ func (m *Module) DefaultConfig() config.Map {
 		"robotstxt.filepath": "frontend/robots.txt",
 	}
 }
+
+// Inject dependencies
+func (h *handler) Inject(cfg *struct {
+	Filepath   string          `inject:"config:robotstxt.filepath"`
+	FileSystem http.FileSystem `inject:"flamingo.filesystem.robotstxt,optional"`
+}) {
+	h.fs = cfg.FileSystem
+	h.name = cfg.Filepath
+	if h.fs == nil {
+		h.fs = http.Dir(filepath.Dir(cfg.Filepath))
+		h.name = filepath.Base(cfg.Filepath)
+	}
+}
+
+// ServeHTTP serves the robots.txt
+func (h *handler) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	flamingo.ServeFile(rw, req, h.fs, h.name)
+}
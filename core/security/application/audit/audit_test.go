@@ -0,0 +1,172 @@
+package audit
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"flamingo.me/flamingo/v3/core/security/application/voter"
+	voterMocks "flamingo.me/flamingo/v3/core/security/application/voter/mocks"
+	"flamingo.me/flamingo/v3/core/security/domain"
+	"flamingo.me/flamingo/v3/framework/config"
+	"flamingo.me/flamingo/v3/framework/flamingo"
+	"flamingo.me/flamingo/v3/framework/web"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type (
+	recordingEventRouter struct {
+		events []flamingo.Event
+	}
+
+	recordingLogger struct {
+		flamingo.NullLogger
+		fields  map[flamingo.LogKey]interface{}
+		entries *[]map[flamingo.LogKey]interface{}
+	}
+)
+
+func (r *recordingEventRouter) Dispatch(_ context.Context, event flamingo.Event) {
+	r.events = append(r.events, event)
+}
+
+func (l *recordingLogger) WithContext(context.Context) flamingo.Logger { return l }
+
+func (l *recordingLogger) WithField(key flamingo.LogKey, value interface{}) flamingo.Logger {
+	return l.WithFields(map[flamingo.LogKey]interface{}{key: value})
+}
+
+func (l *recordingLogger) WithFields(fields map[flamingo.LogKey]interface{}) flamingo.Logger {
+	merged := make(map[flamingo.LogKey]interface{}, len(l.fields)+len(fields))
+	for k, v := range l.fields {
+		merged[k] = v
+	}
+	for k, v := range fields {
+		merged[k] = v
+	}
+	return &recordingLogger{fields: merged, entries: l.entries}
+}
+
+func (l *recordingLogger) Info(...interface{}) {
+	*l.entries = append(*l.entries, l.fields)
+}
+
+func TestAuditor_Audit(t *testing.T) {
+	session := web.EmptySession()
+	request := web.CreateRequest(&http.Request{
+		URL:        &url.URL{Path: "/account"},
+		Header:     http.Header{},
+		RemoteAddr: "10.0.0.1:1234",
+	}, session)
+	ctx := web.ContextWithRequest(web.ContextWithSession(context.Background(), session), request)
+
+	attributes := new(voterMocks.SubjectAttributesProvider)
+	attributes.On("Attributes", ctx, session).Return(map[string]interface{}{"sub": "user-1"})
+
+	eventRouter := new(recordingEventRouter)
+	auditor := new(Auditor)
+	auditor.Inject(eventRouter, []voter.SubjectAttributesProvider{attributes}, &struct {
+		Enabled bool `inject:"config:security.audit.enabled,optional"`
+	}{Enabled: true})
+
+	auditor.Audit(ctx, nil, &domain.AuditEvent{Type: domain.AuditLogin})
+
+	require.Len(t, eventRouter.events, 1)
+	event := eventRouter.events[0].(*domain.AuditEvent)
+	assert.Equal(t, domain.AuditLogin, event.Type)
+	assert.Equal(t, "user-1", event.Subject)
+	assert.Equal(t, "/account", event.Path)
+	assert.Equal(t, "10.0.0.1:1234", event.RemoteAddress)
+	assert.False(t, event.Time.IsZero())
+
+	t.Run("disabled", func(t *testing.T) {
+		disabled := new(Auditor)
+		disabled.Inject(eventRouter, nil, nil)
+		disabled.Audit(ctx, nil, &domain.AuditEvent{Type: domain.AuditLogin})
+
+		var nilAuditor *Auditor
+		nilAuditor.Audit(ctx, nil, &domain.AuditEvent{Type: domain.AuditLogin})
+
+		assert.Len(t, eventRouter.events, 1)
+	})
+}
+
+func TestLogSink_Write(t *testing.T) {
+	var entries []map[flamingo.LogKey]interface{}
+	sink := new(LogSink)
+	sink.Inject(&recordingLogger{entries: &entries}, &struct {
+		AuditLogger flamingo.Logger `inject:"security.audit,optional"`
+		Redact      config.Slice    `inject:"config:security.audit.redact,optional"`
+	}{Redact: config.Slice{"subject", "remoteAddress", "email"}})
+
+	sink.Write(context.Background(), &domain.AuditEvent{
+		Type:          domain.AuditAccessDenied,
+		Time:          time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC),
+		Subject:       "user-1",
+		RemoteAddress: "10.0.0.1",
+		Path:          "/admin",
+		Access: &domain.AccessAudit{
+			Permission: "PermissionAdmin",
+			Strategy:   "affirmative",
+			Votes:      []domain.VoteAudit{{Voter: "*voter.PermissionVoter", Decision: "denied"}},
+		},
+		Fields: map[string]interface{}{"email": "user@example.com", "provider": "main"},
+	})
+
+	require.Len(t, entries, 1)
+	entry := entries[0]
+	assert.Equal(t, "audit", entry[flamingo.LogKeyCategory])
+	assert.Equal(t, domain.AuditAccessDenied, entry["audit_type"])
+	assert.Equal(t, "/admin", entry["audit_path"])
+	assert.Equal(t, "PermissionAdmin", entry["audit_permission"])
+	assert.Equal(t, "*voter.PermissionVoter=denied", entry["audit_votes"])
+	assert.Equal(t, "main", entry["audit_provider"])
+	assert.Regexp(t, "^redacted:[0-9a-f]{16}$", entry["audit_subject"])
+	assert.Regexp(t, "^redacted:[0-9a-f]{16}$", entry["audit_email"])
+	assert.NotContains(t, entry, flamingo.LogKey("audit_sessionId"))
+}
+
+func TestSubscriber_Notify(t *testing.T) {
+	sink := new(recordingSink)
+	sink.On("Write", mock.Anything, mock.Anything).Once()
+
+	eventRouter := new(recordingEventRouter)
+	auditor := new(Auditor)
+	auditor.Inject(eventRouter, nil, &struct {
+		Enabled bool `inject:"config:security.audit.enabled,optional"`
+	}{Enabled: true})
+
+	subscriber := new(Subscriber)
+	subscriber.Inject([]Sink{sink}, auditor)
+
+	subscriber.Notify(context.Background(), &domain.AuditEvent{Type: domain.AuditLogout})
+	subscriber.Notify(context.Background(), &web.SessionRegeneratedEvent{Session: web.EmptySession()})
+	subscriber.Notify(context.Background(), &auditableEvent{provider: "main"})
+
+	sink.AssertExpectations(t)
+	require.Len(t, eventRouter.events, 2)
+	assert.Equal(t, domain.AuditSessionRegenerated, eventRouter.events[0].(*domain.AuditEvent).Type)
+	assert.Equal(t, domain.AuditTokenRefreshed, eventRouter.events[1].(*domain.AuditEvent).Type)
+	assert.Equal(t, "main", eventRouter.events[1].(*domain.AuditEvent).Fields["provider"])
+}
+
+// auditableEvent is an event of another module, like the token refresh of core/oauth
+type auditableEvent struct {
+	provider string
+}
+
+func (e *auditableEvent) Audit() (*web.Session, *domain.AuditEvent) {
+	return web.EmptySession(), &domain.AuditEvent{Type: domain.AuditTokenRefreshed, Fields: map[string]interface{}{"provider": e.provider}}
+}
+
+type recordingSink struct {
+	mock.Mock
+}
+
+func (s *recordingSink) Write(ctx context.Context, event *domain.AuditEvent) {
+	s.Called(ctx, event)
+}
@@ -0,0 +1,82 @@
+package audit
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"flamingo.me/flamingo/v3/core/security/application/voter"
+	"flamingo.me/flamingo/v3/core/security/domain"
+	"flamingo.me/flamingo/v3/framework/flamingo"
+	"flamingo.me/flamingo/v3/framework/web"
+)
+
+// Auditor completes audit events with the request information and dispatches them via the event router
+type Auditor struct {
+	eventRouter flamingo.EventRouter
+	attributes  []voter.SubjectAttributesProvider
+	enabled     bool
+}
+
+var now = time.Now
+
+// Inject dependencies
+func (a *Auditor) Inject(eventRouter flamingo.EventRouter, attributes []voter.SubjectAttributesProvider, cfg *struct {
+	Enabled bool `inject:"config:security.audit.enabled,optional"`
+}) {
+	a.eventRouter = eventRouter
+	a.attributes = attributes
+	if cfg != nil {
+		a.enabled = cfg.Enabled
+	}
+}
+
+// Enabled checks if audit events are dispatched, callers can skip collecting expensive information otherwise
+func (a *Auditor) Enabled() bool {
+	return a != nil && a.enabled && a.eventRouter != nil
+}
+
+// Audit dispatches the event. The time, session, request path and remote address, and the subject are added if not set.
+// The session defaults to the session of the context.
+func (a *Auditor) Audit(ctx context.Context, session *web.Session, event *domain.AuditEvent) {
+	if !a.Enabled() {
+		return
+	}
+
+	if event.Time.IsZero() {
+		event.Time = now()
+	}
+
+	if session == nil {
+		session = web.SessionFromContext(ctx)
+	}
+	if session != nil {
+		if event.SessionID == "" && session.ID() != "" {
+			event.SessionID = session.IDHash()
+		}
+		if event.Subject == "" {
+			event.Subject = a.subject(ctx, session)
+		}
+	}
+
+	if req := web.RequestFromContext(ctx); req != nil {
+		if event.Path == "" {
+			event.Path = req.Request().URL.Path
+		}
+		if event.RemoteAddress == "" {
+			event.RemoteAddress = strings.Join(req.RemoteAddress(), ", ")
+		}
+	}
+
+	a.eventRouter.Dispatch(ctx, event)
+}
+
+// subject returns the sub attribute of the session's user
+func (a *Auditor) subject(ctx context.Context, session *web.Session) string {
+	for _, provider := range a.attributes {
+		if sub, ok := provider.Attributes(ctx, session)["sub"].(string); ok && sub != "" {
+			return sub
+		}
+	}
+	return ""
+}
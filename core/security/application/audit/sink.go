@@ -0,0 +1,130 @@
+package audit
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+
+	"flamingo.me/flamingo/v3/core/security/domain"
+	"flamingo.me/flamingo/v3/framework/config"
+	"flamingo.me/flamingo/v3/framework/flamingo"
+	"flamingo.me/flamingo/v3/framework/web"
+	"github.com/pkg/errors"
+)
+
+type (
+	// Sink writes audit events, e.g. into a log or an external audit system. Sinks are registered via BindMulti.
+	Sink interface {
+		Write(ctx context.Context, event *domain.AuditEvent)
+	}
+
+	// LogSink writes the audit events into the audit logger.
+	// The audit logger is the flamingo.Logger annotated with "security.audit", or the default logger.
+	LogSink struct {
+		logger flamingo.Logger
+		redact map[string]bool
+	}
+
+	// Subscriber passes the audit events to the sinks, and audits the session regeneration and auditable events of other modules
+	Subscriber struct {
+		sinks   []Sink
+		auditor *Auditor
+	}
+)
+
+var _ Sink = new(LogSink)
+
+// Inject dependencies
+func (s *LogSink) Inject(logger flamingo.Logger, cfg *struct {
+	AuditLogger flamingo.Logger `inject:"security.audit,optional"`
+	Redact      config.Slice    `inject:"config:security.audit.redact,optional"`
+}) {
+	s.logger = logger
+	s.redact = make(map[string]bool)
+
+	if cfg != nil {
+		if cfg.AuditLogger != nil {
+			s.logger = cfg.AuditLogger
+		}
+
+		var redact []string
+		if err := cfg.Redact.MapInto(&redact); err != nil {
+			panic(errors.Wrap(err, "security.audit.redact"))
+		}
+		for _, field := range redact {
+			s.redact[strings.ToLower(field)] = true
+		}
+	}
+
+	s.logger = s.logger.WithField(flamingo.LogKeyCategory, "audit").WithField(flamingo.LogKeyModule, "security")
+}
+
+// Write logs the event, the configured fields are redacted
+func (s *LogSink) Write(ctx context.Context, event *domain.AuditEvent) {
+	fields := map[flamingo.LogKey]interface{}{
+		"audit_type": event.Type,
+		"audit_time": event.Time,
+	}
+	s.add(fields, "subject", event.Subject)
+	s.add(fields, "sessionId", event.SessionID)
+	s.add(fields, "remoteAddress", event.RemoteAddress)
+	s.add(fields, "path", event.Path)
+
+	if event.Access != nil {
+		s.add(fields, "permission", event.Access.Permission)
+		s.add(fields, "object", event.Access.Object)
+		s.add(fields, "strategy", event.Access.Strategy)
+		votes := make([]string, len(event.Access.Votes))
+		for i, vote := range event.Access.Votes {
+			votes[i] = vote.Voter + "=" + vote.Decision
+		}
+		s.add(fields, "votes", strings.Join(votes, ", "))
+	}
+
+	keys := make([]string, 0, len(event.Fields))
+	for key := range event.Fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		s.add(fields, key, fmt.Sprint(event.Fields[key]))
+	}
+
+	s.logger.WithContext(ctx).WithFields(fields).Info("security audit: ", event.Type)
+}
+
+// add the field, redacted fields are replaced by a hash, so events of the same user can still be correlated
+func (s *LogSink) add(fields map[flamingo.LogKey]interface{}, key string, value string) {
+	if value == "" {
+		return
+	}
+	if s.redact[strings.ToLower(key)] {
+		hash := sha256.Sum256([]byte(value))
+		value = "redacted:" + hex.EncodeToString(hash[:8])
+	}
+	fields[flamingo.LogKey("audit_"+key)] = value
+}
+
+// Inject dependencies
+func (s *Subscriber) Inject(sinks []Sink, auditor *Auditor) {
+	s.sinks = sinks
+	s.auditor = auditor
+}
+
+// Notify writes audit events into the sinks
+func (s *Subscriber) Notify(ctx context.Context, event flamingo.Event) {
+	switch event := event.(type) {
+	case *domain.AuditEvent:
+		for _, sink := range s.sinks {
+			sink.Write(ctx, event)
+		}
+	case *web.SessionRegeneratedEvent:
+		s.auditor.Audit(ctx, event.Session, &domain.AuditEvent{Type: domain.AuditSessionRegenerated})
+	case domain.AuditableEvent:
+		session, auditEvent := event.Audit()
+		s.auditor.Audit(ctx, session, auditEvent)
+	}
+}
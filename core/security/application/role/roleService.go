@@ -6,6 +6,7 @@ import (
 	"flamingo.me/flamingo/v3/core/security/domain"
 	"flamingo.me/flamingo/v3/framework/config"
 	"flamingo.me/flamingo/v3/framework/web"
+	"github.com/pkg/errors"
 )
 
 type (
@@ -19,21 +20,28 @@ type (
 		AllPermissions(context.Context, *web.Session) []string
 	}
 
+	// DenyingService is a Service which also returns the explicitly denied permissions
+	DenyingService interface {
+		Service
+		Permissions(context.Context, *web.Session) (granted []string, denied []string)
+	}
+
 	// The ServiceImpl is the default Service implementation
 	ServiceImpl struct {
 		providers           []Provider
-		permissionHierarchy map[string][]string
+		permissionHierarchy *domain.PermissionHierarchy
 	}
 )
 
+var _ DenyingService = new(ServiceImpl)
+
 // Inject dependencies
 func (s *ServiceImpl) Inject(p []Provider, cfg *struct {
 	PermissionHierarchy config.Map `inject:"config:security.roles.permissionHierarchy"`
 }) {
 	s.providers = p
 
-	var permissionHierarchy map[string][]string
-	err := cfg.PermissionHierarchy.MapInto(&permissionHierarchy)
+	permissionHierarchy, err := ParsePermissionHierarchy(cfg.PermissionHierarchy)
 	if err != nil {
 		panic(err)
 	}
@@ -41,8 +49,31 @@ func (s *ServiceImpl) Inject(p []Provider, cfg *struct {
 	s.permissionHierarchy = permissionHierarchy
 }
 
-// AllPermissions returns all available permissions, based on their hierarchy
+// ParsePermissionHierarchy creates the hierarchy of the security.roles.permissionHierarchy config
+func ParsePermissionHierarchy(cfg config.Map) (*domain.PermissionHierarchy, error) {
+	var inherited map[string][]string
+	if err := cfg.MapInto(&inherited); err != nil {
+		return nil, errors.Wrap(err, "security.roles.permissionHierarchy")
+	}
+
+	permissionHierarchy, err := domain.NewPermissionHierarchy(inherited)
+	if err != nil {
+		return nil, errors.Wrap(err, "security.roles.permissionHierarchy")
+	}
+
+	return permissionHierarchy, nil
+}
+
+// AllPermissions returns all granted permissions, based on their hierarchy.
+// Explicitly denied permissions are not contained, use Permissions to get them as well.
 func (s *ServiceImpl) AllPermissions(ctx context.Context, session *web.Session) []string {
+	granted, _ := s.Permissions(ctx, session)
+	return granted
+}
+
+// Permissions returns the granted and the explicitly denied permissions, based on their hierarchy.
+// Denied permissions are returned without the domain.PermissionDenyPrefix, see domain.SplitPermissions.
+func (s *ServiceImpl) Permissions(ctx context.Context, session *web.Session) (granted []string, denied []string) {
 	rolesChan := make(chan []domain.Role)
 
 	for index := range s.providers {
@@ -62,22 +93,11 @@ func (s *ServiceImpl) AllPermissions(ctx context.Context, session *web.Session)
 		permissions = append(permissions, extracted...)
 	}
 
-	return s.removeDuplicates(permissions)
+	return domain.SplitPermissions(s.removeDuplicates(permissions))
 }
 
 func (s *ServiceImpl) extractPermissions(role domain.Role) []string {
-	permissions := role.Permissions()
-
-	for _, permission := range role.Permissions() {
-		hierarchy, ok := s.permissionHierarchy[permission]
-		if !ok {
-			continue
-		}
-
-		permissions = append(permissions, hierarchy...)
-	}
-
-	return permissions
+	return s.permissionHierarchy.Expand(role.Permissions())
 }
 
 func (s *ServiceImpl) removeDuplicates(permissions []string) []string {
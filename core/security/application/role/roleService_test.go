@@ -8,6 +8,8 @@ import (
 	"flamingo.me/flamingo/v3/core/security/domain"
 	"flamingo.me/flamingo/v3/framework/config"
 	"flamingo.me/flamingo/v3/framework/web"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"github.com/stretchr/testify/suite"
 )
 
@@ -59,6 +61,12 @@ func (t *ServiceImplTestSuite) TearDownTest() {
 	t.service = nil
 }
 
+func (t *ServiceImplTestSuite) hierarchy(inherited map[string][]string) *domain.PermissionHierarchy {
+	hierarchy, err := domain.NewPermissionHierarchy(inherited)
+	t.Require().NoError(err)
+	return hierarchy
+}
+
 func (t *ServiceImplTestSuite) TestAll_RemoveDuplicates() {
 	roles := []domain.Role{
 		domain.StringRole("SomePermission"),
@@ -81,11 +89,11 @@ func (t *ServiceImplTestSuite) TestAll_UseHierarchy() {
 		domain.StringRole("Permission3"),
 	}
 
-	t.service.permissionHierarchy = map[string][]string{
+	t.service.permissionHierarchy = t.hierarchy(map[string][]string{
 		"Permission1": {"Permission11"},
 		"Permission2": {"Permission21", "Permission22"},
 		"Permission3": {"Permission31", "Permission32", "Permission33"},
-	}
+	})
 
 	t.firstProvider.On("All", t.context, t.webSession).Return(firstRoles).Once()
 	t.secondProvider.On("All", t.context, t.webSession).Return(secondRoles).Once()
@@ -115,11 +123,11 @@ func (t *ServiceImplTestSuite) TestAll_Complete() {
 		domain.StringRole("Permission3"),
 	}
 
-	t.service.permissionHierarchy = map[string][]string{
+	t.service.permissionHierarchy = t.hierarchy(map[string][]string{
 		"Permission1": {"Permission11", "Permission21", "Permission31"},
 		"Permission2": {"Permission21", "Permission22", "Permission32"},
 		"Permission3": {"Permission31", "Permission32", "Permission33"},
-	}
+	})
 
 	t.firstProvider.On("All", t.context, t.webSession).Return(firstRoles).Once()
 	t.secondProvider.On("All", t.context, t.webSession).Return(secondRoles).Once()
@@ -137,3 +145,66 @@ func (t *ServiceImplTestSuite) TestAll_Complete() {
 		"Permission33",
 	}, t.service.AllPermissions(t.context, t.webSession))
 }
+
+func (t *ServiceImplTestSuite) TestAll_InheritedAndDenied() {
+	t.service.permissionHierarchy = t.hierarchy(map[string][]string{
+		"PermissionAdmin":  {"PermissionEditor", "order.*", "!order.delete"},
+		"PermissionEditor": {"PermissionView"},
+	})
+
+	t.firstProvider.On("All", t.context, t.webSession).Return([]domain.Role{domain.StringRole("PermissionAdmin")}).Twice()
+	t.secondProvider.On("All", t.context, t.webSession).Return(nil).Twice()
+	t.thirdProvider.On("All", t.context, t.webSession).Return([]domain.Role{domain.NewRole("editor", []string{"PermissionEditor", "order.delete"})}).Twice()
+
+	t.ElementsMatch([]string{
+		"PermissionAdmin",
+		"PermissionEditor",
+		"PermissionView",
+		"order.*",
+	}, t.service.AllPermissions(t.context, t.webSession), "denied permissions are neither contained nor granted")
+
+	granted, denied := t.service.Permissions(t.context, t.webSession)
+	t.ElementsMatch([]string{"PermissionAdmin", "PermissionEditor", "PermissionView", "order.*"}, granted)
+	t.Equal([]string{"order.delete"}, denied)
+}
+
+func (t *ServiceImplTestSuite) TestAll_Wildcard() {
+	t.service.permissionHierarchy = t.hierarchy(map[string][]string{
+		"PermissionAdmin": {"admin.*", "!admin.system"},
+		"admin.users":     {"user.view", "user.edit"},
+		"admin.orders":    {"order.view"},
+		"admin.system":    {"system.restart"},
+		"PermissionView":  {"catalog.view"},
+	})
+
+	t.firstProvider.On("All", t.context, t.webSession).Return([]domain.Role{domain.StringRole("PermissionAdmin")}).Once()
+	t.secondProvider.On("All", t.context, t.webSession).Return([]domain.Role{domain.NewRole("support", []string{"admin.u*"})}).Once()
+	t.thirdProvider.On("All", t.context, t.webSession).Return(nil).Once()
+
+	t.ElementsMatch([]string{
+		"PermissionAdmin",
+		"admin.*",
+		"admin.u*",
+		"admin.users",
+		"user.view",
+		"user.edit",
+		"admin.orders",
+		"order.view",
+		"system.restart",
+	}, t.service.AllPermissions(t.context, t.webSession), "wildcards grant the matching permissions of the hierarchy with their inherited permissions")
+}
+
+func TestParsePermissionHierarchy(t *testing.T) {
+	hierarchy, err := ParsePermissionHierarchy(config.Map{
+		"PermissionAdmin":  config.Slice{"PermissionEditor"},
+		"PermissionEditor": config.Slice{"PermissionView"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"PermissionAdmin", "PermissionEditor", "PermissionView"}, hierarchy.Expand([]string{"PermissionAdmin"}))
+
+	_, err = ParsePermissionHierarchy(config.Map{
+		"PermissionAdmin":  config.Slice{"PermissionEditor"},
+		"PermissionEditor": config.Slice{"PermissionAdmin"},
+	})
+	assert.Error(t, err)
+}
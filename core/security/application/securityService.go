@@ -2,9 +2,10 @@ package application
 
 import (
 	"context"
-	"flamingo.me/flamingo/v3/core/security/application/role"
 	"fmt"
 
+	"flamingo.me/flamingo/v3/core/security/application/audit"
+	"flamingo.me/flamingo/v3/core/security/application/role"
 	"flamingo.me/flamingo/v3/core/security/application/voter"
 	"flamingo.me/flamingo/v3/core/security/domain"
 	"flamingo.me/flamingo/v3/framework/web"
@@ -32,6 +33,8 @@ type (
 	SecurityServiceImpl struct {
 		voters            []voter.SecurityVoter
 		roleService       role.Service
+		attributes        []voter.SubjectAttributesProvider
+		auditor           *audit.Auditor
 		voterStrategy     string
 		allowIfAllAbstain bool
 	}
@@ -41,35 +44,134 @@ var _ SecurityService = &SecurityServiceImpl{}
 
 // Inject dependencies
 func (s *SecurityServiceImpl) Inject(v []voter.SecurityVoter, r role.Service, cfg *struct {
-	VoterStrategy     string `inject:"config:security.roles.voters.strategy"`
-	AllowIfAllAbstain bool   `inject:"config:security.roles.voters.allowIfAllAbstain"`
+	VoterStrategy     string                            `inject:"config:security.roles.voters.strategy"`
+	AllowIfAllAbstain bool                              `inject:"config:security.roles.voters.allowIfAllAbstain"`
+	Attributes        []voter.SubjectAttributesProvider `inject:",optional"`
+	Auditor           *audit.Auditor                    `inject:",optional"`
 }) {
 	s.voters = v
 	s.roleService = r
 	s.voterStrategy = cfg.VoterStrategy
 	s.allowIfAllAbstain = cfg.AllowIfAllAbstain
+	s.attributes = cfg.Attributes
+	s.auditor = cfg.Auditor
 }
 
 // IsLoggedIn checks if the user is granted login permission
 func (s *SecurityServiceImpl) IsLoggedIn(ctx context.Context, session *web.Session) bool {
-	return s.IsGranted(ctx, session, domain.PermissionAuthorized, nil)
+	return s.isGranted(ctx, session, domain.PermissionAuthorized, nil, false)
 }
 
 // IsLoggedOut checks if the user is not granted login permission
 func (s *SecurityServiceImpl) IsLoggedOut(ctx context.Context, session *web.Session) bool {
-	return !s.IsGranted(ctx, session, domain.PermissionAuthorized, nil)
+	return !s.isGranted(ctx, session, domain.PermissionAuthorized, nil, false)
 }
 
-// IsGranted checks for a specific permission of the user
+// IsGranted checks for a specific permission of the user, the decision is audited
 func (s *SecurityServiceImpl) IsGranted(ctx context.Context, session *web.Session, desiredPermission string, object interface{}) bool {
-	allPermissions := s.roleService.AllPermissions(ctx, session)
+	return s.isGranted(ctx, session, desiredPermission, object, s.auditor.Enabled())
+}
+
+func (s *SecurityServiceImpl) isGranted(ctx context.Context, session *web.Session, desiredPermission string, object interface{}, audited bool) bool {
+	granted, denied := s.permissions(ctx, session)
+
+	// explicitly denied permissions always win, regardless of the voters and the strategy
+	for _, permission := range denied {
+		if domain.MatchPermission(permission, desiredPermission) {
+			if audited {
+				s.auditDenied(ctx, session, desiredPermission, object, permission)
+			}
+			return false
+		}
+	}
+
+	// context voters get the denied permissions with the domain.PermissionDenyPrefix,
+	// so they can check them with domain.IsPermissionGranted
+	allPermissions := make([]string, 0, len(granted)+len(denied))
+	allPermissions = append(allPermissions, granted...)
+	for _, permission := range denied {
+		allPermissions = append(allPermissions, domain.PermissionDenyPrefix+permission)
+	}
+	subject := voter.NewSubject(session, allPermissions, func() map[string]interface{} {
+		return s.subjectAttributes(ctx, session)
+	})
 
 	var results []voter.AccessDecision
 	for index := range s.voters {
-		results = append(results, s.voters[index].Vote(allPermissions, desiredPermission, object))
+		if contextVoter, ok := s.voters[index].(voter.ContextVoter); ok {
+			results = append(results, contextVoter.VoteWithContext(ctx, subject, desiredPermission, object))
+			continue
+		}
+		results = append(results, s.voters[index].Vote(granted, desiredPermission, object))
+	}
+
+	decision := s.decide(results)
+	if audited {
+		s.auditDecision(ctx, session, subject, desiredPermission, object, results, decision)
+	}
+
+	return decision
+}
+
+// permissions returns the granted and the explicitly denied permissions, see role.DenyingService
+func (s *SecurityServiceImpl) permissions(ctx context.Context, session *web.Session) (granted []string, denied []string) {
+	roleService, ok := s.roleService.(role.DenyingService)
+	if !ok {
+		return s.roleService.AllPermissions(ctx, session), nil
+	}
+
+	return roleService.Permissions(ctx, session)
+}
+
+// auditDenied dispatches the access decision for an explicitly denied permission
+func (s *SecurityServiceImpl) auditDenied(ctx context.Context, session *web.Session, desiredPermission string, object interface{}, denied string) {
+	access := &domain.AccessAudit{
+		Permission: desiredPermission,
+		Strategy:   s.voterStrategy,
+		Denied:     denied,
 	}
+	if object != nil {
+		access.Object = fmt.Sprintf("%T", object)
+	}
+
+	event := &domain.AuditEvent{Type: domain.AuditAccessDenied, Access: access}
+	event.Subject, _ = s.subjectAttributes(ctx, session)["sub"].(string)
 
-	return s.decide(results)
+	s.auditor.Audit(ctx, session, event)
+}
+
+// auditDecision dispatches the access decision with the votes of all voters
+func (s *SecurityServiceImpl) auditDecision(ctx context.Context, session *web.Session, subject *voter.Subject, desiredPermission string, object interface{}, results []voter.AccessDecision, granted bool) {
+	access := &domain.AccessAudit{
+		Permission: desiredPermission,
+		Strategy:   s.voterStrategy,
+		Votes:      make([]domain.VoteAudit, len(results)),
+	}
+	if object != nil {
+		access.Object = fmt.Sprintf("%T", object)
+	}
+	for i, result := range results {
+		access.Votes[i] = domain.VoteAudit{Voter: fmt.Sprintf("%T", s.voters[i]), Decision: result.String()}
+	}
+
+	event := &domain.AuditEvent{Type: domain.AuditAccessDenied, Access: access}
+	if granted {
+		event.Type = domain.AuditAccessGranted
+	}
+	event.Subject, _ = subject.Attributes()["sub"].(string)
+
+	s.auditor.Audit(ctx, session, event)
+}
+
+// subjectAttributes merges the attributes of all providers, later providers overwrite earlier ones
+func (s *SecurityServiceImpl) subjectAttributes(ctx context.Context, session *web.Session) map[string]interface{} {
+	attributes := make(map[string]interface{})
+	for _, provider := range s.attributes {
+		for key, value := range provider.Attributes(ctx, session) {
+			attributes[key] = value
+		}
+	}
+	return attributes
 }
 
 func (s *SecurityServiceImpl) decide(results []voter.AccessDecision) bool {
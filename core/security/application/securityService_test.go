@@ -4,11 +4,16 @@ import (
 	"context"
 	"testing"
 
+	"flamingo.me/flamingo/v3/core/security/application/audit"
+	"flamingo.me/flamingo/v3/core/security/application/role"
 	roleMocks "flamingo.me/flamingo/v3/core/security/application/role/mocks"
 	"flamingo.me/flamingo/v3/core/security/application/voter"
 	voterMocks "flamingo.me/flamingo/v3/core/security/application/voter/mocks"
 	"flamingo.me/flamingo/v3/core/security/domain"
+	"flamingo.me/flamingo/v3/framework/config"
+	"flamingo.me/flamingo/v3/framework/flamingo"
 	"flamingo.me/flamingo/v3/framework/web"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/suite"
 )
 
@@ -55,8 +60,10 @@ func (t *SecurityServiceTestSuite) SetupTest() {
 	t.roleService = &roleMocks.Service{}
 	t.service = &SecurityServiceImpl{}
 	t.service.Inject(voters, t.roleService, &struct {
-		VoterStrategy     string `inject:"config:security.roles.voters.strategy"`
-		AllowIfAllAbstain bool   `inject:"config:security.roles.voters.allowIfAllAbstain"`
+		VoterStrategy     string                            `inject:"config:security.roles.voters.strategy"`
+		AllowIfAllAbstain bool                              `inject:"config:security.roles.voters.allowIfAllAbstain"`
+		Attributes        []voter.SubjectAttributesProvider `inject:",optional"`
+		Auditor           *audit.Auditor                    `inject:",optional"`
 	}{})
 }
 
@@ -431,3 +438,70 @@ func (t *SecurityServiceTestSuite) TestIsGranted() {
 		t.Equal(testCase.decision, t.service.IsGranted(t.context, webSession, "SomePermission", nil))
 	}
 }
+
+func TestSecurityServiceImpl_IsGrantedWithContextVoter(t *testing.T) {
+	ctx := context.Background()
+	webSession := web.EmptySession()
+	object := &struct{ OwnerID string }{OwnerID: "user-1"}
+
+	roleService := &roleMocks.Service{}
+	roleService.On("AllPermissions", ctx, webSession).Return([]string{domain.PermissionAuthorized})
+	attributes := &voterMocks.SubjectAttributesProvider{}
+	attributes.On("Attributes", ctx, webSession).Return(map[string]interface{}{"sub": "user-1"})
+
+	policyVoter := new(voter.PolicyVoter)
+	policyVoter.Inject(flamingo.NullLogger{}, &struct {
+		Policies config.Map `inject:"config:security.policies,optional"`
+	}{
+		Policies: config.Map{"OrderEdit": "object.ownerID == user.sub"},
+	})
+
+	service := &SecurityServiceImpl{}
+	service.Inject([]voter.SecurityVoter{policyVoter}, roleService, &struct {
+		VoterStrategy     string                            `inject:"config:security.roles.voters.strategy"`
+		AllowIfAllAbstain bool                              `inject:"config:security.roles.voters.allowIfAllAbstain"`
+		Attributes        []voter.SubjectAttributesProvider `inject:",optional"`
+		Auditor           *audit.Auditor                    `inject:",optional"`
+	}{VoterStrategy: VoterStrategyAffirmative, Attributes: []voter.SubjectAttributesProvider{attributes}})
+
+	assert.True(t, service.IsGranted(ctx, webSession, "OrderEdit", object))
+	object.OwnerID = "user-2"
+	assert.False(t, service.IsGranted(ctx, webSession, "OrderEdit", object))
+	assert.False(t, service.IsGranted(ctx, webSession, "OrderDelete", object))
+	attributes.AssertNumberOfCalls(t, "Attributes", 2)
+}
+
+func TestSecurityServiceImpl_DeniedPermissions(t *testing.T) {
+	ctx := context.Background()
+	webSession := web.EmptySession()
+
+	provider := &roleMocks.Provider{}
+	provider.On("All", ctx, webSession).Return([]domain.Role{domain.StringRole("PermissionAdmin")})
+
+	roleService := &role.ServiceImpl{}
+	roleService.Inject([]role.Provider{provider}, &struct {
+		PermissionHierarchy config.Map `inject:"config:security.roles.permissionHierarchy"`
+	}{
+		PermissionHierarchy: config.Map{"PermissionAdmin": config.Slice{"order.*", "!order.delete"}},
+	})
+
+	service := &SecurityServiceImpl{}
+	service.Inject([]voter.SecurityVoter{new(voter.PermissionVoter)}, roleService, &struct {
+		VoterStrategy     string                            `inject:"config:security.roles.voters.strategy"`
+		AllowIfAllAbstain bool                              `inject:"config:security.roles.voters.allowIfAllAbstain"`
+		Attributes        []voter.SubjectAttributesProvider `inject:",optional"`
+		Auditor           *audit.Auditor                    `inject:",optional"`
+	}{VoterStrategy: VoterStrategyAffirmative})
+
+	assert.True(t, service.IsGranted(ctx, webSession, "order.edit", nil))
+	assert.False(t, service.IsGranted(ctx, webSession, "order.delete", nil), "denied permissions are not granted")
+	assert.NotContains(t, roleService.AllPermissions(ctx, webSession), "!order.delete")
+
+	// a legacy voter which grants everything is not asked for denied permissions and gets only the granted ones
+	legacyVoter := &voterMocks.SecurityVoter{}
+	legacyVoter.On("Vote", []string{"PermissionAdmin", "order.*"}, "order.edit", nil).Return(voter.AccessGranted).Once()
+	service.voters = []voter.SecurityVoter{legacyVoter}
+	assert.True(t, service.IsGranted(ctx, webSession, "order.edit", nil))
+	assert.False(t, service.IsGranted(ctx, webSession, "order.delete", nil), "explicit denies win over the strategy")
+	legacyVoter.AssertExpectations(t)
+}
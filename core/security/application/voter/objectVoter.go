@@ -0,0 +1,73 @@
+package voter
+
+import (
+	"context"
+	"reflect"
+
+	"flamingo.me/dingo"
+)
+
+type (
+	// ObjectVoter votes on objects of a specific type, e.g. if the user may edit an order.
+	// It is registered for the object type with BindObjectVoter.
+	ObjectVoter interface {
+		VoteOnObject(ctx context.Context, subject *Subject, desiredPermission string, object interface{}) AccessDecision
+	}
+
+	// ObjectVoterRegistry delegates the vote to the ObjectVoter registered for the type of the object
+	ObjectVoterRegistry struct {
+		voters map[string]ObjectVoter
+	}
+)
+
+var _ ContextVoter = new(ObjectVoterRegistry)
+
+// BindObjectVoter registers the voter for objects of the same type as the given example, pointers and values share the voter:
+//
+//	voter.BindObjectVoter(injector, new(domain.Order), new(OrderVoter))
+func BindObjectVoter(injector *dingo.Injector, object interface{}, objectVoter ObjectVoter) {
+	injector.BindMap(new(ObjectVoter), ObjectType(object)).To(objectVoter)
+}
+
+// ObjectType returns the registry key of the object's type
+func ObjectType(object interface{}) string {
+	t := reflect.TypeOf(object)
+	if t == nil {
+		return ""
+	}
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.PkgPath() == "" {
+		return t.String()
+	}
+	return t.PkgPath() + "." + t.Name()
+}
+
+// Inject dependencies
+func (r *ObjectVoterRegistry) Inject(cfg *struct {
+	Voters map[string]ObjectVoter `inject:",optional"`
+}) {
+	if cfg != nil {
+		r.voters = cfg.Voters
+	}
+}
+
+// Vote without context
+func (r *ObjectVoterRegistry) Vote(allAssignedPermissions []string, desiredPermission string, forObject interface{}) AccessDecision {
+	return r.VoteWithContext(context.Background(), NewSubject(nil, allAssignedPermissions, nil), desiredPermission, forObject)
+}
+
+// VoteWithContext asks the voter of the object's type, it abstains for objects without voter
+func (r *ObjectVoterRegistry) VoteWithContext(ctx context.Context, subject *Subject, desiredPermission string, forObject interface{}) AccessDecision {
+	if forObject == nil {
+		return AccessAbstained
+	}
+
+	objectVoter, ok := r.voters[ObjectType(forObject)]
+	if !ok {
+		return AccessAbstained
+	}
+
+	return objectVoter.VoteOnObject(ctx, subject, desiredPermission, forObject)
+}
@@ -0,0 +1,50 @@
+package voter
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type (
+	testOrder struct {
+		OwnerID string
+	}
+
+	testOrderVoter struct{}
+)
+
+func (testOrderVoter) VoteOnObject(_ context.Context, subject *Subject, desiredPermission string, object interface{}) AccessDecision {
+	order := object.(*testOrder)
+	if desiredPermission == "OrderEdit" && order.OwnerID == subject.Attributes()["sub"] {
+		return AccessGranted
+	}
+	return AccessDenied
+}
+
+func TestObjectType(t *testing.T) {
+	assert.Equal(t, "flamingo.me/flamingo/v3/core/security/application/voter.testOrder", ObjectType(new(testOrder)))
+	assert.Equal(t, ObjectType(new(testOrder)), ObjectType(testOrder{}))
+	assert.Equal(t, "string", ObjectType("order"))
+	assert.Equal(t, "", ObjectType(nil))
+}
+
+func TestObjectVoterRegistry_VoteWithContext(t *testing.T) {
+	registry := new(ObjectVoterRegistry)
+	registry.Inject(&struct {
+		Voters map[string]ObjectVoter `inject:",optional"`
+	}{
+		Voters: map[string]ObjectVoter{ObjectType(testOrder{}): testOrderVoter{}},
+	})
+
+	subject := NewSubject(nil, nil, func() map[string]interface{} {
+		return map[string]interface{}{"sub": "user-1"}
+	})
+
+	assert.Equal(t, AccessGranted, registry.VoteWithContext(context.Background(), subject, "OrderEdit", &testOrder{OwnerID: "user-1"}))
+	assert.Equal(t, AccessDenied, registry.VoteWithContext(context.Background(), subject, "OrderEdit", &testOrder{OwnerID: "user-2"}))
+	assert.Equal(t, AccessAbstained, registry.VoteWithContext(context.Background(), subject, "OrderEdit", "no order"))
+	assert.Equal(t, AccessAbstained, registry.VoteWithContext(context.Background(), subject, "OrderEdit", nil))
+	assert.Equal(t, AccessDenied, registry.Vote(nil, "OrderEdit", &testOrder{OwnerID: "user-1"}), "without context there is no subject")
+}
@@ -14,22 +14,12 @@ func (v *PermissionVoter) Vote(allAssignedPermissions []string, desiredPermissio
 	}
 
 	permissionSet, ok := forObject.(domain.PermissionSet)
-	if ok && !v.hasPermission(permissionSet.Permissions(), desiredPermission) {
+	if ok && !domain.IsPermissionGranted(permissionSet.Permissions(), desiredPermission) {
 		return AccessDenied
 	}
 
-	if !v.hasPermission(allAssignedPermissions, desiredPermission) {
+	if !domain.IsPermissionGranted(allAssignedPermissions, desiredPermission) {
 		return AccessDenied
 	}
 	return AccessGranted
 }
-
-func (v *PermissionVoter) hasPermission(allAssignedPermissions []string, desiredPermission string) bool {
-	for _, permission := range allAssignedPermissions {
-		if permission == desiredPermission {
-			return true
-		}
-	}
-
-	return false
-}
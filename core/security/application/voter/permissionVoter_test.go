@@ -72,3 +72,8 @@ func (t *PermissionVoterTestSuite) TestVote_AccessDeniedWithObject() {
 	t.object.On("Permissions").Return([]string{}).Once()
 	t.Equal(AccessDenied, t.voter.Vote([]string{}, "RoleAdministrator", t.object))
 }
+
+func (t *PermissionVoterTestSuite) TestVote_Wildcard() {
+	t.Equal(AccessGranted, t.voter.Vote([]string{"order.*"}, "order.edit", nil))
+	t.Equal(AccessDenied, t.voter.Vote([]string{"order.*", "!order.delete"}, "order.delete", nil))
+}
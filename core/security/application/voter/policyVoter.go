@@ -0,0 +1,86 @@
+package voter
+
+import (
+	"context"
+
+	"flamingo.me/flamingo/v3/core/security/domain"
+	"flamingo.me/flamingo/v3/framework/config"
+	"flamingo.me/flamingo/v3/framework/flamingo"
+	"github.com/pkg/errors"
+)
+
+// PolicyVoter votes with the policy expressions configured per permission in security.policies
+type PolicyVoter struct {
+	policies map[string]*domain.Policy
+	logger   flamingo.Logger
+}
+
+var _ ContextVoter = new(PolicyVoter)
+
+// Inject dependencies
+func (v *PolicyVoter) Inject(logger flamingo.Logger, cfg *struct {
+	Policies config.Map `inject:"config:security.policies,optional"`
+}) {
+	v.logger = logger.WithField(flamingo.LogKeyModule, "security")
+
+	if cfg != nil {
+		policies, err := parsePolicies(cfg.Policies)
+		if err != nil {
+			panic(err)
+		}
+		v.policies = policies
+	}
+}
+
+func parsePolicies(cfg config.Map) (map[string]*domain.Policy, error) {
+	policies := make(map[string]*domain.Policy, len(cfg))
+	for permission, expression := range cfg {
+		s, ok := expression.(string)
+		if !ok {
+			return nil, errors.Errorf("security.policies.%s: expected an expression, but got %T", permission, expression)
+		}
+		policy, err := domain.ParsePolicy(s)
+		if err != nil {
+			return nil, errors.Wrapf(err, "security.policies.%s", permission)
+		}
+		policies[permission] = policy
+	}
+	return policies, nil
+}
+
+// Vote without context, the policy has no user attributes
+func (v *PolicyVoter) Vote(allAssignedPermissions []string, desiredPermission string, forObject interface{}) AccessDecision {
+	return v.VoteWithContext(context.Background(), NewSubject(nil, allAssignedPermissions, nil), desiredPermission, forObject)
+}
+
+// VoteWithContext evaluates the policy of the permission with the variables `user` (the subject's attributes and permissions)
+// and `object`. It abstains for permissions without policy, and denies if the policy fails.
+func (v *PolicyVoter) VoteWithContext(ctx context.Context, subject *Subject, desiredPermission string, forObject interface{}) AccessDecision {
+	policy, ok := v.policies[desiredPermission]
+	if !ok {
+		return AccessAbstained
+	}
+
+	attributes := subject.Attributes()
+	user := make(map[string]interface{}, len(attributes)+1)
+	for key, value := range attributes {
+		user[key] = value
+	}
+	user["permissions"] = subject.Permissions
+
+	granted, err := policy.Evaluate(map[string]interface{}{
+		"user":   user,
+		"object": forObject,
+	})
+	if err != nil {
+		if v.logger != nil {
+			v.logger.WithContext(ctx).Warn(err)
+		}
+		return AccessDenied
+	}
+
+	if granted {
+		return AccessGranted
+	}
+	return AccessDenied
+}
@@ -0,0 +1,48 @@
+package voter
+
+import (
+	"context"
+	"testing"
+
+	"flamingo.me/flamingo/v3/framework/config"
+	"flamingo.me/flamingo/v3/framework/flamingo"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestPolicyVoter(policies config.Map) *PolicyVoter {
+	voter := new(PolicyVoter)
+	voter.Inject(flamingo.NullLogger{}, &struct {
+		Policies config.Map `inject:"config:security.policies,optional"`
+	}{
+		Policies: policies,
+	})
+	return voter
+}
+
+func TestPolicyVoter_VoteWithContext(t *testing.T) {
+	voter := newTestPolicyVoter(config.Map{
+		"OrderEdit":   `object.ownerID == user.sub || "PermissionAdmin" in user.permissions`,
+		"OrderDelete": `object.ownerID.missing`,
+	})
+
+	subject := NewSubject(nil, []string{"PermissionAuthorized"}, func() map[string]interface{} {
+		return map[string]interface{}{"sub": "user-1"}
+	})
+	admin := NewSubject(nil, []string{"PermissionAdmin"}, nil)
+
+	assert.Equal(t, AccessGranted, voter.VoteWithContext(context.Background(), subject, "OrderEdit", map[string]interface{}{"ownerID": "user-1"}))
+	assert.Equal(t, AccessDenied, voter.VoteWithContext(context.Background(), subject, "OrderEdit", map[string]interface{}{"ownerID": "user-2"}))
+	assert.Equal(t, AccessGranted, voter.VoteWithContext(context.Background(), admin, "OrderEdit", map[string]interface{}{"ownerID": "user-2"}))
+	assert.Equal(t, AccessDenied, voter.VoteWithContext(context.Background(), subject, "OrderDelete", nil), "failing policies deny")
+	assert.Equal(t, AccessAbstained, voter.VoteWithContext(context.Background(), subject, "OrderView", nil))
+	assert.Equal(t, AccessGranted, voter.Vote([]string{"PermissionAdmin"}, "OrderEdit", nil))
+}
+
+func TestPolicyVoter_InvalidPolicy(t *testing.T) {
+	assert.Panics(t, func() {
+		newTestPolicyVoter(config.Map{"OrderEdit": `object.ownerID ==`})
+	})
+	assert.Panics(t, func() {
+		newTestPolicyVoter(config.Map{"OrderEdit": true})
+	})
+}
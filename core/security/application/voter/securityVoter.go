@@ -18,3 +18,16 @@ const (
 	// AccessDenied defines access decision in case when voter denies an access
 	AccessDenied AccessDecision = iota
 )
+
+// String returns the name of the decision, e.g. for audit events
+func (d AccessDecision) String() string {
+	switch d {
+	case AccessAbstained:
+		return "abstained"
+	case AccessGranted:
+		return "granted"
+	case AccessDenied:
+		return "denied"
+	}
+	return "unknown"
+}
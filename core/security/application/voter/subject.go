@@ -0,0 +1,63 @@
+package voter
+
+import (
+	"context"
+	"sync"
+
+	"flamingo.me/flamingo/v3/core/security/domain"
+	"flamingo.me/flamingo/v3/framework/web"
+)
+
+type (
+	// Subject is the user whose access is decided
+	Subject struct {
+		// Session of the request, nil for decisions outside of requests
+		Session *web.Session
+		// Permissions of all roles of the user
+		Permissions []string
+
+		attributesLoader func() map[string]interface{}
+		attributesOnce   sync.Once
+		attributes       map[string]interface{}
+	}
+
+	// SubjectAttributesProvider provides attributes of the subject, e.g. the core/oauth module provides sub, email, groups
+	// and the mapped attributes of the logged in user
+	SubjectAttributesProvider interface {
+		Attributes(ctx context.Context, session *web.Session) map[string]interface{}
+	}
+
+	// ContextVoter is a SecurityVoter which votes with the request context and the subject.
+	// The SecurityService calls VoteWithContext instead of Vote for these voters.
+	ContextVoter interface {
+		SecurityVoter
+		VoteWithContext(ctx context.Context, subject *Subject, desiredPermission string, forObject interface{}) AccessDecision
+	}
+)
+
+// NewSubject creates a subject, the attributes are loaded on first use
+func NewSubject(session *web.Session, permissions []string, attributes func() map[string]interface{}) *Subject {
+	return &Subject{
+		Session:          session,
+		Permissions:      permissions,
+		attributesLoader: attributes,
+	}
+}
+
+// Attributes of the subject, the result must not be modified
+func (s *Subject) Attributes() map[string]interface{} {
+	s.attributesOnce.Do(func() {
+		if s.attributesLoader != nil {
+			s.attributes = s.attributesLoader()
+		}
+		if s.attributes == nil {
+			s.attributes = make(map[string]interface{})
+		}
+	})
+	return s.attributes
+}
+
+// HasPermission checks if the subject is granted the permission, including wildcards and denied permissions
+func (s *Subject) HasPermission(permission string) bool {
+	return domain.IsPermissionGranted(s.Permissions, permission)
+}
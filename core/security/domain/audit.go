@@ -0,0 +1,61 @@
+package domain
+
+import (
+	"time"
+
+	"flamingo.me/flamingo/v3/framework/web"
+)
+
+// Audit event types
+const (
+	AuditLogin              = "login"
+	AuditLogout             = "logout"
+	AuditAccessGranted      = "access.granted"
+	AuditAccessDenied       = "access.denied"
+	AuditSessionRegenerated = "session.regenerated"
+	AuditTokenRefreshed     = "token.refreshed"
+)
+
+type (
+	// AuditEvent is a security relevant action, dispatched via the flamingo.EventRouter if security.audit.enabled is set
+	AuditEvent struct {
+		Type string
+		Time time.Time
+		// Subject identifies the user, e.g. the oauth sub, empty for anonymous users
+		Subject string
+		// SessionID is the hash of the session ID
+		SessionID string
+		// RemoteAddress of the request
+		RemoteAddress string
+		// Path of the request
+		Path string
+		// Access is the access decision of access.granted and access.denied events
+		Access *AccessAudit
+		// Fields contain additional information, e.g. the oauth provider
+		Fields map[string]interface{}
+	}
+
+	// AuditableEvent is an event of another module which is audited, e.g. the login event of core/oauth.
+	// The audit subscriber completes and dispatches the audit event, so these modules do not depend on the audit package.
+	AuditableEvent interface {
+		// Audit returns the session the event belongs to and the audit event
+		Audit() (*web.Session, *AuditEvent)
+	}
+
+	// AccessAudit describes how an access decision was made
+	AccessAudit struct {
+		Permission string
+		// Object is the type of the object the permission was checked for
+		Object   string
+		Strategy string
+		Votes    []VoteAudit
+		// Denied is the explicitly denied permission which matched, the voters are not asked then
+		Denied string
+	}
+
+	// VoteAudit is the result of a single voter
+	VoteAudit struct {
+		Voter    string
+		Decision string
+	}
+)
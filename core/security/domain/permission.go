@@ -0,0 +1,205 @@
+package domain
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// PermissionDenyPrefix marks a permission as explicitly denied, e.g. `!order.delete`.
+	// A denied permission is not granted, even if another permission or role grants it.
+	PermissionDenyPrefix = "!"
+	// PermissionWildcard at the end of a permission grants all permissions with the same prefix, e.g. `order.*`
+	PermissionWildcard = "*"
+)
+
+type (
+	// PermissionHierarchy contains the permissions inherited by a permission, e.g.
+	//	PermissionAdmin: [PermissionEditor, "order.*", "!order.delete"]
+	//	PermissionEditor: [PermissionView]
+	// Inheritance is transitive, so PermissionAdmin also grants PermissionView.
+	PermissionHierarchy struct {
+		inherited map[string][]string
+		expanded  map[string][]string
+	}
+)
+
+// NewPermissionHierarchy creates the hierarchy and expands the inheritance, cyclic inheritance is an error
+func NewPermissionHierarchy(inherited map[string][]string) (*PermissionHierarchy, error) {
+	h := &PermissionHierarchy{
+		inherited: inherited,
+		expanded:  make(map[string][]string, len(inherited)),
+	}
+
+	for _, permission := range h.Permissions() {
+		if _, err := h.expand(permission, nil); err != nil {
+			return nil, err
+		}
+	}
+
+	return h, nil
+}
+
+// expand resolves the inherited permissions depth first, path is the chain of permissions currently expanded
+func (h *PermissionHierarchy) expand(permission string, path []string) ([]string, error) {
+	if expanded, ok := h.expanded[permission]; ok {
+		return expanded, nil
+	}
+
+	for i, p := range path {
+		if p == permission {
+			return nil, errors.Errorf("cyclic permission inheritance: %s", strings.Join(append(path[i:], permission), " -> "))
+		}
+	}
+	path = append(path, permission)
+
+	expanded := []string{permission}
+	for _, child := range h.inherited[permission] {
+		if strings.HasPrefix(child, PermissionDenyPrefix) {
+			expanded = append(expanded, child)
+			continue
+		}
+		childExpanded, err := h.expand(child, path)
+		if err != nil {
+			return nil, err
+		}
+		expanded = append(expanded, childExpanded...)
+
+		// a wildcard grants the matching permissions of the hierarchy, including their inherited permissions
+		for _, matched := range h.wildcardMatches(child) {
+			if inPath(path, matched) {
+				continue
+			}
+			matchedExpanded, err := h.expand(matched, path)
+			if err != nil {
+				return nil, err
+			}
+			expanded = append(expanded, matchedExpanded...)
+		}
+	}
+
+	expanded = uniquePermissions(expanded)
+	h.expanded[permission] = expanded
+
+	return expanded, nil
+}
+
+// Permissions returns all permissions with inherited permissions, sorted
+func (h *PermissionHierarchy) Permissions() []string {
+	permissions := make([]string, 0, len(h.inherited))
+	for permission := range h.inherited {
+		permissions = append(permissions, permission)
+	}
+	sort.Strings(permissions)
+	return permissions
+}
+
+// wildcardMatches returns the permissions of the hierarchy matched by a wildcard permission, e.g. admin.users for admin.*
+func (h *PermissionHierarchy) wildcardMatches(permission string) []string {
+	if !strings.HasSuffix(permission, PermissionWildcard) {
+		return nil
+	}
+
+	var matches []string
+	for _, p := range h.Permissions() {
+		if p != permission && MatchPermission(permission, p) {
+			matches = append(matches, p)
+		}
+	}
+	return matches
+}
+
+func inPath(path []string, permission string) bool {
+	for _, p := range path {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// Expand adds the inherited permissions to the given permissions, denied permissions are kept with the PermissionDenyPrefix.
+// Wildcard permissions also add the matching permissions of the hierarchy with their inherited permissions.
+func (h *PermissionHierarchy) Expand(permissions []string) []string {
+	var expanded []string
+	for _, permission := range permissions {
+		if inherited, ok := h.expanded[permission]; ok {
+			expanded = append(expanded, inherited...)
+		} else {
+			expanded = append(expanded, permission)
+		}
+
+		for _, matched := range h.wildcardMatches(permission) {
+			expanded = append(expanded, h.expanded[matched]...)
+		}
+	}
+	return uniquePermissions(expanded)
+}
+
+// SplitPermissions separates the granted permissions and the denied permissions, which are returned without the
+// PermissionDenyPrefix. Granted permissions which are denied are left out, wildcard grants are kept.
+func SplitPermissions(permissions []string) (granted []string, denied []string) {
+	for _, permission := range permissions {
+		if strings.HasPrefix(permission, PermissionDenyPrefix) {
+			denied = append(denied, strings.TrimPrefix(permission, PermissionDenyPrefix))
+		}
+	}
+
+	for _, permission := range permissions {
+		if strings.HasPrefix(permission, PermissionDenyPrefix) {
+			continue
+		}
+		if !strings.HasSuffix(permission, PermissionWildcard) && matchesAny(denied, permission) {
+			continue
+		}
+		granted = append(granted, permission)
+	}
+
+	return granted, denied
+}
+
+func matchesAny(permissions []string, permission string) bool {
+	for _, p := range permissions {
+		if MatchPermission(p, permission) {
+			return true
+		}
+	}
+	return false
+}
+
+func uniquePermissions(permissions []string) []string {
+	seen := make(map[string]bool, len(permissions))
+	unique := permissions[:0:0]
+	for _, permission := range permissions {
+		if !seen[permission] {
+			unique = append(unique, permission)
+			seen[permission] = true
+		}
+	}
+	return unique
+}
+
+// MatchPermission checks if the granted permission, which may end with the PermissionWildcard, matches the permission
+func MatchPermission(granted, permission string) bool {
+	if strings.HasSuffix(granted, PermissionWildcard) {
+		return strings.HasPrefix(permission, strings.TrimSuffix(granted, PermissionWildcard))
+	}
+	return granted == permission
+}
+
+// IsPermissionGranted checks if the permissions grant the desired permission and do not deny it
+func IsPermissionGranted(permissions []string, desired string) bool {
+	granted := false
+	for _, permission := range permissions {
+		if strings.HasPrefix(permission, PermissionDenyPrefix) {
+			if MatchPermission(strings.TrimPrefix(permission, PermissionDenyPrefix), desired) {
+				return false
+			}
+			continue
+		}
+		granted = granted || MatchPermission(permission, desired)
+	}
+	return granted
+}
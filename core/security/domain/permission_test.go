@@ -0,0 +1,82 @@
+package domain_test
+
+import (
+	"testing"
+
+	"flamingo.me/flamingo/v3/core/security/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewPermissionHierarchy(t *testing.T) {
+	hierarchy, err := domain.NewPermissionHierarchy(map[string][]string{
+		"PermissionSuperAdmin": {"PermissionAdmin", "*"},
+		"PermissionAdmin":      {"PermissionEditor", "order.*", "!order.delete"},
+		"PermissionEditor":     {"PermissionView"},
+		"PermissionView":       nil,
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"PermissionAdmin", "PermissionEditor", "PermissionSuperAdmin", "PermissionView"}, hierarchy.Permissions())
+	assert.Equal(t,
+		[]string{"PermissionAdmin", "PermissionEditor", "PermissionView", "order.*", "!order.delete", "custom"},
+		hierarchy.Expand([]string{"PermissionAdmin", "PermissionView", "custom"}),
+	)
+	assert.Equal(t,
+		[]string{"PermissionSuperAdmin", "PermissionAdmin", "PermissionEditor", "PermissionView", "order.*", "!order.delete", "*"},
+		hierarchy.Expand([]string{"PermissionSuperAdmin"}),
+	)
+}
+
+func TestNewPermissionHierarchy_Cycle(t *testing.T) {
+	_, err := domain.NewPermissionHierarchy(map[string][]string{
+		"PermissionA": {"PermissionB"},
+		"PermissionB": {"PermissionC"},
+		"PermissionC": {"PermissionA"},
+	})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "PermissionA -> PermissionB -> PermissionC -> PermissionA")
+
+	_, err = domain.NewPermissionHierarchy(map[string][]string{
+		"PermissionA": {"PermissionA"},
+	})
+	assert.Error(t, err)
+}
+
+func TestIsPermissionGranted(t *testing.T) {
+	permissions := []string{"PermissionView", "order.*", "!order.delete", "!report.*"}
+
+	assert.True(t, domain.IsPermissionGranted(permissions, "PermissionView"))
+	assert.True(t, domain.IsPermissionGranted(permissions, "order.edit"))
+	assert.True(t, domain.IsPermissionGranted(permissions, "order.items.edit"))
+	assert.False(t, domain.IsPermissionGranted(permissions, "order"))
+	assert.False(t, domain.IsPermissionGranted(permissions, "order.delete"))
+	assert.False(t, domain.IsPermissionGranted(permissions, "PermissionEdit"))
+	assert.False(t, domain.IsPermissionGranted(append(permissions, "*"), "report.view"))
+	assert.True(t, domain.IsPermissionGranted(append(permissions, "*"), "PermissionEdit"))
+}
+
+func TestPermissionHierarchy_ExpandWildcard(t *testing.T) {
+	hierarchy, err := domain.NewPermissionHierarchy(map[string][]string{
+		"PermissionAdmin": {"admin.*"},
+		"admin.root":      {"admin.*"},
+		"admin.users":     {"user.view", "!user.delete"},
+		"admin.orders":    {"order.view"},
+	})
+	require.NoError(t, err, "wildcards matching the permission itself are no cycle")
+
+	assert.Equal(t,
+		[]string{"PermissionAdmin", "admin.*", "admin.orders", "order.view", "admin.root", "admin.users", "user.view", "!user.delete"},
+		hierarchy.Expand([]string{"PermissionAdmin"}),
+	)
+	assert.Equal(t,
+		[]string{"admin.u*", "admin.users", "user.view", "!user.delete"},
+		hierarchy.Expand([]string{"admin.u*"}),
+	)
+}
+
+func TestSplitPermissions(t *testing.T) {
+	granted, denied := domain.SplitPermissions([]string{"order.*", "order.delete", "!order.delete", "report.view", "!report.*", "PermissionView"})
+	assert.Equal(t, []string{"order.*", "PermissionView"}, granted)
+	assert.Equal(t, []string{"order.delete", "report.*"}, denied)
+}
@@ -0,0 +1,466 @@
+package domain
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/pkg/errors"
+)
+
+type (
+	// Policy is a parsed policy expression, e.g. `object.ownerID == user.sub || "PermissionAdmin" in user.permissions`.
+	//
+	// Expressions support
+	//  - paths into the variables: user.sub, object.ownerID, object.items.0.id
+	//  - literals: "string", 'string', numbers, true, false and null
+	//  - comparisons: ==, !=, <, <=, >, >=
+	//  - membership: `value in list`, also for substrings and map keys
+	//  - logic: &&, ||, ! and parentheses
+	Policy struct {
+		expression string
+		root       policyNode
+	}
+
+	policyNode interface {
+		eval(vars map[string]interface{}) (interface{}, error)
+	}
+
+	policyLiteral struct{ value interface{} }
+	policyPath    struct{ path []string }
+	policyNot     struct{ node policyNode }
+	policyBinary  struct {
+		op          string
+		left, right policyNode
+	}
+
+	policyToken struct {
+		kind  string // "op", "ident", "string", "number", "eof"
+		value string
+		pos   int
+	}
+
+	policyParser struct {
+		tokens []policyToken
+		pos    int
+	}
+)
+
+// policyComparisons are the comparison operators, which can not be chained
+var policyComparisons = map[string]bool{"==": true, "!=": true, "<": true, "<=": true, ">": true, ">=": true}
+
+// ParsePolicy parses a policy expression
+func ParsePolicy(expression string) (*Policy, error) {
+	tokens, err := tokenizePolicy(expression)
+	if err != nil {
+		return nil, errors.Wrapf(err, "policy %q", expression)
+	}
+
+	parser := &policyParser{tokens: tokens}
+	root, err := parser.parseOr()
+	if err == nil && parser.peek().kind != "eof" {
+		err = errors.Errorf("unexpected %q at %d", parser.peek().value, parser.peek().pos)
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "policy %q", expression)
+	}
+
+	return &Policy{expression: expression, root: root}, nil
+}
+
+// String returns the expression
+func (p *Policy) String() string {
+	return p.expression
+}
+
+// Evaluate the policy with the given variables, e.g. user and object. The result must be a boolean.
+func (p *Policy) Evaluate(vars map[string]interface{}) (bool, error) {
+	result, err := p.root.eval(vars)
+	if err != nil {
+		return false, errors.Wrapf(err, "policy %q", p.expression)
+	}
+
+	b, ok := result.(bool)
+	if !ok {
+		return false, errors.Errorf("policy %q: result %v is not a boolean", p.expression, result)
+	}
+	return b, nil
+}
+
+func tokenizePolicy(expression string) ([]policyToken, error) {
+	var tokens []policyToken
+	runes := []rune(expression)
+
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+
+		case r == '"' || r == '\'':
+			start := i
+			var value strings.Builder
+			for i++; i < len(runes) && runes[i] != r; i++ {
+				if runes[i] == '\\' && i+1 < len(runes) {
+					i++
+				}
+				value.WriteRune(runes[i])
+			}
+			if i >= len(runes) {
+				return nil, errors.Errorf("unterminated string at %d", start)
+			}
+			i++
+			tokens = append(tokens, policyToken{kind: "string", value: value.String(), pos: start})
+
+		case unicode.IsDigit(r) || (r == '-' && i+1 < len(runes) && unicode.IsDigit(runes[i+1]) && precedesOperand(tokens)):
+			start := i
+			for i++; i < len(runes) && (unicode.IsDigit(runes[i]) || runes[i] == '.'); i++ {
+			}
+			tokens = append(tokens, policyToken{kind: "number", value: string(runes[start:i]), pos: start})
+
+		case unicode.IsLetter(r) || r == '_':
+			start := i
+			for i++; i < len(runes) && (unicode.IsLetter(runes[i]) || unicode.IsDigit(runes[i]) || runes[i] == '_' || runes[i] == '.'); i++ {
+			}
+			tokens = append(tokens, policyToken{kind: "ident", value: string(runes[start:i]), pos: start})
+
+		default:
+			op := ""
+			for _, candidate := range []string{"&&", "||", "==", "!=", "<=", ">=", "<", ">", "!", "(", ")"} {
+				if strings.HasPrefix(string(runes[i:]), candidate) {
+					op = candidate
+					break
+				}
+			}
+			if op == "" {
+				return nil, errors.Errorf("unexpected %q at %d", string(r), i)
+			}
+			tokens = append(tokens, policyToken{kind: "op", value: op, pos: i})
+			i += len(op)
+		}
+	}
+
+	return append(tokens, policyToken{kind: "eof", pos: len(runes)}), nil
+}
+
+// precedesOperand checks if a minus starts a negative number instead of being an unexpected operator
+func precedesOperand(tokens []policyToken) bool {
+	if len(tokens) == 0 {
+		return true
+	}
+	last := tokens[len(tokens)-1]
+	return (last.kind == "op" && last.value != ")") || (last.kind == "ident" && last.value == "in")
+}
+
+func (p *policyParser) peek() policyToken {
+	return p.tokens[p.pos]
+}
+
+func (p *policyParser) next() policyToken {
+	token := p.tokens[p.pos]
+	if token.kind != "eof" {
+		p.pos++
+	}
+	return token
+}
+
+func (p *policyParser) accept(kind, value string) bool {
+	if token := p.peek(); token.kind == kind && token.value == value {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *policyParser) parseOr() (policyNode, error) {
+	left, err := p.parseAnd()
+	for err == nil && p.accept("op", "||") {
+		var right policyNode
+		right, err = p.parseAnd()
+		left = &policyBinary{op: "||", left: left, right: right}
+	}
+	return left, err
+}
+
+func (p *policyParser) parseAnd() (policyNode, error) {
+	left, err := p.parseComparison()
+	for err == nil && p.accept("op", "&&") {
+		var right policyNode
+		right, err = p.parseComparison()
+		left = &policyBinary{op: "&&", left: left, right: right}
+	}
+	return left, err
+}
+
+func (p *policyParser) parseComparison() (policyNode, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+
+	token := p.peek()
+	isComparison := (token.kind == "op" && policyComparisons[token.value]) || (token.kind == "ident" && token.value == "in")
+	if !isComparison {
+		return left, nil
+	}
+	p.next()
+
+	right, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	return &policyBinary{op: token.value, left: left, right: right}, nil
+}
+
+func (p *policyParser) parseUnary() (policyNode, error) {
+	if p.accept("op", "!") {
+		node, err := p.parseUnary()
+		return &policyNot{node: node}, err
+	}
+	return p.parsePrimary()
+}
+
+func (p *policyParser) parsePrimary() (policyNode, error) {
+	token := p.next()
+	switch token.kind {
+	case "string":
+		return &policyLiteral{value: token.value}, nil
+	case "number":
+		f, err := strconv.ParseFloat(token.value, 64)
+		if err != nil {
+			return nil, errors.Errorf("invalid number %q at %d", token.value, token.pos)
+		}
+		return &policyLiteral{value: f}, nil
+	case "ident":
+		switch token.value {
+		case "true":
+			return &policyLiteral{value: true}, nil
+		case "false":
+			return &policyLiteral{value: false}, nil
+		case "null", "nil":
+			return &policyLiteral{value: nil}, nil
+		case "in":
+			return nil, errors.Errorf("unexpected \"in\" at %d", token.pos)
+		}
+		return &policyPath{path: strings.Split(token.value, ".")}, nil
+	case "op":
+		if token.value == "(" {
+			node, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			if !p.accept("op", ")") {
+				return nil, errors.Errorf("missing ) at %d", p.peek().pos)
+			}
+			return node, nil
+		}
+	case "eof":
+		return nil, errors.New("unexpected end of expression")
+	}
+	return nil, errors.Errorf("unexpected %q at %d", token.value, token.pos)
+}
+
+func (n *policyLiteral) eval(map[string]interface{}) (interface{}, error) {
+	return n.value, nil
+}
+
+// eval resolves the path, missing values are nil
+func (n *policyPath) eval(vars map[string]interface{}) (interface{}, error) {
+	var current interface{} = vars
+	for _, part := range n.path {
+		current = policyField(current, part)
+		if current == nil {
+			return nil, nil
+		}
+	}
+	return normalizePolicyValue(current), nil
+}
+
+func (n *policyNot) eval(vars map[string]interface{}) (interface{}, error) {
+	value, err := n.node.eval(vars)
+	if err != nil {
+		return nil, err
+	}
+	b, ok := value.(bool)
+	if !ok {
+		return nil, errors.Errorf("! expects a boolean, got %v", value)
+	}
+	return !b, nil
+}
+
+func (n *policyBinary) eval(vars map[string]interface{}) (interface{}, error) {
+	left, err := n.left.eval(vars)
+	if err != nil {
+		return nil, err
+	}
+
+	// logical operators short circuit, a missing value is false
+	if n.op == "&&" || n.op == "||" {
+		l := left == true
+		if (n.op == "&&" && !l) || (n.op == "||" && l) {
+			return l, nil
+		}
+		right, err := n.right.eval(vars)
+		if err != nil {
+			return nil, err
+		}
+		return right == true, nil
+	}
+
+	right, err := n.right.eval(vars)
+	if err != nil {
+		return nil, err
+	}
+
+	switch n.op {
+	case "==":
+		return policyEqual(left, right), nil
+	case "!=":
+		return !policyEqual(left, right), nil
+	case "in":
+		return policyContains(right, left), nil
+	}
+
+	return policyCompare(n.op, left, right)
+}
+
+// policyField returns the field of a map, struct (by json tag or case insensitive name) or list (by index)
+func policyField(value interface{}, name string) interface{} {
+	v := reflect.ValueOf(value)
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+
+	switch v.Kind() {
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			return nil
+		}
+		field := v.MapIndex(reflect.ValueOf(name).Convert(v.Type().Key()))
+		if !field.IsValid() {
+			return nil
+		}
+		return field.Interface()
+
+	case reflect.Struct:
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if field.PkgPath != "" {
+				continue
+			}
+			tag := strings.Split(field.Tag.Get("json"), ",")[0]
+			if tag == name || (tag == "" && strings.EqualFold(field.Name, name)) {
+				return v.Field(i).Interface()
+			}
+		}
+
+	case reflect.Slice, reflect.Array:
+		index, err := strconv.Atoi(name)
+		if err == nil && index >= 0 && index < v.Len() {
+			return v.Index(index).Interface()
+		}
+	}
+
+	return nil
+}
+
+// normalizePolicyValue converts numbers to float64, and named string and bool types to their base type
+func normalizePolicyValue(value interface{}) interface{} {
+	v := reflect.ValueOf(value)
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint())
+	case reflect.Float32, reflect.Float64:
+		return v.Float()
+	case reflect.String:
+		return v.String()
+	case reflect.Bool:
+		return v.Bool()
+	case reflect.Ptr:
+		if v.IsNil() {
+			return nil
+		}
+	}
+	return value
+}
+
+func policyEqual(a, b interface{}) bool {
+	a, b = normalizePolicyValue(a), normalizePolicyValue(b)
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+// policyContains checks if the list contains the element, the string the substring, or the map the key
+func policyContains(container, element interface{}) bool {
+	v := reflect.ValueOf(container)
+	switch v.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			if policyEqual(v.Index(i).Interface(), element) {
+				return true
+			}
+		}
+	case reflect.String:
+		s, ok := normalizePolicyValue(element).(string)
+		return ok && strings.Contains(v.String(), s)
+	case reflect.Map:
+		if v.Type().Key().Kind() == reflect.String {
+			s, ok := normalizePolicyValue(element).(string)
+			return ok && v.MapIndex(reflect.ValueOf(s).Convert(v.Type().Key())).IsValid()
+		}
+	}
+	return false
+}
+
+// policyCompare compares numbers or strings, values of other types can not be ordered
+func policyCompare(op string, a, b interface{}) (interface{}, error) {
+	a, b = normalizePolicyValue(a), normalizePolicyValue(b)
+
+	var cmp int
+	switch a := a.(type) {
+	case float64:
+		b, ok := b.(float64)
+		if !ok {
+			return false, nil
+		}
+		switch {
+		case a < b:
+			cmp = -1
+		case a > b:
+			cmp = 1
+		}
+	case string:
+		b, ok := b.(string)
+		if !ok {
+			return false, nil
+		}
+		cmp = strings.Compare(a, b)
+	default:
+		if a == nil || b == nil {
+			return false, nil
+		}
+		return nil, errors.Errorf("%s can not compare %s", op, fmt.Sprintf("%T and %T", a, b))
+	}
+
+	switch op {
+	case "<":
+		return cmp < 0, nil
+	case "<=":
+		return cmp <= 0, nil
+	case ">":
+		return cmp > 0, nil
+	case ">=":
+		return cmp >= 0, nil
+	}
+	return nil, errors.Errorf("unknown operator %s", op)
+}
@@ -0,0 +1,90 @@
+package domain_test
+
+import (
+	"testing"
+
+	"flamingo.me/flamingo/v3/core/security/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type policyOrder struct {
+	OwnerID string `json:"ownerID"`
+	Total   int
+	Items   []string
+}
+
+func TestPolicy_Evaluate(t *testing.T) {
+	vars := map[string]interface{}{
+		"user": map[string]interface{}{
+			"sub":         "user-1",
+			"groups":      []string{"sales", "support"},
+			"permissions": []string{"PermissionAuthorized"},
+			"level":       3,
+		},
+		"object": &policyOrder{OwnerID: "user-1", Total: 150, Items: []string{"a", "b"}},
+	}
+
+	tests := []struct {
+		expression string
+		want       bool
+	}{
+		{`object.ownerID == user.sub`, true},
+		{`object.ownerID != user.sub`, false},
+		{`object.total > 100 && user.level >= 3`, true},
+		{`object.total < 100 || "sales" in user.groups`, true},
+		{`"PermissionAdmin" in user.permissions`, false},
+		{`!("PermissionAdmin" in user.permissions)`, true},
+		{`object.items.1 == 'b'`, true},
+		{`user.missing == null`, true},
+		{`"user" in user.sub`, true},
+		{`user.level > -1`, true},
+		{`true && (false || true)`, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expression, func(t *testing.T) {
+			policy, err := domain.ParsePolicy(tt.expression)
+			require.NoError(t, err)
+			got, err := policy.Evaluate(vars)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestPolicy_EvaluateErrors(t *testing.T) {
+	vars := map[string]interface{}{"user": map[string]interface{}{"sub": "user-1", "level": 3}}
+
+	for _, expression := range []string{
+		`user.missing`,
+		`user.sub`,
+		`!user.sub`,
+		`true > false`,
+	} {
+		t.Run(expression, func(t *testing.T) {
+			policy, err := domain.ParsePolicy(expression)
+			require.NoError(t, err)
+			_, err = policy.Evaluate(vars)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestParsePolicy_Errors(t *testing.T) {
+	for _, expression := range []string{
+		``,
+		`user.sub ==`,
+		`(user.sub == "a"`,
+		`user.sub == "a`,
+		`user.sub = "a"`,
+		`a == b == c`,
+		`in user.groups`,
+		`user.level == 3 && object.total - 150`,
+	} {
+		t.Run(expression, func(t *testing.T) {
+			_, err := domain.ParsePolicy(expression)
+			assert.Error(t, err)
+		})
+	}
+}
@@ -0,0 +1,82 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"flamingo.me/flamingo/v3/core/security/application/role"
+	"flamingo.me/flamingo/v3/core/security/domain"
+	"flamingo.me/flamingo/v3/framework/config"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+// Command groups the security commands
+func Command(area *config.Area) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "security",
+		Short: "Security configuration",
+	}
+
+	cmd.AddCommand(hierarchyCmd(area))
+
+	return cmd
+}
+
+// hierarchyCmd prints the permissions granted and denied by each permission of the hierarchy.
+// The roles of a user are only known at runtime by the role providers, so they are not resolved.
+func hierarchyCmd(area *config.Area) *cobra.Command {
+	var contextName string
+
+	cmd := &cobra.Command{
+		Use:   "hierarchy [permission...]",
+		Short: "Print the permissions granted and denied by each permission of the security.roles.permissionHierarchy",
+		Long: "Print the permissions granted and denied by each permission of the security.roles.permissionHierarchy.\n" +
+			"The permissions of a role are those of its permissions, roles of users are not resolved.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if contextName != "" {
+				flatArea, err := area.Flat()
+				if err != nil {
+					return err
+				}
+				contextArea, ok := flatArea[contextName]
+				if !ok {
+					return errors.Errorf("unknown context %q", contextName)
+				}
+				area = contextArea
+			}
+
+			cfg, _ := area.Config("security.roles.permissionHierarchy")
+			hierarchyConfig, _ := cfg.(config.Map)
+			hierarchy, err := role.ParsePermissionHierarchy(hierarchyConfig)
+			if err != nil {
+				return err
+			}
+
+			permissions := args
+			if len(permissions) == 0 {
+				permissions = hierarchy.Permissions()
+			}
+			printPermissions(cmd.OutOrStdout(), hierarchy, permissions)
+
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&contextName, "context", "c", "", "Name of the context (relative context path), defaults to the root context")
+
+	return cmd
+}
+
+func printPermissions(w io.Writer, hierarchy *domain.PermissionHierarchy, permissions []string) {
+	for _, permission := range permissions {
+		granted, denied := domain.SplitPermissions(hierarchy.Expand([]string{permission}))
+
+		fmt.Fprintln(w, permission)
+		fmt.Fprintf(w, "  granted: %s\n", strings.Join(granted, ", "))
+		if len(denied) > 0 {
+			fmt.Fprintf(w, "  denied:  %s\n", strings.Join(denied, ", "))
+		}
+	}
+}
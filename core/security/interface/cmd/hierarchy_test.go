@@ -0,0 +1,28 @@
+package cmd
+
+import (
+	"bytes"
+	"testing"
+
+	"flamingo.me/flamingo/v3/core/security/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPrintPermissions(t *testing.T) {
+	hierarchy, err := domain.NewPermissionHierarchy(map[string][]string{
+		"PermissionAdmin":  {"PermissionEditor", "order.*", "!order.delete"},
+		"PermissionEditor": {"PermissionView"},
+	})
+	require.NoError(t, err)
+
+	buf := new(bytes.Buffer)
+	printPermissions(buf, hierarchy, hierarchy.Permissions())
+
+	assert.Equal(t, `PermissionAdmin
+  granted: PermissionAdmin, PermissionEditor, PermissionView, order.*
+  denied:  order.delete
+PermissionEditor
+  granted: PermissionEditor, PermissionView
+`, buf.String())
+}
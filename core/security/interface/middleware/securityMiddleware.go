@@ -48,7 +48,10 @@ type (
 	}
 )
 
-var _ RedirectURLMaker = new(RedirectURLMakerImpl)
+var (
+	_ RedirectURLMaker = new(RedirectURLMakerImpl)
+	_ web.RouteGuard   = new(SecurityMiddleware)
+)
 
 // Inject dependencies
 func (r *RedirectURLMakerImpl) Inject(router web.ReverseRouter) {
@@ -127,9 +130,51 @@ func (m *SecurityMiddleware) HandleIfNotGrantedWithFallback(action web.Action, f
 	return m.handleForPermissionAndFallback(action, fallback, false, permission)
 }
 
+// Guard applies the protection of a route declared in the routes.yml:
+// users which are not logged in are redirected to the login, users without the permission get a 403 page.
+// If the route has a fallback route name or path, users are redirected there instead.
+func (m *SecurityMiddleware) Guard(protection web.RouteProtection, action web.Action) web.Action {
+	return func(ctx context.Context, req *web.Request) web.Result {
+		if protection.RequiresLogin && !m.securityService.IsLoggedIn(ctx, req.Session()) {
+			return m.routeFallback(ctx, req, protection.Fallback, m.RedirectToLoginFallback)
+		}
+
+		if protection.Permission != "" && !m.securityService.IsGranted(ctx, req.Session(), protection.Permission, nil) {
+			m.logIfNeeded(req, fmt.Sprintf("request to protected route without permission %s", protection.Permission))
+			return m.routeFallback(ctx, req, protection.Fallback, m.forbiddenAction(protection.Permission))
+		}
+
+		return action(ctx, req)
+	}
+}
+
+// routeFallback redirects to the fallback route or path, or calls the denied action if there is no fallback
+func (m *SecurityMiddleware) routeFallback(ctx context.Context, req *web.Request, fallback string, denied web.Action) web.Result {
+	if fallback == "" {
+		return denied(ctx, req)
+	}
+
+	if strings.HasPrefix(fallback, "/") {
+		redirectURL, err := m.redirectURLMaker.URL(ctx, fallback)
+		if err != nil {
+			m.logger.Error(err)
+			return denied(ctx, req)
+		}
+		return m.responder.URLRedirect(redirectURL)
+	}
+
+	return m.responder.RouteRedirect(fallback, nil)
+}
+
 // RedirectToLoginFallback fallback helper action
 func (m *SecurityMiddleware) RedirectToLoginFallback(ctx context.Context, req *web.Request) web.Result {
 	m.logIfNeeded(req, "request to only-authenticated page as unauthenticated user")
+	// stateless API clients can not follow the login redirect
+	if isBearerRequest(req) {
+		response := m.responder.Unauthorized(errors.New("invalid bearer token"))
+		response.Header.Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+		return response
+	}
 	redirectURL := m.redirectURL(ctx, req, m.loginPathRedirectStrategy, m.loginPathRedirectPath, req.Request().URL.String())
 	return m.responder.RouteRedirect("auth.login", map[string]string{
 		"redirecturl": redirectURL.String(),
@@ -143,6 +188,11 @@ func (m *SecurityMiddleware) RedirectToLogoutFallback(ctx context.Context, req *
 	return m.responder.URLRedirect(redirectURL)
 }
 
+func isBearerRequest(req *web.Request) bool {
+	header := req.Request().Header.Get("Authorization")
+	return len(header) > 7 && strings.EqualFold(header[:7], "Bearer ")
+}
+
 func (m *SecurityMiddleware) handleForPermissionAndFallback(action web.Action, fallback web.Action, ifGranted bool, permission string) web.Action {
 	return func(ctx context.Context, req *web.Request) web.Result {
 		granted := m.securityService.IsGranted(ctx, req.Session(), permission, nil)
@@ -143,6 +143,24 @@ func (t *SecurityMiddlewareTestSuite) TestRedirectToLoginFallback() {
 	t.Equal("auth.login", response.To)
 }
 
+func (t *SecurityMiddlewareTestSuite) TestRedirectToLoginFallback_Bearer() {
+	request := web.CreateRequest(&http.Request{
+		URL: &url.URL{
+			Path: "/api",
+		},
+		Header: http.Header{
+			"Authorization": []string{"Bearer invalid"},
+		},
+	}, t.webSession)
+
+	result := t.middleware.RedirectToLoginFallback(t.context, request)
+	response, ok := result.(*web.ServerErrorResponse)
+
+	t.True(ok)
+	t.Equal(uint(http.StatusUnauthorized), response.Response.Status)
+	t.Equal(`Bearer error="invalid_token"`, response.Header.Get("WWW-Authenticate"))
+}
+
 func (t *SecurityMiddlewareTestSuite) TestHandleIfLoggedOut_ForbiddenWithReferrer() {
 	redirectURL, err := url.Parse("/http-referrer")
 	t.NoError(err)
@@ -236,3 +254,51 @@ func (t *SecurityMiddlewareTestSuite) TestHandleIfNotGranted_Allowed() {
 	result := action(t.context, t.request)
 	t.Exactly(t.response, result)
 }
+
+func (t *SecurityMiddlewareTestSuite) TestGuard_RequiresLogin() {
+	redirectURL, err := url.Parse("/home")
+	t.NoError(err)
+
+	action := t.middleware.Guard(web.RouteProtection{RequiresLogin: true}, t.action)
+	t.middleware.loginPathRedirectStrategy = PathRedirectStrategy
+	t.securityService.On("IsLoggedIn", t.context, t.webSession).Return(false).Once()
+	t.redirectURLMaker.On("URL", t.context, "/home").Return(redirectURL, nil).Once()
+
+	response, ok := action(t.context, t.request).(*web.RouteRedirectResponse)
+	t.True(ok)
+	t.Equal("auth.login", response.To)
+
+	t.securityService.On("IsLoggedIn", t.context, t.webSession).Return(true).Once()
+	t.Exactly(t.response, action(t.context, t.request))
+}
+
+func (t *SecurityMiddlewareTestSuite) TestGuard_Permission() {
+	action := t.middleware.Guard(web.RouteProtection{Permission: "SomePermission"}, t.action)
+	t.securityService.On("IsGranted", t.context, t.webSession, "SomePermission", nil).Return(false).Once()
+
+	response, ok := action(t.context, t.request).(*web.ServerErrorResponse)
+	t.True(ok)
+	t.Equal(uint(http.StatusForbidden), response.Response.Status)
+
+	t.securityService.On("IsGranted", t.context, t.webSession, "SomePermission", nil).Return(true).Once()
+	t.Exactly(t.response, action(t.context, t.request))
+}
+
+func (t *SecurityMiddlewareTestSuite) TestGuard_Fallback() {
+	action := t.middleware.Guard(web.RouteProtection{Permission: "SomePermission", Fallback: "shop.upgrade"}, t.action)
+	t.securityService.On("IsGranted", t.context, t.webSession, "SomePermission", nil).Return(false).Once()
+
+	routeRedirect, ok := action(t.context, t.request).(*web.RouteRedirectResponse)
+	t.True(ok)
+	t.Equal("shop.upgrade", routeRedirect.To)
+
+	redirectURL, err := url.Parse("https://shop.example/upgrade")
+	t.NoError(err)
+	action = t.middleware.Guard(web.RouteProtection{RequiresLogin: true, Fallback: "/upgrade"}, t.action)
+	t.securityService.On("IsLoggedIn", t.context, t.webSession).Return(false).Once()
+	t.redirectURLMaker.On("URL", t.context, "/upgrade").Return(redirectURL, nil).Once()
+
+	urlRedirect, ok := action(t.context, t.request).(*web.URLRedirectResponse)
+	t.True(ok)
+	t.Equal(redirectURL, urlRedirect.URL)
+}
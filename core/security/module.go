@@ -3,13 +3,17 @@ package security
 import (
 	"flamingo.me/dingo"
 	"flamingo.me/flamingo/v3/core/security/application"
+	"flamingo.me/flamingo/v3/core/security/application/audit"
 	"flamingo.me/flamingo/v3/core/security/application/role"
 	"flamingo.me/flamingo/v3/core/security/application/voter"
 	"flamingo.me/flamingo/v3/core/security/domain"
+	"flamingo.me/flamingo/v3/core/security/interface/cmd"
 	"flamingo.me/flamingo/v3/core/security/interface/controller"
 	"flamingo.me/flamingo/v3/core/security/interface/middleware"
 	"flamingo.me/flamingo/v3/framework/config"
+	"flamingo.me/flamingo/v3/framework/flamingo"
 	"flamingo.me/flamingo/v3/framework/web"
+	"github.com/spf13/cobra"
 )
 
 type (
@@ -39,9 +43,16 @@ func (m *Module) Configure(injector *dingo.Injector) {
 
 	injector.BindMulti(new(voter.SecurityVoter)).To(voter.IsLoggedInVoter{})
 	injector.BindMulti(new(voter.SecurityVoter)).To(voter.PermissionVoter{})
+	injector.BindMulti(new(voter.SecurityVoter)).To(voter.ObjectVoterRegistry{})
+	injector.BindMulti(new(voter.SecurityVoter)).To(voter.PolicyVoter{})
 	injector.Bind(new(role.Service)).To(role.ServiceImpl{})
 	injector.Bind(new(application.SecurityService)).To(application.SecurityServiceImpl{})
 	injector.Bind(new(middleware.RedirectURLMaker)).To(middleware.RedirectURLMakerImpl{})
+	injector.BindMulti(new(web.RouteGuard)).To(middleware.SecurityMiddleware{})
+	injector.Bind(audit.Auditor{}).In(dingo.ChildSingleton)
+	injector.BindMulti(new(audit.Sink)).To(audit.LogSink{})
+	flamingo.BindEventSubscriber(injector).To(audit.Subscriber{})
+	injector.BindMulti(new(cobra.Command)).ToProvider(cmd.Command)
 }
 
 // DefaultConfig for core security module
@@ -66,7 +77,12 @@ func (m *Module) DefaultConfig() config.Map {
 					"allowIfAllAbstain": false,
 				},
 			},
+			"policies":     config.Map{},
 			"eventLogging": false,
+			"audit": config.Map{
+				"enabled": false,
+				"redact":  config.Slice{},
+			},
 		},
 	}
 }
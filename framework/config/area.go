@@ -2,10 +2,10 @@
 package config
 
 import (
-	"encoding/json"
 	"fmt"
 	"reflect"
 	"strings"
+	"sync"
 
 	"flamingo.me/dingo"
 	"github.com/pkg/errors"
@@ -25,6 +25,16 @@ type (
 		Routes        []Route
 		Configuration Map
 		LoadedConfig  Map
+
+		// basedir is the directory the configuration has been loaded from
+		basedir string
+		// source is the original area a flat area has been derived from
+		source *Area
+		// derived holds the flat areas derived from this area, by their relative name
+		derived map[string]*Area
+		// loadHistory holds the provenance of all loaded config keys, history adds module defaults and overrides
+		loadHistory map[string][]Provenance
+		history     map[string][]Provenance
 	}
 
 	// Map contains configuration
@@ -48,9 +58,18 @@ type (
 		Path       string
 		Controller string
 		Name       string
+		// RequiresLogin restricts the route to logged in users
+		RequiresLogin bool
+		// Permission restricts the route to users granted the permission
+		Permission string
+		// Fallback is the route name or path to redirect to if the access is denied
+		Fallback string
 	}
 )
 
+// areaMu guards the fields of all areas which Reload replaces, while requests read them concurrently
+var areaMu sync.RWMutex
+
 // NewArea creates a new Area with optional childs
 func NewArea(name string, modules []dingo.Module, childs ...*Area) *Area {
 	ctx := &Area{
@@ -194,36 +213,25 @@ func (m Map) Flat() Map {
 	return res
 }
 
-// MapInto tries to map the configuration map into a given interface
+// MapInto tries to map the configuration map into a given interface.
+// Numbers are converted to the int, uint and float types of the target, strings like "30s" to time.Duration,
+// sizes like "10MB" to ByteSize and strings to url.URL. Errors name the offending key.
 func (m Map) MapInto(out interface{}) error {
-	jsonBytes, err := json.Marshal(m)
-
-	if err != nil {
-		return errors.Wrap(err, "Problem with marshaling map")
-	}
-
-	err = json.Unmarshal(jsonBytes, out)
-	if err != nil {
-		return errors.Wrap(err, fmt.Sprintf("Problem with unmarshaling into given structure %T", out))
-	}
-
-	return nil
+	return mapInto(m, out)
 }
 
-// MapInto tries to map the configuration map into a given interface
+// MapInto tries to map the configuration slice into a given interface, see Map.MapInto
 func (s Slice) MapInto(out interface{}) error {
-	jsonBytes, err := json.Marshal(s)
-
-	if err != nil {
-		return errors.Wrap(err, "Problem with marshaling map")
-	}
+	return mapInto(s, out)
+}
 
-	err = json.Unmarshal(jsonBytes, &out)
-	if err != nil {
-		return errors.Wrap(err, fmt.Sprintf("Problem with unmarshaling into given structure %T", out))
+func mapInto(in, out interface{}) error {
+	v := reflect.ValueOf(out)
+	if v.Kind() != reflect.Ptr || v.IsNil() {
+		return errors.Errorf("Problem with unmarshaling into given structure %T: not a pointer", out)
 	}
 
-	return nil
+	return errors.Wrap(decode("", in, v.Elem()), fmt.Sprintf("Problem with unmarshaling into given structure %T", out))
 }
 
 // Get a value by it's path
@@ -276,39 +284,15 @@ func (area *Area) GetInitializedInjector() (*dingo.Injector, error) {
 
 	area.Modules = resolveDependencies(area.Modules, nil)
 
-	area.Configuration = make(Map)
-	for _, module := range area.Modules {
-		if cfgmodule, ok := module.(DefaultConfigModule); ok {
-			if err := area.Configuration.Add(cfgmodule.DefaultConfig()); err != nil {
-				return nil, err
-			}
-		}
-	}
-
-	if err := area.Configuration.Add(Map{"area": area.Name}); err != nil {
+	if err := area.configure(); err != nil {
 		return nil, err
 	}
-	if err := area.Configuration.Add(area.LoadedConfig); err != nil {
-		return nil, err
-	}
-
-	for _, module := range area.Modules {
-		if cfgmodule, ok := module.(OverrideConfigModule); ok {
-			if err := area.Configuration.Add(cfgmodule.OverrideConfig(area.Configuration)); err != nil {
-				return nil, err
-			}
-		}
-	}
 
 	for k, v := range area.Configuration.Flat() {
 		if v == nil {
 			continue
 		}
-		injector.Bind(v).AnnotatedWith("config:" + k).ToInstance(v)
-		if vf, ok := v.(float64); ok && vf == float64(int64(vf)) {
-			injector.Bind(new(int64)).AnnotatedWith("config:" + k).ToInstance(int64(vf))
-			injector.Bind(new(int)).AnnotatedWith("config:" + k).ToInstance(int(int64(vf)))
-		}
+		bindConfig(injector, k, v)
 	}
 
 	if config, ok := area.Configuration.Get("flamingo.modules.disabled"); ok {
@@ -322,6 +306,47 @@ func (area *Area) GetInitializedInjector() (*dingo.Injector, error) {
 	return injector, nil
 }
 
+// configure builds the area's configuration from the modules default configuration, the loaded config and overrides
+func (area *Area) configure() error {
+	configuration := make(Map)
+	history := make(map[string][]Provenance)
+	for _, module := range area.Modules {
+		if cfgmodule, ok := module.(DefaultConfigModule); ok {
+			defaultConfig := cfgmodule.DefaultConfig()
+			if err := configuration.Add(defaultConfig); err != nil {
+				return err
+			}
+			history = recordProvenance(history, defaultConfig, "default of "+moduleName(module), nil)
+		}
+	}
+
+	if err := configuration.Add(Map{"area": area.Name}); err != nil {
+		return err
+	}
+	history = recordProvenance(history, Map{"area": area.Name}, "area", nil)
+
+	if err := configuration.Add(area.LoadedConfig); err != nil {
+		return err
+	}
+	for k, loaded := range area.loadHistory {
+		history[k] = append(history[k], loaded...)
+	}
+
+	for _, module := range area.Modules {
+		if cfgmodule, ok := module.(OverrideConfigModule); ok {
+			overrideConfig := cfgmodule.OverrideConfig(configuration)
+			if err := configuration.Add(overrideConfig); err != nil {
+				return err
+			}
+			history = recordProvenance(history, overrideConfig, "override of "+moduleName(module), nil)
+		}
+	}
+
+	area.Configuration = configuration
+	area.history = history
+	return nil
+}
+
 func disableModule(input []dingo.Module, disabled string) []dingo.Module {
 	for i, module := range input {
 		tm := reflect.TypeOf(module).Elem()
@@ -348,7 +373,10 @@ func (area *Area) Flat() (map[string]*Area, error) {
 			return nil, err
 		}
 		for cn, flatchild := range flat {
-			res[area.Name+`/`+cn] = MergeFrom(*flatchild, *area)
+			merged := MergeFrom(*flatchild, *area)
+			merged.source = flatchild.origin()
+			merged.source.derive(area.Name+`/`+cn, merged)
+			res[area.Name+`/`+cn] = merged
 		}
 	}
 
@@ -362,33 +390,64 @@ func MergeFrom(baseContext, incomingContext Area) *Area {
 		baseContext.Configuration = make(Map)
 	}
 
+	baseContext.Routes = mergeRoutes(baseContext.Routes, incomingContext.Routes)
+	baseContext.derived = nil
+
+	var err error
+	if baseContext.Injector, err = baseContext.GetInitializedInjector(); err != nil {
+		panic(err)
+	}
+
+	return &baseContext
+}
+
+// mergeRoutes adds all incoming routes for controllers which are not already routed
+func mergeRoutes(base, incoming []Route) []Route {
 	knownhandler := make(map[string]bool)
-	for _, route := range baseContext.Routes {
+	for _, route := range base {
 		knownhandler[route.Controller] = true
 	}
 
-	for _, route := range incomingContext.Routes {
+	for _, route := range incoming {
 		if !knownhandler[route.Controller] {
-			baseContext.Routes = append(baseContext.Routes, route)
+			base = append(base, route)
 		}
 	}
 
-	var err error
-	if baseContext.Injector, err = baseContext.GetInitializedInjector(); err != nil {
-		panic(err)
+	return base
+}
+
+// origin returns the area a flat area has been derived from, or the area itself
+func (area *Area) origin() *Area {
+	if area.source != nil {
+		return area.source
 	}
+	return area
+}
 
-	return &baseContext
+// derive remembers a flat area derived from this area, so it can be updated on reload
+func (area *Area) derive(name string, derived *Area) {
+	if area.derived == nil {
+		area.derived = make(map[string]*Area)
+	}
+	area.derived[name] = derived
 }
 
 // Config get a config value (recursive thru all parents if possible)
 func (area *Area) Config(key string) (interface{}, bool) {
+	areaMu.RLock()
+	defer areaMu.RUnlock()
+
+	return area.config(key)
+}
+
+func (area *Area) config(key string) (interface{}, bool) {
 	if config, ok := area.Configuration.Get(key); ok {
 		return config, true
 	}
 
 	if area.Parent != nil {
-		return area.Parent.Config(key)
+		return area.Parent.config(key)
 	}
 
 	return nil, false
@@ -396,13 +455,14 @@ func (area *Area) Config(key string) (interface{}, bool) {
 
 // HasConfigKey checks recursive if the config has a given key
 func (area *Area) HasConfigKey(key string) bool {
-	if _, ok := area.Configuration.Get(key); ok {
-		return true
-	}
+	_, ok := area.Config(key)
+	return ok
+}
 
-	if area.Parent != nil {
-		return area.Parent.HasConfigKey(key)
-	}
+// GetRoutes returns the routes of the area, safe to be called while the configuration is reloaded
+func (area *Area) GetRoutes() []Route {
+	areaMu.RLock()
+	defer areaMu.RUnlock()
 
-	return false
+	return area.Routes
 }
@@ -3,13 +3,16 @@ package config
 import (
 	"encoding/json"
 	"fmt"
+	"sort"
 
+	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
 )
 
 // Cmd command: The Area for which the config is to be printed need to be passed. This will be done by Dingo if a Provider is used for example.
 func Cmd(area *Area) *cobra.Command {
 	var contextName string
+	var explain string
 
 	cmd := &cobra.Command{
 		Use:   "config",
@@ -25,10 +28,12 @@ func Cmd(area *Area) *cobra.Command {
 				}
 			}
 
-			if len(args) > 0 {
+			if explain != "" {
+				explainConfigArea(area, explain, contextName == "")
+			} else if len(args) > 0 {
 				for _, c := range args {
 					cfg, _ := area.Config(c)
-					x, _ := json.MarshalIndent(cfg, "", "  ")
+					x, _ := json.MarshalIndent(maskSecrets(c, cfg), "", "  ")
 					fmt.Println(c + ":")
 					fmt.Println(string(x))
 					fmt.Println()
@@ -46,6 +51,63 @@ func Cmd(area *Area) *cobra.Command {
 		"",
 		"Name of the context (relative context path) - set this if you like to see only this context. Otherwise it will show all.",
 	)
+	cmd.Flags().StringVar(&explain, "explain", "", "Show where the values of the given config key (and its sub keys) have been set, in loading order")
+
+	cmd.AddCommand(diffCmd(area))
+
+	return cmd
+}
+
+func diffCmd(area *Area) *cobra.Command {
+	var contexts []string
+
+	cmd := &cobra.Command{
+		Use:   "diff [areaA areaB]",
+		Short: "Compare the configuration of two areas, or of all areas for two CONTEXT values",
+		Long: `Compare the configuration of two areas, e.g.: config diff root/de root/en
+Or compare the configuration for two CONTEXT values (optionally limited to given areas), e.g.: config diff --context dev --context prod`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(contexts) == 0 {
+				if len(args) != 2 {
+					return errors.New("two areas or two --context values are required")
+				}
+				left, right := area.areaByPath(args[0]), area.areaByPath(args[1])
+				if left == nil || right == nil {
+					return errors.Errorf("unknown area, known areas are: %v", area.areaPaths())
+				}
+				printDiff(args[0]+" -> "+args[1], Diff(left.EffectiveConfig(), right.EffectiveConfig()))
+				return nil
+			}
+
+			if len(contexts) != 2 {
+				return errors.New("exactly two --context values are required")
+			}
+
+			left, err := LoadContexts(area, contexts[0])
+			if err != nil {
+				return err
+			}
+			right, err := LoadContexts(area, contexts[1])
+			if err != nil {
+				return err
+			}
+
+			paths := args
+			if len(paths) == 0 {
+				paths = area.areaPaths()
+			}
+			for _, path := range paths {
+				leftArea, rightArea := left.areaByPath(path), right.areaByPath(path)
+				if leftArea == nil || rightArea == nil {
+					return errors.Errorf("unknown area %q, known areas are: %v", path, area.areaPaths())
+				}
+				printDiff(path+": CONTEXT "+contexts[0]+" -> "+contexts[1], Diff(leftArea.EffectiveConfig(), rightArea.EffectiveConfig()))
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringArrayVar(&contexts, "context", nil, "CONTEXT value to compare, must be given twice, e.g. --context dev --context prod")
 
 	return cmd
 }
@@ -55,9 +117,69 @@ func dumpConfigArea(a *Area) {
 	fmt.Println("**************************")
 	fmt.Println("Area: ", a.Name)
 	fmt.Println("**************************")
-	x, _ := json.MarshalIndent(a.Configuration, "", "  ")
+	x, _ := json.MarshalIndent(maskSecrets("", a.Configuration), "", "  ")
 	fmt.Println(string(x))
 	for _, routeConfig := range a.Childs {
 		dumpConfigArea(routeConfig)
 	}
 }
+
+func explainConfigArea(a *Area, key string, recursive bool) {
+	fmt.Println()
+	fmt.Println("**************************")
+	fmt.Println("Area: ", a.Name)
+	fmt.Println("**************************")
+
+	explained := a.Explain(key)
+	keys := make([]string, 0, len(explained))
+	for k := range explained {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	if len(keys) == 0 {
+		fmt.Println(key + ": not set")
+	}
+	for _, k := range keys {
+		fmt.Println(k + ":")
+		for i, p := range explained[k] {
+			marker := " "
+			if i == len(explained[k])-1 {
+				marker = "*"
+			}
+			fmt.Printf("  %s %s  (%s)\n", marker, formatValue(k, p.Value), p.Source)
+		}
+	}
+
+	if recursive {
+		for _, child := range a.Childs {
+			explainConfigArea(child, key, recursive)
+		}
+	}
+}
+
+func printDiff(title string, diff []Difference) {
+	fmt.Println()
+	fmt.Println("**************************")
+	fmt.Println("Diff: ", title)
+	fmt.Println("**************************")
+
+	if len(diff) == 0 {
+		fmt.Println("no differences")
+	}
+	for _, d := range diff {
+		switch {
+		case !d.InRight:
+			fmt.Printf("- %s: %s\n", d.Key, formatValue(d.Key, d.Left))
+		case !d.InLeft:
+			fmt.Printf("+ %s: %s\n", d.Key, formatValue(d.Key, d.Right))
+		default:
+			fmt.Printf("~ %s: %s -> %s\n", d.Key, formatValue(d.Key, d.Left), formatValue(d.Key, d.Right))
+		}
+	}
+}
+
+func formatValue(key string, v interface{}) string {
+	x, _ := json.Marshal(maskSecrets(key, v))
+	return string(x)
+}
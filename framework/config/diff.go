@@ -0,0 +1,119 @@
+package config
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+type (
+	// Difference describes a configuration key with different values in two configurations
+	Difference struct {
+		Key     string
+		Left    interface{}
+		InLeft  bool
+		Right   interface{}
+		InRight bool
+	}
+)
+
+// Diff compares the leaf values of two configuration maps, sorted by key
+func Diff(left, right Map) []Difference {
+	leftFlat, rightFlat := flatLeaves(left), flatLeaves(right)
+
+	keys := make(map[string]struct{}, len(leftFlat)+len(rightFlat))
+	for k := range leftFlat {
+		keys[k] = struct{}{}
+	}
+	for k := range rightFlat {
+		keys[k] = struct{}{}
+	}
+
+	var result []Difference
+	for k := range keys {
+		l, inLeft := leftFlat[k]
+		r, inRight := rightFlat[k]
+		if inLeft == inRight && reflect.DeepEqual(l, r) {
+			continue
+		}
+		result = append(result, Difference{Key: k, Left: l, InLeft: inLeft, Right: r, InRight: inRight})
+	}
+
+	sort.Slice(result, func(i, j int) bool { return result[i].Key < result[j].Key })
+	return result
+}
+
+// EffectiveConfig returns the configuration of an area including all values inherited from its parents
+func (area *Area) EffectiveConfig() Map {
+	areaMu.RLock()
+	defer areaMu.RUnlock()
+
+	return area.effectiveConfig()
+}
+
+func (area *Area) effectiveConfig() Map {
+	result := make(Map)
+	if area.Parent != nil {
+		for k, v := range flatLeaves(area.Parent.effectiveConfig()) {
+			result[k] = v
+		}
+	}
+	for k, v := range flatLeaves(area.Configuration) {
+		result[k] = v
+	}
+	return result
+}
+
+// areaByPath finds an area in the tree by its relative path, e.g. root/child
+func (area *Area) areaByPath(path string) *Area {
+	parts := strings.SplitN(path, "/", 2)
+	if parts[0] != area.Name {
+		return nil
+	}
+	if len(parts) == 1 {
+		return area
+	}
+	for _, child := range area.Childs {
+		if found := child.areaByPath(parts[1]); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+// areaPaths returns the relative paths of all areas in the tree
+func (area *Area) areaPaths() []string {
+	paths := []string{area.Name}
+	for _, child := range area.Childs {
+		for _, p := range child.areaPaths() {
+			paths = append(paths, area.Name+"/"+p)
+		}
+	}
+	return paths
+}
+
+// LoadContexts loads the configuration of an already loaded root area for a different CONTEXT,
+// without changing the running configuration. Each context may contain multiple contexts separated by `:`.
+func LoadContexts(root *Area, contexts string) (*Area, error) {
+	shadow, err := loadShadow(root, strings.Split(contexts, ":"))
+	if err != nil {
+		return nil, errors.Wrapf(err, "loading context %q failed", contexts)
+	}
+
+	var configure func(area *Area) error
+	configure = func(area *Area) error {
+		if err := area.configure(); err != nil {
+			return errors.Wrapf(err, "area %q", area.Name)
+		}
+		for _, child := range area.Childs {
+			if err := configure(child); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	return shadow, configure(shadow)
+}
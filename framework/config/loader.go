@@ -1,15 +1,20 @@
 package config
 
 import (
+	"encoding/json"
+	"fmt"
 	"io/ioutil"
 	"log"
 	"os"
 	"path/filepath"
 	"regexp"
+	"strconv"
 	"strings"
 	"sync"
 
 	"github.com/ghodss/yaml"
+	"github.com/pelletier/go-toml"
+	"github.com/pkg/errors"
 )
 
 var (
@@ -27,14 +32,32 @@ func Load(root *Area, basedir string) error {
 		flagSet.Parse(os.Args[1:])
 	})
 
-	load(root, basedir, "/")
+	root.basedir = basedir
+	if err := loadAll(root, basedir, contexts()); err != nil {
+		return err
+	}
+
+	_, err := root.GetFlatContexts()
+	return err
+}
+
+// contexts returns the configured CONTEXT list
+func contexts() []string {
+	return strings.Split(os.Getenv("CONTEXT"), ":")
+}
+
+// loadAll loads the configuration of the area tree from basedir, the CONTEXTFILE and the additional config
+func loadAll(root *Area, basedir string, contexts []string) error {
+	if err := load(root, basedir, "/", contexts); err != nil {
+		return err
+	}
 
 	// load additional single context file
 	for _, file := range strings.Split(os.Getenv("CONTEXTFILE"), ":") {
 		if file == "" {
 			continue
 		}
-		if err := loadConfigFile(root, file); err != nil {
+		if err := loadConfigFile(root, file, "CONTEXTFILE "+file); err != nil {
 			return err
 		}
 	}
@@ -43,45 +66,99 @@ func Load(root *Area, basedir string) error {
 		if debugLog {
 			log.Printf("Loading %q", add)
 		}
-		if err := loadConfig(root, []byte(add)); err != nil {
+		if err := loadConfig(root, []byte(add), "--flamingo-config"); err != nil {
 			return err
 		}
 	}
 
-	_, err := root.GetFlatContexts()
-	return err
+	return nil
 }
 
 // LoadConfigFile loads a config
 func LoadConfigFile(area *Area, file string) error {
-	if err := loadConfigFile(area, file); err != nil {
+	if err := loadConfigFile(area, file, file); err != nil {
 		return err
 	}
 	_, err := area.GetFlatContexts()
 	return err
 }
 
-func load(area *Area, basedir, curdir string) {
-	loadConfigFile(area, filepath.Join(basedir, curdir, "config.yml"))
-	loadRoutes(area, filepath.Join(basedir, curdir, "routes.yml"))
-	for _, context := range strings.Split(os.Getenv("CONTEXT"), ":") {
+// configExtensions are the supported config and routes file formats, in the order they are loaded.
+// If a file exists in multiple formats, the values of later formats override the earlier ones.
+var configExtensions = []string{".yml", ".yaml", ".json", ".toml"}
+
+func load(area *Area, basedir, curdir string, contexts []string) error {
+	dir := filepath.Join(basedir, curdir)
+
+	if err := loadConfigFiles(area, filepath.Join(dir, "config"), ""); err != nil {
+		return err
+	}
+	if err := loadRoutesFiles(area, filepath.Join(dir, "routes")); err != nil {
+		return err
+	}
+	for _, context := range contexts {
 		if context == "" {
 			continue
 		}
-		loadConfigFile(area, filepath.Join(basedir, curdir, "config_"+context+".yml"))
-		loadRoutes(area, filepath.Join(basedir, curdir, "routes_"+context+".yml"))
+		if err := loadConfigFiles(area, filepath.Join(dir, "config_"+context), "CONTEXT "+context); err != nil {
+			return err
+		}
+		if err := loadRoutesFiles(area, filepath.Join(dir, "routes_"+context)); err != nil {
+			return err
+		}
+	}
+	if err := loadConfigFiles(area, filepath.Join(dir, "config_local"), ""); err != nil {
+		return err
+	}
+	if err := loadRoutesFiles(area, filepath.Join(dir, "routes_local")); err != nil {
+		return err
 	}
-	loadConfigFile(area, filepath.Join(basedir, curdir, "config_local.yml"))
-	loadRoutes(area, filepath.Join(basedir, curdir, "routes_local.yml"))
 
 	for _, child := range area.Childs {
-		load(child, basedir, filepath.Join(curdir, child.Name))
+		if err := load(child, basedir, filepath.Join(curdir, child.Name), contexts); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// loadConfigFiles loads all existing formats of a config file, given without extension
+func loadConfigFiles(area *Area, basename, reason string) error {
+	for _, ext := range configExtensions {
+		if err := loadOptionalConfigFile(area, basename+ext, reason); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// loadRoutesFiles loads all existing formats of a routes file, given without extension
+func loadRoutesFiles(area *Area, basename string) error {
+	for _, ext := range configExtensions {
+		if err := loadRoutes(area, basename+ext); err != nil && !os.IsNotExist(err) {
+			return errors.Wrapf(err, "routes file %q", basename+ext)
+		}
 	}
+	return nil
+}
+
+// loadOptionalConfigFile loads a config file, but ignores it if it does not exist.
+// The optional reason is noted in the provenance of the loaded values.
+func loadOptionalConfigFile(area *Area, filename, reason string) error {
+	source := filename
+	if reason != "" {
+		source += " (" + reason + ")"
+	}
+	if err := loadConfigFile(area, filename, source); err != nil && !os.IsNotExist(err) {
+		return errors.Wrapf(err, "config file %q", filename)
+	}
+	return nil
 }
 
 var regex = regexp.MustCompile(`%%ENV:([^%\n]+)%%(([^%\n]+)%%)?`)
 
-func loadConfigFile(area *Area, filename string) error {
+func loadConfigFile(area *Area, filename, source string) error {
 	config, err := ioutil.ReadFile(filename)
 	if err != nil {
 		if debugLog {
@@ -92,32 +169,144 @@ func loadConfigFile(area *Area, filename string) error {
 	if debugLog {
 		log.Println(area.Name, "loading", filename)
 	}
-	return loadConfig(area, config)
+	return loadConfigFormat(area, config, source, filepath.Ext(filename))
 }
 
-func loadConfig(area *Area, config []byte) error {
-	config = []byte(regex.ReplaceAllFunc(
-		config,
-		func(a []byte) []byte {
-			value := os.Getenv(string(regex.FindSubmatch(a)[1]))
-			if value == "" {
-				value = string(regex.FindSubmatch(a)[3])
-			}
-			return []byte(value)
-		},
-	))
+func loadConfig(area *Area, config []byte, source string) error {
+	return loadConfigFormat(area, config, source, ".yml")
+}
+
+// loadConfigFormat expands ENV and secret placeholders and adds the config in the format given by the file extension
+func loadConfigFormat(area *Area, config []byte, source, ext string) error {
+	substitutions := envSubstitutions(config)
 
 	cfg := make(Map)
-	err := yaml.Unmarshal(config, &cfg)
+	if err := unmarshal(ext, substitute(config, substitutions, false), &cfg); err != nil {
+		return errors.Wrapf(err, "parsing config from %s failed", source)
+	}
+
+	envKeys, err := envSubstitutedKeys(ext, config, substitutions)
 	if err != nil {
-		panic(err)
+		return errors.Wrapf(err, "parsing the ENV placeholders of the config from %s failed", source)
+	}
+
+	if err := replaceSecrets(cfg); err != nil {
+		return errors.Wrapf(err, "config from %s", source)
 	}
 
 	if area.LoadedConfig == nil {
 		area.LoadedConfig = make(Map)
 	}
 
-	return area.LoadedConfig.Add(cfg)
+	if err := area.LoadedConfig.Add(cfg); err != nil {
+		return err
+	}
+
+	area.loadHistory = recordProvenance(area.loadHistory, cfg, source, envKeys)
+
+	return nil
+}
+
+// envSubstitution is an ENV placeholder in a raw config and the value it is replaced with
+type envSubstitution struct {
+	start, end int
+	name       string
+	value      string
+}
+
+// envSubstitutions returns the ENV placeholders of the raw config.
+// ENV placeholders are replaced in the raw config, so they can also be used unquoted, e.g. for numbers.
+func envSubstitutions(config []byte) []envSubstitution {
+	var substitutions []envSubstitution
+	for _, match := range regex.FindAllSubmatchIndex(config, -1) {
+		name := string(config[match[2]:match[3]])
+		value := os.Getenv(name)
+		if value == "" {
+			if match[6] >= 0 {
+				value = string(config[match[6]:match[7]])
+			}
+			name += " (default)"
+		}
+		substitutions = append(substitutions, envSubstitution{start: match[0], end: match[1], name: name, value: value})
+	}
+	return substitutions
+}
+
+// substitute replaces the ENV placeholders with their values, or with their markers if marked is set
+func substitute(config []byte, substitutions []envSubstitution, marked bool) []byte {
+	result := make([]byte, 0, len(config))
+	last := 0
+	for i, s := range substitutions {
+		result = append(result, config[last:s.start]...)
+		if marked {
+			result = append(result, envMarker(i)...)
+		} else {
+			result = append(result, s.value...)
+		}
+		last = s.end
+	}
+	return append(result, config[last:]...)
+}
+
+// envMarker is a unique number replacing the ENV placeholder with the index i to find the keys it is used for.
+// Numbers are valid wherever a value is expected, quoted or unquoted, in all config formats.
+func envMarker(i int) string {
+	return strconv.FormatInt(envMarkerBase+int64(i), 10)
+}
+
+const envMarkerBase = 7364019285000000
+
+// envSubstitutedKeys returns the sources of the config keys set via ENV placeholders, e.g. "ENV:PORT".
+// The config is parsed once with the placeholders replaced by markers, the keys of a placeholder are the keys
+// whose values contain its marker.
+func envSubstitutedKeys(ext string, config []byte, substitutions []envSubstitution) (map[string]string, error) {
+	if len(substitutions) == 0 {
+		return nil, nil
+	}
+
+	marked := make(Map)
+	if err := unmarshal(ext, substitute(config, substitutions, true), &marked); err != nil {
+		return nil, err
+	}
+
+	keys := make(map[string]string)
+	for key, value := range flatLeaves(marked) {
+		text := markedText(value)
+		for i, s := range substitutions {
+			if !strings.Contains(text, envMarker(i)) {
+				continue
+			}
+			if keys[key] != "" {
+				keys[key] += ", "
+			}
+			keys[key] += "ENV:" + s.name
+		}
+	}
+
+	return keys, nil
+}
+
+// markedText returns the text of a value parsed with ENV markers, numbers are formatted without exponent
+func markedText(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case Slice:
+		texts := make([]string, len(v))
+		for i, item := range v {
+			texts[i] = markedText(item)
+		}
+		return strings.Join(texts, " ")
+	case Map:
+		var texts []string
+		for _, item := range v {
+			texts = append(texts, markedText(item))
+		}
+		return strings.Join(texts, " ")
+	}
+	return fmt.Sprint(value)
 }
 
 func loadRoutes(area *Area, filename string) error {
@@ -129,9 +318,18 @@ func loadRoutes(area *Area, filename string) error {
 		return err
 	}
 
-	err = yaml.Unmarshal(routes, &area.Routes)
-	if err != nil {
-		panic(err)
+	ext := filepath.Ext(filename)
+	if ext == ".toml" {
+		// TOML documents are tables, so the routes are expected as [[routes]] array
+		var tomlRoutes struct {
+			Routes []Route
+		}
+		if err := unmarshal(ext, routes, &tomlRoutes); err != nil {
+			return err
+		}
+		area.Routes = tomlRoutes.Routes
+	} else if err := unmarshal(ext, routes, &area.Routes); err != nil {
+		return err
 	}
 
 	if debugLog {
@@ -140,3 +338,26 @@ func loadRoutes(area *Area, filename string) error {
 
 	return nil
 }
+
+// unmarshal decodes YAML, JSON or TOML, depending on the file extension.
+// All formats are decoded via JSON, so values have the same types regardless of the format (e.g. numbers are float64).
+func unmarshal(ext string, data []byte, v interface{}) error {
+	switch ext {
+	case ".json":
+		return json.Unmarshal(data, v)
+
+	case ".toml":
+		tree, err := toml.LoadBytes(data)
+		if err != nil {
+			return err
+		}
+		jsonData, err := json.Marshal(tree.ToMap())
+		if err != nil {
+			return err
+		}
+		return json.Unmarshal(jsonData, v)
+
+	default:
+		return yaml.Unmarshal(data, v)
+	}
+}
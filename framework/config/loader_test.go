@@ -69,21 +69,77 @@ func TestLoad(t *testing.T) {
 
 	t.Run("invalid config file", func(t *testing.T) {
 		root := new(Area)
-		assert.Panics(t, func() {
-			_ = Load(root, "testdata/invalid")
-		})
+		assert.Error(t, Load(root, "testdata/invalid"))
 	})
 
 	t.Run("valid config file with invalid additional config", func(t *testing.T) {
 		root := new(Area)
-		assert.Panics(t, func() {
-			require.NoError(t, flagSet.Set("flamingo-config", "baz"))
-			defer func() { initFlagSet() }()
+		require.NoError(t, flagSet.Set("flamingo-config", "baz"))
+		defer func() { initFlagSet() }()
+
+		assert.Error(t, Load(root, "testdata/valid"))
+	})
+
+	t.Run("yaml, json and toml files", func(t *testing.T) {
+		root := new(Area)
+		require.NoError(t, os.Setenv("TEST_FORMAT", "from-env"))
+		require.NoError(t, Load(root, "testdata/formats"))
+
+		assert.Equal(t, Shim("yml", true), Shim(root.Configuration.Get("only.yml")))
+		assert.Equal(t, Shim("yaml", true), Shim(root.Configuration.Get("only.yaml")))
+		assert.Equal(t, Shim(2.0, true), Shim(root.Configuration.Get("only.json")))
+		assert.Equal(t, Shim(3.0, true), Shim(root.Configuration.Get("only.toml")))
+		assert.Equal(t, Shim("toml", true), Shim(root.Configuration.Get("override")))
+		assert.Equal(t, Shim("from-env", true), Shim(root.Configuration.Get("env.json")))
+		assert.Equal(t, Shim("from-env", true), Shim(root.Configuration.Get("env.toml")))
+
+		assert.Equal(t, []Route{{Path: "/toml", Controller: "toml.controller", Name: "toml"}}, root.Routes)
+	})
+
+	t.Run("invalid json config file", func(t *testing.T) {
+		root := new(Area)
+		err := loadConfigFile(root, "testdata/formats/invalid.json", "test")
+		assert.Error(t, err)
+	})
 
-			_ = Load(root, "testdata/valid")
-		})
+}
+
+func TestLoadSecrets(t *testing.T) {
+	require.NoError(t, os.Setenv("TEST_SECRET_BASE64", "ZW52LXNlY3JldA=="))
+	RegisterSecretProvider("test", MapSecretProvider{"db.password": "provider-secret"})
+
+	t.Run("secrets are resolved", func(t *testing.T) {
+		root := new(Area)
+		require.NoError(t, Load(root, "testdata/secrets"))
+
+		assert.Equal(t, Shim("file-secret", true), Shim(root.Configuration.Get("secrets.file")))
+		assert.Equal(t, Shim("fallback", true), Shim(root.Configuration.Get("secrets.missingFile")))
+		assert.Equal(t, Shim("provider-secret", true), Shim(root.Configuration.Get("secrets.provider")))
+		assert.Equal(t, Shim("flamingo", true), Shim(root.Configuration.Get("secrets.base64")))
+		assert.Equal(t, Shim("env-secret", true), Shim(root.Configuration.Get("secrets.base64Env")))
+		assert.Equal(t, Shim("-----BEGIN CERTIFICATE-----\nfoo: \"bar\" # not a comment\n-----END CERTIFICATE-----", true), Shim(root.Configuration.Get("secrets.certificate")))
+		assert.Equal(t, Shim(Slice{"provider-secret"}, true), Shim(root.Configuration.Get("secrets.nested.list")))
 	})
 
+	t.Run("secrets are masked", func(t *testing.T) {
+		root := new(Area)
+		require.NoError(t, Load(root, "testdata/secrets"))
+
+		masked := maskSecrets("", root.Configuration).(Map)
+		assert.Equal(t, Shim(secretMask, true), Shim(masked.Get("secrets.file")))
+		assert.Equal(t, Shim(secretMask, true), Shim(masked.Get("secrets.provider")))
+		assert.Equal(t, Shim(secretMask, true), Shim(masked.Get("secrets.certificate")))
+		assert.Equal(t, Shim(Slice{secretMask}, true), Shim(masked.Get("secrets.nested.list")))
+		assert.Equal(t, Shim("fallback", true), Shim(masked.Get("secrets.missingFile")))
+		assert.Equal(t, Shim("provider-secret", true), Shim(masked.Get("plain.sameValue")), "only keys loaded from secrets are masked")
+		assert.Equal(t, secretMask, maskSecrets("secrets.file", "file-secret"))
+		assert.Equal(t, Shim("file-secret", true), Shim(root.Configuration.Get("secrets.file")))
+	})
+
+	t.Run("unknown provider", func(t *testing.T) {
+		root := new(Area)
+		assert.Error(t, loadConfig(root, []byte("foo: '%%SECRET:unknown:key%%'"), "test"))
+	})
 }
 
 func Shim(a, b interface{}) []interface{} {
@@ -47,7 +47,7 @@ func (m *Module) Configure(injector *dingo.Injector) {
 		if v == nil {
 			continue
 		}
-		injector.Bind(v).AnnotatedWith("config:" + k).ToInstance(v)
+		bindConfig(injector, k, v)
 	}
 }
 
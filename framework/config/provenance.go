@@ -0,0 +1,83 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+)
+
+type (
+	// Provenance describes the source which set a configuration value, e.g. a config file, an environment variable,
+	// a CONTEXT or CONTEXTFILE, the --flamingo-config flag or a module's default or override config
+	Provenance struct {
+		Source string
+		Value  interface{}
+	}
+)
+
+// recordProvenance adds all leaf values of cfg with the given source to the history,
+// envKeys are the ENV sources of the keys set via ENV placeholders
+func recordProvenance(history map[string][]Provenance, cfg Map, source string, envKeys map[string]string) map[string][]Provenance {
+	if history == nil {
+		history = make(map[string][]Provenance)
+	}
+
+	for k, v := range flatLeaves(cfg) {
+		p := Provenance{Source: source, Value: v}
+		if env, ok := envKeys[k]; ok {
+			p.Source += " via " + env
+		}
+		history[k] = append(history[k], p)
+	}
+
+	return history
+}
+
+// flatLeaves returns the leaf values of cfg by their full key, e.g. "a.b" for {"a": {"b": 1}}
+func flatLeaves(cfg Map) map[string]interface{} {
+	flat := make(Map)
+	if err := flat.Add(cfg); err != nil {
+		return nil
+	}
+
+	leaves := make(map[string]interface{})
+	for k, v := range flat.Flat() {
+		if _, ok := v.(Map); ok {
+			continue
+		}
+		leaves[k] = v
+	}
+	return leaves
+}
+
+// moduleName returns the full name of a module as used in flamingo.modules.disabled
+func moduleName(module interface{}) string {
+	t := reflect.TypeOf(module)
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t.PkgPath() + "." + t.Name()
+}
+
+// Explain returns the provenance of a configuration key and all keys below it, in the order the values have been set.
+// The last entry of a key is the value in effect. If the area does not know the key, the parent's provenance is returned.
+func (area *Area) Explain(key string) map[string][]Provenance {
+	areaMu.RLock()
+	defer areaMu.RUnlock()
+
+	return area.explain(key)
+}
+
+func (area *Area) explain(key string) map[string][]Provenance {
+	result := make(map[string][]Provenance)
+	for k, history := range area.history {
+		if key == "" || k == key || strings.HasPrefix(k, key+".") {
+			result[k] = history
+		}
+	}
+
+	if len(result) == 0 && area.Parent != nil {
+		return area.Parent.explain(key)
+	}
+
+	return result
+}
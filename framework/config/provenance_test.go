@@ -0,0 +1,102 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"flamingo.me/dingo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type provenanceTestModule struct{}
+
+func (*provenanceTestModule) Configure(*dingo.Injector) {}
+
+func (*provenanceTestModule) DefaultConfig() Map {
+	return Map{"foo.bar.test": 0, "module.default": "x"}
+}
+
+func TestArea_Explain(t *testing.T) {
+	require.NoError(t, os.Setenv("TEST1", "test-value"))
+	require.NoError(t, os.Setenv("CONTEXT", "dev"))
+	defer func() {
+		require.NoError(t, os.Unsetenv("CONTEXT"))
+	}()
+	require.NoError(t, flagSet.Set("flamingo-config", "baz: bam"))
+	defer func() { initFlagSet() }()
+
+	root := NewArea("root", []dingo.Module{new(provenanceTestModule)})
+	require.NoError(t, Load(root, "testdata/valid"))
+
+	explained := root.Explain("foo")
+	assert.Equal(t, []Provenance{
+		{Source: "default of flamingo.me/flamingo/v3/framework/config.provenanceTestModule", Value: 0.0},
+		{Source: filepath.Join("testdata/valid", "config.yml"), Value: 1.0},
+	}, explained["foo.bar.test"])
+	assert.Equal(t, []Provenance{
+		{Source: filepath.Join("testdata/valid", "config_dev.yml") + " (CONTEXT dev)", Value: nil},
+	}, explained["foo"])
+
+	assert.Equal(t, []Provenance{
+		{Source: filepath.Join("testdata/valid", "config.yml") + " via ENV:TEST1", Value: "test-value"},
+	}, root.Explain("env.var.test1")["env.var.test1"])
+	assert.Equal(t, []Provenance{
+		{Source: filepath.Join("testdata/valid", "config.yml"), Value: "test-value"},
+	}, root.Explain("env.sameValue")["env.sameValue"], "a value equal to an ENV value is not from ENV")
+	assert.Equal(t, []Provenance{
+		{Source: filepath.Join("testdata/valid", "config.yml") + " via ENV:TEST1", Value: "prefix-test-value"},
+	}, root.Explain("env.embedded")["env.embedded"])
+	assert.Equal(t, []Provenance{
+		{Source: "--flamingo-config", Value: "bam"},
+	}, root.Explain("baz")["baz"])
+	assert.Empty(t, root.Explain("unknown"))
+}
+
+func TestEnvSubstitutedKeys(t *testing.T) {
+	require.NoError(t, os.Setenv("PROVENANCE_FLAG", "true"))
+	defer func() { require.NoError(t, os.Unsetenv("PROVENANCE_FLAG")) }()
+
+	config := []byte(`{
+		"enabled": %%ENV:PROVENANCE_FLAG%%,
+		"name": "x-%%ENV:PROVENANCE_NAME%%name%%",
+		"list": [%%ENV:PROVENANCE_FLAG%%],
+		"plain": true
+	}`)
+	substitutions := envSubstitutions(config)
+
+	keys, err := envSubstitutedKeys(".json", config, substitutions)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"enabled": "ENV:PROVENANCE_FLAG",
+		"name":    "ENV:PROVENANCE_NAME (default)",
+		"list":    "ENV:PROVENANCE_FLAG",
+	}, keys)
+}
+
+func TestDiff(t *testing.T) {
+	left := Map{"a": "1", "b.c": 2.0, "d": "same"}
+	right := Map{"a": "2", "b.e": true, "d": "same"}
+
+	assert.Equal(t, []Difference{
+		{Key: "a", Left: "1", InLeft: true, Right: "2", InRight: true},
+		{Key: "b.c", Left: 2.0, InLeft: true},
+		{Key: "b.e", Right: true, InRight: true},
+	}, Diff(left, right))
+}
+
+func TestLoadContexts(t *testing.T) {
+	root := NewArea("root", nil)
+	require.NoError(t, Load(root, "testdata/valid"))
+
+	dev, err := LoadContexts(root, "dev")
+	require.NoError(t, err)
+
+	diff := Diff(root.areaByPath("root").EffectiveConfig(), dev.areaByPath("root").EffectiveConfig())
+	assert.Contains(t, diff, Difference{Key: "foo.bar.test", Left: 1.0, InLeft: true})
+	assert.Contains(t, diff, Difference{Key: "foo", InLeft: false, Right: nil, InRight: true})
+
+	// the running configuration is not affected
+	assert.Equal(t, Shim(1.0, true), Shim(root.Configuration.Get("foo.bar.test")))
+}
@@ -0,0 +1,211 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+type (
+	// ChangedEvent is dispatched after the configuration or routes of an area have been reloaded
+	ChangedEvent struct {
+		Area *Area
+	}
+
+	// Watcher polls the configuration files of an area tree for changes
+	Watcher struct {
+		root  *Area
+		state map[string]fileState
+	}
+
+	fileState struct {
+		modTime time.Time
+		size    int64
+	}
+
+	reloadedArea struct {
+		area          *Area
+		loadedConfig  Map
+		loadHistory   map[string][]Provenance
+		routes        []Route
+		configuration Map
+		history       map[string][]Provenance
+	}
+)
+
+var watchedFiles = regexp.MustCompile(`^(config|routes).*\.(yml|yaml|json|toml)$`)
+
+// NewWatcher creates a Watcher for the configuration directories of the given (loaded) root area
+func NewWatcher(root *Area) *Watcher {
+	w := &Watcher{root: root}
+	w.state = w.snapshot()
+	return w
+}
+
+// Changed reports if a configuration file has been added, removed or modified since the last call
+func (w *Watcher) Changed() bool {
+	state := w.snapshot()
+	changed := !reflect.DeepEqual(w.state, state)
+	w.state = state
+	return changed
+}
+
+func (w *Watcher) snapshot() map[string]fileState {
+	state := make(map[string]fileState)
+
+	var walk func(area *Area, curdir string)
+	walk = func(area *Area, curdir string) {
+		dir := filepath.Join(w.root.basedir, curdir)
+		files, _ := ioutil.ReadDir(dir)
+		for _, file := range files {
+			if !file.IsDir() && watchedFiles.MatchString(file.Name()) {
+				state[filepath.Join(dir, file.Name())] = fileState{modTime: file.ModTime(), size: file.Size()}
+			}
+		}
+		for _, child := range area.Childs {
+			walk(child, filepath.Join(curdir, child.Name))
+		}
+	}
+	walk(w.root, "/")
+
+	for _, file := range strings.Split(os.Getenv("CONTEXTFILE"), ":") {
+		if file == "" {
+			continue
+		}
+		if info, err := os.Stat(file); err == nil {
+			state[file] = fileState{modTime: info.ModTime(), size: info.Size()}
+		}
+	}
+
+	return state
+}
+
+// Reload loads the configuration of an already loaded root area again.
+// The configuration and routes of the area tree and its flat areas are only updated if everything could be loaded,
+// so a broken configuration file does not affect the running application.
+// It returns all areas with a changed configuration or changed routes, including the flat areas derived from them.
+// Please note that already injected configuration values are not updated, modules need to listen for the ChangedEvent.
+func Reload(root *Area) ([]*Area, error) {
+	shadow, err := loadShadow(root, contexts())
+	if err != nil {
+		return nil, errors.Wrap(err, "config reload failed")
+	}
+
+	var reloaded []reloadedArea
+	if err := collectReloaded(root, shadow, false, &reloaded); err != nil {
+		return nil, errors.Wrap(err, "config reload failed")
+	}
+
+	// derived flat areas are prepared before anything is applied, so a failure does not leave a partial reload
+	var derived []reloadedArea
+	for _, r := range reloaded {
+		for _, flat := range r.area.derived {
+			tmp := *flat
+			tmp.LoadedConfig = r.loadedConfig
+			tmp.loadHistory = r.loadHistory
+			if err := tmp.configure(); err != nil {
+				return nil, errors.Wrapf(err, "config reload of %q failed", flat.Name)
+			}
+			derived = append(derived, reloadedArea{
+				area:          flat,
+				loadedConfig:  r.loadedConfig,
+				loadHistory:   r.loadHistory,
+				configuration: tmp.Configuration,
+				history:       tmp.history,
+			})
+		}
+	}
+
+	areaMu.Lock()
+	defer areaMu.Unlock()
+
+	var changed []*Area
+	for _, r := range reloaded {
+		r.area.LoadedConfig = r.loadedConfig
+		r.area.loadHistory = r.loadHistory
+		r.area.Routes = r.routes
+		r.area.Configuration = r.configuration
+		r.area.history = r.history
+		changed = append(changed, r.area)
+	}
+	for _, d := range derived {
+		d.area.LoadedConfig = d.loadedConfig
+		d.area.loadHistory = d.loadHistory
+		d.area.Routes = inheritedRoutes(d.area.source)
+		d.area.Configuration = d.configuration
+		d.area.history = d.history
+		changed = append(changed, d.area)
+	}
+
+	return changed, nil
+}
+
+// collectReloaded compares the area tree with the freshly loaded shadow tree and prepares the changed areas
+func collectReloaded(area, shadow *Area, parentChanged bool, reloaded *[]reloadedArea) error {
+	changed := parentChanged ||
+		!reflect.DeepEqual(area.LoadedConfig, shadow.LoadedConfig) ||
+		!reflect.DeepEqual(area.Routes, shadow.Routes)
+
+	if changed {
+		tmp := *area
+		tmp.LoadedConfig = shadow.LoadedConfig
+		tmp.loadHistory = shadow.loadHistory
+		if err := tmp.configure(); err != nil {
+			return errors.Wrapf(err, "area %q", area.Name)
+		}
+		*reloaded = append(*reloaded, reloadedArea{
+			area:          area,
+			loadedConfig:  shadow.LoadedConfig,
+			loadHistory:   shadow.loadHistory,
+			routes:        shadow.Routes,
+			configuration: tmp.Configuration,
+			history:       tmp.history,
+		})
+	}
+
+	for i, child := range area.Childs {
+		if err := collectReloaded(child, shadow.Childs[i], changed, reloaded); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// loadShadow loads the configuration of the area tree for the given contexts into an empty copy of the tree
+func loadShadow(root *Area, contexts []string) (shadow *Area, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = errors.Errorf("%v", r)
+		}
+	}()
+
+	shadow = shadowOf(root)
+	return shadow, loadAll(shadow, root.basedir, contexts)
+}
+
+// shadowOf creates an empty copy of the area tree to load the configuration into
+func shadowOf(area *Area) *Area {
+	shadow := &Area{Name: area.Name, Modules: area.Modules}
+	for _, child := range area.Childs {
+		childShadow := shadowOf(child)
+		childShadow.Parent = shadow
+		shadow.Childs = append(shadow.Childs, childShadow)
+	}
+	return shadow
+}
+
+// inheritedRoutes returns the routes of an area including the routes of all parents, as done for flat areas
+func inheritedRoutes(area *Area) []Route {
+	routes := append([]Route(nil), area.Routes...)
+	for parent := area.Parent; parent != nil; parent = parent.Parent {
+		routes = mergeRoutes(routes, parent.Routes)
+	}
+	return routes
+}
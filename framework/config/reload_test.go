@@ -0,0 +1,100 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReload(t *testing.T) {
+	dir, err := ioutil.TempDir("", "flamingo-config-reload")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	write := func(name, content string) {
+		require.NoError(t, ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644))
+		// make sure the modification time changes on file systems with a coarse resolution
+		later := time.Now().Add(time.Second)
+		require.NoError(t, os.Chtimes(filepath.Join(dir, name), later, later))
+	}
+
+	write("config.yml", "foo.bar: 1")
+	write("routes.yml", "- path: /\n  controller: home")
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "child"), os.ModePerm))
+
+	child := NewArea("child", nil)
+	root := NewArea("root", nil, child)
+	require.NoError(t, Load(root, dir))
+
+	watcher := NewWatcher(root)
+	assert.False(t, watcher.Changed())
+
+	t.Run("unchanged", func(t *testing.T) {
+		changed, err := Reload(root)
+		assert.NoError(t, err)
+		assert.Empty(t, changed)
+	})
+
+	t.Run("changed config", func(t *testing.T) {
+		write("config.yml", "foo.bar: 2")
+		assert.True(t, watcher.Changed())
+		assert.False(t, watcher.Changed())
+
+		changed, err := Reload(root)
+		assert.NoError(t, err)
+		assert.Contains(t, changed, root)
+		assert.Contains(t, changed, child)
+		assert.Equal(t, Shim(2.0, true), Shim(root.Configuration.Get("foo.bar")))
+	})
+
+	t.Run("changed routes are inherited", func(t *testing.T) {
+		write("routes.yml", "- path: /home\n  controller: home")
+
+		changed, err := Reload(root)
+		assert.NoError(t, err)
+		assert.Contains(t, changed, root)
+		assert.Equal(t, "/home", root.Routes[0].Path)
+
+		require.NotEmpty(t, child.derived)
+		for _, flat := range child.derived {
+			assert.Contains(t, changed, flat)
+			assert.Equal(t, "/home", flat.Routes[0].Path)
+		}
+	})
+
+	t.Run("concurrent reads during reload", func(t *testing.T) {
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			for i := 0; i < 100; i++ {
+				child.Config("foo.bar")
+				root.GetRoutes()
+				root.Explain("foo")
+			}
+		}()
+
+		write("config.yml", "foo.bar: 3")
+		_, err := Reload(root)
+		assert.NoError(t, err)
+		<-done
+
+		value, ok := child.Config("foo.bar")
+		assert.True(t, ok)
+		assert.Equal(t, 3.0, value)
+	})
+
+	t.Run("broken config keeps the previous state", func(t *testing.T) {
+		write("config.yml", "foo.bar: [")
+		assert.True(t, watcher.Changed())
+
+		changed, err := Reload(root)
+		assert.Error(t, err)
+		assert.Empty(t, changed)
+		assert.Equal(t, Shim(3.0, true), Shim(root.Configuration.Get("foo.bar")))
+	})
+}
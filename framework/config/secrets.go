@@ -0,0 +1,246 @@
+package config
+
+import (
+	"encoding/base64"
+	"io/ioutil"
+	"log"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+type (
+	// SecretProvider resolves secrets referenced via `%%SECRET:provider:key%%` placeholders.
+	// Providers are registered with RegisterSecretProvider before the configuration is loaded,
+	// usually from an init function or the project's main package.
+	SecretProvider interface {
+		Secret(key string) (string, error)
+	}
+
+	// MapSecretProvider is a static SecretProvider, e.g. to stub a vault-style key-value store locally
+	MapSecretProvider map[string]string
+
+	secretRegistry struct {
+		mu        sync.RWMutex
+		providers map[string]SecretProvider
+		// keys are the config keys a secret has been loaded into
+		keys map[string]struct{}
+	}
+)
+
+const secretMask = "*****"
+
+var (
+	secretRegex = regexp.MustCompile(`%%(FILE|SECRET|BASE64):([^%\n]+)%%(([^%\n]+)%%)?`)
+	secrets     = &secretRegistry{
+		providers: make(map[string]SecretProvider),
+		keys:      make(map[string]struct{}),
+	}
+)
+
+// Secret returns the value for the given key
+func (m MapSecretProvider) Secret(key string) (string, error) {
+	if v, ok := m[key]; ok {
+		return v, nil
+	}
+	return "", errors.Errorf("secret %q not found", key)
+}
+
+// RegisterSecretProvider makes a SecretProvider available under the given name for `%%SECRET:name:key%%` placeholders
+func RegisterSecretProvider(name string, provider SecretProvider) {
+	secrets.mu.Lock()
+	defer secrets.mu.Unlock()
+
+	secrets.providers[name] = provider
+}
+
+func (r *secretRegistry) provider(name string) (SecretProvider, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	p, ok := r.providers[name]
+	return p, ok
+}
+
+// remember the config key a secret has been loaded into. Keys are normalized, e.g. "a.b" for {"a": {"b": ...}},
+// so a key is masked regardless of how the config file nests it.
+func (r *secretRegistry) remember(key string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.keys[key] = struct{}{}
+}
+
+func (r *secretRegistry) isSecret(key string) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	_, ok := r.keys[key]
+	return ok
+}
+
+// resolveSecret resolves a single placeholder expression like `FILE:/path`, `SECRET:provider:key` or `BASE64:...`
+func resolveSecret(kind, ref string) (string, error) {
+	switch kind {
+	case "FILE":
+		content, err := ioutil.ReadFile(ref)
+		if err != nil {
+			return "", errors.Wrapf(err, "can not read secret file %q", ref)
+		}
+		// mounted secrets are often created with a trailing newline
+		return strings.TrimRight(string(content), "\r\n"), nil
+
+	case "SECRET":
+		parts := strings.SplitN(ref, ":", 2)
+		if len(parts) != 2 {
+			return "", errors.Errorf("invalid secret reference %q, expected provider:key", ref)
+		}
+		provider, ok := secrets.provider(parts[0])
+		if !ok {
+			return "", errors.Errorf("secret provider %q not registered", parts[0])
+		}
+		value, err := provider.Secret(parts[1])
+		if err != nil {
+			return "", errors.Wrapf(err, "secret provider %q", parts[0])
+		}
+		return value, nil
+
+	case "BASE64":
+		encoded := ref
+		if parts := strings.SplitN(ref, ":", 2); len(parts) == 2 {
+			switch parts[0] {
+			case "FILE", "SECRET":
+				var err error
+				if encoded, err = resolveSecret(parts[0], parts[1]); err != nil {
+					return "", err
+				}
+			case "ENV":
+				encoded = os.Getenv(parts[1])
+			}
+		}
+		decoded, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+		if err != nil {
+			return "", errors.Wrap(err, "can not decode base64 secret")
+		}
+		return string(decoded), nil
+	}
+
+	return "", errors.Errorf("unknown secret placeholder %q", kind)
+}
+
+// replaceSecrets substitutes the secret placeholders in the string values of a parsed config and remembers the keys
+// of resolved secrets for masking. Secrets are replaced after parsing, so their values can contain any character.
+func replaceSecrets(cfg Map) error {
+	_, err := resolveSecrets("", cfg)
+	return err
+}
+
+// resolveSecrets replaces the secret placeholders in the value at the given key and its sub keys
+func resolveSecrets(key string, v interface{}) (interface{}, error) {
+	switch v := v.(type) {
+	case Map:
+		return v, resolveMapSecrets(key, v)
+	case map[string]interface{}:
+		return v, resolveMapSecrets(key, v)
+	case Slice:
+		return v, resolveSliceSecrets(key, v)
+	case []interface{}:
+		return v, resolveSliceSecrets(key, v)
+	case string:
+		return resolveStringSecrets(key, v)
+	}
+	return v, nil
+}
+
+func resolveMapSecrets(key string, m map[string]interface{}) error {
+	for k, sv := range m {
+		resolved, err := resolveSecrets(joinKey(key, k), sv)
+		if err != nil {
+			return err
+		}
+		m[k] = resolved
+	}
+	return nil
+}
+
+func resolveSliceSecrets(key string, s []interface{}) error {
+	for i, sv := range s {
+		resolved, err := resolveSecrets(joinKey(key, strconv.Itoa(i)), sv)
+		if err != nil {
+			return err
+		}
+		s[i] = resolved
+	}
+	return nil
+}
+
+func resolveStringSecrets(key string, value string) (string, error) {
+	var err error
+
+	value = secretRegex.ReplaceAllStringFunc(value, func(a string) string {
+		match := secretRegex.FindStringSubmatch(a)
+		kind, ref, fallback := match[1], match[2], match[4]
+
+		secret, resolveErr := resolveSecret(kind, ref)
+		if resolveErr != nil {
+			if fallback != "" {
+				if debugLog {
+					log.Printf("secret %s:%s not resolved, using default: %v", kind, ref, resolveErr)
+				}
+				return fallback
+			}
+			if err == nil {
+				err = errors.Wrapf(resolveErr, "config key %q", key)
+			}
+			return a
+		}
+
+		if debugLog {
+			log.Printf("resolved secret %s:%s for %s", kind, ref, key)
+		}
+		secrets.remember(key)
+
+		return secret
+	})
+
+	return value, err
+}
+
+// maskSecrets returns a copy of the config value at the given key with the values of all keys holding a secret masked
+func maskSecrets(key string, v interface{}) interface{} {
+	if secrets.isSecret(key) {
+		return secretMask
+	}
+
+	switch v := v.(type) {
+	case Map:
+		masked := make(Map, len(v))
+		for k, sv := range v {
+			masked[k] = maskSecrets(joinKey(key, k), sv)
+		}
+		return masked
+	case Slice:
+		masked := make(Slice, len(v))
+		for i, sv := range v {
+			masked[i] = maskSecrets(joinKey(key, strconv.Itoa(i)), sv)
+		}
+		return masked
+	case map[string]interface{}:
+		return maskSecrets(key, Map(v))
+	case []interface{}:
+		return maskSecrets(key, Slice(v))
+	}
+	return v
+}
+
+// joinKey returns the config key of sub below key
+func joinKey(key, sub string) string {
+	if key == "" {
+		return sub
+	}
+	return key + "." + sub
+}
@@ -0,0 +1,454 @@
+package config
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"flamingo.me/dingo"
+	"github.com/pkg/errors"
+)
+
+type (
+	// ByteSize is a size in bytes, which can be configured as number or as string with a unit, e.g. "512KiB" or "10MB".
+	// Decimal units (kB, MB, GB, TB) are multiples of 1000, binary units (KiB, MiB, GiB, TiB) multiples of 1024.
+	ByteSize int64
+)
+
+var (
+	durationType = reflect.TypeOf(time.Duration(0))
+	byteSizeType = reflect.TypeOf(ByteSize(0))
+	urlType      = reflect.TypeOf(url.URL{})
+	mapType      = reflect.TypeOf(Map{})
+	sliceType    = reflect.TypeOf(Slice{})
+
+	byteSizeUnits = map[string]float64{
+		"":    1,
+		"b":   1,
+		"k":   1000,
+		"kb":  1000,
+		"m":   1000 * 1000,
+		"mb":  1000 * 1000,
+		"g":   1000 * 1000 * 1000,
+		"gb":  1000 * 1000 * 1000,
+		"t":   1000 * 1000 * 1000 * 1000,
+		"tb":  1000 * 1000 * 1000 * 1000,
+		"ki":  1 << 10,
+		"kib": 1 << 10,
+		"mi":  1 << 20,
+		"mib": 1 << 20,
+		"gi":  1 << 30,
+		"gib": 1 << 30,
+		"ti":  1 << 40,
+		"tib": 1 << 40,
+	}
+
+	// injectableTypes are the additional types a config value can be injected as, depending on the type of the value
+	injectableTypes = map[reflect.Type][]reflect.Type{
+		reflect.TypeOf(float64(0)): {
+			reflect.TypeOf(int(0)),
+			reflect.TypeOf(int64(0)),
+			reflect.TypeOf(int32(0)),
+			reflect.TypeOf(uint(0)),
+			reflect.TypeOf(uint64(0)),
+			reflect.TypeOf(uint32(0)),
+			reflect.TypeOf(float32(0)),
+			byteSizeType,
+		},
+		reflect.TypeOf(""): {
+			durationType,
+			byteSizeType,
+			urlType,
+		},
+		sliceType: {
+			reflect.TypeOf([]string(nil)),
+			reflect.TypeOf([]int(nil)),
+			reflect.TypeOf([]float64(nil)),
+		},
+	}
+)
+
+// ParseByteSize parses a size like "1024", "512KiB", "1.5 GB" or "10mb"
+func ParseByteSize(s string) (ByteSize, error) {
+	s = strings.TrimSpace(s)
+	i := strings.IndexFunc(s, func(r rune) bool {
+		return (r < '0' || r > '9') && r != '.'
+	})
+	if i == -1 {
+		i = len(s)
+	}
+
+	number, err := strconv.ParseFloat(s[:i], 64)
+	if err != nil {
+		return 0, errors.Errorf("invalid byte size %q", s)
+	}
+	unit, ok := byteSizeUnits[strings.ToLower(strings.TrimSpace(s[i:]))]
+	if !ok {
+		return 0, errors.Errorf("invalid byte size unit in %q", s)
+	}
+
+	size := number * unit
+	if size > math.MaxInt64 {
+		return 0, errors.Errorf("byte size %q is too large", s)
+	}
+	return ByteSize(size), nil
+}
+
+// bindConfig binds a config value for `inject:"config:..."`, with its own type and all types it can be converted to,
+// e.g. integral numbers as int, duration strings as time.Duration and lists of strings as []string.
+// If the value can not be converted, the type is bound to a provider failing with the conversion error, so injecting
+// e.g. "10 seconds" as time.Duration names the config key instead of failing with a missing binding.
+func bindConfig(injector *dingo.Injector, key string, value interface{}) {
+	injector.Bind(value).AnnotatedWith("config:" + key).ToInstance(value)
+
+	for _, t := range injectableTypes[reflect.TypeOf(value)] {
+		converted, err := convertConfig(key, value, t)
+		if err != nil {
+			injector.Bind(reflect.Zero(t).Interface()).AnnotatedWith("config:" + key).ToProvider(failingProvider(t, err))
+			continue
+		}
+		injector.Bind(converted.Interface()).AnnotatedWith("config:" + key).ToInstance(converted.Interface())
+	}
+}
+
+// convertConfig converts a config value to one of its injectableTypes
+func convertConfig(key string, value interface{}, t reflect.Type) (reflect.Value, error) {
+	converted := reflect.New(t).Elem()
+	if t == urlType && !strings.Contains(value.(string), "://") {
+		return converted, decodeError(key, value, converted, errors.New("not an absolute URL"))
+	}
+	if err := decode(key, value, converted); err != nil {
+		return converted, err
+	}
+	return converted, nil
+}
+
+// failingProvider returns a provider for the type t which panics with err when it is injected
+func failingProvider(t reflect.Type, err error) interface{} {
+	return reflect.MakeFunc(reflect.FuncOf(nil, []reflect.Type{t}, false), func([]reflect.Value) []reflect.Value {
+		panic(err)
+	}).Interface()
+}
+
+// decode maps a config value into out, converting it to the type of out if necessary.
+// Structs are decoded from a Map, using the field's json tag or its name (case insensitive) as key,
+// and the field's `default` tag if the key is missing.
+func decode(key string, in interface{}, out reflect.Value) error {
+	if in == nil {
+		return nil
+	}
+
+	if out.Kind() == reflect.Ptr {
+		if out.IsNil() {
+			out.Set(reflect.New(out.Type().Elem()))
+		}
+		return decode(key, in, out.Elem())
+	}
+
+	switch out.Type() {
+	case mapType, sliceType:
+		if reflect.TypeOf(in) == out.Type() {
+			out.Set(reflect.ValueOf(in))
+			return nil
+		}
+
+	case durationType:
+		if s, ok := in.(string); ok {
+			d, err := time.ParseDuration(s)
+			if err != nil {
+				return decodeError(key, in, out, err)
+			}
+			out.SetInt(int64(d))
+			return nil
+		}
+
+	case byteSizeType:
+		if s, ok := in.(string); ok {
+			size, err := ParseByteSize(s)
+			if err != nil {
+				return decodeError(key, in, out, err)
+			}
+			out.SetInt(int64(size))
+			return nil
+		}
+
+	case urlType:
+		s, ok := in.(string)
+		if !ok {
+			return decodeError(key, in, out, nil)
+		}
+		u, err := url.Parse(s)
+		if err != nil {
+			return decodeError(key, in, out, err)
+		}
+		out.Set(reflect.ValueOf(*u))
+		return nil
+	}
+
+	if out.CanAddr() {
+		switch u := out.Addr().Interface().(type) {
+		case json.Unmarshaler:
+			data, err := json.Marshal(plain(in))
+			if err != nil {
+				return decodeError(key, in, out, err)
+			}
+			if err := u.UnmarshalJSON(data); err != nil {
+				return decodeError(key, in, out, err)
+			}
+			return nil
+
+		case encoding.TextUnmarshaler:
+			if s, ok := in.(string); ok {
+				if err := u.UnmarshalText([]byte(s)); err != nil {
+					return decodeError(key, in, out, err)
+				}
+				return nil
+			}
+		}
+	}
+
+	switch out.Kind() {
+	case reflect.Interface:
+		v := reflect.ValueOf(plain(in))
+		if !v.Type().Implements(out.Type()) {
+			return decodeError(key, in, out, nil)
+		}
+		out.Set(v)
+
+	case reflect.String:
+		s, ok := in.(string)
+		if !ok {
+			return decodeError(key, in, out, nil)
+		}
+		out.SetString(s)
+
+	case reflect.Bool:
+		switch v := in.(type) {
+		case bool:
+			out.SetBool(v)
+		case string:
+			b, err := strconv.ParseBool(v)
+			if err != nil {
+				return decodeError(key, in, out, err)
+			}
+			out.SetBool(b)
+		default:
+			return decodeError(key, in, out, nil)
+		}
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		f, err := number(in)
+		if err != nil {
+			return decodeError(key, in, out, err)
+		}
+		if f != math.Trunc(f) || f < math.MinInt64 || f > math.MaxInt64 || out.OverflowInt(int64(f)) {
+			return decodeError(key, in, out, errors.New("not an integer in range"))
+		}
+		out.SetInt(int64(f))
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		f, err := number(in)
+		if err != nil {
+			return decodeError(key, in, out, err)
+		}
+		if f != math.Trunc(f) || f < 0 || f > math.MaxUint64 || out.OverflowUint(uint64(f)) {
+			return decodeError(key, in, out, errors.New("not an unsigned integer in range"))
+		}
+		out.SetUint(uint64(f))
+
+	case reflect.Float32, reflect.Float64:
+		f, err := number(in)
+		if err != nil {
+			return decodeError(key, in, out, err)
+		}
+		if out.OverflowFloat(f) {
+			return decodeError(key, in, out, errors.New("out of range"))
+		}
+		out.SetFloat(f)
+
+	case reflect.Slice:
+		list, ok := asSlice(in)
+		if !ok {
+			return decodeError(key, in, out, nil)
+		}
+		result := reflect.MakeSlice(out.Type(), len(list), len(list))
+		for i, v := range list {
+			if err := decode(fmt.Sprintf("%s[%d]", key, i), v, result.Index(i)); err != nil {
+				return err
+			}
+		}
+		out.Set(result)
+
+	case reflect.Array:
+		list, ok := asSlice(in)
+		if !ok || len(list) > out.Len() {
+			return decodeError(key, in, out, nil)
+		}
+		for i, v := range list {
+			if err := decode(fmt.Sprintf("%s[%d]", key, i), v, out.Index(i)); err != nil {
+				return err
+			}
+		}
+
+	case reflect.Map:
+		m, ok := asMap(in)
+		if !ok || out.Type().Key().Kind() != reflect.String {
+			return decodeError(key, in, out, nil)
+		}
+		if out.IsNil() {
+			out.Set(reflect.MakeMap(out.Type()))
+		}
+		for k, v := range m {
+			value := reflect.New(out.Type().Elem()).Elem()
+			if err := decode(joinKey(key, k), v, value); err != nil {
+				return err
+			}
+			out.SetMapIndex(reflect.ValueOf(k).Convert(out.Type().Key()), value)
+		}
+
+	case reflect.Struct:
+		m, ok := asMap(in)
+		if !ok {
+			return decodeError(key, in, out, nil)
+		}
+		return decodeStruct(key, m, out)
+
+	default:
+		return decodeError(key, in, out, nil)
+	}
+
+	return nil
+}
+
+// decodeStruct maps the config map into the exported fields of a struct
+func decodeStruct(key string, m map[string]interface{}, out reflect.Value) error {
+	for i := 0; i < out.NumField(); i++ {
+		field := out.Type().Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+
+		name := field.Name
+		if tag, ok := field.Tag.Lookup("json"); ok {
+			tagName := strings.Split(tag, ",")[0]
+			if tagName == "-" {
+				continue
+			}
+			if tagName != "" {
+				name = tagName
+			} else if field.Anonymous && field.Type.Kind() == reflect.Struct {
+				name = ""
+			}
+		} else if field.Anonymous && field.Type.Kind() == reflect.Struct {
+			name = ""
+		}
+
+		// embedded structs are decoded from the same map, as done by encoding/json
+		if name == "" {
+			if err := decodeStruct(key, m, out.Field(i)); err != nil {
+				return err
+			}
+			continue
+		}
+
+		name, value, found := lookup(m, name)
+		if !found {
+			defaultValue, ok := field.Tag.Lookup("default")
+			if !ok {
+				continue
+			}
+			value = defaultValue
+		}
+		if err := decode(joinKey(key, name), value, out.Field(i)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// lookup finds a key in a map, preferring an exact match over a case insensitive one, and returns the matching key
+func lookup(m map[string]interface{}, name string) (string, interface{}, bool) {
+	if v, ok := m[name]; ok {
+		return name, v, true
+	}
+	for k, v := range m {
+		if strings.EqualFold(k, name) {
+			return k, v, true
+		}
+	}
+	return name, nil, false
+}
+
+func number(in interface{}) (float64, error) {
+	switch v := in.(type) {
+	case float64:
+		return v, nil
+	case int:
+		return float64(v), nil
+	case int64:
+		return float64(v), nil
+	case string:
+		return strconv.ParseFloat(v, 64)
+	}
+	return 0, errors.New("not a number")
+}
+
+func asSlice(in interface{}) ([]interface{}, bool) {
+	switch v := in.(type) {
+	case Slice:
+		return v, true
+	case []interface{}:
+		return v, true
+	}
+	return nil, false
+}
+
+func asMap(in interface{}) (map[string]interface{}, bool) {
+	switch v := in.(type) {
+	case Map:
+		return v, true
+	case map[string]interface{}:
+		return v, true
+	}
+	return nil, false
+}
+
+// plain converts Map and Slice values into map[string]interface{} and []interface{}, as returned by encoding/json
+func plain(in interface{}) interface{} {
+	switch v := in.(type) {
+	case Map:
+		result := make(map[string]interface{}, len(v))
+		for k, vv := range v {
+			result[k] = plain(vv)
+		}
+		return result
+	case map[string]interface{}:
+		return plain(Map(v))
+	case Slice:
+		result := make([]interface{}, len(v))
+		for i, vv := range v {
+			result[i] = plain(vv)
+		}
+		return result
+	case []interface{}:
+		return plain(Slice(v))
+	}
+	return in
+}
+
+func decodeError(key string, in interface{}, out reflect.Value, cause error) error {
+	if key == "" {
+		key = "."
+	}
+	if cause != nil {
+		return errors.Errorf("config key %q: can not use %T %v as %s: %v", key, in, in, out.Type(), cause)
+	}
+	return errors.Errorf("config key %q: can not use %T %v as %s", key, in, in, out.Type())
+}
@@ -0,0 +1,143 @@
+package config
+
+import (
+	"net/url"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseByteSize(t *testing.T) {
+	for in, expected := range map[string]ByteSize{
+		"1024":    1024,
+		"10B":     10,
+		"512KiB":  512 * 1024,
+		"1.5 GB":  1500 * 1000 * 1000,
+		"10mb":    10 * 1000 * 1000,
+		"2MiB":    2 * 1024 * 1024,
+		" 1 Ti  ": 1 << 40,
+	} {
+		size, err := ParseByteSize(in)
+		assert.NoError(t, err, in)
+		assert.Equal(t, expected, size, in)
+	}
+
+	for _, in := range []string{"", "MB", "10 parsecs", "1.2.3kb"} {
+		_, err := ParseByteSize(in)
+		assert.Error(t, err, in)
+	}
+}
+
+func TestMapMapIntoTypes(t *testing.T) {
+	type backend struct {
+		Name    string
+		URL     *url.URL
+		Weight  uint8 `json:"w"`
+		Timeout time.Duration
+	}
+
+	type resultType struct {
+		Port       int
+		MaxSize    ByteSize
+		Timeout    time.Duration
+		Retry      time.Duration `default:"500ms"`
+		Ratio      float32
+		Endpoint   url.URL
+		Tags       []string
+		Backends   []backend
+		Limits     map[string]int64
+		Raw        Map
+		Keep       string
+		unexported string
+	}
+
+	m := make(Map)
+	require.NoError(t, m.Add(Map{
+		"port":     8080,
+		"maxSize":  "10MiB",
+		"timeout":  "1m30s",
+		"ratio":    0.5,
+		"endpoint": "https://example.com/api",
+		"tags":     []interface{}{"a", "b"},
+		"backends": []interface{}{
+			map[string]interface{}{"name": "one", "url": "http://one:80", "w": 1, "timeout": "1s"},
+			map[string]interface{}{"name": "two", "url": "http://two:80", "w": 2},
+		},
+		"limits.a":   1,
+		"limits.b":   2,
+		"raw.foo":    "bar",
+		"unexported": "ignored",
+	}))
+
+	result := resultType{Keep: "default"}
+	require.NoError(t, m.MapInto(&result))
+
+	assert.Equal(t, 8080, result.Port)
+	assert.Equal(t, ByteSize(10*1024*1024), result.MaxSize)
+	assert.Equal(t, 90*time.Second, result.Timeout)
+	assert.Equal(t, 500*time.Millisecond, result.Retry)
+	assert.Equal(t, float32(0.5), result.Ratio)
+	assert.Equal(t, "example.com", result.Endpoint.Host)
+	assert.Equal(t, []string{"a", "b"}, result.Tags)
+	require.Len(t, result.Backends, 2)
+	assert.Equal(t, "one", result.Backends[0].Name)
+	assert.Equal(t, "one:80", result.Backends[0].URL.Host)
+	assert.Equal(t, uint8(2), result.Backends[1].Weight)
+	assert.Equal(t, time.Second, result.Backends[0].Timeout)
+	assert.Equal(t, map[string]int64{"a": 1, "b": 2}, result.Limits)
+	assert.Equal(t, Map{"foo": "bar"}, result.Raw)
+	assert.Equal(t, "default", result.Keep)
+	assert.Empty(t, result.unexported)
+}
+
+func TestMapMapIntoErrors(t *testing.T) {
+	var result struct {
+		Port     int
+		Timeout  time.Duration
+		Backends []struct {
+			Weight uint8
+		}
+	}
+
+	err := Map{"port": 1.5}.MapInto(&result)
+	assert.Contains(t, err.Error(), `config key "port"`)
+
+	err = Map{"port": "http"}.MapInto(&result)
+	assert.Contains(t, err.Error(), `config key "port"`)
+
+	err = Map{"timeout": "soon"}.MapInto(&result)
+	assert.Contains(t, err.Error(), `config key "timeout"`)
+
+	err = Map{"backends": Slice{Map{"weight": 1}, Map{"weight": 300}}}.MapInto(&result)
+	assert.Contains(t, err.Error(), `config key "backends[1].weight"`)
+
+	assert.Error(t, Map{}.MapInto(result))
+}
+
+func TestSliceMapInto(t *testing.T) {
+	var result []time.Duration
+	require.NoError(t, Slice{"1s", "2m"}.MapInto(&result))
+	assert.Equal(t, []time.Duration{time.Second, 2 * time.Minute}, result)
+}
+
+func TestConvertConfig(t *testing.T) {
+	converted, err := convertConfig("timeout", "30s", durationType)
+	require.NoError(t, err)
+	assert.Equal(t, 30*time.Second, converted.Interface())
+
+	_, err = convertConfig("timeout", "30 seconds", durationType)
+	assert.EqualError(t, err, `config key "timeout": can not use string 30 seconds as time.Duration: time: unknown unit " seconds" in duration "30 seconds"`)
+
+	_, err = convertConfig("retries", 1.5, reflect.TypeOf(int(0)))
+	assert.EqualError(t, err, `config key "retries": can not use float64 1.5 as int: not an integer in range`)
+
+	_, err = convertConfig("endpoint", "localhost", urlType)
+	assert.EqualError(t, err, `config key "endpoint": can not use string localhost as url.URL: not an absolute URL`)
+
+	provider := failingProvider(durationType, err)
+	assert.Equal(t, reflect.TypeOf(func() time.Duration { return 0 }), reflect.TypeOf(provider))
+	assert.PanicsWithValue(t, err, func() { reflect.ValueOf(provider).Call(nil) })
+}
@@ -0,0 +1,116 @@
+package framework
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"flamingo.me/flamingo/v3/framework/config"
+	"flamingo.me/flamingo/v3/framework/flamingo"
+)
+
+type (
+	// configReloader watches the configuration files while the server is running in debug mode
+	// and reloads the configuration and routes of all areas on change
+	configReloader struct {
+		area     *config.Area
+		logger   flamingo.Logger
+		enabled  bool
+		interval time.Duration
+		mu       sync.Mutex
+		cancel   context.CancelFunc
+	}
+
+	eventRouterProvider func() flamingo.EventRouter
+)
+
+// Inject dependencies
+func (c *configReloader) Inject(
+	area *config.Area,
+	logger flamingo.Logger,
+	cfg *struct {
+		Debug    bool    `inject:"config:debug.mode"`
+		Enabled  bool    `inject:"config:flamingo.config.reload.enabled"`
+		Interval float64 `inject:"config:flamingo.config.reload.interval"`
+	},
+) {
+	c.area = area
+	c.logger = logger.WithField(flamingo.LogKeyModule, "framework").WithField(flamingo.LogKeyCategory, "config.reload")
+	if cfg != nil {
+		c.enabled = cfg.Debug && cfg.Enabled
+		c.interval = time.Duration(cfg.Interval) * time.Millisecond
+	}
+}
+
+// Notify starts the watcher on server start and stops it on shutdown
+func (c *configReloader) Notify(ctx context.Context, event flamingo.Event) {
+	switch event.(type) {
+	case *flamingo.ServerStartEvent:
+		c.start()
+	case *flamingo.ServerShutdownEvent:
+		c.stop()
+	}
+}
+
+func (c *configReloader) start() {
+	if !c.enabled || c.interval <= 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.cancel != nil {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	c.cancel = cancel
+
+	c.logger.Info("watching configuration files for changes")
+	go c.watch(ctx, config.NewWatcher(c.area))
+}
+
+func (c *configReloader) stop() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.cancel != nil {
+		c.cancel()
+		c.cancel = nil
+	}
+}
+
+func (c *configReloader) watch(ctx context.Context, watcher *config.Watcher) {
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if watcher.Changed() {
+				c.reload(ctx)
+			}
+		}
+	}
+}
+
+// reload the configuration and notify every changed area via its own event router
+func (c *configReloader) reload(ctx context.Context) {
+	changed, err := config.Reload(c.area)
+	if err != nil {
+		c.logger.Error(err)
+		return
+	}
+
+	for _, area := range changed {
+		if area.Injector == nil {
+			continue
+		}
+		c.logger.Info("configuration reloaded for area ", area.Name)
+		eventRouter := area.Injector.GetInstance(new(eventRouterProvider)).(eventRouterProvider)()
+		eventRouter.Dispatch(ctx, &config.ChangedEvent{Area: area})
+	}
+}
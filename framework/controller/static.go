@@ -4,23 +4,42 @@ import (
 	"context"
 	"net/http"
 
+	"flamingo.me/flamingo/v3/framework/flamingo"
 	"flamingo.me/flamingo/v3/framework/web"
 )
 
 type fileResponse struct {
-	r *web.Request
+	r  *web.Request
+	fs http.FileSystem
 }
 
-// Apply result by calling http.ServeFile
+// Apply result by serving the file from the file system, or by calling http.ServeFile without file system
 func (fr fileResponse) Apply(ctx context.Context, rw http.ResponseWriter) error {
-	http.ServeFile(rw, fr.r.Request(), fr.r.Params["name"])
+	if fr.fs == nil {
+		http.ServeFile(rw, fr.r.Request(), fr.r.Params["name"])
+		return nil
+	}
+	flamingo.ServeFile(rw, fr.r.Request(), fr.fs, fr.r.Params["name"])
 	return nil
 }
 
-// Static is a controller to handle file requests
-type Static struct{}
+// Static is a controller to handle file requests.
+// The files are served from the http.FileSystem bound with flamingo.FileSystemStatic.
+// Without a bound file system the name is passed to http.ServeFile, as before file systems could be bound.
+type Static struct {
+	fs http.FileSystem
+}
+
+// Inject dependencies
+func (controller *Static) Inject(cfg *struct {
+	FileSystem http.FileSystem `inject:"flamingo.filesystem.static,optional"`
+}) {
+	if cfg != nil {
+		controller.fs = cfg.FileSystem
+	}
+}
 
-// File returns a fileResponse which uses http.ServeFile to respond to the request
-func (*Static) File(ctx context.Context, r *web.Request) web.Result {
-	return fileResponse{r: r}
+// File returns a fileResponse which serves the file of the name parameter
+func (controller *Static) File(ctx context.Context, r *web.Request) web.Result {
+	return fileResponse{r: r, fs: controller.fs}
 }
@@ -0,0 +1,207 @@
+package flamingo
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"flamingo.me/dingo"
+)
+
+type (
+	// MemoryFileSystem is an in-memory http.FileSystem of file contents by their slash separated path,
+	// directories are derived from the paths. It is useful for tests and small generated assets.
+	MemoryFileSystem map[string]string
+
+	memoryFile struct {
+		*bytes.Reader
+		info memoryFileInfo
+	}
+
+	memoryDir struct {
+		info    memoryFileInfo
+		entries []os.FileInfo
+		offset  int
+	}
+
+	memoryFileInfo struct {
+		name  string
+		size  int64
+		isDir bool
+	}
+)
+
+// Filesystem annotations of modules reading files, a http.FileSystem bound with them replaces the default directory
+const (
+	FileSystemTemplates = "flamingo.filesystem.templates"
+	FileSystemStatic    = "flamingo.filesystem.static"
+	FileSystemRobotsTxt = "flamingo.filesystem.robotstxt"
+)
+
+var (
+	_ http.FileSystem = MemoryFileSystem{}
+	_ http.File       = new(memoryFile)
+	_ http.File       = new(memoryDir)
+)
+
+// BindFileSystem binds a http.FileSystem with the given annotation, e.g. to ship templates compiled into the binary:
+//
+//	flamingo.BindFileSystem(injector, flamingo.FileSystemTemplates).ToInstance(assets)
+func BindFileSystem(injector *dingo.Injector, annotation string) *dingo.Binding {
+	return injector.Bind(new(http.FileSystem)).AnnotatedWith(annotation)
+}
+
+// ServeFile replies to the request with the named file of the file system, like http.ServeFile.
+// Directories and missing files are not found.
+func ServeFile(rw http.ResponseWriter, req *http.Request, fs http.FileSystem, name string) {
+	file, err := fs.Open(name)
+	if err != nil {
+		serveFileError(rw, err)
+		return
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		serveFileError(rw, err)
+		return
+	}
+	if info.IsDir() {
+		http.NotFound(rw, req)
+		return
+	}
+
+	http.ServeContent(rw, req, info.Name(), info.ModTime(), file)
+}
+
+func serveFileError(rw http.ResponseWriter, err error) {
+	switch {
+	case os.IsNotExist(err):
+		http.Error(rw, "404 page not found", http.StatusNotFound)
+	case os.IsPermission(err):
+		http.Error(rw, "403 Forbidden", http.StatusForbidden)
+	default:
+		http.Error(rw, "500 Internal Server Error", http.StatusInternalServerError)
+	}
+}
+
+// Open the file or directory with the given name
+func (fs MemoryFileSystem) Open(name string) (http.File, error) {
+	name = cleanPath(name)
+
+	prefix := name + "/"
+	if name == "" {
+		prefix = ""
+	}
+
+	children := make(map[string]*memoryFileInfo)
+	for file, content := range fs {
+		file = cleanPath(file)
+		if file == name {
+			return &memoryFile{
+				Reader: bytes.NewReader([]byte(content)),
+				info:   memoryFileInfo{name: path.Base(name), size: int64(len(content))},
+			}, nil
+		}
+		if !strings.HasPrefix(file, prefix) {
+			continue
+		}
+		parts := strings.SplitN(strings.TrimPrefix(file, prefix), "/", 2)
+		if len(parts) == 2 {
+			children[parts[0]] = &memoryFileInfo{name: parts[0], isDir: true}
+		} else if _, ok := children[parts[0]]; !ok {
+			children[parts[0]] = &memoryFileInfo{name: parts[0], size: int64(len(content))}
+		}
+	}
+
+	if len(children) == 0 && name != "" {
+		return nil, &os.PathError{Op: "open", Path: name, Err: os.ErrNotExist}
+	}
+
+	dir := &memoryDir{info: memoryFileInfo{name: path.Base("/" + name), isDir: true}}
+	for _, child := range children {
+		dir.entries = append(dir.entries, *child)
+	}
+	sort.Slice(dir.entries, func(i, j int) bool { return dir.entries[i].Name() < dir.entries[j].Name() })
+
+	return dir, nil
+}
+
+// cleanPath returns the slash separated path without leading slash, the root directory is empty
+func cleanPath(name string) string {
+	return strings.TrimPrefix(path.Clean("/"+name), "/")
+}
+
+// Close the file
+func (f *memoryFile) Close() error {
+	return nil
+}
+
+// Readdir fails for files
+func (f *memoryFile) Readdir(count int) ([]os.FileInfo, error) {
+	return nil, &os.PathError{Op: "readdir", Path: f.info.name, Err: os.ErrInvalid}
+}
+
+// Stat returns the file info
+func (f *memoryFile) Stat() (os.FileInfo, error) {
+	return f.info, nil
+}
+
+// Close the directory
+func (d *memoryDir) Close() error {
+	return nil
+}
+
+// Read fails for directories
+func (d *memoryDir) Read([]byte) (int, error) {
+	return 0, &os.PathError{Op: "read", Path: d.info.name, Err: os.ErrInvalid}
+}
+
+// Seek fails for directories
+func (d *memoryDir) Seek(int64, int) (int64, error) {
+	return 0, &os.PathError{Op: "seek", Path: d.info.name, Err: os.ErrInvalid}
+}
+
+// Readdir returns the directory entries, see os.File.Readdir
+func (d *memoryDir) Readdir(count int) ([]os.FileInfo, error) {
+	if count <= 0 {
+		entries := d.entries[d.offset:]
+		d.offset = len(d.entries)
+		return entries, nil
+	}
+
+	if d.offset >= len(d.entries) {
+		return nil, io.EOF
+	}
+	end := d.offset + count
+	if end > len(d.entries) {
+		end = len(d.entries)
+	}
+	entries := d.entries[d.offset:end]
+	d.offset = end
+	return entries, nil
+}
+
+// Stat returns the directory info
+func (d *memoryDir) Stat() (os.FileInfo, error) {
+	return d.info, nil
+}
+
+func (i memoryFileInfo) Name() string       { return i.name }
+func (i memoryFileInfo) Size() int64        { return i.size }
+func (i memoryFileInfo) ModTime() time.Time { return time.Time{} }
+func (i memoryFileInfo) IsDir() bool        { return i.isDir }
+func (i memoryFileInfo) Sys() interface{}   { return nil }
+
+// Mode of the file, files are read only
+func (i memoryFileInfo) Mode() os.FileMode {
+	if i.isDir {
+		return os.ModeDir | 0555
+	}
+	return 0444
+}
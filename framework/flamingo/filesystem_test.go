@@ -0,0 +1,113 @@
+package flamingo_test
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"flamingo.me/flamingo/v3/framework/flamingo"
+)
+
+func TestMemoryFileSystem_Open(t *testing.T) {
+	fs := flamingo.MemoryFileSystem{
+		"index.html":         "index",
+		"css/main.css":       "body {}",
+		"/css/print/a.css":   "a",
+		"img/logo.svg":       "<svg/>",
+		"img/icons/cart.svg": "<svg/>",
+	}
+
+	t.Run("file", func(t *testing.T) {
+		for _, name := range []string{"css/main.css", "/css/main.css", "css/../css/main.css"} {
+			file, err := fs.Open(name)
+			require.NoError(t, err, name)
+			content, err := ioutil.ReadAll(file)
+			assert.NoError(t, err)
+			assert.Equal(t, "body {}", string(content))
+
+			info, err := file.Stat()
+			assert.NoError(t, err)
+			assert.Equal(t, "main.css", info.Name())
+			assert.Equal(t, int64(7), info.Size())
+			assert.False(t, info.IsDir())
+
+			_, err = file.Readdir(-1)
+			assert.Error(t, err)
+		}
+	})
+
+	t.Run("directory", func(t *testing.T) {
+		dir, err := fs.Open("css")
+		require.NoError(t, err)
+		info, err := dir.Stat()
+		assert.NoError(t, err)
+		assert.True(t, info.IsDir())
+
+		entries, err := dir.Readdir(-1)
+		assert.NoError(t, err)
+		require.Len(t, entries, 2)
+		assert.Equal(t, "main.css", entries[0].Name())
+		assert.False(t, entries[0].IsDir())
+		assert.Equal(t, "print", entries[1].Name())
+		assert.True(t, entries[1].IsDir())
+
+		root, err := fs.Open("/")
+		require.NoError(t, err)
+		entries, err = root.Readdir(2)
+		assert.NoError(t, err)
+		require.Len(t, entries, 2)
+		assert.Equal(t, "css", entries[0].Name())
+		assert.Equal(t, "img", entries[1].Name())
+		entries, err = root.Readdir(2)
+		assert.NoError(t, err)
+		require.Len(t, entries, 1)
+		assert.Equal(t, "index.html", entries[0].Name())
+		_, err = root.Readdir(2)
+		assert.Error(t, err)
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		for _, name := range []string{"missing.html", "css/main", "cs"} {
+			_, err := fs.Open(name)
+			assert.True(t, os.IsNotExist(err), name)
+		}
+	})
+}
+
+func TestServeFile(t *testing.T) {
+	fs := flamingo.MemoryFileSystem{
+		"robots.txt":   "User-agent: *",
+		"css/main.css": "body {}",
+	}
+
+	tests := []struct {
+		name        string
+		file        string
+		status      int
+		body        string
+		contentType string
+	}{
+		{name: "text file", file: "robots.txt", status: http.StatusOK, body: "User-agent: *", contentType: "text/plain; charset=utf-8"},
+		{name: "css file", file: "/css/main.css", status: http.StatusOK, body: "body {}", contentType: "text/css; charset=utf-8"},
+		{name: "directory", file: "css", status: http.StatusNotFound},
+		{name: "missing file", file: "missing.txt", status: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			flamingo.ServeFile(rec, httptest.NewRequest(http.MethodGet, "/", nil), fs, tt.file)
+
+			assert.Equal(t, tt.status, rec.Code)
+			if tt.status == http.StatusOK {
+				assert.Equal(t, tt.body, rec.Body.String())
+				assert.Equal(t, tt.contentType, rec.Header().Get("Content-Type"))
+			}
+		})
+	}
+}
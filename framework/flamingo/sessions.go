@@ -1,100 +1,299 @@
 package flamingo
 
 import (
+	"crypto/sha256"
+	"net/http"
 	"net/url"
 	"os"
+	"strings"
+	"time"
 
 	"flamingo.me/dingo"
 	"flamingo.me/flamingo/v3/framework/config"
 	"github.com/boj/redistore"
 	"github.com/gomodule/redigo/redis"
 	"github.com/gorilla/sessions"
+	"github.com/pkg/errors"
 	"github.com/zemirco/memorystore"
 )
 
-// SessionModule for session management
-type SessionModule struct {
-	backend              string
-	secret               string
-	fileName             string
-	secure               bool
-	storeLength          int
-	maxAge               int
-	path                 string
-	redisHost            string
-	redisPassword        string
-	redisIdleConnections int
-	redisMaxAge          int
+type (
+	// SessionModule for session management
+	SessionModule struct {
+		backend              string
+		secret               string
+		previousSecrets      []string
+		encrypt              bool
+		fileName             string
+		secure               bool
+		httpOnly             bool
+		sameSite             string
+		domain               string
+		storeLength          int
+		maxAge               int
+		path                 string
+		redisHost            string
+		redisPassword        string
+		redisIdleConnections int
+		redisMaxAge          int
+	}
+
+	// SessionStore creates the gorilla session store of a session backend.
+	// Modules provide own backends via BindSessionStore, which are selected with the session.backend config.
+	SessionStore interface {
+		Create(options SessionStoreOptions) (sessions.Store, error)
+	}
+
+	// SessionStoreOptions are the backend independent settings of a session store
+	SessionStoreOptions struct {
+		// KeyPairs are the hash and block keys of the cookie codecs, see securecookie.CodecsFromPairs.
+		// The first pair encodes, all pairs decode, so previous secrets are still accepted.
+		KeyPairs [][]byte
+		// Cookie are the options of the session cookie, including the max age of the session
+		Cookie sessions.Options
+		// MaxLength is the maximum size of a stored session
+		MaxLength int
+	}
+
+	// memorySessionStore keeps the sessions in memory
+	memorySessionStore struct{}
+
+	// fileSessionStore keeps the sessions in files
+	fileSessionStore struct {
+		path string
+	}
+
+	// cookieSessionStore keeps the whole session in the cookie, which should be encrypted
+	cookieSessionStore struct{}
+
+	// redisSessionStore keeps the sessions in redis
+	redisSessionStore struct {
+		pool   *redis.Pool
+		maxAge int
+	}
+)
+
+// BindSessionStore registers a session store backend, which is used if session.backend is the given name
+func BindSessionStore(injector *dingo.Injector, backend string, store SessionStore) {
+	injector.BindMap(new(SessionStore), backend).To(store)
 }
 
 // Inject dependencies
 func (m *SessionModule) Inject(config *struct {
 	// session config is optional to allow usage of the DefaultConfig
-	Backend  string `inject:"config:session.backend"`
-	Secret   string `inject:"config:session.secret"`
-	FileName string `inject:"config:session.file"`
-	Secure   bool   `inject:"config:session.cookie.secure"`
-	// float64 is used due to the injection as config from json - int is not possible on this
-	StoreLength          float64 `inject:"config:session.store.length"`
-	MaxAge               float64 `inject:"config:session.max.age"`
-	Path                 string  `inject:"config:session.cookie.path"`
-	RedisURL             string  `inject:"config:session.redis.url"`
-	RedisHost            string  `inject:"config:session.redis.host"`
-	RedisPassword        string  `inject:"config:session.redis.password"`
-	RedisIdleConnections float64 `inject:"config:session.redis.idle.connections"`
-	RedisMaxAge          float64 `inject:"config:session.redis.maxAge"`
+	Backend              string       `inject:"config:session.backend"`
+	Secret               string       `inject:"config:session.secret"`
+	PreviousSecrets      config.Slice `inject:"config:session.previousSecrets,optional"`
+	Encrypt              bool         `inject:"config:session.encrypt,optional"`
+	FileName             string       `inject:"config:session.file"`
+	Secure               bool         `inject:"config:session.cookie.secure"`
+	HTTPOnly             bool         `inject:"config:session.cookie.httpOnly,optional"`
+	SameSite             string       `inject:"config:session.cookie.sameSite,optional"`
+	Domain               string       `inject:"config:session.cookie.domain,optional"`
+	StoreLength          int          `inject:"config:session.store.length"`
+	MaxAge               int          `inject:"config:session.max.age"`
+	Path                 string       `inject:"config:session.cookie.path"`
+	RedisURL             string       `inject:"config:session.redis.url"`
+	RedisHost            string       `inject:"config:session.redis.host"`
+	RedisPassword        string       `inject:"config:session.redis.password"`
+	RedisIdleConnections int          `inject:"config:session.redis.idle.connections"`
+	RedisMaxAge          int          `inject:"config:session.redis.maxAge"`
 }) {
 	m.backend = config.Backend
 	m.secret = config.Secret
+	if err := config.PreviousSecrets.MapInto(&m.previousSecrets); err != nil {
+		panic(errors.Wrap(err, "session.previousSecrets"))
+	}
+	m.encrypt = config.Encrypt
 	m.fileName = config.FileName
 	m.secure = config.Secure
-	m.storeLength = int(config.StoreLength)
-	m.maxAge = int(config.MaxAge)
+	m.httpOnly = config.HTTPOnly
+	m.sameSite = config.SameSite
+	m.domain = config.Domain
+	m.storeLength = config.StoreLength
+	m.maxAge = config.MaxAge
 	m.path = config.Path
 	m.redisHost, m.redisPassword = getRedisConnectionInformation(config.RedisURL, config.RedisHost, config.RedisPassword)
-	m.redisIdleConnections = int(config.RedisIdleConnections)
-	m.maxAge = int(config.MaxAge)
+	m.redisIdleConnections = config.RedisIdleConnections
+	m.redisMaxAge = config.RedisMaxAge
 }
 
 // Configure DI
 func (m *SessionModule) Configure(injector *dingo.Injector) {
-	switch m.backend {
-	case "redis":
-		sessionStore, err := redistore.NewRediStore(int(m.redisIdleConnections), "tcp", m.redisHost, m.redisPassword, []byte(m.secret))
+	BindSessionStore(injector, "memory", new(memorySessionStore))
+	BindSessionStore(injector, "file", &fileSessionStore{path: m.fileName})
+	BindSessionStore(injector, "cookie", new(cookieSessionStore))
+
+	if m.backend == "redis" {
+		pool := newRedisPool(m.redisIdleConnections, m.redisHost, m.redisPassword)
+		BindSessionStore(injector, "redis", &redisSessionStore{pool: pool, maxAge: m.redisMaxAge})
+		injector.Bind(new(redis.Pool)).ToInstance(pool)
+	}
+
+	options, err := m.storeOptions()
+	if err != nil {
+		panic(err)
+	}
+
+	injector.Bind(new(sessions.Store)).ToProvider(func(stores func() map[string]SessionStore, logger Logger) sessions.Store {
+		store, err := newSessionStore(m.backend, stores(), options, logger.WithField(LogKeyModule, "session"))
 		if err != nil {
-			panic(err) // todo: don't panic? fallback?
+			panic(err)
+		}
+		return store
+	}).In(dingo.Singleton)
+}
+
+// storeOptions creates the backend independent settings
+func (m *SessionModule) storeOptions() (SessionStoreOptions, error) {
+	sameSite, err := parseSameSite(m.sameSite)
+	if err != nil {
+		return SessionStoreOptions{}, err
+	}
+
+	return SessionStoreOptions{
+		KeyPairs: sessionKeyPairs(m.secret, m.previousSecrets, m.encrypt),
+		Cookie: sessions.Options{
+			Path:     m.path,
+			Domain:   m.domain,
+			MaxAge:   m.maxAge,
+			Secure:   m.secure,
+			HttpOnly: m.httpOnly,
+			SameSite: sameSite,
+		},
+		MaxLength: m.storeLength,
+	}, nil
+}
+
+// newSessionStore creates the store of the configured backend, an unknown or failing backend is an error.
+// A backend which is temporarily unreachable, e.g. redis, returns its store together with the error,
+// this degraded store is used anyway so the start is not prevented.
+func newSessionStore(backend string, stores map[string]SessionStore, options SessionStoreOptions, logger Logger) (sessions.Store, error) {
+	if backend == "" {
+		backend = "memory"
+	}
+
+	sessionStore, ok := stores[backend]
+	if !ok {
+		return nil, errors.Errorf("session.backend: unknown backend %q", backend)
+	}
+
+	store, err := sessionStore.Create(options)
+	if err != nil && store == nil {
+		return nil, errors.Wrapf(err, "session.backend %q", backend)
+	}
+	if err != nil {
+		logger.Error("session backend ", backend, " is degraded: ", err)
+	}
+
+	return store, nil
+}
+
+// sessionKeyPairs derives the codec keys from the secrets, the current secret first.
+// The secret itself is the hash key, so existing sessions stay valid, encryption uses an AES-256 key derived from it.
+func sessionKeyPairs(secret string, previousSecrets []string, encrypt bool) [][]byte {
+	var keyPairs [][]byte
+	for i, s := range append([]string{secret}, previousSecrets...) {
+		if i > 0 && s == "" {
+			continue
+		}
+		var blockKey []byte
+		if encrypt {
+			key := sha256.Sum256([]byte(s))
+			blockKey = key[:]
 		}
+		keyPairs = append(keyPairs, []byte(s), blockKey)
+	}
+	return keyPairs
+}
+
+// parseSameSite maps the session.cookie.sameSite config, an empty value omits the attribute
+func parseSameSite(sameSite string) (http.SameSite, error) {
+	switch strings.ToLower(sameSite) {
+	case "":
+		return http.SameSiteDefaultMode, nil
+	case "lax":
+		return http.SameSiteLaxMode, nil
+	case "strict":
+		return http.SameSiteStrictMode, nil
+	case "none":
+		return http.SameSiteNoneMode, nil
+	}
+	return http.SameSiteDefaultMode, errors.Errorf("session.cookie.sameSite: invalid value %q, expected lax, strict, none or empty", sameSite)
+}
+
+// Create the memory store
+func (*memorySessionStore) Create(options SessionStoreOptions) (sessions.Store, error) {
+	sessionStore := memorystore.NewMemoryStore(options.KeyPairs...)
+
+	sessionStore.MaxLength(options.MaxLength)
+	sessionStore.MaxAge(options.Cookie.MaxAge)
+	cookie := options.Cookie
+	sessionStore.Options = &cookie
+
+	return sessionStore, nil
+}
+
+// Create the file store, the directory is created if it does not exist
+func (s *fileSessionStore) Create(options SessionStoreOptions) (sessions.Store, error) {
+	if err := os.MkdirAll(s.path, os.ModePerm); err != nil {
+		return nil, errors.Wrap(err, "session directory")
+	}
+
+	sessionStore := sessions.NewFilesystemStore(s.path, options.KeyPairs...)
+
+	sessionStore.MaxLength(options.MaxLength)
+	sessionStore.MaxAge(options.Cookie.MaxAge)
+	cookie := options.Cookie
+	sessionStore.Options = &cookie
+
+	return sessionStore, nil
+}
+
+// Create the cookie store, the session size is limited by the browsers' cookie size
+func (*cookieSessionStore) Create(options SessionStoreOptions) (sessions.Store, error) {
+	sessionStore := sessions.NewCookieStore(options.KeyPairs...)
+
+	sessionStore.MaxAge(options.Cookie.MaxAge)
+	cookie := options.Cookie
+	sessionStore.Options = &cookie
+
+	return sessionStore, nil
+}
+
+// Create the redis store. If redis is not reachable the store is returned anyway together with the error,
+// it connects as soon as redis is available, until then every request gets a new session.
+func (s *redisSessionStore) Create(options SessionStoreOptions) (sessions.Store, error) {
+	sessionStore, err := redistore.NewRediStoreWithPool(s.pool, options.KeyPairs...)
+	if sessionStore == nil {
+		return nil, err
+	}
+
+	sessionStore.SetMaxAge(options.Cookie.MaxAge)
+	sessionStore.SetMaxLength(options.MaxLength)
+	cookie := options.Cookie
+	sessionStore.Options = &cookie
+	sessionStore.DefaultMaxAge = s.maxAge
+
+	return sessionStore, errors.Wrap(err, "redis not reachable")
+}
 
-		sessionStore.SetMaxAge(m.maxAge)
-		sessionStore.SetMaxLength(m.storeLength)
-		sessionStore.Options.Secure = m.secure
-		sessionStore.Options.HttpOnly = true
-		sessionStore.Options.Path = m.path
-		sessionStore.DefaultMaxAge = m.redisMaxAge
-
-		injector.Bind(new(sessions.Store)).ToInstance(sessionStore)
-		injector.Bind(new(redis.Pool)).ToInstance(sessionStore.Pool)
-	case "file":
-		os.Mkdir(m.fileName, os.ModePerm)
-		sessionStore := sessions.NewFilesystemStore(m.fileName, []byte(m.secret))
-
-		sessionStore.MaxLength(m.storeLength)
-		sessionStore.MaxAge(m.maxAge)
-		sessionStore.Options.Secure = m.secure
-		sessionStore.Options.HttpOnly = true
-		sessionStore.Options.Path = m.path
-
-		injector.Bind(new(sessions.Store)).ToInstance(sessionStore)
-	default: //memory
-		sessionStore := memorystore.NewMemoryStore([]byte(m.secret))
-
-		sessionStore.MaxLength(m.storeLength)
-		sessionStore.MaxAge(m.maxAge)
-		sessionStore.Options.Secure = m.secure
-		sessionStore.Options.HttpOnly = true
-		sessionStore.Options.Path = m.path
-
-		injector.Bind(new(sessions.Store)).ToInstance(sessionStore)
+// newRedisPool creates the connection pool, connections are established on demand
+func newRedisPool(idleConnections int, host, password string) *redis.Pool {
+	return &redis.Pool{
+		MaxIdle:     idleConnections,
+		IdleTimeout: 240 * time.Second,
+		TestOnBorrow: func(c redis.Conn, t time.Time) error {
+			_, err := c.Do("PING")
+			return err
+		},
+		Dial: func() (redis.Conn, error) {
+			var options []redis.DialOption
+			if password != "" {
+				options = append(options, redis.DialPassword(password))
+			}
+			return redis.Dial("tcp", host, options...)
+		},
 	}
 }
 
@@ -103,10 +302,15 @@ func (m *SessionModule) DefaultConfig() config.Map {
 	return config.Map{
 		"session.backend":                "memory",
 		"session.secret":                 "flamingosecret",
+		"session.previousSecrets":        config.Slice{},
+		"session.encrypt":                false,
 		"session.file":                   "/sessions",
 		"session.store.length":           1024 * 1024,
 		"session.max.age":                60 * 60 * 24 * 30,
 		"session.cookie.secure":          true,
+		"session.cookie.httpOnly":        true,
+		"session.cookie.sameSite":        "",
+		"session.cookie.domain":          "",
 		"session.cookie.path":            "/",
 		"session.redis.url":              "",
 		"session.redis.host":             "redis",
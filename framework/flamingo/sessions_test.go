@@ -2,9 +2,14 @@ package flamingo
 
 import (
 	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 
+	"github.com/gorilla/sessions"
+	"github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 type testData struct {
@@ -74,3 +79,112 @@ func TestGetRedisConnectionInformation(t *testing.T) {
 		}
 	})
 }
+
+type failingSessionStore struct {
+	store sessions.Store
+}
+
+func (f *failingSessionStore) Create(SessionStoreOptions) (sessions.Store, error) {
+	return f.store, errors.New("backend not available")
+}
+
+func TestNewSessionStore(t *testing.T) {
+	options := SessionStoreOptions{KeyPairs: sessionKeyPairs("secret", nil, false), Cookie: sessions.Options{Path: "/", MaxAge: 3600}}
+	degraded := sessions.NewCookieStore([]byte("secret"))
+
+	stores := map[string]SessionStore{
+		"memory":   new(memorySessionStore),
+		"cookie":   new(cookieSessionStore),
+		"failing":  new(failingSessionStore),
+		"degraded": &failingSessionStore{store: degraded},
+	}
+
+	store, err := newSessionStore("cookie", stores, options, NullLogger{})
+	assert.NoError(t, err)
+	assert.IsType(t, new(sessions.CookieStore), store)
+
+	store, err = newSessionStore("degraded", stores, options, NullLogger{})
+	assert.NoError(t, err)
+	assert.Equal(t, degraded, store, "a degraded store is used anyway")
+
+	_, err = newSessionStore("failing", stores, options, NullLogger{})
+	assert.Error(t, err, "a failing backend prevents the start")
+
+	_, err = newSessionStore("unknown", stores, options, NullLogger{})
+	assert.Error(t, err, "an unknown backend prevents the start")
+}
+
+func TestRedisSessionStore_Unreachable(t *testing.T) {
+	store, err := (&redisSessionStore{pool: newRedisPool(1, "127.0.0.1:1", "")}).Create(SessionStoreOptions{KeyPairs: sessionKeyPairs("secret", nil, false)})
+	assert.Error(t, err)
+	assert.NotNil(t, store, "the store connects as soon as redis is available")
+}
+
+func TestSessionKeyPairs_Rotation(t *testing.T) {
+	for _, encrypt := range []bool{false, true} {
+		t.Run(fmt.Sprintf("encrypt %v", encrypt), func(t *testing.T) {
+			cookie := func(secret string, previous []string) (sessions.Store, *http.Cookie) {
+				store, err := new(cookieSessionStore).Create(SessionStoreOptions{
+					KeyPairs: sessionKeyPairs(secret, previous, encrypt),
+					Cookie:   sessions.Options{Path: "/", MaxAge: 3600, HttpOnly: true, SameSite: http.SameSiteLaxMode},
+				})
+				require.NoError(t, err)
+
+				req := httptest.NewRequest(http.MethodGet, "/", nil)
+				session, err := store.New(req, "flamingo")
+				require.NoError(t, err)
+				session.Values["user"] = "flamingo"
+
+				rec := httptest.NewRecorder()
+				require.NoError(t, store.Save(req, rec, session))
+				cookies := rec.Result().Cookies()
+				require.Len(t, cookies, 1)
+				return store, cookies[0]
+			}
+
+			load := func(store sessions.Store, c *http.Cookie) (interface{}, error) {
+				req := httptest.NewRequest(http.MethodGet, "/", nil)
+				req.AddCookie(c)
+				session, err := store.New(req, "flamingo")
+				return session.Values["user"], err
+			}
+
+			_, oldCookie := cookie("old-secret", nil)
+			rotated, newCookie := cookie("new-secret", []string{"old-secret"})
+			assert.Equal(t, http.SameSiteLaxMode, newCookie.SameSite)
+			assert.True(t, newCookie.HttpOnly)
+
+			user, err := load(rotated, oldCookie)
+			assert.NoError(t, err, "sessions of the previous secret are still valid")
+			assert.Equal(t, "flamingo", user)
+
+			user, err = load(rotated, newCookie)
+			assert.NoError(t, err)
+			assert.Equal(t, "flamingo", user)
+
+			withoutPrevious, _ := cookie("new-secret", nil)
+			_, err = load(withoutPrevious, oldCookie)
+			assert.Error(t, err, "the previous secret is not accepted anymore")
+
+			if encrypt {
+				assert.NotContains(t, newCookie.Value, "flamingo")
+			}
+		})
+	}
+}
+
+func TestParseSameSite(t *testing.T) {
+	for value, expected := range map[string]http.SameSite{
+		"":       http.SameSiteDefaultMode,
+		"lax":    http.SameSiteLaxMode,
+		"Strict": http.SameSiteStrictMode,
+		"none":   http.SameSiteNoneMode,
+	} {
+		sameSite, err := parseSameSite(value)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, sameSite)
+	}
+
+	_, err := parseSameSite("sometimes")
+	assert.Error(t, err)
+}
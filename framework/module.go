@@ -45,9 +45,11 @@ func (initmodule *InitModule) Configure(injector *dingo.Injector) {
 	web.BindRoutes(injector, new(routes))
 
 	injector.Bind(new(flamingo.EventRouter)).To(flamingo.DefaultEventRouter{})
+	flamingo.BindEventSubscriber(injector).To(configReloader{})
 
 	injector.Bind(web.Router{}).In(dingo.ChildSingleton)
 	injector.Bind(new(web.ReverseRouter)).To(web.Router{})
+	flamingo.BindEventSubscriber(injector).To(web.Router{})
 	injector.Bind(web.RouterRegistry{}).In(dingo.Singleton).ToProvider(web.NewRegistry)
 	injector.BindMulti(new(web.Filter)).To(new(filter.MetricsFilter))
 
@@ -91,14 +93,16 @@ func (r *routes) Routes(registry *web.RouterRegistry) {
 // DefaultConfig for this module
 func (initmodule *InitModule) DefaultConfig() config.Map {
 	return config.Map{
-		"debug.mode":                    true,
-		"flamingo.router.notfound":      web.FlamingoNotfound,
-		"flamingo.router.error":         web.FlamingoError,
-		"flamingo.router.timeout":       float64(60000),
-		"flamingo.template.err403":      "error/403",
-		"flamingo.template.err404":      "error/404",
-		"flamingo.template.errWithCode": "error/withCode",
-		"flamingo.template.err503":      "error/503",
-		"session.name":                  "flamingo",
+		"debug.mode":                      true,
+		"flamingo.config.reload.enabled":  true,
+		"flamingo.config.reload.interval": float64(1000),
+		"flamingo.router.notfound":        web.FlamingoNotfound,
+		"flamingo.router.error":           web.FlamingoError,
+		"flamingo.router.timeout":         float64(60000),
+		"flamingo.template.err403":        "error/403",
+		"flamingo.template.err404":        "error/404",
+		"flamingo.template.errWithCode":   "error/withCode",
+		"flamingo.template.err503":        "error/503",
+		"session.name":                    "flamingo",
 	}
 }
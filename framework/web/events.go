@@ -22,4 +22,10 @@ type (
 		OnRequestEvent
 		Error error
 	}
+
+	// SessionRegeneratedEvent is dispatched after the session got a new ID, see Session.Regenerate
+	SessionRegeneratedEvent struct {
+		Request *Request
+		Session *Session
+	}
 )
@@ -6,6 +6,7 @@ import (
 	"io"
 	"net/http"
 	"strings"
+	"sync"
 	"time"
 
 	"flamingo.me/flamingo/v3/framework/flamingo"
@@ -21,6 +22,7 @@ import (
 type (
 	handler struct {
 		routerRegistry *RouterRegistry
+		registryMu     sync.RWMutex
 		filter         []Filter
 
 		eventRouter flamingo.EventRouter
@@ -32,6 +34,11 @@ type (
 	}
 
 	emptyResponseWriter struct{}
+
+	// sessionDeleter is implemented by session stores which delete sessions explicitly, e.g. the redis store
+	sessionDeleter interface {
+		Delete(r *http.Request, w http.ResponseWriter, session *sessions.Session) error
+	}
 )
 
 var (
@@ -73,6 +80,20 @@ func (h *handler) getSession(ctx context.Context, httpRequest *http.Request) (gs
 	return
 }
 
+func (h *handler) registry() *RouterRegistry {
+	h.registryMu.RLock()
+	defer h.registryMu.RUnlock()
+
+	return h.routerRegistry
+}
+
+func (h *handler) setRegistry(registry *RouterRegistry) {
+	h.registryMu.Lock()
+	defer h.registryMu.Unlock()
+
+	h.routerRegistry = registry
+}
+
 func panicToError(p interface{}) error {
 	if p == nil {
 		return nil
@@ -97,9 +118,10 @@ func (h *handler) ServeHTTP(rw http.ResponseWriter, httpRequest *http.Request) {
 	defer span.End()
 
 	gs := h.getSession(ctx, httpRequest)
+	routerRegistry := h.registry()
 
 	_, span = trace.StartSpan(ctx, "router/matchRequest")
-	controller, params, handler := h.routerRegistry.matchRequest(httpRequest)
+	controller, params, handler := routerRegistry.matchRequest(httpRequest)
 
 	if handler != nil {
 		ctx, _ = tag.New(ctx, tag.Upsert(ControllerKey, handler.GetHandlerName()), tag.Insert(opencensus.KeyArea, "-"))
@@ -119,6 +141,10 @@ func (h *handler) ServeHTTP(rw http.ResponseWriter, httpRequest *http.Request) {
 	}
 	ctx = ContextWithRequest(ContextWithSession(ctx, req.Session()), req)
 
+	if h.sessionStore != nil {
+		req.session.applyPendingRegenerate()
+	}
+
 	var finishErr error
 	defer func() {
 		// fire finish event
@@ -141,7 +167,7 @@ func (h *handler) ServeHTTP(rw http.ResponseWriter, httpRequest *http.Request) {
 			defer func() {
 				if err := panicToError(recover()); err != nil {
 					h.logger.WithContext(ctx).Error(err)
-					response = h.routerRegistry.handler[FlamingoError].any(context.WithValue(ctx, RouterError, err), r)
+					response = routerRegistry.handler[FlamingoError].any(context.WithValue(ctx, RouterError, err), r)
 					span.SetStatus(trace.Status{Code: trace.StatusCodeAborted, Message: "controller panic"})
 				}
 			}()
@@ -155,7 +181,7 @@ func (h *handler) ServeHTTP(rw http.ResponseWriter, httpRequest *http.Request) {
 			} else {
 				err := errors.Errorf("action for method %q not found and no any fallback", req.Request().Method)
 				h.logger.WithContext(ctx).Warn(err)
-				response = h.routerRegistry.handler[FlamingoNotfound].any(context.WithValue(ctx, RouterError, err), r)
+				response = routerRegistry.handler[FlamingoNotfound].any(context.WithValue(ctx, RouterError, err), r)
 				span.SetStatus(trace.Status{Code: trace.StatusCodeNotFound, Message: "action not found"})
 			}
 
@@ -169,6 +195,11 @@ func (h *handler) ServeHTTP(rw http.ResponseWriter, httpRequest *http.Request) {
 		ctx, span := trace.StartSpan(ctx, "router/sessions/save")
 		if err := h.sessionStore.Save(req.Request(), rw, gs); err != nil {
 			h.logger.WithContext(ctx).Warn(err)
+		} else {
+			if previousIDs := req.session.takePreviousIDs(); len(previousIDs) > 0 {
+				h.deleteSessions(ctx, req, gs, previousIDs)
+				h.eventRouter.Dispatch(ctx, &SessionRegeneratedEvent{Request: req, Session: req.Session()})
+			}
 		}
 		span.End()
 	}
@@ -193,6 +224,10 @@ func (h *handler) ServeHTTP(rw http.ResponseWriter, httpRequest *http.Request) {
 	// ensure that the session has been saved in the backend
 	if h.sessionStore != nil {
 		ctx, span := trace.StartSpan(ctx, "router/sessions/persist")
+		if previousIDs := req.session.takePreviousIDs(); len(previousIDs) > 0 {
+			// the session was regenerated while the result was applied, the client can not get the new ID anymore
+			req.session.deferRegenerate(previousIDs)
+		}
 		if err := h.sessionStore.Save(req.Request(), emptyResponseWriter{}, gs); err != nil {
 			h.logger.WithContext(ctx).Warn(err)
 		}
@@ -214,7 +249,7 @@ func (h *handler) ServeHTTP(rw http.ResponseWriter, httpRequest *http.Request) {
 			}
 		}()
 
-		if err := h.routerRegistry.handler[FlamingoError].any(context.WithValue(ctx, RouterError, finalErr), req).Apply(ctx, rw); err != nil {
+		if err := routerRegistry.handler[FlamingoError].any(context.WithValue(ctx, RouterError, finalErr), req).Apply(ctx, rw); err != nil {
 			finishErr = err
 			h.logger.WithContext(ctx).Error(err)
 			rw.WriteHeader(http.StatusInternalServerError)
@@ -223,6 +258,31 @@ func (h *handler) ServeHTTP(rw http.ResponseWriter, httpRequest *http.Request) {
 	}
 }
 
+// deleteSessions removes the sessions replaced by Session.Regenerate from the store.
+// Stores without Delete method remove sessions which are saved with a negative MaxAge, as done by gorilla's stores.
+func (h *handler) deleteSessions(ctx context.Context, req *Request, gs *sessions.Session, ids []string) {
+	for _, id := range ids {
+		previous := sessions.NewSession(h.sessionStore, gs.Name())
+		previous.ID = id
+		options := sessions.Options{}
+		if gs.Options != nil {
+			options = *gs.Options
+		}
+		options.MaxAge = -1
+		previous.Options = &options
+
+		var err error
+		if deleter, ok := h.sessionStore.(sessionDeleter); ok {
+			err = deleter.Delete(req.Request(), emptyResponseWriter{}, previous)
+		} else {
+			err = h.sessionStore.Save(req.Request(), emptyResponseWriter{}, previous)
+		}
+		if err != nil {
+			h.logger.WithContext(ctx).Warn("failed to delete regenerated session: ", err)
+		}
+	}
+}
+
 // emptyResponseWriter to be able to properly persist sessions
 func (emptyResponseWriter) Header() http.Header        { return http.Header{} }
 func (emptyResponseWriter) Write([]byte) (int, error)  { return 0, io.ErrUnexpectedEOF }
@@ -26,10 +26,11 @@ type (
 
 	// Handler defines a concrete Controller
 	Handler struct {
-		path     *Path
-		handler  string
-		params   map[string]*param
-		catchall bool
+		path       *Path
+		handler    string
+		params     map[string]*param
+		catchall   bool
+		protection *RouteProtection
 	}
 
 	handlerAction struct {
@@ -492,6 +493,11 @@ func (handler *Handler) GetHandlerName() string {
 	return handler.handler
 }
 
+// GetProtection returns the protection of the route's controller declared in the routes.yml, nil for unprotected routes
+func (handler *Handler) GetProtection() *RouteProtection {
+	return handler.protection
+}
+
 // Normalize enforces a normalization of passed parameters
 func (handler *Handler) Normalize(params ...string) *Handler {
 	if handler.path.normalize == nil {
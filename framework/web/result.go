@@ -410,6 +410,13 @@ func (r *Responder) NotFound(err error) *ServerErrorResponse {
 	return r.ServerErrorWithCodeAndTemplate(err, r.templateNotFound, http.StatusNotFound)
 }
 
+// Unauthorized creates a 401 error response
+func (r *Responder) Unauthorized(err error) *ServerErrorResponse {
+	r.getLogger().Warn(err)
+
+	return r.ServerErrorWithCodeAndTemplate(err, r.templateErrorWithCode, http.StatusUnauthorized)
+}
+
 // Forbidden creates a 403 error response
 func (r *Responder) Forbidden(err error) *ServerErrorResponse {
 	r.getLogger().Warn(err)
@@ -0,0 +1,82 @@
+package web
+
+import (
+	"strings"
+
+	"flamingo.me/flamingo/v3/framework/config"
+)
+
+type (
+	// RouteProtection restricts the access to a route, declared in the routes.yml
+	RouteProtection struct {
+		// RequiresLogin restricts the route to logged in users
+		RequiresLogin bool
+		// Permission restricts the route to users granted the permission
+		Permission string
+		// Fallback is the route name or path to redirect to if the access is denied
+		Fallback string
+	}
+
+	// RouteGuard applies the protection of routes, e.g. the core/security module.
+	// It is registered via BindMulti, all guards wrap the actions of a protected route.
+	RouteGuard interface {
+		Guard(protection RouteProtection, action Action) Action
+	}
+
+	routeGuardProvider func() []RouteGuard
+)
+
+// routeProtection returns the protection of a route from the routes.yml, nil if the route is not protected
+func routeProtection(route config.Route) *RouteProtection {
+	if !route.RequiresLogin && route.Permission == "" {
+		return nil
+	}
+
+	return &RouteProtection{
+		RequiresLogin: route.RequiresLogin,
+		Permission:    route.Permission,
+		Fallback:      route.Fallback,
+	}
+}
+
+// String describes the protection, e.g. for the routes command
+func (p *RouteProtection) String() string {
+	if p == nil {
+		return ""
+	}
+
+	var parts []string
+	if p.RequiresLogin {
+		parts = append(parts, "login")
+	}
+	if p.Permission != "" {
+		parts = append(parts, "permission "+p.Permission)
+	}
+	if p.Fallback != "" {
+		parts = append(parts, "fallback "+p.Fallback)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// guard wraps all actions of the handler with the guards, data actions are not routed and stay unprotected
+func (ha handlerAction) guard(protection RouteProtection, guards []RouteGuard) handlerAction {
+	wrap := func(action Action) Action {
+		for _, guard := range guards {
+			action = guard.Guard(protection, action)
+		}
+		return action
+	}
+
+	guarded := handlerAction{
+		method: make(map[string]Action, len(ha.method)),
+		data:   ha.data,
+	}
+	for method, action := range ha.method {
+		guarded.method[method] = wrap(action)
+	}
+	if ha.any != nil {
+		guarded.any = wrap(ha.any)
+	}
+
+	return guarded
+}
@@ -8,6 +8,7 @@ import (
 	"reflect"
 	"strconv"
 	"strings"
+	"sync"
 
 	"flamingo.me/flamingo/v3/framework/config"
 	"flamingo.me/flamingo/v3/framework/flamingo"
@@ -38,11 +39,15 @@ type (
 		eventRouter    flamingo.EventRouter
 		filterProvider filterProvider
 		routesProvider routesProvider
+		routeGuards    routeGuardProvider
 		logger         flamingo.Logger
 		routerRegistry *RouterRegistry
-		configArea     *config.Area
-		sessionStore   sessions.Store
-		sessionName    string
+		registryMu     sync.RWMutex
+		// handlers are all handlers created by Handler, they are updated when the routes are reloaded
+		handlers     []*handler
+		configArea   *config.Area
+		sessionStore sessions.Store
+		sessionName  string
 	}
 )
 
@@ -67,6 +72,7 @@ func (r *Router) Inject(
 	eventRouter flamingo.EventRouter,
 	filterProvider filterProvider,
 	routesProvider routesProvider,
+	routeGuards routeGuardProvider,
 	logger flamingo.Logger,
 	configArea *config.Area,
 ) {
@@ -85,6 +91,7 @@ func (r *Router) Inject(
 	r.eventRouter = eventRouter
 	r.filterProvider = filterProvider
 	r.routesProvider = routesProvider
+	r.routeGuards = routeGuards
 	r.logger = logger
 	r.configArea = configArea
 	r.sessionStore = cfg.SessionStore
@@ -96,39 +103,150 @@ func (r *Router) Inject(
 
 // Handler creates and returns new instance of http.Handler interface
 func (r *Router) Handler() http.Handler {
-	r.routerRegistry = NewRegistry()
-
 	if r.base == nil {
 		r.base, _ = url.Parse("/")
 	}
 
+	registry, err := r.buildRegistry()
+	if err != nil {
+		panic(err)
+	}
+	h := &handler{
+		routerRegistry: registry,
+		filter:         r.filterProvider(),
+		eventRouter:    r.eventRouter,
+		logger:         r.logger.WithField(flamingo.LogKeyModule, "web").WithField(flamingo.LogKeyCategory, "handler"),
+		sessionStore:   r.sessionStore,
+		sessionName:    r.sessionName,
+		prefix:         strings.TrimRight(r.base.Path, "/"),
+	}
+
+	r.registryMu.Lock()
+	r.handlers = append(r.handlers, h)
+	r.registryMu.Unlock()
+	r.setRegistry(registry)
+
+	return h
+}
+
+// buildRegistry registers the routes of all routes modules and the config area
+func (r *Router) buildRegistry() (*RouterRegistry, error) {
+	registry := NewRegistry()
+
 	for _, m := range r.routesProvider() {
-		m.Routes(r.routerRegistry)
+		m.Routes(registry)
 	}
 
 	if r.configArea != nil {
-		for _, route := range r.configArea.Routes {
-			r.routerRegistry.Route(route.Path, route.Controller)
+		for _, route := range r.configArea.GetRoutes() {
+			if handler, err := registry.Route(route.Path, route.Controller); err == nil {
+				handler.protection = routeProtection(route)
+			}
 			if route.Name != "" {
-				r.routerRegistry.Alias(route.Name, route.Controller)
+				registry.Alias(route.Name, route.Controller)
 			}
 		}
 	}
 
-	for _, handler := range r.routerRegistry.routes {
-		if _, ok := r.routerRegistry.handler[handler.handler]; !ok {
-			panic(errors.Errorf("The handler %q has no controller, registered for path %q", handler.handler, handler.path.path))
+	for _, handler := range registry.routes {
+		if _, ok := registry.handler[handler.handler]; !ok {
+			return nil, errors.Errorf("The handler %q has no controller, registered for path %q", handler.handler, handler.path.path)
 		}
 	}
 
-	return &handler{
-		routerRegistry: r.routerRegistry,
-		filter:         r.filterProvider(),
-		eventRouter:    r.eventRouter,
-		logger:         r.logger.WithField(flamingo.LogKeyModule, "web").WithField(flamingo.LogKeyCategory, "handler"),
-		sessionStore:   r.sessionStore,
-		sessionName:    r.sessionName,
-		prefix:         strings.TrimRight(r.base.Path, "/"),
+	if err := r.guardRoutes(registry); err != nil {
+		return nil, err
+	}
+
+	return registry, nil
+}
+
+// guardRoutes applies the route guards to the controllers of protected routes, so the controller is protected on
+// every route, e.g. also if a module registers the same path. Protected routes without guard are an error, as well
+// as different protections for the same controller and protected paths also routed to unprotected controllers.
+func (r *Router) guardRoutes(registry *RouterRegistry) error {
+	protections := make(map[string]*RouteProtection)
+	for _, route := range registry.routes {
+		if route.protection == nil {
+			continue
+		}
+		if protection, ok := protections[route.handler]; ok && *protection != *route.protection {
+			return errors.Errorf("The controller %q is protected differently on path %q (%s) and on another path (%s)", route.handler, route.path.path, route.protection, protection)
+		}
+		protections[route.handler] = route.protection
+	}
+
+	if len(protections) == 0 {
+		return nil
+	}
+
+	for _, route := range registry.routes {
+		if route.protection == nil {
+			continue
+		}
+		for _, other := range registry.routes {
+			if _, protected := protections[other.handler]; !protected && other.path.path == route.path.path {
+				return errors.Errorf("The path %q is protected (%s), but also routed to the unprotected controller %q", route.path.path, route.protection, other.handler)
+			}
+		}
+	}
+
+	var guards []RouteGuard
+	if r.routeGuards != nil {
+		guards = r.routeGuards()
+	}
+
+	for name, protection := range protections {
+		if len(guards) == 0 {
+			return errors.Errorf("The controller %q is protected (%s), but no route guard is registered, e.g. by the security module", name, protection)
+		}
+		registry.handler[name] = registry.handler[name].guard(*protection, guards)
+	}
+
+	for _, route := range registry.routes {
+		if protection, ok := protections[route.handler]; ok {
+			route.protection = protection
+		}
+	}
+
+	return nil
+}
+
+func (r *Router) registry() *RouterRegistry {
+	r.registryMu.RLock()
+	defer r.registryMu.RUnlock()
+
+	return r.routerRegistry
+}
+
+func (r *Router) setRegistry(registry *RouterRegistry) {
+	r.registryMu.Lock()
+	defer r.registryMu.Unlock()
+
+	r.routerRegistry = registry
+	for _, h := range r.handlers {
+		h.setRegistry(registry)
+	}
+}
+
+// serving checks if a handler has been created, only then the routes are reloaded
+func (r *Router) serving() bool {
+	r.registryMu.RLock()
+	defer r.registryMu.RUnlock()
+
+	return len(r.handlers) > 0
+}
+
+// Notify rebuilds the routes when the configuration of the router's area has been reloaded
+func (r *Router) Notify(ctx context.Context, event flamingo.Event) {
+	if e, ok := event.(*config.ChangedEvent); ok && e.Area == r.configArea && r.serving() {
+		registry, err := r.buildRegistry()
+		if err != nil {
+			r.logger.WithContext(ctx).Error("routes reload failed, keeping previous routes: ", err)
+			return
+		}
+		r.setRegistry(registry)
+		r.logger.WithContext(ctx).Info("routes reloaded for area ", r.configArea.Name)
 	}
 }
 
@@ -160,7 +278,7 @@ func (r *Router) relative(to string, params map[string]string) (string, error) {
 		return to, nil
 	}
 
-	p, err := r.routerRegistry.Reverse(to, params)
+	p, err := r.registry().Reverse(to, params)
 	return strings.TrimLeft(p, "/"), err
 }
 
@@ -257,7 +375,7 @@ func (r *Router) Data(ctx context.Context, handler string, params map[interface{
 
 	req := RequestFromContext(ctx)
 
-	if c, ok := r.routerRegistry.handler[handler]; ok {
+	if c, ok := r.registry().handler[handler]; ok {
 		if c.data != nil {
 			return c.data(ctx, req, dataParams(params))
 		}
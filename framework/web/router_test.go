@@ -12,6 +12,7 @@ import (
 	"github.com/gorilla/sessions"
 	"github.com/stretchr/testify/assert"
 
+	"flamingo.me/flamingo/v3/framework/config"
 	"flamingo.me/flamingo/v3/framework/flamingo"
 )
 
@@ -203,7 +204,7 @@ func TestRouterRelativeAndAbsolute(t *testing.T) {
 			Path:        path,
 			External:    external,
 			SessionName: "test",
-		}, new(flamingo.DefaultEventRouter), func() []Filter { return nil }, func() []RoutesModule { return nil }, flamingo.NullLogger{}, nil)
+		}, new(flamingo.DefaultEventRouter), func() []Filter { return nil }, func() []RoutesModule { return nil }, func() []RouteGuard { return nil }, flamingo.NullLogger{}, nil)
 
 		registry.HandleGet("test", func(context.Context, *Request) Result {
 			return &Response{}
@@ -523,3 +524,148 @@ func TestRouterRelativeAndAbsolute(t *testing.T) {
 		assert.Equal(t, "http://external.domain/external-path/test", absoluteURL.String())
 	})
 }
+
+type testRoutesModule struct{}
+
+func (testRoutesModule) Routes(registry *RouterRegistry) {
+	registry.HandleAny("home", func(context.Context, *Request) Result { return nil })
+}
+
+func TestRouterReloadRoutes(t *testing.T) {
+	area := config.NewArea("root", nil)
+	area.Routes = []config.Route{{Path: "/", Controller: "home"}}
+
+	router := &Router{
+		eventRouter:    new(flamingo.DefaultEventRouter),
+		filterProvider: func() []Filter { return nil },
+		routesProvider: func() []RoutesModule { return []RoutesModule{testRoutesModule{}} },
+		logger:         flamingo.NullLogger{},
+		configArea:     area,
+	}
+
+	h := router.Handler()
+	other := router.Handler()
+
+	path, err := router.registry().Reverse("home", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "/", path)
+
+	t.Run("other areas are ignored", func(t *testing.T) {
+		area.Routes = []config.Route{{Path: "/home", Controller: "home"}}
+		router.Notify(context.Background(), &config.ChangedEvent{Area: config.NewArea("other", nil)})
+
+		path, err := router.registry().Reverse("home", nil)
+		assert.NoError(t, err)
+		assert.Equal(t, "/", path)
+	})
+
+	t.Run("routes are reloaded", func(t *testing.T) {
+		router.Notify(context.Background(), &config.ChangedEvent{Area: area})
+
+		path, err := router.registry().Reverse("home", nil)
+		assert.NoError(t, err)
+		assert.Equal(t, "/home", path)
+		assert.Equal(t, router.registry(), h.(*handler).registry())
+		assert.Equal(t, router.registry(), other.(*handler).registry(), "all handlers get the reloaded routes")
+	})
+
+	t.Run("invalid routes keep the previous routes", func(t *testing.T) {
+		area.Routes = []config.Route{{Path: "/unknown", Controller: "unknown"}}
+		router.Notify(context.Background(), &config.ChangedEvent{Area: area})
+
+		path, err := router.registry().Reverse("home", nil)
+		assert.NoError(t, err)
+		assert.Equal(t, "/home", path)
+	})
+}
+
+type testRouteGuard struct{}
+
+func (testRouteGuard) Guard(protection RouteProtection, action Action) Action {
+	return func(ctx context.Context, r *Request) Result {
+		if r.Request().Header.Get("X-Permission") != protection.Permission {
+			return &Response{Status: http.StatusForbidden}
+		}
+		return action(ctx, r)
+	}
+}
+
+type testAdminRoutesModule struct{}
+
+func (testAdminRoutesModule) Routes(registry *RouterRegistry) {
+	registry.HandleAny("admin", func(context.Context, *Request) Result { return nil })
+	_, _ = registry.Route("/admin", "admin")
+}
+
+func TestRouterProtectedRoutes(t *testing.T) {
+	area := config.NewArea("root", nil)
+	area.Routes = []config.Route{
+		{Path: "/", Controller: "home"},
+		{Path: "/admin", Controller: "admin", Permission: "PermissionAdmin", Fallback: "/"},
+		{Path: "/backoffice", Controller: "admin"},
+	}
+
+	router := &Router{
+		eventRouter:    new(flamingo.DefaultEventRouter),
+		filterProvider: func() []Filter { return nil },
+		routesProvider: func() []RoutesModule { return []RoutesModule{testRoutesModule{}, testAdminRoutesModule{}} },
+		logger:         flamingo.NullLogger{},
+		configArea:     area,
+	}
+
+	t.Run("protected routes need a guard", func(t *testing.T) {
+		_, err := router.buildRegistry()
+		assert.Error(t, err)
+	})
+
+	router.routeGuards = func() []RouteGuard { return []RouteGuard{testRouteGuard{}} }
+
+	t.Run("protected paths must not be routed to unprotected controllers", func(t *testing.T) {
+		area.Routes = []config.Route{
+			{Path: "/", Controller: "home"},
+			{Path: "/", Controller: "admin", Permission: "PermissionAdmin"},
+		}
+		_, err := router.buildRegistry()
+		assert.Error(t, err)
+	})
+
+	t.Run("controllers must not be protected differently", func(t *testing.T) {
+		area.Routes = []config.Route{
+			{Path: "/admin", Controller: "admin", Permission: "PermissionAdmin"},
+			{Path: "/backoffice", Controller: "admin", RequiresLogin: true},
+		}
+		_, err := router.buildRegistry()
+		assert.Error(t, err)
+	})
+
+	area.Routes = []config.Route{
+		{Path: "/", Controller: "home"},
+		{Path: "/admin", Controller: "admin", Permission: "PermissionAdmin", Fallback: "/"},
+		{Path: "/backoffice", Controller: "admin"},
+	}
+	server := httptest.NewServer(router.Handler())
+	defer server.Close()
+
+	status := func(path, permission string) int {
+		request, err := http.NewRequest(http.MethodGet, server.URL+path, nil)
+		assert.NoError(t, err)
+		request.Header.Set("X-Permission", permission)
+		response, err := http.DefaultClient.Do(request)
+		assert.NoError(t, err)
+		assert.NoError(t, response.Body.Close())
+		return response.StatusCode
+	}
+
+	assert.Equal(t, http.StatusOK, status("/", ""))
+	assert.Equal(t, http.StatusForbidden, status("/admin", ""), "the module route of the path is protected")
+	assert.Equal(t, http.StatusOK, status("/admin", "PermissionAdmin"))
+	assert.Equal(t, http.StatusForbidden, status("/backoffice", ""), "other routes of the controller are protected")
+
+	for _, route := range router.registry().GetRoutes() {
+		if route.GetHandlerName() == "admin" {
+			assert.Equal(t, "permission PermissionAdmin, fallback /", route.GetProtection().String())
+		} else {
+			assert.Nil(t, route.GetProtection())
+		}
+	}
+}
@@ -46,17 +46,24 @@ func dumpRoutes(router *Router, area *config.Area) {
 	if router == nil {
 		return
 	}
-	if router.routerRegistry == nil {
+	registry := router.registry()
+	if registry == nil {
 		router.Handler()
+		registry = router.registry()
 	}
 	fmt.Println()
 	fmt.Println("***************************************************************************")
-	fmt.Println(" Route                						| Handler-Name:               ")
+	fmt.Println(" Route                						| Handler-Name:               | Protection:")
 	fmt.Println("****************************************************************************")
-	for _, routeHandler := range router.routerRegistry.routes {
+	for _, routeHandler := range registry.routes {
 		routePath := routeHandler.path.path + "(" + strings.Join(routeHandler.path.params, ";") + ")"
 		spaceAmount1 := int(math.Max(0, float64(60-len(routePath))))
-		fmt.Printf("    %s%s| %s\n", routePath, strings.Repeat(" ", spaceAmount1), routeHandler.handler)
+		protection := ""
+		if routeHandler.protection != nil {
+			spaceAmount2 := int(math.Max(0, float64(28-len(routeHandler.handler))))
+			protection = strings.Repeat(" ", spaceAmount2) + "| " + routeHandler.protection.String()
+		}
+		fmt.Printf("    %s%s| %s%s\n", routePath, strings.Repeat(" ", spaceAmount1), routeHandler.handler, protection)
 	}
 }
 
@@ -64,8 +71,10 @@ func dumpHandler(router *Router, area *config.Area) {
 	if router == nil {
 		return
 	}
-	if router.routerRegistry == nil {
+	registry := router.registry()
+	if registry == nil {
 		router.Handler()
+		registry = router.registry()
 	}
 	// router.Init(area)
 	fmt.Println()
@@ -73,9 +82,9 @@ func dumpHandler(router *Router, area *config.Area) {
 	fmt.Println(" Handle-name                	 | registered actions               ")
 	fmt.Println("****************************************************************************")
 
-	handlerNamesSorted := getSortedMapKeys(router.routerRegistry.handler)
+	handlerNamesSorted := getSortedMapKeys(registry.handler)
 	for _, handlerKey := range handlerNamesSorted {
-		handler := router.routerRegistry.handler[handlerKey]
+		handler := registry.handler[handlerKey]
 		var actions []string
 		if handler.data != nil {
 			actions = append(actions, "DATA")
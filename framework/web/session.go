@@ -2,8 +2,11 @@ package web
 
 import (
 	"context"
+	"crypto/rand"
 	"crypto/sha256"
+	"encoding/base32"
 	"fmt"
+	"strings"
 	"sync"
 
 	"github.com/gorilla/sessions"
@@ -14,10 +17,15 @@ type Session struct {
 	mu       sync.RWMutex
 	s        *sessions.Session
 	hashedid string
+	// previousIDs are replaced by Regenerate, they are removed from the session store when the session is saved
+	previousIDs []string
 }
 
 const contextSession contextKeyType = "session"
 
+// sessionRegeneratePending marks a session which was regenerated after the response has been written
+const sessionRegeneratePending = "flamingo.session.regeneratePending"
+
 // EmptySession creates an empty session instance for testing etc.
 func EmptySession() *Session {
 	return &Session{s: sessions.NewSession(nil, "")}
@@ -78,6 +86,73 @@ func (s *Session) ID() (id string) {
 	return s.s.ID
 }
 
+// Regenerate assigns a new ID to the session while keeping all values, including flashes.
+// It prevents session fixation and should be called whenever the privileges of the session change, e.g. on login.
+// The new ID is assigned immediately and the session is stored with it at the end of the request,
+// afterwards the session with the previous ID is removed from the store.
+// If the response has already been written, e.g. when called while a template is rendered, the session keeps its ID
+// and is regenerated with the next request.
+func (s *Session) Regenerate() *Session {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.s.ID != "" {
+		s.previousIDs = append(s.previousIDs, s.s.ID)
+	}
+
+	// copy the values, so they are not shared with the previous session in stores which keep them in memory
+	values := make(map[interface{}]interface{}, len(s.s.Values))
+	for k, v := range s.s.Values {
+		values[k] = v
+	}
+	s.s.Values = values
+	s.s.ID = newSessionID()
+	s.s.IsNew = true
+	s.hashedid = ""
+
+	return s
+}
+
+// newSessionID generates a random ID in the format of the gorilla session stores, which keep a given ID when saving
+func newSessionID() string {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		// the store generates an ID when saving the session
+		return ""
+	}
+	return strings.TrimRight(base32.StdEncoding.EncodeToString(key), "=")
+}
+
+// takePreviousIDs returns the IDs replaced by Regenerate since the last call
+func (s *Session) takePreviousIDs() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ids := s.previousIDs
+	s.previousIDs = nil
+	return ids
+}
+
+// deferRegenerate undoes a Regenerate whose new ID can not be sent anymore, because the response has been written.
+// The session keeps the ID of the client and is regenerated with the next request, see applyPendingRegenerate.
+func (s *Session) deferRegenerate(previousIDs []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.s.ID = previousIDs[0]
+	s.s.IsNew = false
+	s.hashedid = ""
+	s.s.Values[sessionRegeneratePending] = true
+}
+
+// applyPendingRegenerate regenerates the session if a regeneration was deferred by the previous request
+func (s *Session) applyPendingRegenerate() {
+	if _, pending := s.Load(sessionRegeneratePending); pending {
+		s.Delete(sessionRegeneratePending)
+		s.Regenerate()
+	}
+}
+
 // Keys returns an unordered list of session keys
 func (s *Session) Keys() []interface{} {
 	s.mu.RLock()
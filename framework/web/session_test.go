@@ -0,0 +1,187 @@
+package web
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"testing"
+
+	"github.com/gorilla/sessions"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"flamingo.me/flamingo/v3/framework/flamingo"
+)
+
+func TestSession_Regenerate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sessions")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	router := &Router{
+		eventRouter:    new(flamingo.DefaultEventRouter),
+		filterProvider: func() []Filter { return nil },
+		routesProvider: func() []RoutesModule { return nil },
+		logger:         flamingo.NullLogger{},
+		sessionStore:   sessions.NewFilesystemStore(dir, []byte("secret")),
+		sessionName:    "flamingo",
+	}
+
+	var id, regeneratedID string
+	var value interface{}
+	var flashes []interface{}
+
+	registry := NewRegistry()
+	registry.HandleGet("set", func(_ context.Context, r *Request) Result {
+		r.Session().Store("value", "before login")
+		r.Session().AddFlash("flash")
+		return nil
+	})
+	registry.HandleGet("login", func(_ context.Context, r *Request) Result {
+		previousID := r.Session().ID()
+		regeneratedID = r.Session().Regenerate().ID()
+		assert.NotEmpty(t, regeneratedID, "the new id is assigned immediately")
+		assert.NotEqual(t, previousID, regeneratedID)
+		return nil
+	})
+	registry.HandleGet("get", func(_ context.Context, r *Request) Result {
+		id = r.Session().ID()
+		value, _ = r.Session().Load("value")
+		flashes = r.Session().Flashes()
+		return nil
+	})
+	for _, route := range []string{"set", "login", "get"} {
+		_, err := registry.Route("/"+route, route)
+		require.NoError(t, err)
+	}
+
+	h := router.Handler()
+	h.(*handler).routerRegistry = registry
+
+	server := httptest.NewServer(h)
+	defer server.Close()
+	serverURL, err := url.Parse(server.URL)
+	require.NoError(t, err)
+
+	jar, err := cookiejar.New(nil)
+	require.NoError(t, err)
+	client := &http.Client{Jar: jar}
+
+	get := func(client *http.Client, path string) {
+		res, err := client.Get(server.URL + path)
+		require.NoError(t, err)
+		require.NoError(t, res.Body.Close())
+	}
+
+	get(client, "/set")
+	get(client, "/get")
+	previousID := id
+	require.NotEmpty(t, previousID)
+	previousCookies := jar.Cookies(serverURL)
+
+	get(client, "/set")
+	get(client, "/login")
+	get(client, "/get")
+	assert.NotEqual(t, previousID, id, "the session has a new id")
+	assert.Equal(t, regeneratedID, id, "the session is stored with the id assigned by Regenerate")
+	assert.Equal(t, "before login", value, "values are kept")
+	assert.Equal(t, []interface{}{"flash"}, flashes, "flashes are kept")
+
+	// the previous session is removed from the store, its id only gets an empty session
+	attackerJar, err := cookiejar.New(nil)
+	require.NoError(t, err)
+	attackerJar.SetCookies(serverURL, previousCookies)
+	get(&http.Client{Jar: attackerJar}, "/get")
+	assert.Nil(t, value)
+}
+
+// applyFunc is a result which runs a function when it is applied, e.g. to simulate template functions
+type applyFunc func(ctx context.Context, rw http.ResponseWriter) error
+
+func (f applyFunc) Apply(ctx context.Context, rw http.ResponseWriter) error {
+	return f(ctx, rw)
+}
+
+func TestSession_RegenerateDuringApply(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sessions")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	router := &Router{
+		eventRouter:    new(flamingo.DefaultEventRouter),
+		filterProvider: func() []Filter { return nil },
+		routesProvider: func() []RoutesModule { return nil },
+		logger:         flamingo.NullLogger{},
+		sessionStore:   sessions.NewFilesystemStore(dir, []byte("secret")),
+		sessionName:    "flamingo",
+	}
+
+	var id string
+	var value interface{}
+
+	registry := NewRegistry()
+	registry.HandleGet("set", func(_ context.Context, r *Request) Result {
+		r.Session().Store("value", "roles")
+		return nil
+	})
+	registry.HandleGet("render", func(_ context.Context, r *Request) Result {
+		return applyFunc(func(ctx context.Context, rw http.ResponseWriter) error {
+			_, err := rw.Write([]byte("rendered"))
+			// e.g. the roles of the session changed while the template is rendered
+			r.Session().Regenerate()
+			return err
+		})
+	})
+	registry.HandleGet("get", func(_ context.Context, r *Request) Result {
+		id = r.Session().ID()
+		value, _ = r.Session().Load("value")
+		return nil
+	})
+	for _, route := range []string{"set", "render", "get"} {
+		_, err := registry.Route("/"+route, route)
+		require.NoError(t, err)
+	}
+
+	h := router.Handler()
+	h.(*handler).routerRegistry = registry
+
+	server := httptest.NewServer(h)
+	defer server.Close()
+	serverURL, err := url.Parse(server.URL)
+	require.NoError(t, err)
+
+	jar, err := cookiejar.New(nil)
+	require.NoError(t, err)
+	client := &http.Client{Jar: jar}
+
+	get := func(client *http.Client, path string) {
+		res, err := client.Get(server.URL + path)
+		require.NoError(t, err)
+		require.NoError(t, res.Body.Close())
+	}
+
+	get(client, "/set")
+	get(client, "/get")
+	previousID := id
+	require.NotEmpty(t, previousID)
+	previousCookies := jar.Cookies(serverURL)
+
+	get(client, "/render")
+	get(client, "/get")
+	assert.NotEmpty(t, id)
+	assert.NotEqual(t, previousID, id, "the regeneration is applied with the next request")
+	regeneratedID := id
+	get(client, "/get")
+	assert.Equal(t, regeneratedID, id, "the session has a new id")
+	assert.Equal(t, "roles", value, "values are kept")
+
+	attackerJar, err := cookiejar.New(nil)
+	require.NoError(t, err)
+	attackerJar.SetCookies(serverURL, previousCookies)
+	get(&http.Client{Jar: attackerJar}, "/get")
+	assert.Nil(t, value, "the previous session is removed from the store")
+}